@@ -8,10 +8,15 @@ package cli
 // ////////////////////////////////////////////////////////////////////////////////// //
 
 import (
+	gocontext "context"
 	"fmt"
 	"os"
 	"os/exec"
+	"slices"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/essentialkaos/ek/v13/errors"
 	"github.com/essentialkaos/ek/v13/fmtc"
@@ -22,6 +27,7 @@ import (
 	"github.com/essentialkaos/ek/v13/pager"
 	"github.com/essentialkaos/ek/v13/progress"
 	"github.com/essentialkaos/ek/v13/signal"
+	"github.com/essentialkaos/ek/v13/sortutil"
 	"github.com/essentialkaos/ek/v13/strutil"
 	"github.com/essentialkaos/ek/v13/support"
 	"github.com/essentialkaos/ek/v13/support/deps"
@@ -29,6 +35,7 @@ import (
 	"github.com/essentialkaos/ek/v13/terminal"
 	"github.com/essentialkaos/ek/v13/terminal/input"
 	"github.com/essentialkaos/ek/v13/terminal/tty"
+	"github.com/essentialkaos/ek/v13/timeutil"
 	"github.com/essentialkaos/ek/v13/usage"
 	"github.com/essentialkaos/ek/v13/usage/completion/bash"
 	"github.com/essentialkaos/ek/v13/usage/completion/fish"
@@ -41,7 +48,10 @@ import (
 	knfr "github.com/essentialkaos/ek/v13/knf/validators/regexp"
 	knfs "github.com/essentialkaos/ek/v13/knf/validators/system"
 
+	"github.com/essentialkaos/rep/v3/repo"
 	"github.com/essentialkaos/rep/v3/repo/index"
+	"github.com/essentialkaos/rep/v3/repo/storage"
+	"github.com/essentialkaos/rep/v3/repo/storage/fs"
 )
 
 // ////////////////////////////////////////////////////////////////////////////////// //
@@ -75,6 +85,14 @@ const (
 	COMMAND_REINDEX      = "reindex"
 	COMMAND_PURGE_CACHE  = "purge-cache"
 	COMMAND_STATS        = "stats"
+	COMMAND_DEP_GRAPH    = "dep-graph"
+	COMMAND_EXPORT       = "export"
+	COMMAND_IMPORT       = "import"
+	COMMAND_DIFF         = "diff"
+	COMMAND_DOWNLOAD     = "download"
+	COMMAND_TAG          = "tag"
+	COMMAND_METRICS      = "metrics"
+	COMMAND_VERIFY       = "verify"
 	COMMAND_HELP         = "help"
 )
 
@@ -98,15 +116,41 @@ const (
 	COMMAND_SHORT_REINDEX      = "ri"
 	COMMAND_SHORT_PURGE_CACHE  = "pc"
 	COMMAND_SHORT_STATS        = "st"
+	COMMAND_SHORT_DEP_GRAPH    = "dg"
+	COMMAND_SHORT_EXPORT       = "ex"
+	COMMAND_SHORT_IMPORT       = "im"
+	COMMAND_SHORT_DIFF         = "df"
+	COMMAND_SHORT_DOWNLOAD     = "dl"
+	COMMAND_SHORT_TAG          = "tg"
+	COMMAND_SHORT_METRICS      = "mt"
+	COMMAND_SHORT_VERIFY       = "vf"
 	COMMAND_SHORT_HELP         = "h"
 )
 
 // Global preferences
 const (
-	STORAGE_TYPE        = "storage:type"
-	STORAGE_DATA        = "storage:data"
-	STORAGE_CACHE       = "storage:cache"
-	STORAGE_SPLIT_FILES = "storage:split-files"
+	STORAGE_TYPE             = "storage:type"
+	STORAGE_DATA             = "storage:data"
+	STORAGE_CACHE            = "storage:cache"
+	STORAGE_SPLIT_FILES      = "storage:split-files"
+	STORAGE_SPLIT_DEPTH      = "storage:split-depth"
+	STORAGE_LAYOUT           = "storage:layout"
+	STORAGE_NESTED_CACHE     = "storage:nested-cache"
+	STORAGE_HARD_LINK        = "storage:hard-link"
+	STORAGE_VERIFY_COPY      = "storage:verify-copy"
+	STORAGE_MIN_FREE_SPACE   = "storage:min-free-space"
+	STORAGE_MEM_CACHE        = "storage:mem-cache"
+	STORAGE_MEM_CACHE_MAX    = "storage:mem-cache-max-size"
+	STORAGE_ATTRS_RETRIES    = "storage:attrs-retries"
+	STORAGE_SKIP_DBS         = "storage:skip-dbs"
+	STORAGE_MAINTENANCE_FLAG = "storage:maintenance-flag"
+
+	S3_ENDPOINT   = "s3:endpoint"
+	S3_REGION     = "s3:region"
+	S3_BUCKET     = "s3:bucket"
+	S3_PREFIX     = "s3:prefix"
+	S3_ACCESS_KEY = "s3:access-key"
+	S3_SECRET_KEY = "s3:secret-key"
 
 	INDEX_CHECKSUM         = "index:checksum"
 	INDEX_PRETTY           = "index:pretty"
@@ -114,35 +158,68 @@ const (
 	INDEX_SPLIT            = "index:split"
 	INDEX_SKIP_SYMLINKS    = "index:skip-symlinks"
 	INDEX_CHANGELOG_LIMIT  = "index:changelog-limit"
+	INDEX_RETAIN_OLD_MD    = "index:retain-old-md"
 	INDEX_MD_FILENAMES     = "index:md-filenames"
 	INDEX_DISTRO           = "index:distro"
 	INDEX_CONTENT          = "index:content"
 	INDEX_REVISION         = "index:revision"
+	INDEX_LOCATION_PREFIX  = "index:location-prefix"
 	INDEX_DELTAS           = "index:deltas"
 	INDEX_NUM_DELTAS       = "index:num-deltas"
 	INDEX_WORKERS          = "index:workers"
 	INDEX_COMPRESSION_TYPE = "index:compression-type"
+	INDEX_ZCHUNK           = "index:zchunk"
+	INDEX_NO_DATABASE      = "index:no-database"
 
 	LOG_DIR_PERMS  = "log:dir-perms"
 	LOG_FILE_PERMS = "log:file-perms"
 	LOG_DIR        = "log:dir"
 
 	TEMP_DIR = "temp:dir"
+
+	LOCK_TIMEOUT = "lock:timeout"
+	LOCK_WAIT    = "lock:wait"
+
+	UI_PAGER = "ui:pager"
+)
+
+const (
+	LOCK_TIMEOUT_DEFAULT = 5 * time.Minute // Default lock expiration time
+	LOCK_WAIT_DEFAULT    = 5 * time.Minute // Default lock waiting time
 )
 
+// PAGER_ENV is name of environment variable with pager command
+const PAGER_ENV = "REP_PAGER"
+
 // Repository preferences
 const (
 	REPOSITORY_NAME        = "repository:name"
 	REPOSITORY_FILE_FILTER = "repository:file-filter"
 	REPOSITORY_REPLACE     = "repository:replace"
+	REPOSITORY_GROUP_FILE  = "repository:group-file"
+
+	REPOSITORY_MAX_VERSIONS = "repository:max-versions"
+	REPOSITORY_AUTO_REINDEX = "repository:auto-reindex"
+	REPOSITORY_CACHE_INFO   = "repository:cache-info"
+
+	REPOSITORY_REQUIRE_FIELDS = "repository:require-fields"
 
 	PERMISSIONS_USER  = "permissions:user"
 	PERMISSIONS_GROUP = "permissions:group"
 	PERMISSIONS_FILE  = "permissions:file"
 	PERMISSIONS_DIR   = "permissions:dir"
 
-	SIGN_REQUIRED = "sign:required"
-	SIGN_KEY      = "sign:key"
+	SIGN_REQUIRED     = "sign:required"
+	SIGN_MODE         = "sign:mode"
+	SIGN_KEY          = "sign:key"
+	SIGN_KEY_ID       = "sign:key-id"
+	SIGN_TRUSTED_KEYS = "sign:trusted-keys"
+)
+
+// Signing modes
+const (
+	SIGN_MODE_FILE  = "file"
+	SIGN_MODE_AGENT = "agent"
 )
 
 // ////////////////////////////////////////////////////////////////////////////////// //
@@ -155,14 +232,34 @@ const (
 	OPT_ARCH           = "aa:arch"
 	OPT_MOVE           = "m:move"
 	OPT_NO_SOURCE      = "ns:no-source"
+	OPT_ONLY_SOURCE    = "os:only-src"
 	OPT_IGNORE_FILTER  = "if:ignore-filter"
 	OPT_POSTPONE_INDEX = "pi:postpone-index"
 	OPT_FORCE          = "f:force"
 	OPT_FULL           = "F:full"
 	OPT_SHOW_ALL       = "A:show-all"
+	OPT_KEEP_SOURCE    = "ks:keep-source"
 	OPT_EPOCH          = "E:epoch"
 	OPT_STATUS         = "S:status"
 	OPT_PAGER          = "P:pager"
+	OPT_FIELDS         = "fl:fields"
+	OPT_SORT           = "so:sort"
+	OPT_RELEASE_ONLY   = "ro:release-only"
+	OPT_TIMEOUT        = "T:timeout"
+	OPT_FILE           = "ff:file"
+	OPT_ALL_REPOS      = "ar:all-repos"
+	OPT_WHY            = "w:why"
+	OPT_ATOMIC         = "at:atomic"
+	OPT_DIFF           = "df:diff"
+	OPT_SAVE           = "sv:save"
+	OPT_SUMMARY        = "sm:summary"
+	OPT_TAG            = "tg:tag"
+	OPT_DRY_RUN        = "dr:dry-run"
+	OPT_JSON           = "j:json"
+	OPT_BINARIES       = "bn:binaries"
+	OPT_FIX            = "fx:fix"
+	OPT_SYNC_NOARCH    = "sn:sync-noarch"
+	OPT_VERBOSE        = "V:verbose"
 	OPT_NO_COLOR       = "nc:no-color"
 	OPT_HELP           = "h:help"
 	OPT_VER            = "v:version"
@@ -192,14 +289,34 @@ var optMap = options.Map{
 	OPT_ALL:            {Type: options.BOOL},
 	OPT_MOVE:           {Type: options.BOOL},
 	OPT_NO_SOURCE:      {Type: options.BOOL},
+	OPT_ONLY_SOURCE:    {Type: options.BOOL},
 	OPT_IGNORE_FILTER:  {Type: options.BOOL},
 	OPT_POSTPONE_INDEX: {Type: options.BOOL},
 	OPT_FORCE:          {Type: options.BOOL},
 	OPT_FULL:           {Type: options.BOOL},
 	OPT_SHOW_ALL:       {Type: options.BOOL},
+	OPT_KEEP_SOURCE:    {Type: options.BOOL},
 	OPT_EPOCH:          {Type: options.BOOL},
 	OPT_STATUS:         {Type: options.BOOL},
 	OPT_PAGER:          {Type: options.BOOL},
+	OPT_FIELDS:         {},
+	OPT_SORT:           {},
+	OPT_RELEASE_ONLY:   {Type: options.BOOL},
+	OPT_TIMEOUT:        {},
+	OPT_FILE:           {Type: options.BOOL},
+	OPT_ALL_REPOS:      {Type: options.BOOL},
+	OPT_WHY:            {Type: options.BOOL},
+	OPT_ATOMIC:         {Type: options.BOOL},
+	OPT_DIFF:           {Type: options.BOOL},
+	OPT_SAVE:           {Type: options.BOOL},
+	OPT_SUMMARY:        {Type: options.BOOL},
+	OPT_TAG:            {},
+	OPT_DRY_RUN:        {Type: options.BOOL},
+	OPT_JSON:           {Type: options.BOOL},
+	OPT_BINARIES:       {Type: options.BOOL},
+	OPT_FIX:            {Type: options.BOOL},
+	OPT_SYNC_NOARCH:    {Type: options.BOOL},
+	OPT_VERBOSE:        {Type: options.BOOL},
 	OPT_NO_COLOR:       {Type: options.BOOL},
 	OPT_HELP:           {Type: options.BOOL},
 	OPT_VER:            {Type: options.MIXED},
@@ -216,18 +333,51 @@ var repoNamePattern = `[0-9a-zA-Z_\-]+`
 
 // ////////////////////////////////////////////////////////////////////////////////// //
 
+// globalConfig contains global configuration
+var globalConfig *knf.Config
+
 // configs contains repositories configs
 var configs map[string]*knf.Config
 
-// isCanceled is a flag for marking that user want to cancel app execution
-var isCanceled = false
+// isCanceled is a flag for marking that user want to cancel app execution. Flag
+// is atomic because it is set by signal handler and read by parallel workers.
+var isCanceled atomic.Bool
 
 // isCancelProtected is a flag for marking current execution from canceling
 var isCancelProtected = false
 
+// cancelCtx is context which will be canceled on termination signal or timeout
+// and used for interrupting long-running cancel protected operations
+var cancelCtx, cancelFunc = gocontext.WithCancelCause(gocontext.Background())
+
+// activeCtx is context of running command which must be cleaned on shutdown
+var activeCtx *context
+
+// activeLocks contains names of lock files created by app
+var activeLocks = map[string]bool{}
+
+// activeMu is lock for active context and locks
+var activeMu sync.Mutex
+
 // rawOutput is raw output flag
 var rawOutput = false
 
+// jsonStacks contains package stacks for JSON output (sub-repository → stack)
+var jsonStacks = map[string]repo.PackageStack{}
+
+// maintenanceFlag is path to maintenance flag file created by reindex
+var maintenanceFlag = ""
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// RepositoryInfo contains basic info about configured repository
+type RepositoryInfo struct {
+	Name       string `json:"name"`        // Repository name
+	ConfigFile string `json:"config_file"` // Path to repository configuration file
+	DataDir    string `json:"data_dir"`    // Path to directory with repository data
+	CacheDir   string `json:"cache_dir"`   // Path to directory with repository cache
+}
+
 // ////////////////////////////////////////////////////////////////////////////////// //
 
 func Init(gitRev string, gomod []byte) {
@@ -265,11 +415,10 @@ func Init(gitRev string, gomod []byte) {
 	err := errors.Chain(
 		checkPermissions,
 		loadGlobalConfig,
-		validateGlobalConfig,
 		loadRepoConfigs,
-		validateRepoConfigs,
 		configureRepoCache,
 		configureSignalHandlers,
+		configureTimeout,
 	)
 
 	if err != nil {
@@ -286,6 +435,56 @@ func Init(gitRev string, gomod []byte) {
 	shutdown(0)
 }
 
+// GetRepositories reads global and repositories configuration files and returns
+// info about all configured repositories sorted by name. Configuration files are
+// not validated.
+func GetRepositories() ([]RepositoryInfo, error) {
+	return getRepositories(CONFIG_FILE, CONFIG_DIR)
+}
+
+// getRepositories reads given global configuration file and repositories
+// configuration files from given directory and returns info about all configured
+// repositories sorted by name. Unlike the command processing, it doesn't change
+// global configuration.
+func getRepositories(globalConfigFile, configDir string) ([]RepositoryInfo, error) {
+	globalCfg, err := knf.Read(globalConfigFile)
+
+	if err != nil {
+		return nil, fmt.Errorf("Can't load global coniguration: %w", err)
+	}
+
+	repoConfigs, err := readRepoConfigs(configDir)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var repoNames []string
+
+	for repoName := range repoConfigs {
+		repoNames = append(repoNames, repoName)
+	}
+
+	sortutil.StringsNatural(repoNames)
+
+	var result []RepositoryInfo
+
+	for _, repoName := range repoNames {
+		dataOptions := getFSDataOptions(globalCfg, repoConfigs[repoName])
+
+		result = append(result, RepositoryInfo{
+			Name:       repoName,
+			ConfigFile: repoConfigs[repoName].File(),
+			DataDir:    dataOptions.DataDir,
+			CacheDir:   dataOptions.CacheDir,
+		})
+	}
+
+	return result, nil
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
 // configureUI configure user interface
 func configureUI() {
 	fmtc.DisableColors = true
@@ -326,12 +525,31 @@ func configureUI() {
 		fmtc.DisableColors = true
 	}
 
-	if !tty.IsTTY() {
+	if !tty.IsTTY() || options.GetB(OPT_JSON) {
 		fmtc.DisableColors = true
 		rawOutput = true
 	}
 }
 
+// setupPager configures pager using command from REP_PAGER environment variable
+// or ui:pager configuration property
+func setupPager() error {
+	pagerCmd := strings.TrimSpace(os.Getenv(PAGER_ENV))
+
+	if pagerCmd == "" {
+		pagerCmd = strings.TrimSpace(globalConfig.GetS(UI_PAGER))
+	}
+
+	if pagerCmd == "" {
+		return pager.Setup()
+	}
+
+	// Colors configured for terminal output must be kept after redirecting
+	// output to pager, so pager must be able to render escape sequences
+	// (e.g. "less -R")
+	return pager.Setup(pagerCmd)
+}
+
 // checkPermissions checks that user has enough permissions
 func checkPermissions() error {
 	curUser, err := system.CurrentUser()
@@ -347,19 +565,27 @@ func checkPermissions() error {
 	return nil
 }
 
-// loadGlobalConfig loads global configuration file
+// loadGlobalConfig loads and validates global configuration file
 func loadGlobalConfig() error {
-	err := knf.Global(CONFIG_FILE)
+	cfg, err := knf.Read(CONFIG_FILE)
 
 	if err != nil {
 		return fmt.Errorf("Can't load global coniguration: %w", err)
 	}
 
+	err = validateGlobalConfig(cfg)
+
+	if err != nil {
+		return err
+	}
+
+	globalConfig = cfg
+
 	return nil
 }
 
 // validateGlobalConfig validates global configuration file properties
-func validateGlobalConfig() error {
+func validateGlobalConfig(cfg *knf.Config) error {
 	validators := knf.Validators{
 		{STORAGE_DATA, knfv.Set, nil},
 		{STORAGE_CACHE, knfv.Set, nil},
@@ -372,12 +598,31 @@ func validateGlobalConfig() error {
 		{LOG_DIR, knff.Perms, "DWX"},
 		{TEMP_DIR, knff.Perms, "DRWX"},
 
+		{STORAGE_SPLIT_DEPTH, knfv.InRange, knfv.Range{From: 0, To: fs.SPLIT_DEPTH_MAX}},
+
 		{INDEX_CHECKSUM, knfv.SetToAny, index.CheckSumMethods},
 		{INDEX_MD_FILENAMES, knfv.SetToAny, index.MDFilenames},
 		{INDEX_COMPRESSION_TYPE, knfv.SetToAny, index.CompressionMethods},
+		{INDEX_RETAIN_OLD_MD, knfv.TypeNum, nil},
+		{INDEX_RETAIN_OLD_MD, knfv.Greater, 0},
+		{INDEX_ZCHUNK, knfv.TypeBool, nil},
+		{INDEX_NO_DATABASE, knfv.TypeBool, nil},
+
+		{LOCK_TIMEOUT, validateDuration, nil},
+		{LOCK_WAIT, validateDuration, nil},
 	}
 
-	errs := knf.Validate(validators)
+	if cfg.GetS(STORAGE_TYPE) == storage.TYPE_S3 {
+		validators = append(validators, knf.Validators{
+			{S3_ENDPOINT, knfv.Set, nil},
+			{S3_REGION, knfv.Set, nil},
+			{S3_BUCKET, knfv.Set, nil},
+			{S3_ACCESS_KEY, knfv.Set, nil},
+			{S3_SECRET_KEY, knfv.Set, nil},
+		}...)
+	}
+
+	errs := cfg.Validate(validators)
 
 	if !errs.IsEmpty() {
 		return fmt.Errorf("Error while global configuration file validation: %w", errs.First())
@@ -386,67 +631,186 @@ func validateGlobalConfig() error {
 	return nil
 }
 
-// loadRepoConfigs loads repositories configuration files
+// validateDuration validates that property contains valid duration
+// (e.g. 30s, 15m, 1h)
+func validateDuration(config knf.IConfig, prop string, value any) error {
+	if config.GetS(prop) == "" || config.GetTD(prop) > 0 {
+		return nil
+	}
+
+	return fmt.Errorf(
+		"Property %s contains invalid duration value %q", prop, config.GetS(prop),
+	)
+}
+
+// validateKeyFiles validates that property contains list of readable key files
+func validateKeyFiles(config knf.IConfig, prop string, value any) error {
+	for _, file := range strutil.Fields(config.GetS(prop)) {
+		err := fsutil.ValidatePerms("FRS", file)
+
+		if err != nil {
+			return fmt.Errorf("Property %s contains invalid key file: %w", prop, err)
+		}
+	}
+
+	return nil
+}
+
+// loadRepoConfigs loads and validates repositories configuration files
 func loadRepoConfigs() error {
+	repoConfigs, err := readRepoConfigs(CONFIG_DIR)
+
+	if err != nil {
+		return err
+	}
+
+	for _, cfg := range repoConfigs {
+		err = validateRepoConfig(cfg)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(repoConfigs) != 0 {
+		configs = repoConfigs
+	}
+
+	return nil
+}
+
+// readRepoConfigs reads repositories configuration files from given directory
+func readRepoConfigs(configDir string) (map[string]*knf.Config, error) {
 	filter := fsutil.ListingFilter{MatchPatterns: []string{"*.knf"}}
-	configFiles := fsutil.List(CONFIG_DIR, false, filter)
+	configFiles := fsutil.List(configDir, false, filter)
 
 	if len(configFiles) == 0 {
-		return nil
+		return nil, nil
 	}
 
-	fsutil.ListToAbsolute(CONFIG_DIR, configFiles)
+	fsutil.ListToAbsolute(configDir, configFiles)
 
-	configs = make(map[string]*knf.Config)
+	result := make(map[string]*knf.Config)
 
 	for _, cf := range configFiles {
 		cfg, err := knf.Read(cf)
 
 		if err != nil {
-			return err
+			return nil, err
+		}
+
+		repoName := cfg.GetS(REPOSITORY_NAME)
+
+		if isReservedRepoName(repoName) {
+			return nil, fmt.Errorf(
+				"Error while repository configuration file validation (%s): Repository name %q is reserved for command",
+				cfg.File(), repoName,
+			)
+		}
+
+		if result[repoName] != nil {
+			return nil, fmt.Errorf(
+				"Repository name %q is used in more than one configuration file (%s and %s)",
+				repoName, result[repoName].File(), cfg.File(),
+			)
 		}
 
-		configs[cfg.GetS(REPOSITORY_NAME)] = cfg
+		result[repoName] = cfg
 	}
 
-	return nil
+	return result, nil
 }
 
-// validateRepoConfigs validates repositories configuration files
-func validateRepoConfigs() error {
-	for _, cfg := range configs {
-		validators := knf.Validators{
-			{PERMISSIONS_USER, knfs.User, nil},
-			{PERMISSIONS_GROUP, knfs.Group, nil},
-			{REPOSITORY_NAME, knfr.Regexp, repoNamePattern},
-		}
+// validateRepoConfig validates repository configuration file
+func validateRepoConfig(cfg *knf.Config) error {
+	validators := knf.Validators{
+		{PERMISSIONS_USER, knfs.User, nil},
+		{PERMISSIONS_GROUP, knfs.Group, nil},
+		{REPOSITORY_NAME, knfr.Regexp, repoNamePattern},
+	}
 
-		validators = validators.AddIf(
-			cfg.HasProp(SIGN_KEY),
-			knf.Validators{
-				{SIGN_KEY, knff.Perms, "FR"},
-				{SIGN_KEY, knff.FileMode, os.FileMode(0600)},
-			},
-		)
+	validators = validators.AddIf(
+		cfg.HasProp(SIGN_MODE),
+		knf.Validators{
+			{SIGN_MODE, knfv.SetToAny, []string{SIGN_MODE_FILE, SIGN_MODE_AGENT}},
+		},
+	)
+
+	validators = validators.AddIf(
+		cfg.GetS(SIGN_MODE) == SIGN_MODE_AGENT,
+		knf.Validators{
+			{SIGN_KEY_ID, knfv.Set, nil},
+		},
+	)
 
-		errs := cfg.Validate(validators)
+	validators = validators.AddIf(
+		cfg.HasProp(SIGN_KEY) && cfg.GetS(SIGN_MODE) != SIGN_MODE_AGENT,
+		knf.Validators{
+			{SIGN_KEY, knff.Perms, "FR"},
+			{SIGN_KEY, knff.FileMode, os.FileMode(0600)},
+		},
+	)
 
-		if errs.IsEmpty() {
-			continue
-		}
+	validators = validators.AddIf(
+		cfg.HasProp(SIGN_TRUSTED_KEYS),
+		knf.Validators{
+			{SIGN_TRUSTED_KEYS, validateKeyFiles, nil},
+		},
+	)
 
+	validators = validators.AddIf(
+		cfg.HasProp(REPOSITORY_GROUP_FILE),
+		knf.Validators{
+			{REPOSITORY_GROUP_FILE, knff.Perms, "FRS"},
+		},
+	)
+
+	validators = validators.AddIf(
+		cfg.HasProp(REPOSITORY_MAX_VERSIONS),
+		knf.Validators{
+			{REPOSITORY_MAX_VERSIONS, knfv.TypeNum, nil},
+			{REPOSITORY_MAX_VERSIONS, knfv.Greater, 0},
+		},
+	)
+
+	validators = validators.AddIf(
+		cfg.HasProp(REPOSITORY_AUTO_REINDEX),
+		knf.Validators{
+			{REPOSITORY_AUTO_REINDEX, knfv.TypeBool, nil},
+		},
+	)
+
+	validators = validators.AddIf(
+		cfg.HasProp(REPOSITORY_CACHE_INFO),
+		knf.Validators{
+			{REPOSITORY_CACHE_INFO, knfv.TypeBool, nil},
+		},
+	)
+
+	errs := cfg.Validate(validators)
+
+	if !errs.IsEmpty() {
 		return fmt.Errorf(
 			"Error while repository configuration file validation (%s): %w",
 			cfg.File(), errs.First(),
 		)
 	}
 
+	for _, field := range strutil.Fields(cfg.GetS(REPOSITORY_REQUIRE_FIELDS)) {
+		if !slices.Contains(repo.MetaFields, field) {
+			return fmt.Errorf(
+				"Error while repository configuration file validation (%s): Unknown required field %q (supported fields: %s)",
+				cfg.File(), field, strings.Join(repo.MetaFields, ", "),
+			)
+		}
+	}
+
 	return nil
 }
 
 // configureRepoCache configures cache for repository data
 func configureRepoCache() error {
-	cacheDir := knf.GetS(STORAGE_CACHE)
+	cacheDir := globalConfig.GetS(STORAGE_CACHE)
 
 	for repo := range configs {
 		repoCacheDir := cacheDir + "/" + repo
@@ -483,6 +847,19 @@ func getPrimaryRepoName() string {
 	return ""
 }
 
+// isReservedRepoName returns true if given repository name collides with
+// command name or command shortcut
+func isReservedRepoName(name string) bool {
+	if name == "" {
+		return false
+	}
+
+	_, isCommand := commands[name]
+	_, isShortcut := commandsShortcurts[name]
+
+	return isCommand || isShortcut
+}
+
 // process starts command processing
 func process(args options.Arguments) bool {
 	if len(configs) == 1 && configs[args.Get(0).String()] == nil {
@@ -494,6 +871,13 @@ func process(args options.Arguments) bool {
 	switch repo {
 	case COMMAND_HELP, COMMAND_SHORT_HELP, COMMAND_GEN_KEY:
 		return runSimpleCommand(repo, args[1:])
+	case COMMAND_METRICS, COMMAND_SHORT_METRICS:
+		return runSimpleCommand(COMMAND_METRICS, args[1:])
+	case COMMAND_PURGE_CACHE, COMMAND_SHORT_PURGE_CACHE:
+		// Purging cache for all repositories doesn't require repository name
+		if options.GetB(OPT_ALL_REPOS) {
+			return runSimpleCommand(COMMAND_PURGE_CACHE, args[1:])
+		}
 	}
 
 	if len(configs) == 0 {
@@ -511,7 +895,7 @@ func process(args options.Arguments) bool {
 	}
 
 	if options.GetB(OPT_PAGER) && tty.IsTTY() {
-		if pager.Setup() == nil {
+		if setupPager() == nil {
 			defer pager.Complete()
 		}
 	}
@@ -524,17 +908,71 @@ func process(args options.Arguments) bool {
 	return runCommand(configs[repo], args.Get(1).String(), args[2:])
 }
 
+// configureTimeout configures timer for command execution timeout
+func configureTimeout() error {
+	if !options.Has(OPT_TIMEOUT) {
+		return nil
+	}
+
+	timeout, err := timeutil.ParseDuration(options.GetS(OPT_TIMEOUT), 's')
+
+	if err != nil {
+		return fmt.Errorf("Can't parse %s option value: %w", options.F(OPT_TIMEOUT), err)
+	}
+
+	if timeout <= 0 {
+		return fmt.Errorf("Timeout must be greater than zero")
+	}
+
+	time.AfterFunc(timeout, func() {
+		timeoutHandler(timeout)
+	})
+
+	return nil
+}
+
+// timeoutHandler is handler for command execution timeout
+func timeoutHandler(timeout time.Duration) {
+	terminal.Error(
+		"\nCommand execution took longer than %s and will be interrupted",
+		timeutil.PrettyDuration(timeout),
+	)
+
+	if !isCancelProtected {
+		shutdown(1)
+	}
+
+	isCanceled.Store(true)
+
+	cancelFunc(fmt.Errorf("Command execution timeout (%s) reached", timeutil.PrettyDuration(timeout)))
+}
+
 // sigHandler is handler for TERM, QUIT and INT signals
 func sigHandler() {
 	if !isCancelProtected {
 		shutdown(1)
 	}
 
-	isCanceled = true
+	isCanceled.Store(true)
+
+	cancelFunc(fmt.Errorf("Command execution interrupted"))
 }
 
-// shutdown cleans temporary data and exits from CLI
+// shutdown cleans temporary data, removes locks and exits from CLI
 func shutdown(ec int) {
+	removeMaintenanceFlag()
+	removeActiveLocks()
+
+	activeMu.Lock()
+
+	if activeCtx != nil {
+		activeCtx.Temp.Clean()
+		activeCtx.Logger.Flush()
+	}
+
+	activeMu.Unlock()
+
+	fs.RemoveMemCacheDirs()
 	os.Exit(ec)
 }
 
@@ -610,7 +1048,7 @@ func genUsage() *usage.Info {
 	info.AddCommand(COMMAND_CLEANUP, "Remove old versions of packages", "?num", "?filter")
 	info.AddCommand(COMMAND_CHECK, "Check repositories consistency", "?errors-num")
 	info.AddCommand(COMMAND_SIGN, "Sign one or more packages", "file…")
-	info.AddCommand(COMMAND_RESIGN, "Resign all packages in repository")
+	info.AddCommand(COMMAND_RESIGN, "Resign all or some packages in repository", "?query…")
 	info.AddCommand(COMMAND_ADD, "Add one or more packages to testing repository", "file…")
 	info.AddCommand(COMMAND_REMOVE, "Remove package or packages from repository", "query…")
 	info.AddCommand(COMMAND_RELEASE, "Copy package or packages from testing to release repository", "query…")
@@ -618,6 +1056,14 @@ func genUsage() *usage.Info {
 	info.AddCommand(COMMAND_REINDEX, "Create or update repository index")
 	info.AddCommand(COMMAND_PURGE_CACHE, "Clean all cached data")
 	info.AddCommand(COMMAND_STATS, "Show some statistics information about repositories")
+	info.AddCommand(COMMAND_DEP_GRAPH, "Export graph of dependencies between packages", "?format")
+	info.AddCommand(COMMAND_EXPORT, "Export sub-repository to gzip compressed tarball", "file")
+	info.AddCommand(COMMAND_IMPORT, "Import packages from existing repository to testing repository", "dir")
+	info.AddCommand(COMMAND_DIFF, "Show differences between testing and release repositories")
+	info.AddCommand(COMMAND_DOWNLOAD, "Download package files to current directory", "query…")
+	info.AddCommand(COMMAND_TAG, "Manage packages tags", "action", "?tag", "?query…")
+	info.AddCommand(COMMAND_METRICS, "Print metrics for all repositories in Prometheus format")
+	info.AddCommand(COMMAND_VERIFY, "Verify signatures of all packages in repository")
 	info.AddCommand(COMMAND_HELP, "Show detailed information about command", "command")
 
 	info.AddOption(OPT_RELEASE, "Run command only on release {s}(stable){!} repository")
@@ -626,57 +1072,130 @@ func genUsage() *usage.Info {
 	info.AddOption(OPT_ARCH, `Package architecture`, "arch")
 	info.AddOption(OPT_MOVE, `Move {s}(remove after successful action){!} packages`)
 	info.AddOption(OPT_NO_SOURCE, `Ignore source packages`)
+	info.AddOption(OPT_ONLY_SOURCE, `Show only source packages`)
 	info.AddOption(OPT_IGNORE_FILTER, `Ignore repository file filter`)
 	info.AddOption(OPT_POSTPONE_INDEX, `Postpone repository reindex`)
 	info.AddOption(OPT_FORCE, `Answer "yes" for all questions`)
 	info.AddOption(OPT_FULL, `Full reindex`)
 	info.AddOption(OPT_SHOW_ALL, `Show all versions of packages`)
+	info.AddOption(OPT_KEEP_SOURCE, `Count builds of source packages instead of versions`)
 	info.AddOption(OPT_STATUS, "Show package status {s-}(released or not){!}")
 	info.AddOption(OPT_EPOCH, `Show epoch info`)
 	info.AddOption(OPT_PAGER, "Use pager for long output")
+	info.AddOption(OPT_FIELDS, "Comma-separated list of fields for raw output", "fields")
+	info.AddOption(OPT_SORT, "Sort order {s-}(name, size, date-add or date-build){!}", "order")
+	info.AddOption(OPT_RELEASE_ONLY, "Show only packages which present in release but absent in testing")
+	info.AddOption(OPT_FILE, "Read info directly from RPM file")
+	info.AddOption(OPT_ALL_REPOS, "Process all repositories")
+	info.AddOption(OPT_WHY, "Show search terms matched by every package")
+	info.AddOption(OPT_ATOMIC, "Release all packages or none of them")
+	info.AddOption(OPT_DIFF, "Show changes since the previous run")
+	info.AddOption(OPT_SAVE, "Save stats snapshot for comparison with --diff")
+	info.AddOption(OPT_SUMMARY, "Show number of files and total size for every package bundle")
+	info.AddOption(OPT_TAG, "Show only packages with given tag", "tag")
+	info.AddOption(OPT_DRY_RUN, "Show what would be done without making any changes")
+	info.AddOption(OPT_JSON, "Print data in JSON format")
+	info.AddOption(OPT_BINARIES, "Show binary packages built from given source packages")
+	info.AddOption(OPT_FIX, "Fix wrong owner and permissions of repository files")
+	info.AddOption(OPT_SYNC_NOARCH, "Copy noarch packages to architecture directories which don't contain them")
+	info.AddOption(OPT_TIMEOUT, "Maximum command execution time {s-}(e.g. 30s, 5m, 1h){!}", "duration")
+	info.AddOption(OPT_VERBOSE, "Show additional information {s-}(e.g. time spent on cache warm-up){!}")
 	info.AddOption(OPT_NO_COLOR, "Disable colors in output")
 	info.AddOption(OPT_HELP, "Show this help message")
 	info.AddOption(OPT_VER, "Show version")
 
+	info.BoundOptions(COMMAND_ADD, OPT_DRY_RUN)
 	info.BoundOptions(COMMAND_ADD, OPT_FORCE)
 	info.BoundOptions(COMMAND_ADD, OPT_IGNORE_FILTER)
 	info.BoundOptions(COMMAND_ADD, OPT_MOVE)
 	info.BoundOptions(COMMAND_ADD, OPT_NO_SOURCE)
+	info.BoundOptions(COMMAND_ADD, OPT_RELEASE)
+	info.BoundOptions(COMMAND_CHECK, OPT_FIX)
+	info.BoundOptions(COMMAND_CHECK, OPT_FORCE)
 	info.BoundOptions(COMMAND_CLEANUP, OPT_FORCE)
+	info.BoundOptions(COMMAND_CLEANUP, OPT_KEEP_SOURCE)
 	info.BoundOptions(COMMAND_CLEANUP, OPT_RELEASE)
 	info.BoundOptions(COMMAND_CLEANUP, OPT_TESTING)
+	info.BoundOptions(COMMAND_DIFF, OPT_JSON)
+	info.BoundOptions(COMMAND_DOWNLOAD, OPT_ARCH)
+	info.BoundOptions(COMMAND_DOWNLOAD, OPT_FORCE)
+	info.BoundOptions(COMMAND_DOWNLOAD, OPT_RELEASE)
+	info.BoundOptions(COMMAND_EXPORT, OPT_RELEASE)
+	info.BoundOptions(COMMAND_EXPORT, OPT_TESTING)
+	info.BoundOptions(COMMAND_EXPORT, OPT_NO_SOURCE)
 	info.BoundOptions(COMMAND_FIND, OPT_RELEASE)
 	info.BoundOptions(COMMAND_FIND, OPT_STATUS)
 	info.BoundOptions(COMMAND_FIND, OPT_TESTING)
 	info.BoundOptions(COMMAND_FIND, OPT_PAGER)
+	info.BoundOptions(COMMAND_FIND, OPT_FIELDS)
+	info.BoundOptions(COMMAND_FIND, OPT_SORT)
+	info.BoundOptions(COMMAND_FIND, OPT_WHY)
+	info.BoundOptions(COMMAND_FIND, OPT_SUMMARY)
+	info.BoundOptions(COMMAND_FIND, OPT_NO_SOURCE)
+	info.BoundOptions(COMMAND_FIND, OPT_ONLY_SOURCE)
+	info.BoundOptions(COMMAND_FIND, OPT_TAG)
+	info.BoundOptions(COMMAND_FIND, OPT_JSON)
+	info.BoundOptions(COMMAND_IMPORT, OPT_IGNORE_FILTER)
+	info.BoundOptions(COMMAND_IMPORT, OPT_NO_SOURCE)
 	info.BoundOptions(COMMAND_INFO, OPT_ARCH)
 	info.BoundOptions(COMMAND_INFO, OPT_PAGER)
+	info.BoundOptions(COMMAND_INFO, OPT_FILE)
+	info.BoundOptions(COMMAND_INFO, OPT_JSON)
 	info.BoundOptions(COMMAND_LIST, OPT_EPOCH)
 	info.BoundOptions(COMMAND_LIST, OPT_RELEASE)
 	info.BoundOptions(COMMAND_LIST, OPT_SHOW_ALL)
 	info.BoundOptions(COMMAND_LIST, OPT_STATUS)
 	info.BoundOptions(COMMAND_LIST, OPT_TESTING)
 	info.BoundOptions(COMMAND_LIST, OPT_PAGER)
+	info.BoundOptions(COMMAND_LIST, OPT_FIELDS)
+	info.BoundOptions(COMMAND_LIST, OPT_SORT)
+	info.BoundOptions(COMMAND_LIST, OPT_RELEASE_ONLY)
+	info.BoundOptions(COMMAND_LIST, OPT_SUMMARY)
+	info.BoundOptions(COMMAND_LIST, OPT_NO_SOURCE)
+	info.BoundOptions(COMMAND_LIST, OPT_ONLY_SOURCE)
+	info.BoundOptions(COMMAND_LIST, OPT_TAG)
+	info.BoundOptions(COMMAND_LIST, OPT_JSON)
 	info.BoundOptions(COMMAND_PAYLOAD, OPT_ARCH)
 	info.BoundOptions(COMMAND_PAYLOAD, OPT_PAGER)
 	info.BoundOptions(COMMAND_PURGE_CACHE, OPT_RELEASE)
 	info.BoundOptions(COMMAND_PURGE_CACHE, OPT_TESTING)
+	info.BoundOptions(COMMAND_PURGE_CACHE, OPT_ALL_REPOS)
+	info.BoundOptions(COMMAND_REINDEX, OPT_ARCH)
 	info.BoundOptions(COMMAND_REINDEX, OPT_FULL)
 	info.BoundOptions(COMMAND_REINDEX, OPT_RELEASE)
 	info.BoundOptions(COMMAND_REINDEX, OPT_TESTING)
+	info.BoundOptions(COMMAND_REINDEX, OPT_SYNC_NOARCH)
 	info.BoundOptions(COMMAND_RELEASE, OPT_FORCE)
+	info.BoundOptions(COMMAND_RELEASE, OPT_ATOMIC)
+	info.BoundOptions(COMMAND_RELEASE, OPT_DRY_RUN)
 	info.BoundOptions(COMMAND_REMOVE, OPT_ALL)
 	info.BoundOptions(COMMAND_REMOVE, OPT_FORCE)
+	info.BoundOptions(COMMAND_REMOVE, OPT_DRY_RUN)
 	info.BoundOptions(COMMAND_SIGN, OPT_IGNORE_FILTER)
+	info.BoundOptions(COMMAND_RESIGN, OPT_ARCH)
 	info.BoundOptions(COMMAND_RESIGN, OPT_FORCE)
+	info.BoundOptions(COMMAND_STATS, OPT_ARCH)
 	info.BoundOptions(COMMAND_STATS, OPT_RELEASE)
 	info.BoundOptions(COMMAND_STATS, OPT_TESTING)
 	info.BoundOptions(COMMAND_STATS, OPT_PAGER)
+	info.BoundOptions(COMMAND_STATS, OPT_DIFF)
+	info.BoundOptions(COMMAND_STATS, OPT_SAVE)
+	info.BoundOptions(COMMAND_STATS, OPT_ALL_REPOS)
+	info.BoundOptions(COMMAND_STATS, OPT_JSON)
+	info.BoundOptions(COMMAND_VERIFY, OPT_RELEASE)
+	info.BoundOptions(COMMAND_VERIFY, OPT_TESTING)
+	info.BoundOptions(COMMAND_VERIFY, OPT_JSON)
+	info.BoundOptions(COMMAND_DEP_GRAPH, OPT_RELEASE)
+	info.BoundOptions(COMMAND_DEP_GRAPH, OPT_TESTING)
 	info.BoundOptions(COMMAND_UNRELEASE, OPT_FORCE)
+	info.BoundOptions(COMMAND_UNRELEASE, OPT_DRY_RUN)
 	info.BoundOptions(COMMAND_WHICH_SOURCE, OPT_EPOCH)
 	info.BoundOptions(COMMAND_WHICH_SOURCE, OPT_RELEASE)
 	info.BoundOptions(COMMAND_WHICH_SOURCE, OPT_TESTING)
 	info.BoundOptions(COMMAND_WHICH_SOURCE, OPT_PAGER)
+	info.BoundOptions(COMMAND_WHICH_SOURCE, OPT_FILE)
+	info.BoundOptions(COMMAND_WHICH_SOURCE, OPT_JSON)
+	info.BoundOptions(COMMAND_WHICH_SOURCE, OPT_BINARIES)
 
 	return info
 }
@@ -8,19 +8,25 @@ package cli
 // ////////////////////////////////////////////////////////////////////////////////// //
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"slices"
+	"strings"
 
 	"github.com/essentialkaos/ek/v13/fmtc"
 	"github.com/essentialkaos/ek/v13/options"
 	"github.com/essentialkaos/ek/v13/path"
+	"github.com/essentialkaos/ek/v13/pluralize"
 	"github.com/essentialkaos/ek/v13/spinner"
 	"github.com/essentialkaos/ek/v13/terminal"
 	"github.com/essentialkaos/ek/v13/terminal/input"
 
+	"github.com/essentialkaos/rep/v3/repo"
 	"github.com/essentialkaos/rep/v3/repo/data"
 	"github.com/essentialkaos/rep/v3/repo/rpm"
 	"github.com/essentialkaos/rep/v3/repo/sign"
+	"github.com/essentialkaos/rep/v3/repo/storage/fs"
 )
 
 // ////////////////////////////////////////////////////////////////////////////////// //
@@ -39,18 +45,34 @@ func cmdAdd(ctx *context, args options.Arguments) bool {
 		return false
 	}
 
+	r := ctx.Repo.Testing
+
+	if options.GetB(OPT_RELEASE) {
+		r = ctx.Repo.Release
+	}
+
+	if options.GetB(OPT_DRY_RUN) {
+		return addRPMFilesDryRun(ctx, r, files)
+	}
+
 	if !options.GetB(OPT_FORCE) {
 		printFilesList(files)
 
-		ok, err := input.ReadAnswer("Do you want to add these packages?", "n")
+		if r.Is(data.REPO_RELEASE) {
+			terminal.Warn("Packages will be added directly to the release repository, bypassing the testing repository.\n")
+		}
+
+		ok, err := input.ReadAnswer(
+			fmt.Sprintf("Do you want to add these packages to %s repository?", r.Name), "n",
+		)
 
 		if err != nil || !ok {
 			return false
 		}
 	}
 
-	if !isSignRequired(ctx.Repo.Testing, files) {
-		return addRPMFiles(ctx, files, nil)
+	if !isSignRequired(r, files) {
+		return addRPMFiles(ctx, r, files, nil)
 	}
 
 	signingKey, ok := getRepoSigningKey(ctx.Repo)
@@ -59,7 +81,7 @@ func cmdAdd(ctx *context, args options.Arguments) bool {
 		return false
 	}
 
-	return addRPMFiles(ctx, files, signingKey)
+	return addRPMFiles(ctx, r, files, signingKey)
 }
 
 // ////////////////////////////////////////////////////////////////////////////////// //
@@ -74,8 +96,47 @@ func printFilesList(files []string) {
 	fmtc.NewLine()
 }
 
-// addRPMFiles adds given RPM files to testing repository
-func addRPMFiles(ctx *context, files []string, signingKey *sign.Key) bool {
+// addRPMFilesDryRun prints list of RPM files which would be added to given
+// sub-repository
+func addRPMFilesDryRun(ctx *context, r *repo.SubRepository, files []string) bool {
+	var hasErrors bool
+	var pkgFiles []string
+
+	for _, file := range files {
+		add, ok := checkRPMFileForAdd(ctx, r, file)
+
+		if !ok {
+			hasErrors = true
+		}
+
+		if add {
+			pkgFiles = append(pkgFiles, file)
+		}
+	}
+
+	if len(pkgFiles) != 0 {
+		if hasErrors {
+			fmtc.NewLine()
+		}
+
+		fmtc.Printfn("{*}Would be added to {?repo}%s{!}{*}:{!}", r.Name)
+
+		for _, file := range pkgFiles {
+			fileName := path.Base(file)
+			replaceTag := fmtc.If(r.HasPackageFile(fileName)).Sprintf(" {s}(replace){!}")
+			fmtc.Printfn("{s-}•{!} {?package}%s{!}%s", fileName, replaceTag)
+		}
+
+		fmtc.NewLine()
+	}
+
+	printDryRunNotice()
+
+	return hasErrors == false
+}
+
+// addRPMFiles adds given RPM files to given sub-repository
+func addRPMFiles(ctx *context, r *repo.SubRepository, files []string, signingKey *sign.Key) bool {
 	tmpDir, err := ctx.Temp.MkDir("rep")
 
 	if err != nil {
@@ -85,12 +146,15 @@ func addRPMFiles(ctx *context, files []string, signingKey *sign.Key) bool {
 
 	isCancelProtected = true
 
-	var hasErrors, hasAdded bool
+	var hasErrors bool
+	var pkgFiles []string
+
+	srcFiles := make(map[string]string)
 
 	for _, file := range files {
-		ok := addRPMFile(ctx, file, tmpDir, signingKey)
+		pkgFile, ok := prepareRPMFile(ctx, r, file, tmpDir, signingKey)
 
-		if isCanceled {
+		if isCanceled.Load() {
 			return false
 		}
 
@@ -99,121 +163,264 @@ func addRPMFiles(ctx *context, files []string, signingKey *sign.Key) bool {
 			continue
 		}
 
-		hasAdded = true
+		if pkgFile != "" {
+			pkgFiles = append(pkgFiles, pkgFile)
+			srcFiles[path.Base(pkgFile)] = file
+		}
 	}
 
-	if hasAdded && !options.GetB(OPT_POSTPONE_INDEX) {
-		fmtc.NewLine()
-		reindexRepository(ctx, ctx.Repo.Testing, false)
+	if len(pkgFiles) == 0 {
+		isCancelProtected = false
+		return hasErrors == false
 	}
 
-	isCancelProtected = false
+	if options.GetB(OPT_MOVE) {
+		spinner.Show("Moving packages to {*}{?repo}%s{!}", r.Name)
+	} else {
+		spinner.Show("Copying packages to {*}{?repo}%s{!}", r.Name)
+	}
 
-	return hasErrors == false
-}
+	added, err := r.AddPackages(pkgFiles)
+	addedFiles := make(map[string]bool)
 
-// addRPMFile adds given RPM file to testing repository
-func addRPMFile(ctx *context, file, tmpDir string, signingKey *sign.Key) bool {
-	var err error
+	for _, relPath := range added {
+		addedFiles[path.Base(relPath)] = true
+	}
 
-	fileName := path.Base(file)
+	spinner.Update(
+		"%s added to {*}{?repo}%s{!}",
+		pluralize.PS(pluralize.En, "%d %s", len(addedFiles), "package", "packages"),
+		r.Name,
+	)
+	spinner.Done(err == nil)
 
-	if options.GetB(OPT_MOVE) {
-		spinner.Show("Moving {?package}%s{!}", fileName)
-	} else {
-		spinner.Show("Copying {?package}%s{!}", fileName)
+	if err != nil {
+		hasErrors = true
+
+		for _, line := range strings.Split(err.Error(), "\n") {
+			terminal.Error("   %s", line)
+		}
+
+		if errors.Is(err, fs.ErrNoFreeSpace) {
+			fmtc.NewLine()
+			terminal.Error("Not enough free space in repository storage")
+		}
 	}
 
-	if !options.GetB(OPT_IGNORE_FILTER) {
-		matchFilePattern, err := path.Match(ctx.Repo.FileFilter, fileName)
+	for _, pkgFile := range pkgFiles {
+		fileName := path.Base(pkgFile)
 
-		if err != nil {
-			printSpinnerAddError(fileName, fmt.Sprintf("Can't parse file filter pattern: %v", err))
-			return false
+		if !addedFiles[fileName] {
+			continue
 		}
 
-		if !matchFilePattern {
-			printSpinnerAddError(fileName, fmt.Sprintf("File doesn't match repository filter (%s)", ctx.Repo.FileFilter))
-			return false
+		if options.GetB(OPT_MOVE) {
+			err = os.Remove(srcFiles[fileName])
+
+			if err != nil {
+				terminal.Error("Can't remove file %s: %v", srcFiles[fileName], err)
+				hasErrors = true
+			}
 		}
+
+		ctx.Logger.Get(r.Name).Print("Added package %s", fileName)
 	}
 
-	if options.GetB(OPT_NO_SOURCE) {
-		matchFilePattern, _ := path.Match("*.src.rpm", fileName)
+	var reindexed bool
 
-		if matchFilePattern {
-			skipOption, _ := options.ParseOptionName(OPT_NO_SOURCE)
-			spinner.Update("{s}Skip %s (due to --%s option){!}", fileName, skipOption)
-			spinner.Skip()
-			return true
+	if len(addedFiles) != 0 {
+		var archs []string
+
+		// In update mode createrepo reuses existing metadata, so it's enough to
+		// reindex only architectures with added packages
+		if globalConfig.GetB(INDEX_UPDATE) {
+			archs = getPackagesArchs(added)
 		}
+
+		reindexed = autoReindexRepositories(ctx, archs, r)
 	}
 
-	if !rpm.IsRPM(file) {
-		printSpinnerAddError(fileName, "File is not an RPM package")
-		return false
+	isCancelProtected = false
+
+	if reindexed && r.Is(data.REPO_TESTING) {
+		if !pruneOutdatedPackages(ctx, r, addedFiles) {
+			hasErrors = true
+		}
 	}
 
-	if ctx.Repo.Testing.HasPackageFile(fileName) && !ctx.Repo.Replace {
-		printSpinnerAddError(fileName, "Package already present in repository and replacement is forbidden in the configuration file")
+	return hasErrors == false
+}
+
+// getPackagesArchs returns list of architectures for given package files paths
+// (relative to repository directory)
+func getPackagesArchs(relPaths []string) []string {
+	var result []string
+
+	for _, relPath := range relPaths {
+		archDir, _, _ := strings.Cut(relPath, "/")
+
+		for _, arch := range data.ArchList {
+			if archDir != "" && data.SupportedArchs[arch].Dir == archDir && !slices.Contains(result, arch) {
+				result = append(result, arch)
+			}
+		}
+	}
+
+	return result
+}
+
+// pruneOutdatedPackages removes outdated versions of added packages from testing
+// repository if retention policy is configured
+func pruneOutdatedPackages(ctx *context, r *repo.SubRepository, addedFiles map[string]bool) bool {
+	maxVersions := configs[r.Parent.Name].GetI(REPOSITORY_MAX_VERSIONS)
+
+	if maxVersions <= 0 {
+		return true
+	}
+
+	if globalConfig.GetB(INDEX_NO_DATABASE) {
+		terminal.Warn("Retention policy can't be applied because generation of SQLite databases is disabled (%s)", INDEX_NO_DATABASE)
+		return true
+	}
+
+	stack, err := r.List("", true)
+
+	if err != nil {
+		terminal.Error("Can't list packages for retention policy check: %v", err)
 		return false
 	}
 
-	pkgFile := file
+	addedPkgs := make(map[string]bool)
 
-	if signingKey != nil {
-		isSignValid, err := sign.IsPackageSignatureValid(file, signingKey)
+	for _, bundle := range stack {
+		pkg := getMainPackageFromBundle(bundle)
 
-		if err != nil {
-			printSpinnerAddError(fileName, fmt.Sprintf("Can't check package signature: %v", err))
-			return false
+		if pkg == nil {
+			continue
 		}
 
-		if !isSignValid {
-			spinner.Update("Signing {?package}%s{!}", fileName)
+		for _, file := range bundle.FlattenFiles() {
+			if addedFiles[path.Base(file.Path)] {
+				addedPkgs[pkg.Name] = true
+				break
+			}
+		}
+	}
 
-			pkgFile = path.Join(tmpDir, fileName)
-			err = sign.SignPackage(file, pkgFile, signingKey)
+	var pkgStack repo.PackageStack
 
-			if err != nil {
-				printSpinnerAddError(fileName, fmt.Sprintf("Can't sign package: %v", err))
-				return false
-			}
+	for _, bundle := range stack {
+		pkg := getMainPackageFromBundle(bundle)
 
-			defer os.Remove(pkgFile)
+		if pkg != nil && addedPkgs[pkg.Name] {
+			pkgStack = append(pkgStack, bundle)
 		}
 	}
 
-	err = ctx.Repo.Testing.AddPackage(pkgFile)
+	pruneStack := extractPackagesToCleanup(pkgStack, maxVersions, "")
+
+	if pruneStack.IsEmpty() {
+		return true
+	}
+
+	fmtc.NewLine()
+	fmtc.Printfn(
+		"{*}Removing outdated packages from {?repo}%s{!}{*} (max versions: %d){!}",
+		r.Name, maxVersions,
+	)
+
+	return removePackagesFiles(ctx, nil, pruneStack.FlattenFiles())
+}
+
+// prepareRPMFile checks given RPM file and signs it if required. It returns path
+// to file which must be added to repository or empty string if file must be skipped.
+func prepareRPMFile(ctx *context, r *repo.SubRepository, file, tmpDir string, signingKey *sign.Key) (string, bool) {
+	add, ok := checkRPMFileForAdd(ctx, r, file)
+
+	if !add {
+		return "", ok
+	}
+
+	if signingKey == nil {
+		return file, true
+	}
+
+	fileName := path.Base(file)
+	isSignValid, err := sign.IsPackageSignatureValid(file, signingKey)
 
 	if err != nil {
-		printSpinnerAddError(fileName, err.Error())
-		return false
+		printSpinnerAddError(fileName, fmt.Sprintf("Can't check package signature: %v", err))
+		return "", false
 	}
 
-	if options.GetB(OPT_MOVE) {
-		err = os.Remove(file)
+	if isSignValid {
+		return file, true
+	}
+
+	spinner.Show("Signing {?package}%s{!}", fileName)
+
+	pkgFile := path.Join(tmpDir, fileName)
+	err = sign.SignPackage(file, pkgFile, signingKey)
+
+	if err != nil {
+		spinner.Done(false)
+		terminal.Error("   Can't sign package: %v", err)
+		return "", false
+	}
+
+	spinner.Update("Package {?package}%s{!} signed", fileName)
+	spinner.Done(true)
+
+	return pkgFile, true
+}
+
+// checkRPMFileForAdd checks if given RPM file can be added to given sub-repository.
+// It returns false as the first value if file must be skipped and false as the
+// second value if file check failed.
+func checkRPMFileForAdd(ctx *context, r *repo.SubRepository, file string) (bool, bool) {
+	fileName := path.Base(file)
+
+	if !options.GetB(OPT_IGNORE_FILTER) {
+		matchFilePattern, err := path.Match(ctx.Repo.FileFilter, fileName)
 
 		if err != nil {
-			printSpinnerAddError(fileName, fmt.Sprintf("Can't remove file: %v", err))
-			return false
+			printSpinnerAddError(fileName, fmt.Sprintf("Can't parse file filter pattern: %v", err))
+			return false, false
 		}
 
-		spinner.Update("Package {?package}%s{!} moved to {*}{?repo}%s{!}", fileName, data.REPO_TESTING)
-		spinner.Done(true)
-	} else {
-		spinner.Update("Package {?package}%s{!} added to {*}{?repo}%s{!}", fileName, data.REPO_TESTING)
-		spinner.Done(true)
+		if !matchFilePattern {
+			printSpinnerAddError(fileName, fmt.Sprintf("File doesn't match repository filter (%s)", ctx.Repo.FileFilter))
+			return false, false
+		}
 	}
 
-	ctx.Logger.Get(data.REPO_TESTING).Print("Added package %s", fileName)
+	if options.GetB(OPT_NO_SOURCE) {
+		matchFilePattern, _ := path.Match("*.src.rpm", fileName)
+
+		if matchFilePattern {
+			skipOption, _ := options.ParseOptionName(OPT_NO_SOURCE)
+			spinner.Show("{s}Skip %s (due to --%s option){!}", fileName, skipOption)
+			spinner.Skip()
+			return false, true
+		}
+	}
+
+	if !rpm.IsRPM(file) {
+		printSpinnerAddError(fileName, "File is not an RPM package")
+		return false, false
+	}
+
+	if r.HasPackageFile(fileName) && !ctx.Repo.Replace {
+		printSpinnerAddError(fileName, "Package already present in repository and replacement is forbidden in the configuration file")
+		return false, false
+	}
 
-	return true
+	return true, true
 }
 
-// printSpinnerAddError stops spinner and shows given error
+// printSpinnerAddError shows error for given file
 func printSpinnerAddError(fileName string, err string) {
-	spinner.Update("Can't add {?package}%s{!}", fileName)
+	spinner.Show("Can't add {?package}%s{!}", fileName)
 	spinner.Done(false)
 	terminal.Error("   %v", err)
 }
@@ -9,6 +9,9 @@ package cli
 
 import (
 	"fmt"
+	"os"
+	"runtime"
+	"strings"
 
 	"github.com/essentialkaos/ek/v13/errors"
 	"github.com/essentialkaos/ek/v13/fmtc"
@@ -27,11 +30,30 @@ import (
 	"github.com/essentialkaos/ek/v13/terminal/input"
 
 	"github.com/essentialkaos/rep/v3/repo"
+	"github.com/essentialkaos/rep/v3/repo/data"
+	"github.com/essentialkaos/rep/v3/repo/helpers"
 	"github.com/essentialkaos/rep/v3/repo/sign"
 )
 
 // ////////////////////////////////////////////////////////////////////////////////// //
 
+// objectAttrs contains owner and permissions for repository files and directories
+type objectAttrs struct {
+	UID       int
+	GID       int
+	FilePerms os.FileMode
+	DirPerms  os.FileMode
+}
+
+// objectFix contains info about package file with wrong owner or permissions of
+// file or its directory
+type objectFix struct {
+	Repo *repo.SubRepository
+	File repo.PackageFile
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
 // checkMaxErrNum is minimal number of check errors to print
 var checkMaxErrNum int
 
@@ -61,7 +83,7 @@ func cmdCheck(ctx *context, args options.Arguments) bool {
 		return false
 	}
 
-	if !checkRepositoriesData(ctx.Repo, releaseStack, testingStack) {
+	if !checkRepositoriesData(ctx, releaseStack, testingStack) {
 		return false
 	}
 
@@ -72,13 +94,15 @@ func cmdCheck(ctx *context, args options.Arguments) bool {
 
 // checkRepositoriesConsistency checks consistency between testing and release
 // repositories
-func checkRepositoriesData(r *repo.Repository, releaseStack, testingStack repo.PackageStack) bool {
+func checkRepositoriesData(ctx *context, releaseStack, testingStack repo.PackageStack) bool {
 	var hasProblems bool
 
+	r := ctx.Repo
+
 	releaseIndex := createIndexForStack(releaseStack)
 	testingIndex := createIndexForStack(testingStack)
 
-	if !checkRepositoriesConsistency(releaseIndex, testingIndex) {
+	if !checkRepositoriesConsistency(r, releaseIndex, testingIndex) {
 		hasProblems = true
 	}
 
@@ -94,7 +118,15 @@ func checkRepositoriesData(r *repo.Repository, releaseStack, testingStack repo.P
 		return false
 	}
 
-	if !checkRepositoriesPermissions(r, releaseIndex, testingIndex) {
+	if !checkRepositoriesFileNames(r, releaseIndex, testingIndex) {
+		hasProblems = true
+	}
+
+	if !waitForUserToContinue() {
+		return false
+	}
+
+	if !checkRepositoriesPermissions(ctx, releaseIndex, testingIndex) {
 		hasProblems = true
 	}
 
@@ -106,14 +138,46 @@ func checkRepositoriesData(r *repo.Repository, releaseStack, testingStack repo.P
 		hasProblems = true
 	}
 
+	if !waitForUserToContinue() {
+		return false
+	}
+
+	if !checkRepositoriesProvides(r, releaseIndex, testingIndex) {
+		hasProblems = true
+	}
+
+	if !waitForUserToContinue() {
+		return false
+	}
+
+	if !checkRepositoriesMeta(r, releaseIndex, testingIndex) {
+		hasProblems = true
+	}
+
+	if !waitForUserToContinue() {
+		return false
+	}
+
+	if !checkRepositoriesOrphans(r) {
+		hasProblems = true
+	}
+
+	if !waitForUserToContinue() {
+		return false
+	}
+
+	if !checkRepositoriesMetaFields(r, releaseIndex, testingIndex) {
+		hasProblems = true
+	}
+
 	return hasProblems == false
 }
 
 // checkRepositoriesConsistency check consistency between release and testing repositories
-func checkRepositoriesConsistency(releaseIndex, testingIndex map[string]*repo.Package) bool {
+func checkRepositoriesConsistency(r *repo.Repository, releaseIndex, testingIndex map[string]*repo.Package) bool {
 	errs := errors.NewBundle()
 
-	fmtc.Println("{*}[1/4]{!} Checking consistency between {?repo}testing{!} and {?repo}release{!} repository…")
+	fmtc.Println("{*}[1/9]{!} Checking consistency between {?repo}testing{!} and {?repo}release{!} repository…")
 
 	switch {
 	case len(releaseIndex) == 0:
@@ -149,6 +213,8 @@ func checkRepositoriesConsistency(releaseIndex, testingIndex map[string]*repo.Pa
 		}
 	}
 
+	errs.Add(checkRepositoriesDuplicates(r))
+
 	if !printCheckErrorsInfo(errs) {
 		return false
 	}
@@ -156,11 +222,43 @@ func checkRepositoriesConsistency(releaseIndex, testingIndex map[string]*repo.Pa
 	return true
 }
 
+// checkRepositoriesDuplicates checks release and testing repositories for
+// package files which exist in storage more than once
+func checkRepositoriesDuplicates(r *repo.Repository) *errors.Bundle {
+	errs := errors.NewBundle()
+
+	for _, subRepo := range []*repo.SubRepository{r.Release, r.Testing} {
+		duplicates, err := subRepo.FindDuplicates()
+
+		if err != nil {
+			errs.Add(fmt.Errorf("Can't check %s repository for duplicate files: %v", subRepo.Name, err))
+			continue
+		}
+
+		var fileNames []string
+
+		for fileName := range duplicates {
+			fileNames = append(fileNames, fileName)
+		}
+
+		sortutil.StringsNatural(fileNames)
+
+		for _, fileName := range fileNames {
+			errs.Add(fmt.Errorf(
+				"Package file %s exists more than once in %s repository (%s)",
+				fileName, subRepo.Name, strings.Join(duplicates[fileName], ", "),
+			))
+		}
+	}
+
+	return errs
+}
+
 // checkRepositoriesCRCInfo validates checksum info
 func checkRepositoriesCRCInfo(r *repo.Repository, releaseIndex, testingIndex map[string]*repo.Package) bool {
 	errs := errors.NewBundle()
 
-	fmtc.Println("\n{*}[2/4]{!} Validating checksum data…")
+	fmtc.Println("\n{*}[2/9]{!} Validating checksum data…")
 
 	totalPackages := len(releaseIndex) + len(testingIndex)
 	pb := progress.New(int64(totalPackages), "")
@@ -185,43 +283,44 @@ func checkRepositoriesCRCInfo(r *repo.Repository, releaseIndex, testingIndex map
 
 // checkRepositoryCRCInfo validates checksum for all repository files
 func checkRepositoryCRCInfo(pb *progress.Bar, r *repo.SubRepository, index map[string]*repo.Package) *errors.Bundle {
-	errs := errors.NewBundle()
+	pkgNames := getSortedPackageIndexKeys(index)
 
-	for _, pkgName := range getSortedPackageIndexKeys(index) {
-		for _, file := range index[pkgName].Files {
+	return runParallel(pb, runtime.NumCPU(), len(pkgNames), func(i int) []error {
+		var errs []error
+
+		for _, file := range index[pkgNames[i]].Files {
 			filePath := r.GetFullPackagePath(file)
 			fileCRC := strutil.Head(hash.FileHash(filePath), 7)
 
 			if fileCRC != file.CRC {
-				errs.Add(fmt.Errorf(
+				errs = append(errs, fmt.Errorf(
 					"Package %s in %s repository contains file %s with checksum mismatch between DB (%s) data and file on disk (%s)",
-					pkgName, r.Name, file.Path, file.CRC, fileCRC,
+					pkgNames[i], r.Name, file.Path, file.CRC, fileCRC,
 				))
 			}
 		}
 
-		pb.Add(1)
-	}
-
-	return errs
+		return errs
+	})
 }
 
-// checkRepositoriesPermissions checks packages permissions in release and testing repositories
-func checkRepositoriesPermissions(r *repo.Repository, releaseIndex, testingIndex map[string]*repo.Package) bool {
+// checkRepositoriesFileNames checks that packages file names match data from
+// packages headers
+func checkRepositoriesFileNames(r *repo.Repository, releaseIndex, testingIndex map[string]*repo.Package) bool {
 	errs := errors.NewBundle()
 
-	fmtc.Println("\n{*}[3/4]{!} Validating permissions…")
+	fmtc.Println("\n{*}[3/9]{!} Validating packages file names…")
 
 	totalPackages := len(releaseIndex) + len(testingIndex)
 	pb := progress.New(int64(totalPackages), "")
 	pb.Start()
 
-	if len(testingIndex) != 0 {
-		errs.Add(checkRepositoryPermissions(pb, r.Testing, testingIndex))
+	if len(releaseIndex) != 0 {
+		errs.Add(checkRepositoryFileNames(pb, r.Release, releaseIndex))
 	}
 
-	if len(releaseIndex) != 0 {
-		errs.Add(checkRepositoryPermissions(pb, r.Release, releaseIndex))
+	if len(testingIndex) != 0 {
+		errs.Add(checkRepositoryFileNames(pb, r.Testing, testingIndex))
 	}
 
 	pb.Finish()
@@ -233,132 +332,274 @@ func checkRepositoriesPermissions(r *repo.Repository, releaseIndex, testingIndex
 	return true
 }
 
-// checkRepositoryPermissions checks packages permissions in given repository
-func checkRepositoryPermissions(pb *progress.Bar, r *repo.SubRepository, index map[string]*repo.Package) *errors.Bundle {
-	errs := errors.NewBundle()
+// checkRepositoryFileNames compares NEVRA from header of every package file
+// with its file name
+func checkRepositoryFileNames(pb *progress.Bar, r *repo.SubRepository, index map[string]*repo.Package) *errors.Bundle {
+	pkgNames := getSortedPackageIndexKeys(index)
 
-	userID, groupID := -1, -1
+	return runParallel(pb, runtime.NumCPU(), len(pkgNames), func(i int) []error {
+		var errs []error
 
-	repoCfg := configs[r.Parent.Name]
-	user := repoCfg.GetS(PERMISSIONS_USER)
-	group := repoCfg.GetS(PERMISSIONS_GROUP)
-	filePerms := repoCfg.GetM(PERMISSIONS_FILE)
-	dirPerms := repoCfg.GetM(PERMISSIONS_DIR)
+		for _, file := range index[pkgNames[i]].Files {
+			fileName := path.Base(file.Path)
+			headerFileName, err := helpers.ExtractPackageFileName(r.GetFullPackagePath(file))
 
-	checkedDirs := map[string]bool{}
+			if err != nil {
+				errs = append(errs, fmt.Errorf(
+					"Error while reading package %s header in %s repository for file %s: %v",
+					pkgNames[i], r.Name, file.Path, err,
+				))
 
-	if user != "" {
-		userInfo, err := system.LookupUser(user)
+				continue
+			}
 
-		if err != nil {
-			errs.Add(err)
-			return errs
+			if fileName != headerFileName {
+				errs = append(errs, fmt.Errorf(
+					"Package %s in %s repository contains file %s with name that doesn't match package header (%s)",
+					pkgNames[i], r.Name, file.Path, headerFileName,
+				))
+			}
 		}
 
-		userID = userInfo.UID
+		return errs
+	})
+}
+
+// checkRepositoriesPermissions checks packages permissions in release and testing repositories
+func checkRepositoriesPermissions(ctx *context, releaseIndex, testingIndex map[string]*repo.Package) bool {
+	errs := errors.NewBundle()
+	r := ctx.Repo
+
+	fmtc.Println("\n{*}[4/9]{!} Validating permissions…")
+
+	attrs, err := getRepoObjectAttrs(r)
+
+	if err != nil {
+		errs.Add(err)
+		return printCheckErrorsInfo(errs)
 	}
 
-	if group != "" {
-		groupInfo, err := system.LookupGroup(group)
+	var fixes []objectFix
 
-		if err != nil {
-			errs.Add(err)
-			return errs
+	totalPackages := len(releaseIndex) + len(testingIndex)
+	pb := progress.New(int64(totalPackages), "")
+	pb.Start()
+
+	if len(testingIndex) != 0 {
+		subErrs, subFixes := checkRepositoryPermissions(pb, r.Testing, attrs, testingIndex)
+		errs.Add(subErrs)
+		fixes = append(fixes, subFixes...)
+	}
+
+	if len(releaseIndex) != 0 {
+		subErrs, subFixes := checkRepositoryPermissions(pb, r.Release, attrs, releaseIndex)
+		errs.Add(subErrs)
+		fixes = append(fixes, subFixes...)
+	}
+
+	pb.Finish()
+
+	if !printCheckErrorsInfo(errs) {
+		if options.GetB(OPT_FIX) && len(fixes) != 0 {
+			return fixObjectsAttrs(ctx, fixes)
 		}
 
-		groupID = groupInfo.GID
+		return false
 	}
 
+	return true
+}
+
+// checkRepositoryPermissions checks packages permissions in given repository
+func checkRepositoryPermissions(pb *progress.Bar, r *repo.SubRepository, attrs *objectAttrs, index map[string]*repo.Package) (*errors.Bundle, []objectFix) {
+	var fixes []objectFix
+
+	errs := errors.NewBundle()
+	checkedDirs := map[string]bool{}
+
 	for _, pkgName := range getSortedPackageIndexKeys(index) {
 		for _, file := range index[pkgName].Files {
 			filePath := r.GetFullPackagePath(file)
+			pkgFileDir := path.Dir(filePath)
+			needFix := false
+
+			if !checkedDirs[pkgFileDir] {
+				pkgDirPerms := fsutil.GetMode(pkgFileDir)
+
+				checkedDirs[pkgFileDir] = true
+
+				if attrs.DirPerms != 0 && pkgDirPerms != 0 && pkgDirPerms != attrs.DirPerms {
+					errs.Add(fmt.Errorf(
+						"Repository %s contains directory %s width wrong permissions (%s ≠ %s)",
+						r.Name, pkgFileDir, pkgDirPerms, attrs.DirPerms,
+					))
+
+					needFix = true
+				}
+			}
+
 			fileUID, fileGID, err := fsutil.GetOwner(filePath)
+			pkgFilePerms := fsutil.GetMode(filePath)
 
-			if err != nil {
+			switch {
+			case err != nil:
 				errs.Add(fmt.Errorf(
 					"Error while checking package %s permissions in %s repository for file %s: %v",
 					pkgName, r.Name, file.Path, err,
 				))
 
 				continue
-			}
 
-			if userID != -1 && fileUID != userID {
+			case attrs.UID != -1 && fileUID != attrs.UID:
 				errs.Add(fmt.Errorf(
 					"Package %s in %s repository contains file %s width wrong owner UID (%d ≠ %d)",
-					pkgName, r.Name, file.Path, fileUID, userID,
+					pkgName, r.Name, file.Path, fileUID, attrs.UID,
 				))
 
-				continue
-			}
+				needFix = true
 
-			if groupID != -1 && fileGID != groupID {
+			case attrs.GID != -1 && fileGID != attrs.GID:
 				errs.Add(fmt.Errorf(
 					"Package %s in %s repository contains file %s width wrong owner GID (%d ≠ %d)",
-					pkgName, r.Name, file.Path, fileGID, groupID,
+					pkgName, r.Name, file.Path, fileGID, attrs.GID,
 				))
 
-				continue
-			}
-
-			pkgFilePerms := fsutil.GetMode(filePath)
-			pkgFileDir := path.Dir(filePath)
+				needFix = true
 
-			if filePerms != 0 && pkgFilePerms != 0 && pkgFilePerms != filePerms {
+			case attrs.FilePerms != 0 && pkgFilePerms != 0 && pkgFilePerms != attrs.FilePerms:
 				errs.Add(fmt.Errorf(
 					"Package %s in %s repository contains file %s width wrong permissions (%s ≠ %s)",
-					pkgName, r.Name, file.Path, pkgFilePerms, filePerms,
+					pkgName, r.Name, file.Path, pkgFilePerms, attrs.FilePerms,
 				))
 
-				continue
+				needFix = true
 			}
 
-			if !checkedDirs[pkgFileDir] {
-				pkgDirPerms := fsutil.GetMode(pkgFileDir)
+			if needFix {
+				fixes = append(fixes, objectFix{Repo: r, File: file})
+			}
+		}
 
-				checkedDirs[pkgFileDir] = true
+		pb.Add(1)
+	}
 
-				if dirPerms != 0 && pkgDirPerms != 0 && pkgDirPerms != dirPerms {
-					errs.Add(fmt.Errorf(
-						"Repository %s contains directory %s width wrong permissions (%s ≠ %s)",
-						r.Name, pkgFileDir, pkgDirPerms, dirPerms,
-					))
+	return errs, fixes
+}
 
-					continue
-				}
-			}
+// getRepoObjectAttrs returns owner and permissions for repository files and
+// directories defined in repository configuration
+func getRepoObjectAttrs(r *repo.Repository) (*objectAttrs, error) {
+	repoCfg := configs[r.Name]
+	attrs := &objectAttrs{
+		UID:       -1,
+		GID:       -1,
+		FilePerms: repoCfg.GetM(PERMISSIONS_FILE),
+		DirPerms:  repoCfg.GetM(PERMISSIONS_DIR),
+	}
+
+	if repoCfg.GetS(PERMISSIONS_USER) != "" {
+		userInfo, err := system.LookupUser(repoCfg.GetS(PERMISSIONS_USER))
+
+		if err != nil {
+			return nil, err
 		}
 
-		pb.Add(1)
+		attrs.UID = userInfo.UID
 	}
 
-	return errs
+	if repoCfg.GetS(PERMISSIONS_GROUP) != "" {
+		groupInfo, err := system.LookupGroup(repoCfg.GetS(PERMISSIONS_GROUP))
+
+		if err != nil {
+			return nil, err
+		}
+
+		attrs.GID = groupInfo.GID
+	}
+
+	return attrs, nil
+}
+
+// fixObjectsAttrs asks user for confirmation and sets configured owner and
+// permissions for given package files and their directories
+func fixObjectsAttrs(ctx *context, fixes []objectFix) bool {
+	if !options.GetB(OPT_FORCE) {
+		fmtc.NewLine()
+
+		ok, err := input.ReadAnswer(fmt.Sprintf(
+			"Do you want to fix owner and permissions for %s %s?",
+			fmtutil.PrettyNum(len(fixes)),
+			pluralize.Pluralize(len(fixes), "package file", "package files"),
+		), "n")
+
+		if err != nil || !ok {
+			return false
+		}
+	}
+
+	errs := errors.NewBundle()
+
+	for _, fix := range fixes {
+		fileName := path.Base(fix.File.Path)
+		err := fix.Repo.RepairPackageAttrs(fix.File)
+
+		if err != nil {
+			errs.Add(fmt.Errorf("%s: %w", fileName, err))
+			continue
+		}
+
+		ctx.Logger.Get(fix.Repo.Name).Print(
+			"Fixed owner and permissions of package %s (%s)",
+			fileName, fix.File.BaseArchFlag.String(),
+		)
+	}
+
+	if !errs.IsEmpty() {
+		for _, err := range errs.All() {
+			terminal.Error(" • %v", err)
+		}
+
+		return false
+	}
+
+	fmtc.Printfn(
+		"{g}Owner and permissions fixed for %s %s{!}",
+		fmtutil.PrettyNum(len(fixes)),
+		pluralize.Pluralize(len(fixes), "package file", "package files"),
+	)
+
+	return true
 }
 
 // checkRepositoriesSignatures checks packages signatures in release and testing repositories
 func checkRepositoriesSignatures(r *repo.Repository, releaseIndex, testingIndex map[string]*repo.Package) bool {
 	errs := errors.NewBundle()
 
-	fmtc.Println("\n{*}[4/4]{!} Validating packages signatures…")
+	fmtc.Println("\n{*}[5/9]{!} Validating packages signatures…")
 
-	key, err := r.SigningKey.Read(nil)
+	keys, err := r.ReadVerificationKeys()
 
 	if err != nil {
-		terminal.Error("Can't read signing key: %v", err)
+		terminal.Error("Can't read signing keys: %v", err)
 		return false
 	}
 
+	if len(keys) == 0 {
+		terminal.Warn("No signing keys defined in configuration file, validation skipped")
+		return true
+	}
+
 	totalPackages := len(releaseIndex) + len(testingIndex)
 
 	pb := progress.New(int64(totalPackages), "")
 	pb.Start()
 
 	if len(testingIndex) != 0 {
-		errs.Add(checkRepositorySignatures(pb, r.Testing, key, testingIndex))
+		errs.Add(checkRepositorySignatures(pb, r.Testing, keys, testingIndex))
 	}
 
 	if len(releaseIndex) != 0 {
-		errs.Add(checkRepositorySignatures(pb, r.Release, key, releaseIndex))
+		errs.Add(checkRepositorySignatures(pb, r.Release, keys, releaseIndex))
 	}
 
 	pb.Finish()
@@ -371,54 +612,213 @@ func checkRepositoriesSignatures(r *repo.Repository, releaseIndex, testingIndex
 }
 
 // checkRepositorySignatures checks packages signatures in given repository
-func checkRepositorySignatures(pb *progress.Bar, r *repo.SubRepository, key *sign.Key, index map[string]*repo.Package) *errors.Bundle {
+func checkRepositorySignatures(pb *progress.Bar, r *repo.SubRepository, keys []*sign.Key, index map[string]*repo.Package) *errors.Bundle {
 	errs := errors.NewBundle()
 
 	for _, pkgName := range getSortedPackageIndexKeys(index) {
 		for _, file := range index[pkgName].Files {
-			filePath := r.GetFullPackagePath(file)
-			hasSign, err := sign.IsPackageSigned(filePath)
+			problem, err := checkPackageSignature(r.GetFullPackagePath(file), keys)
 
-			if err != nil {
+			switch problem {
+			case VERIFY_PROBLEM_ERROR:
 				errs.Add(fmt.Errorf(
 					"Error while checking package %s signature in %s repository for file %s: %v",
 					pkgName, r.Name, file.Path, err,
 				))
 
-				continue
-			}
-
-			if !hasSign {
+			case VERIFY_PROBLEM_UNSIGNED:
 				errs.Add(fmt.Errorf(
 					"Package %s in %s repository contains file %s without signature",
 					pkgName, r.Name, file.Path,
 				))
 
-				continue
+			case VERIFY_PROBLEM_WRONG_KEY:
+				errs.Add(fmt.Errorf(
+					"Package %s in %s repository contains file %s signed with untrusted key",
+					pkgName, r.Name, file.Path,
+				))
 			}
+		}
 
-			isSignValid, err := sign.IsPackageSignatureValid(filePath, key)
+		pb.Add(1)
+	}
 
-			if err != nil {
-				errs.Add(fmt.Errorf(
-					"Error while checking package %s signature in %s repository for file %s: %v",
-					pkgName, r.Name, file.Path, err,
-				))
+	return errs
+}
 
-				continue
-			}
+// checkRepositoriesProvides checks release and testing repositories for capabilities
+// provided by more than one package
+func checkRepositoriesProvides(r *repo.Repository, releaseIndex, testingIndex map[string]*repo.Package) bool {
+	errs := errors.NewBundle()
 
-			if !isSignValid {
-				errs.Add(fmt.Errorf(
-					"Package %s in %s repository contains file %s signed with different key",
-					pkgName, r.Name, file.Path,
-				))
+	fmtc.Println("\n{*}[6/9]{!} Checking provides collisions…")
 
-				continue
-			}
+	if len(testingIndex) != 0 {
+		errs.Add(checkRepositoryProvides(r.Testing))
+	}
+
+	if len(releaseIndex) != 0 {
+		errs.Add(checkRepositoryProvides(r.Release))
+	}
+
+	if !printCheckErrorsInfo(errs) {
+		return false
+	}
+
+	return true
+}
+
+// checkRepositoryProvides checks given repository for capabilities provided by
+// more than one package
+func checkRepositoryProvides(r *repo.SubRepository) *errors.Bundle {
+	errs := errors.NewBundle()
+	collisions, err := r.ProvidesCollisions()
+
+	if err != nil {
+		errs.Add(fmt.Errorf("Can't check provides in %s repository: %v", r.Name, err))
+		return errs
+	}
+
+	for _, collision := range collisions {
+		errs.Add(fmt.Errorf(
+			"Capability %s in %s repository is provided by several packages: %s",
+			collision.Name, r.Name, strings.Join(collision.Packages, ", "),
+		))
+	}
+
+	return errs
+}
+
+// checkRepositoriesMeta checks that all metadata files referenced in release and
+// testing repositories indexes exist
+func checkRepositoriesMeta(r *repo.Repository, releaseIndex, testingIndex map[string]*repo.Package) bool {
+	errs := errors.NewBundle()
+
+	fmtc.Println("\n{*}[7/9]{!} Validating metadata files…")
+
+	if len(testingIndex) != 0 {
+		errs.Add(checkRepositoryMeta(r.Testing))
+	}
+
+	if len(releaseIndex) != 0 {
+		errs.Add(checkRepositoryMeta(r.Release))
+	}
+
+	if !printCheckErrorsInfo(errs) {
+		return false
+	}
+
+	return true
+}
+
+// checkRepositoryMeta checks that all metadata files referenced in repository
+// index exist
+func checkRepositoryMeta(r *repo.SubRepository) *errors.Bundle {
+	errs := errors.NewBundle()
+	missingFiles, err := r.FindMissingMetaFiles()
+
+	if err != nil {
+		errs.Add(fmt.Errorf("Can't check metadata in %s repository: %v", r.Name, err))
+		return errs
+	}
+
+	for _, arch := range data.ArchList {
+		for _, file := range missingFiles[arch] {
+			errs.Add(fmt.Errorf(
+				"Index of %s repository (%s) references missing metadata file %s",
+				r.Name, arch, file,
+			))
 		}
+	}
 
-		pb.Add(1)
+	return errs
+}
+
+// checkRepositoriesOrphans checks release and testing repositories for package
+// files which aren't referenced in index
+func checkRepositoriesOrphans(r *repo.Repository) bool {
+	errs := errors.NewBundle()
+
+	fmtc.Println("\n{*}[8/9]{!} Searching orphan package files…")
+
+	errs.Add(checkRepositoryOrphans(r.Testing))
+	errs.Add(checkRepositoryOrphans(r.Release))
+
+	if !printCheckErrorsInfo(errs) {
+		return false
+	}
+
+	return true
+}
+
+// checkRepositoryOrphans checks repository for package files which aren't
+// referenced in index
+func checkRepositoryOrphans(r *repo.SubRepository) *errors.Bundle {
+	errs := errors.NewBundle()
+	orphanFiles, err := r.FindOrphanPackages()
+
+	if err != nil {
+		errs.Add(fmt.Errorf("Can't find orphan package files in %s repository: %v", r.Name, err))
+		return errs
+	}
+
+	for _, arch := range data.ArchList {
+		for _, file := range orphanFiles[arch] {
+			errs.Add(fmt.Errorf(
+				"Repository %s (%s) contains package file %s which isn't referenced in index",
+				r.Name, arch, file,
+			))
+		}
+	}
+
+	return errs
+}
+
+// checkRepositoriesMetaFields checks that packages in release and testing
+// repositories have all required metadata fields
+func checkRepositoriesMetaFields(r *repo.Repository, releaseIndex, testingIndex map[string]*repo.Package) bool {
+	errs := errors.NewBundle()
+
+	fmtc.Println("\n{*}[9/9]{!} Checking required metadata fields…")
+
+	fields := strutil.Fields(configs[r.Name].GetS(REPOSITORY_REQUIRE_FIELDS))
+
+	if len(fields) == 0 {
+		fmtc.Println("{s-}Required fields are not configured, skipping…{!}")
+		return true
+	}
+
+	if len(testingIndex) != 0 {
+		errs.Add(checkRepositoryMetaFields(r.Testing, fields))
+	}
+
+	if len(releaseIndex) != 0 {
+		errs.Add(checkRepositoryMetaFields(r.Release, fields))
+	}
+
+	if !printCheckErrorsInfo(errs) {
+		return false
+	}
+
+	return true
+}
+
+// checkRepositoryMetaFields checks that packages in repository have all
+// required metadata fields
+func checkRepositoryMetaFields(r *repo.SubRepository, fields []string) *errors.Bundle {
+	errs := errors.NewBundle()
+	emptyFieldsInfo, err := r.FindEmptyMetaFields(fields)
+
+	if err != nil {
+		errs.Add(fmt.Errorf("Can't check packages metadata in %s repository: %v", r.Name, err))
+		return errs
+	}
+
+	for _, info := range emptyFieldsInfo {
+		errs.Add(fmt.Errorf(
+			"Package %s in %s repository has empty required fields: %s",
+			info.Package, r.Name, strings.Join(info.Fields, ", "),
+		))
 	}
 
 	return errs
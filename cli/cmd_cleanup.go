@@ -8,13 +8,18 @@ package cli
 // ////////////////////////////////////////////////////////////////////////////////// //
 
 import (
+	"errors"
 	"fmt"
+	"os"
 	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/essentialkaos/ek/v13/fmtc"
 	"github.com/essentialkaos/ek/v13/fmtutil"
+	"github.com/essentialkaos/ek/v13/fsutil"
 	"github.com/essentialkaos/ek/v13/options"
+	"github.com/essentialkaos/ek/v13/path"
 	"github.com/essentialkaos/ek/v13/terminal"
 	"github.com/essentialkaos/ek/v13/terminal/input"
 
@@ -28,6 +33,12 @@ const MIN_KEEP_CLEANUP_VERS = 3
 
 // ////////////////////////////////////////////////////////////////////////////////// //
 
+// sourceBundles is slice with bundles built from different versions of one
+// source package
+type sourceBundles []repo.PackageBundle
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
 // cmdCleanup is 'cleanup' command handler
 func cmdCleanup(ctx *context, args options.Arguments) bool {
 	var testingStack, releaseStack repo.PackageStack
@@ -115,12 +126,125 @@ func cleanupPackages(ctx *context, releaseStack, testingStack repo.PackageStack)
 		}
 	}
 
+	if options.GetB(OPT_KEEP_SOURCE) {
+		return removeSourceBundles(ctx, releaseStack, testingStack)
+	}
+
 	testingFiles := testingStack.FlattenFiles()
 	releaseFiles := releaseStack.FlattenFiles()
 
 	return removePackagesFiles(ctx, releaseFiles, testingFiles)
 }
 
+// removeSourceBundles removes bundles built from outdated source packages from
+// both repositories
+func removeSourceBundles(ctx *context, releaseStack, testingStack repo.PackageStack) bool {
+	var hasErrors bool
+	var changed []*repo.SubRepository
+
+	isCancelProtected = true
+
+	for _, target := range []struct {
+		r     *repo.SubRepository
+		stack repo.PackageStack
+	}{
+		{ctx.Repo.Release, releaseStack},
+		{ctx.Repo.Testing, testingStack},
+	} {
+		var isRemoved bool
+
+		for _, bundle := range target.stack {
+			err := removeSourceBundle(ctx, target.r, bundle)
+
+			if err != nil {
+				terminal.Error(err.Error())
+				hasErrors = true
+			} else {
+				isRemoved = true
+			}
+
+			if isCanceled.Load() {
+				isCancelProtected = false
+				return false
+			}
+		}
+
+		if isRemoved {
+			changed = append(changed, target.r)
+		}
+	}
+
+	isCancelProtected = false
+
+	if len(changed) != 0 {
+		autoReindexRepositories(ctx, nil, changed...)
+	}
+
+	return hasErrors == false
+}
+
+// removeSourceBundle removes all files of given bundle. Files are staged to
+// temporary directory before removal, so if any file can't be removed, already
+// removed files are restored and bundle stays untouched.
+func removeSourceBundle(ctx *context, r *repo.SubRepository, bundle repo.PackageBundle) error {
+	stageDir, err := ctx.Temp.MkDir("rep")
+
+	if err != nil {
+		return fmt.Errorf("Can't create directory for staging files: %v", err)
+	}
+
+	var files, stagedFiles []string
+
+	for i, file := range bundle.FlattenFiles() {
+		stagedFile := path.Join(stageDir, strconv.Itoa(i), path.Base(file.Path))
+		err = os.Mkdir(path.Dir(stagedFile), 0700)
+
+		if err == nil {
+			err = fsutil.CopyFile(r.GetFullPackagePath(file), stagedFile, 0600)
+		}
+
+		if err != nil {
+			return fmt.Errorf("Can't stage file %s for removal: %v", file.Path, err)
+		}
+
+		files = append(files, file.Path)
+		stagedFiles = append(stagedFiles, stagedFile)
+	}
+
+	for i, file := range bundle.FlattenFiles() {
+		if removePackageFile(ctx, r, file) {
+			continue
+		}
+
+		var errs []error
+
+		for _, stagedFile := range stagedFiles[:i] {
+			errs = append(errs, r.AddPackage(stagedFile))
+		}
+
+		err = errors.Join(errs...)
+
+		if err != nil {
+			return fmt.Errorf(
+				"Can't remove bundle %s from %s repository and restore removed files: %v",
+				bundle[0].Src, r.Name, err,
+			)
+		}
+
+		ctx.Logger.Get(r.Name).Print(
+			"Removal of bundle %s rolled back (%s)",
+			bundle[0].Src, strings.Join(files[:i], ", "),
+		)
+
+		return fmt.Errorf(
+			"Can't remove bundle %s from %s repository, removed files have been restored",
+			bundle[0].Src, r.Name,
+		)
+	}
+
+	return nil
+}
+
 // getStackToCleanup returns stack with packages to remove
 func getStackToCleanup(r *repo.SubRepository, keepNum int, filter string) (repo.PackageStack, error) {
 	stack, err := r.List("", true)
@@ -129,6 +253,10 @@ func getStackToCleanup(r *repo.SubRepository, keepNum int, filter string) (repo.
 		return nil, err
 	}
 
+	if options.GetB(OPT_KEEP_SOURCE) {
+		return extractSourcesToCleanup(stack, keepNum, filter), nil
+	}
+
 	return extractPackagesToCleanup(stack, keepNum, filter), nil
 }
 
@@ -175,6 +303,62 @@ func extractPackagesToCleanup(stack repo.PackageStack, keepNum int, filter strin
 	return result
 }
 
+// extractSourcesToCleanup extracts bundles built from outdated source packages.
+// Bundles are grouped by source package name, so all binary packages built from
+// one source package are removed together.
+func extractSourcesToCleanup(stack repo.PackageStack, keepNum int, filter string) repo.PackageStack {
+	var result repo.PackageStack
+
+	sources := make(map[string]sourceBundles)
+
+	for _, bundle := range stack.Compact() {
+		src := bundle[0].Src
+
+		srcName := getSourcePackageName(src)
+
+		if filter != "" && srcName != filter {
+			continue
+		}
+
+		sources[srcName] = append(sources[srcName], bundle)
+	}
+
+	for _, bundles := range sources {
+		if len(bundles) <= keepNum {
+			continue
+		}
+
+		sort.Sort(sort.Reverse(bundles))
+
+		for _, bundle := range bundles[keepNum:] {
+			result = append(result, bundle)
+		}
+	}
+
+	if len(result) != 0 {
+		sort.Sort(result)
+	}
+
+	return result
+}
+
+// getSourcePackageName returns name of source package from source RPM file name
+func getSourcePackageName(src string) string {
+	src = strings.TrimSuffix(src, ".src.rpm")
+
+	for i := 0; i < 2; i++ {
+		index := strings.LastIndex(src, "-")
+
+		if index == -1 {
+			break
+		}
+
+		src = src[:index]
+	}
+
+	return src
+}
+
 // getMainPackageFromBundle returns main package from bundle
 func getMainPackageFromBundle(bundle repo.PackageBundle) *repo.Package {
 	if len(bundle) == 1 {
@@ -194,3 +378,26 @@ func getMainPackageFromBundle(bundle repo.PackageBundle) *repo.Package {
 
 	return nil
 }
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Len is the number of elements in the collection
+func (s sourceBundles) Len() int {
+	return len(s)
+}
+
+// Swap swaps the elements with indexes i and j
+func (s sourceBundles) Swap(i, j int) {
+	s[i], s[j] = s[j], s[i]
+}
+
+// Less reports whether the element with index i
+// must sort before the element with index j
+func (s sourceBundles) Less(i, j int) bool {
+	// All packages in bundle have the same version and release, so we compare
+	// only them using the same rules as for package stack
+	return repo.PackageStack{
+		repo.PackageBundle{&repo.Package{Version: s[i][0].Version, Release: s[i][0].Release}},
+		repo.PackageBundle{&repo.Package{Version: s[j][0].Version, Release: s[j][0].Release}},
+	}.Less(0, 1)
+}
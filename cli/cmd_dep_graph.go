@@ -0,0 +1,104 @@
+package cli
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2024 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/essentialkaos/ek/v13/options"
+	"github.com/essentialkaos/ek/v13/terminal"
+
+	"github.com/essentialkaos/rep/v3/repo"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+const (
+	GRAPH_FORMAT_DOT  = "dot"
+	GRAPH_FORMAT_JSON = "json"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// cmdDepGraph is 'dep-graph' command handler
+func cmdDepGraph(ctx *context, args options.Arguments) bool {
+	format := strings.ToLower(args.Get(0).String())
+
+	switch format {
+	case "":
+		format = GRAPH_FORMAT_DOT
+	case GRAPH_FORMAT_DOT, GRAPH_FORMAT_JSON:
+		// ok
+	default:
+		terminal.Error("Unknown graph format %q", format)
+		return false
+	}
+
+	if options.GetB(OPT_RELEASE) && options.GetB(OPT_TESTING) {
+		terminal.Error(
+			"Options %s and %s can't be used together",
+			options.F(OPT_RELEASE), options.F(OPT_TESTING),
+		)
+		return false
+	}
+
+	r := ctx.Repo.Release
+
+	if options.GetB(OPT_TESTING) {
+		r = ctx.Repo.Testing
+	}
+
+	graph, err := r.DepGraph()
+
+	if err != nil {
+		terminal.Error(err.Error())
+		return false
+	}
+
+	switch format {
+	case GRAPH_FORMAT_JSON:
+		return printDepGraphJSON(graph)
+	default:
+		printDepGraphDOT(r, graph)
+	}
+
+	return true
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// printDepGraphDOT prints dependency graph in DOT format
+func printDepGraphDOT(r *repo.SubRepository, graph *repo.DepGraph) {
+	fmt.Printf("digraph %q {\n", r.Parent.Name+"-"+r.Name)
+
+	for _, node := range graph.Nodes {
+		fmt.Printf("  %q;\n", node)
+	}
+
+	for _, edge := range graph.Edges {
+		fmt.Printf("  %q -> %q;\n", edge.From, edge.To)
+	}
+
+	fmt.Println("}")
+}
+
+// printDepGraphJSON prints dependency graph in JSON format
+func printDepGraphJSON(graph *repo.DepGraph) bool {
+	graphData, err := json.MarshalIndent(graph, "", "  ")
+
+	if err != nil {
+		terminal.Error("Can't encode graph data: %v", err)
+		return false
+	}
+
+	fmt.Println(string(graphData))
+
+	return true
+}
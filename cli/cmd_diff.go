@@ -0,0 +1,237 @@
+package cli
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2024 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"strings"
+
+	"github.com/essentialkaos/ek/v13/fmtc"
+	"github.com/essentialkaos/ek/v13/fmtutil"
+	"github.com/essentialkaos/ek/v13/options"
+	"github.com/essentialkaos/ek/v13/sortutil"
+	"github.com/essentialkaos/ek/v13/terminal"
+
+	"github.com/essentialkaos/rep/v3/repo"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// repoDiff contains info about differences between testing and release
+// repositories
+type repoDiff struct {
+	OnlyTesting []*repo.Package   `json:"only_testing"`
+	OnlyRelease []*repo.Package   `json:"only_release"`
+	Changed     []*repoDiffChange `json:"changed"`
+}
+
+// repoDiffChange contains info about package versions which present only in
+// one of repositories
+type repoDiffChange struct {
+	Name    string   `json:"name"`
+	Testing []string `json:"testing"`
+	Release []string `json:"release"`
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// cmdDiff is 'diff' command handler
+func cmdDiff(ctx *context, args options.Arguments) bool {
+	releaseStack, err := ctx.Repo.Release.List("", true)
+
+	if err != nil {
+		terminal.Error(err.Error())
+		return false
+	}
+
+	testingStack, err := ctx.Repo.Testing.List("", true)
+
+	if err != nil {
+		terminal.Error(err.Error())
+		return false
+	}
+
+	diff := getRepositoriesDiff(releaseStack, testingStack)
+
+	if options.GetB(OPT_JSON) {
+		return printJSON(diff)
+	}
+
+	printRepositoriesDiff(diff)
+
+	return true
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// getRepositoriesDiff compares release and testing stacks
+func getRepositoriesDiff(releaseStack, testingStack repo.PackageStack) *repoDiff {
+	releaseIndex := createEVRIndexForStack(releaseStack)
+	testingIndex := createEVRIndexForStack(testingStack)
+
+	releaseNames := groupPackagesByName(releaseIndex)
+	testingNames := groupPackagesByName(testingIndex)
+
+	diff := &repoDiff{
+		OnlyTesting: []*repo.Package{},
+		OnlyRelease: []*repo.Package{},
+		Changed:     []*repoDiffChange{},
+	}
+
+	for _, name := range getDiffPackageNames(releaseNames, testingNames) {
+		releasePkgs, testingPkgs := releaseNames[name], testingNames[name]
+
+		switch {
+		case len(releasePkgs) == 0:
+			diff.OnlyTesting = append(diff.OnlyTesting, testingPkgs...)
+			continue
+		case len(testingPkgs) == 0:
+			diff.OnlyRelease = append(diff.OnlyRelease, releasePkgs...)
+			continue
+		}
+
+		change := &repoDiffChange{
+			Name:    name,
+			Testing: getMissingVersions(testingPkgs, releaseIndex),
+			Release: getMissingVersions(releasePkgs, testingIndex),
+		}
+
+		if len(change.Testing) != 0 || len(change.Release) != 0 {
+			diff.Changed = append(diff.Changed, change)
+		}
+	}
+
+	return diff
+}
+
+// createEVRIndexForStack creates index for given stack using package name with
+// full EVR (epoch, version and release) as a key
+func createEVRIndexForStack(stack repo.PackageStack) map[string]*repo.Package {
+	result := map[string]*repo.Package{}
+
+	for _, bundle := range stack {
+		for _, pkg := range bundle {
+			result[pkg.Name+"-"+getPackageEVR(pkg)] = pkg
+		}
+	}
+
+	return result
+}
+
+// getPackageEVR returns package EVR (epoch:version-release), epoch is omitted
+// if it is empty or zero
+func getPackageEVR(pkg *repo.Package) string {
+	if pkg.Epoch == "" || pkg.Epoch == "0" {
+		return pkg.Version + "-" + pkg.Release
+	}
+
+	return pkg.Epoch + ":" + pkg.Version + "-" + pkg.Release
+}
+
+// groupPackagesByName groups packages from index by name
+func groupPackagesByName(index map[string]*repo.Package) map[string][]*repo.Package {
+	result := make(map[string][]*repo.Package)
+
+	for _, fullName := range getSortedPackageIndexKeys(index) {
+		pkg := index[fullName]
+		result[pkg.Name] = append(result[pkg.Name], pkg)
+	}
+
+	return result
+}
+
+// getDiffPackageNames returns sorted slice with names of packages from both
+// repositories
+func getDiffPackageNames(releaseNames, testingNames map[string][]*repo.Package) []string {
+	var result []string
+
+	for name := range releaseNames {
+		result = append(result, name)
+	}
+
+	for name := range testingNames {
+		if releaseNames[name] == nil {
+			result = append(result, name)
+		}
+	}
+
+	sortutil.StringsNatural(result)
+
+	return result
+}
+
+// getMissingVersions returns versions of given packages which are absent in
+// given index
+func getMissingVersions(pkgs []*repo.Package, index map[string]*repo.Package) []string {
+	result := []string{}
+
+	for _, pkg := range pkgs {
+		evr := getPackageEVR(pkg)
+
+		if index[pkg.Name+"-"+evr] == nil {
+			result = append(result, evr)
+		}
+	}
+
+	return result
+}
+
+// printRepositoriesDiff prints info about differences between repositories
+func printRepositoriesDiff(diff *repoDiff) {
+	fmtc.Println("{*}Only in {?repo}testing{!}{*}:{!}")
+	printDiffPackages(diff.OnlyTesting)
+
+	fmtc.Println("\n{*}Only in {?repo}release{!}{*}:{!}")
+	printDiffPackages(diff.OnlyRelease)
+
+	fmtc.Println("\n{*}Different versions:{!}")
+
+	if len(diff.Changed) == 0 {
+		fmtc.Println("{s-}—{!}")
+	}
+
+	for _, change := range diff.Changed {
+		fmtc.Printfn(
+			"{s-}•{!} {?package}%s{!} "+getDiffVersionsFormat(change.Release)+
+				" {s}→{!} "+getDiffVersionsFormat(change.Testing),
+			change.Name, formatDiffVersions(change.Release),
+			formatDiffVersions(change.Testing),
+		)
+	}
+
+	fmtutil.Separator(true)
+}
+
+// printDiffPackages prints list of packages
+func printDiffPackages(pkgs []*repo.Package) {
+	if len(pkgs) == 0 {
+		fmtc.Println("{s-}—{!}")
+		return
+	}
+
+	for _, pkg := range pkgs {
+		fmtc.Printfn("{s-}•{!} {?package}%s{!}", pkg.FullName())
+	}
+}
+
+// getDiffVersionsFormat returns format for versions slice
+func getDiffVersionsFormat(versions []string) string {
+	if len(versions) == 0 {
+		return "{s-}%s{!}"
+	}
+
+	return "%s"
+}
+
+// formatDiffVersions formats slice with versions for output
+func formatDiffVersions(versions []string) string {
+	if len(versions) == 0 {
+		return "—"
+	}
+
+	return strings.Join(versions, ", ")
+}
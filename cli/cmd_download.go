@@ -0,0 +1,139 @@
+package cli
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2024 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"fmt"
+
+	"github.com/essentialkaos/ek/v13/fmtutil"
+	"github.com/essentialkaos/ek/v13/fsutil"
+	"github.com/essentialkaos/ek/v13/options"
+	"github.com/essentialkaos/ek/v13/path"
+	"github.com/essentialkaos/ek/v13/spinner"
+	"github.com/essentialkaos/ek/v13/terminal"
+
+	"github.com/essentialkaos/rep/v3/repo"
+	"github.com/essentialkaos/rep/v3/repo/data"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// cmdDownload is 'download' command handler
+func cmdDownload(ctx *context, args options.Arguments) bool {
+	r := ctx.Repo.Testing
+
+	if options.GetB(OPT_RELEASE) {
+		r = ctx.Repo.Release
+	}
+
+	archFlag := data.ARCH_FLAG_UNKNOWN
+
+	if options.Has(OPT_ARCH) {
+		arch := options.GetS(OPT_ARCH)
+		archFlag = data.SupportedArchs[arch].Flag
+
+		if archFlag == data.ARCH_FLAG_UNKNOWN {
+			terminal.Error("Unknown or unsupported architecture %q", arch)
+			return false
+		}
+	}
+
+	stack, _, err := smartPackageSearch(r, args)
+
+	if err != nil {
+		terminal.Error(err.Error())
+		return false
+	}
+
+	files := getDownloadFiles(stack, archFlag)
+
+	if len(files) == 0 {
+		terminal.Warn("No packages found")
+		return false
+	}
+
+	return downloadPackageFiles(r, files)
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// getDownloadFiles returns unique package files from stack with given arch
+// (ARCH_FLAG_UNKNOWN means any arch)
+func getDownloadFiles(stack repo.PackageStack, archFlag data.ArchFlag) repo.PackageFiles {
+	var result repo.PackageFiles
+
+	added := make(map[string]bool)
+
+	for _, file := range stack.FlattenFiles() {
+		fileName := path.Base(file.Path)
+
+		if added[fileName] {
+			continue
+		}
+
+		if archFlag != data.ARCH_FLAG_UNKNOWN && file.ArchFlag != archFlag {
+			continue
+		}
+
+		added[fileName] = true
+		result = append(result, file)
+	}
+
+	return result
+}
+
+// downloadPackageFiles copies given package files to current directory
+func downloadPackageFiles(r *repo.SubRepository, files repo.PackageFiles) bool {
+	var hasErrors bool
+
+	for _, file := range files {
+		if isCanceled.Load() {
+			return false
+		}
+
+		fileName := path.Base(file.Path)
+
+		spinner.Show("Downloading {?package}%s{!}", fileName)
+
+		err := downloadPackageFile(r, file, fileName)
+
+		if err != nil {
+			spinner.Update("Can't download {?package}%s{!}", fileName)
+			spinner.Done(false)
+			terminal.Error("   %v", err)
+			hasErrors = true
+			continue
+		}
+
+		spinner.Update(
+			"Package {?package}%s{!} downloaded {s-}(%s){!}",
+			fileName, fmtutil.PrettySize(fsutil.GetSize(fileName)),
+		)
+		spinner.Done(true)
+	}
+
+	return !hasErrors
+}
+
+// downloadPackageFile copies package file from storage to given file
+func downloadPackageFile(r *repo.SubRepository, file repo.PackageFile, output string) error {
+	if fsutil.IsExist(output) && !options.GetB(OPT_FORCE) {
+		return fmt.Errorf(
+			"File %s already exists (use %s option to overwrite it)",
+			output, options.Format(OPT_FORCE),
+		)
+	}
+
+	filePath := r.GetFullPackagePath(file)
+
+	if filePath == "" || !fsutil.IsExist(filePath) {
+		return fmt.Errorf("Package file %s is missing in storage", file.Path)
+	}
+
+	return fsutil.CopyFile(filePath, output, 0644)
+}
@@ -0,0 +1,218 @@
+package cli
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2024 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/essentialkaos/ek/v13/fmtutil"
+	"github.com/essentialkaos/ek/v13/fsutil"
+	"github.com/essentialkaos/ek/v13/options"
+	"github.com/essentialkaos/ek/v13/path"
+	"github.com/essentialkaos/ek/v13/pluralize"
+	"github.com/essentialkaos/ek/v13/sortutil"
+	"github.com/essentialkaos/ek/v13/spinner"
+	"github.com/essentialkaos/ek/v13/terminal"
+
+	"github.com/essentialkaos/rep/v3/repo"
+	"github.com/essentialkaos/rep/v3/repo/data"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// cmdExport is 'export' command handler
+func cmdExport(ctx *context, args options.Arguments) bool {
+	output := args.Get(0).Clean().String()
+
+	if fsutil.IsExist(output) {
+		terminal.Error("File %s already exists", output)
+		return false
+	}
+
+	r := ctx.Repo.Release
+
+	if options.GetB(OPT_TESTING) {
+		r = ctx.Repo.Testing
+	}
+
+	files, err := getExportFiles(r, options.GetB(OPT_NO_SOURCE))
+
+	if err != nil {
+		terminal.Error(err)
+		return false
+	}
+
+	if len(files) == 0 {
+		terminal.Warn("There is nothing to export in %s repository", r.Name)
+		return false
+	}
+
+	spinner.Show("Exporting {*}{?repo}%s{!} repository to {*}%s{!}", r.Name, output)
+
+	isCancelProtected = true
+	err = exportFiles(output, files)
+	isCancelProtected = false
+
+	if err != nil {
+		os.Remove(output)
+		spinner.Update("Can't export {*}{?repo}%s{!} repository", r.Name)
+		spinner.Done(false)
+		terminal.Error(err)
+		return false
+	}
+
+	spinner.Update(
+		"{*}{?repo}%s{!} repository exported to {*}%s{!} {s-}(%s, %s){!}",
+		r.Name, output, pluralize.PS(pluralize.En, "%d %s", len(files), "file", "files"),
+		fmtutil.PrettySize(fsutil.GetSize(output)),
+	)
+
+	spinner.Done(true)
+
+	return true
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// getExportFiles returns map with all package and metadata files of
+// sub-repository (key - path in archive, value - path to file)
+func getExportFiles(r *repo.SubRepository, noSource bool) (map[string]string, error) {
+	stack, err := r.List("", true)
+
+	if err != nil {
+		return nil, fmt.Errorf("Can't get packages list: %w", err)
+	}
+
+	result := make(map[string]string)
+
+	for _, file := range stack.FlattenFiles() {
+		if noSource && file.BaseArchFlag == data.ARCH_FLAG_SRC {
+			continue
+		}
+
+		filePath := r.GetFullPackagePath(file)
+
+		if filePath == "" || !fsutil.IsExist(filePath) {
+			return nil, fmt.Errorf("Package file %s is missing in storage", file.Path)
+		}
+
+		archDir := data.SupportedArchs[file.BaseArchFlag.String()].Dir
+		result[path.Join(r.Name, archDir, file.Path)] = filePath
+	}
+
+	for _, arch := range data.ArchList {
+		archDir := data.SupportedArchs[arch].Dir
+
+		if archDir == "" || !r.HasArch(arch) {
+			continue
+		}
+
+		if noSource && arch == data.ARCH_SRC {
+			continue
+		}
+
+		metaDir := path.Dir(r.GetMetaIndexPath(arch))
+
+		if !fsutil.IsExist(metaDir) {
+			continue
+		}
+
+		for _, metaFile := range fsutil.List(metaDir, true, fsutil.ListingFilter{Perms: "F"}) {
+			result[path.Join(r.Name, archDir, "repodata", metaFile)] = path.Join(metaDir, metaFile)
+		}
+	}
+
+	return result, nil
+}
+
+// exportFiles writes given files to gzip compressed tarball
+func exportFiles(output string, files map[string]string) error {
+	fd, err := os.OpenFile(output, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+
+	if err != nil {
+		return fmt.Errorf("Can't create archive: %w", err)
+	}
+
+	defer fd.Close()
+
+	gw := gzip.NewWriter(fd)
+	tw := tar.NewWriter(gw)
+
+	var names []string
+
+	for name := range files {
+		names = append(names, name)
+	}
+
+	sortutil.StringsNatural(names)
+
+	for _, name := range names {
+		err = appendFileToTar(tw, name, files[name])
+
+		if err != nil {
+			return err
+		}
+	}
+
+	err = tw.Close()
+
+	if err != nil {
+		return fmt.Errorf("Can't write archive data: %w", err)
+	}
+
+	err = gw.Close()
+
+	if err != nil {
+		return fmt.Errorf("Can't write archive data: %w", err)
+	}
+
+	return nil
+}
+
+// appendFileToTar adds file to tar archive with given name
+func appendFileToTar(tw *tar.Writer, name, file string) error {
+	fd, err := os.Open(file)
+
+	if err != nil {
+		return fmt.Errorf("Can't open file %s: %w", file, err)
+	}
+
+	defer fd.Close()
+
+	info, err := fd.Stat()
+
+	if err != nil {
+		return fmt.Errorf("Can't get info about file %s: %w", file, err)
+	}
+
+	header, err := tar.FileInfoHeader(info, "")
+
+	if err != nil {
+		return fmt.Errorf("Can't create archive header for file %s: %w", file, err)
+	}
+
+	header.Name = name
+
+	err = tw.WriteHeader(header)
+
+	if err != nil {
+		return fmt.Errorf("Can't write archive header for file %s: %w", file, err)
+	}
+
+	_, err = io.Copy(tw, fd)
+
+	if err != nil {
+		return fmt.Errorf("Can't write file %s to archive: %w", file, err)
+	}
+
+	return nil
+}
@@ -9,6 +9,7 @@ package cli
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/essentialkaos/ek/v13/fmtutil"
 	"github.com/essentialkaos/ek/v13/options"
@@ -23,6 +24,10 @@ import (
 
 // cmdFind is 'find' command handler
 func cmdFind(ctx *context, args options.Arguments) bool {
+	if !parseFieldsOption() || !validateSourceOptions() || !validateSortOption() {
+		return false
+	}
+
 	searchRequest, err := query.Parse(args.Strings())
 
 	if err != nil {
@@ -52,6 +57,10 @@ func cmdFind(ctx *context, args options.Arguments) bool {
 		}
 	}
 
+	if options.GetB(OPT_JSON) {
+		return printJSON(jsonStacks)
+	}
+
 	if !rawOutput {
 		fmtutil.Separator(true)
 	}
@@ -67,7 +76,14 @@ func findPackages(r *repo.SubRepository, searchRequest *query.Request) (repo.Pac
 		return nil, fmt.Errorf("Search query must have at least one search (non-filtering) term")
 	}
 
-	stack, err := r.Find(searchRequest.Query)
+	var err error
+	var stack repo.PackageStack
+
+	if options.GetB(OPT_WHY) {
+		stack, err = r.FindWithMatches(searchRequest.Query)
+	} else {
+		stack, err = r.Find(searchRequest.Query)
+	}
 
 	if err != nil {
 		return nil, err
@@ -82,6 +98,10 @@ func findPackages(r *repo.SubRepository, searchRequest *query.Request) (repo.Pac
 		}
 	}
 
+	if !stack.IsEmpty() && searchRequest.DebugFlag != query.DEBUG_FLAG_NONE {
+		stack = filterDebugPackages(stack, searchRequest.DebugFlag == query.DEBUG_FLAG_ONLY)
+	}
+
 	return stack, err
 }
 
@@ -94,7 +114,17 @@ func findAndShowPackages(r *repo.SubRepository, searchRequest *query.Request) bo
 		return false
 	}
 
-	printPackageList(r, stack, "")
+	stack, err = filterPackageStackByTag(r, stack)
+
+	if err != nil {
+		terminal.Error(err.Error())
+		return false
+	}
+
+	stack = sortPackageStack(filterPackageStackBySource(stack))
+	stack = stack.Slice(searchRequest.Offset, searchRequest.Limit)
+
+	showPackageList(r, stack, "")
 
 	return true
 }
@@ -127,3 +157,24 @@ func filterPackagesByReleaseStatus(r *repo.SubRepository, stack repo.PackageStac
 
 	return stack
 }
+
+// filterDebugPackages removes debug packages (debuginfo, debugsource) from stack or
+// keeps only them
+func filterDebugPackages(stack repo.PackageStack, onlyDebug bool) repo.PackageStack {
+	for _, bundle := range stack {
+		for index, pkg := range bundle {
+			if pkg != nil && isDebugPackage(pkg.Name) != onlyDebug {
+				bundle[index] = nil
+			}
+		}
+	}
+
+	return stack
+}
+
+// isDebugPackage returns true if package with given name contains debug data
+func isDebugPackage(name string) bool {
+	return strings.HasSuffix(name, "-debuginfo") ||
+		strings.HasSuffix(name, "-debugsource") ||
+		strings.HasSuffix(name, "-debug")
+}
@@ -190,6 +190,22 @@ func cmdHelp(ctx *context, args options.Arguments) bool {
 		helpPurgeCache()
 	case COMMAND_STATS, COMMAND_SHORT_STATS:
 		helpStats()
+	case COMMAND_DEP_GRAPH, COMMAND_SHORT_DEP_GRAPH:
+		helpDepGraph()
+	case COMMAND_EXPORT, COMMAND_SHORT_EXPORT:
+		helpExport()
+	case COMMAND_IMPORT, COMMAND_SHORT_IMPORT:
+		helpImport()
+	case COMMAND_DIFF, COMMAND_SHORT_DIFF:
+		helpDiff()
+	case COMMAND_DOWNLOAD, COMMAND_SHORT_DOWNLOAD:
+		helpDownload()
+	case COMMAND_TAG, COMMAND_SHORT_TAG:
+		helpTag()
+	case COMMAND_METRICS, COMMAND_SHORT_METRICS:
+		helpMetrics()
+	case COMMAND_VERIFY, COMMAND_SHORT_VERIFY:
+		helpVerify()
 	case COMMAND_HELP, COMMAND_SHORT_HELP:
 		helpHelp()
 	default:
@@ -269,6 +285,7 @@ func helpList() {
 		examples: []commandExample{
 			{"", "Show a list of all the latest versions of packages in all (release and testing) repositories"},
 			{"my-package", "Show a list of all versions of the package with the given name"},
+			{"'my-package*'", "Show a list of all versions of packages with names starting with my-package"},
 			{
 				info.GetOption(OPT_TESTING).String() + " my-package",
 				"Show a list of all package versions with the given name only in the testing repository",
@@ -281,13 +298,31 @@ func helpList() {
 				info.GetOption(OPT_PAGER).String() + " | more",
 				"View long list of packages with some pager utility (more/less)",
 			},
+			{
+				info.GetOption(OPT_FIELDS).String() + " name,version,path | column -t",
+				"Show a list of packages with only given fields (" + strings.Join(outputFields, ", ") + ")",
+			},
+			{
+				info.GetOption(OPT_SORT).String() + " size",
+				"Show a list of packages sorted by size (biggest first)",
+			},
+			{
+				info.GetOption(OPT_RELEASE_ONLY).String(),
+				"Show a list of packages which were added to the release repository bypassing the testing repository",
+			},
+			{
+				info.GetOption(OPT_SUMMARY).String() + " 'my-package*'",
+				"Show number of files and total size of every package bundle",
+			},
+			{info.GetOption(OPT_ONLY_SOURCE).String(), "Show a list of source packages"},
+			{info.GetOption(OPT_NO_SOURCE).String(), "Show a list of packages without source packages"},
 		},
 		isGlobal: false,
 	}
 
 	help.Usage()
 	help.Paragraph("The command shows a list of all packages in the repository. By default, the command shows only the latest versions of packages within all repositories.")
-	help.Paragraph("You can filter the listing providing part of the package name. In this case, the command will show all versions of packages with the given name part.")
+	help.Paragraph("You can filter the listing providing part of the package name. In this case, the command will show all versions of packages with the given name part. Filter also can be a glob pattern (e.g. 'nginx*' or '*-devel') which is matched against package name or full name.")
 	help.Shortcut()
 	help.Options()
 	help.Examples()
@@ -295,13 +330,17 @@ func helpList() {
 
 // helpWhichSource shows help content about "which-source" command
 func helpWhichSource() {
+	info := genUsage()
 	help := &commandHelp{
 		command:  COMMAND_WHICH_SOURCE,
 		shortcut: COMMAND_SHORT_WHICH_SOURCE,
-		info:     genUsage(),
+		info:     info,
 		examples: []commandExample{
 			{"my-package-1.0", "Simple package search"},
 			{"n:my-package v:1.0* d:3w", "Find packages with search query syntax"},
+			{info.GetOption(OPT_FILE).String() + " my-package-1.0-0.el7.x86_64.rpm", "Show source package name for RPM file"},
+			{info.GetOption(OPT_BINARIES).String() + " my-package", "Show all binary packages built from my-package source package"},
+			{info.GetOption(OPT_BINARIES).String() + " my-package-1.0-0.el7.src.rpm", "Show binary packages built from given source RPM"},
 		},
 		isGlobal: false,
 	}
@@ -309,6 +348,7 @@ func helpWhichSource() {
 	help.Usage()
 	help.Paragraph("This command shows the source package used for package building or source package created while package building. This command is very useful for package searching. You may find the source package and use it in the search query ({s}s:{!} or {s}source:{!} query prefix with {y}" + COMMAND_REMOVE + "{!}, {y}" + COMMAND_RELEASE + "{!}, and {y}" + COMMAND_UNRELEASE + "{!} commands).")
 	help.Paragraph("You can use search query syntax for package selection. For more information about query syntax, see \"rep {?cmd}" + COMMAND_HELP + "{!} {?arg}" + COMMAND_FIND + "{!}\".")
+	help.Paragraph("With " + info.GetOption(OPT_BINARIES).String() + " option, command works in reverse mode: it takes source package names {s}(e.g. my-package){!} or source RPM file names {s}(e.g. my-package-1.0-0.el7.src.rpm){!} and shows all binary packages built from them.")
 	help.Shortcut()
 	help.Options()
 	help.Examples()
@@ -325,17 +365,24 @@ func helpFind() {
 			{"nginx", "Search packages which name starts with \"nginx\""},
 			{"n:nginx", "Search packages with name \"nginx\""},
 			{info.GetOption(OPT_TESTING).String() + " n:nginx", "Search packages with name \"nginx\" only in the testing repository"},
+			{info.GetOption(OPT_JSON).String() + " n:nginx", "Search packages with name \"nginx\" and print result in JSON format"},
 			{"n:'*utils*'", "Search packages with substring \"utils\" in name"},
+			{"n~:NGINX", "Search packages with name \"nginx\" ignoring case"},
+			{"re:'^nginx(-module-.+)?$'", "Search packages which name matches regular expression"},
 			{"n:nginx v:1.21.3", "Search packages with given name and version"},
 			{"n:nginx v:1.21.3 r:1.el7", "Search packages with given name, version and release"},
 			{"n:nginx v:1.21.3 r::1.*", "Search packages with given name, version and release which NOT equals 1"},
 			{"n:nginx v:'1.19.6|1.21.3|1.21.0'", "Search packages with given name and versions"},
+			{"'(' n:nginx '|' n:haproxy v:'2.*' ')' a:x64", "Search nginx packages or haproxy 2.x packages for x86_64"},
 			{"my-package a:x86_64", "Search packages with given name and architecture"},
 			{"s:redis-6.0.4-0.el7.src", "Search packages built from given source package"},
 			{"R:'mylib>=1.16'", "Search packages which require mylib 1.16 or greater"},
 			{"R:'/usr/sbin/useradd'", "Search packages which require useradd utility"},
 			{"P:'postgresql-server=11.*'", "Search packages which provide \"postgresql-server\" package"},
 			{"n:nginx d:7", "Search packages with name \"nginx\" added to the repository in last 7 days"},
+			{"pk:'*@example.com*'", "Search packages packaged by people with email in example.com domain"},
+			{"h:'*.example.com'", "Search packages built on hosts in example.com domain"},
+			{"d:2023-01-01..2023-06-01", "Search packages added to the repository between January 1 and June 1, 2023"},
 			{"D:1w3d12h15m30s", "Search packages built in last 1 week, 3 days, 12 hours, 15 minutes, and 30 seconds"},
 			{"S:10mb", "Search packages with a size around 10 megabytes (size +/- 2%)"},
 			{"S:100mb+", "Search packages bigger than 100 megabytes"},
@@ -345,6 +392,12 @@ func helpFind() {
 			{"@:'/usr/include/curl/*.h'", "Search packages with header files for cURL"},
 			{"n:nginx ^:no", "All nginx packages which not yet released"},
 			{"n:nginx ^:true", "All released nginx packages"},
+			{"n:nginx debug:no", "All nginx packages except debuginfo and debugsource packages"},
+			{info.GetOption(OPT_SORT).String() + " date-add n:nginx", "Search packages with name \"nginx\" and show recently added first"},
+			{"'n:*lib*' limit:50 offset:100", "Search packages with substring \"lib\" in name and show packages from 101 to 150"},
+			{info.GetOption(OPT_WHY).String() + " P:'libssl.so*'", "Search packages which provide libssl and show matched values"},
+			{info.GetOption(OPT_SUMMARY).String() + " s:redis", "Show number of files and total size of packages built from redis sources"},
+			{info.GetOption(OPT_TAG).String() + " security-fix n:openssl", "Search openssl packages with tag \"security-fix\""},
 			{
 				"postgres v:'10.*' | grep -E '(devel|docs)' | awk -F'/' '{print $NF}' | sort -u",
 				"Search packages and process list with found rpm files with grep, awk, and sort",
@@ -368,6 +421,8 @@ func helpFind() {
 	help.Query(query.TERM_SHORT_LICENSE, query.TERM_LICENSE, "Package license", "String")
 	help.Query(query.TERM_SHORT_GROUP, query.TERM_GROUP, "Package group", "String")
 	help.Query(query.TERM_SHORT_VENDOR, query.TERM_VENDOR, "Package vendor", "String")
+	help.Query(query.TERM_SHORT_PACKAGER, query.TERM_PACKAGER, "Package packager", "String")
+	help.Query(query.TERM_SHORT_BUILD_HOST, query.TERM_BUILD_HOST, "Name of host where package was built", "String")
 	help.Query(query.TERM_SHORT_PROVIDES, query.TERM_PROVIDES, "Package name or binary name provided by the package", "Dependency")
 	help.Query(query.TERM_SHORT_REQUIRES, query.TERM_REQUIRES, "Package name or binary name required by the package", "Dependency")
 	help.Query(query.TERM_SHORT_CONFLICTS, query.TERM_CONFLICTS, "Name of conflicting package", "Dependency")
@@ -376,12 +431,14 @@ func helpFind() {
 	help.Query(query.TERM_SHORT_ENHANCES, query.TERM_ENHANCES, "Name of package defined as the enhancement", "Dependency")
 	help.Query(query.TERM_SHORT_SUGGESTS, query.TERM_SUGGESTS, "Name of package defined as the suggestion", "Dependency")
 	help.Query(query.TERM_SHORT_SUPPLEMENTS, query.TERM_SUPPLEMENTS, "Name of package defined as the supplement", "Dependency")
-	help.Query(query.TERM_SHORT_DATE_ADD, query.TERM_DATE_ADD, "Duration since package was added to repository", "Duration")
-	help.Query(query.TERM_SHORT_DATE_BUILD, query.TERM_DATE_BUILD, "Duration since package was built", "Duration")
+	help.Query(query.TERM_SHORT_DATE_ADD, query.TERM_DATE_ADD, "Duration since package was added to repository or range of dates", "Duration")
+	help.Query(query.TERM_SHORT_DATE_BUILD, query.TERM_DATE_BUILD, "Duration since package was built or range of dates", "Duration")
 	help.Query(query.TERM_SHORT_SIZE, query.TERM_SIZE, "Package size", "Size")
 	help.Query(query.TERM_SHORT_FILE, query.TERM_FILE, "Path of config, binary or executable file provided by package", "String")
 	help.Query(query.TERM_SHORT_PAYLOAD, query.TERM_PAYLOAD, "Path of file or directory in package", "String")
+	help.Query(query.TERM_SHORT_REGEX, query.TERM_REGEX, "Package name", "Regexp")
 	help.Query(query.TERM_SHORT_RELEASED, query.TERM_RELEASED, "Release status", "Boolean")
+	help.Query(query.TERM_SHORT_DEBUG, query.TERM_DEBUG, "Debug package (debuginfo or debugsource)", "Boolean")
 
 	fmtc.NewLine()
 
@@ -394,11 +451,15 @@ func helpFind() {
 	fmtc.Println("    {s-}•{!} {&}Dependency{!}    Package name with or without version and release condition")
 	fmtc.Println("    {s-}•{!} {&}Architecture{!}  Package architecture {s}(" + strings.Join(data.ArchList, ", ") + "){!}")
 	fmtc.Println("    {s-}•{!} {&}Size{!}          Size {s}(b/kb/mb/gb){!} with modificators {s-}(see examples){!}")
-	fmtc.Println("    {s-}•{!} {&}Duration{!}      Duration in days or custom duration {s-}(see examples){!}")
+	fmtc.Println("    {s-}•{!} {&}Duration{!}      Duration in days or custom duration, or range of dates {s}(YYYY-MM-DD..YYYY-MM-DD){!} {s-}(see examples){!}")
+	fmtc.Println("    {s-}•{!} {&}Regexp{!}        Regular expression in RE2 syntax")
 
 	fmtc.NewLine()
 
 	help.Paragraph("You can define a few filters at once, in this case, data that match the previous filter will be filtered by the next filter in the query. For negative search use additional colon ({s}:{!}) symbol.")
+	help.Paragraph("You can also define a group of alternative filters using brackets {s}(){!} with alternatives separated by pipe {s}|{!} symbol. In this case, data that match any of alternatives will be found. Note that brackets and pipe symbol must be quoted or escaped in the shell.")
+	help.Paragraph("By default, all string filters are case-sensitive. For case-insensitive search add tilde ({s}~{!}) symbol to the filter name {s-}(e.g. n~:nginx){!}.")
+	help.Paragraph("To limit the number of found packages, use {s}" + query.TERM_LIMIT + ":{!}{s-}N{!} and {s}" + query.TERM_OFFSET + ":{!}{s-}N{!} filters. Limit and offset are applied to the sorted list of packages in every repository separately.")
 
 	help.Shortcut()
 	help.Options()
@@ -417,6 +478,7 @@ func helpInfo() {
 			{"redis-6.0.2", "Show info about the latest release of the specific version of the package"},
 			{"redis-6.0.1-2", "Show info about specific version and release of the package"},
 			{info.GetOption(OPT_ARCH).String() + " src redis", "Show info about the latest version and release of the source package"},
+			{info.GetOption(OPT_FILE).String() + " redis-6.0.1-2.el7.x86_64.rpm", "Show info about package from RPM file"},
 		},
 		isGlobal: false,
 	}
@@ -443,6 +505,7 @@ func helpPayload() {
 			{"redis | grep '\\.conf'", "Show list of files and directories in the package and filter it with grep"},
 			{"redis requires", "Show a list of required dependencies"},
 			{"redis provides", "Show a list of provided dependencies"},
+			{"redis obsoletes", "Show a list of obsoleted packages"},
 		},
 		isGlobal: false,
 	}
@@ -451,17 +514,41 @@ func helpPayload() {
 	help.Paragraph("Show information about package payload.")
 	fmtc.Println("{*}Payload type:{!}\n")
 	fmtc.Printfn(
-		"  {m}%-8s{!} {s}or{!} {m}%-6s{!} %s {s}(used by default){!}", "files", "f",
+		"  {m}%-11s{!} {s}or{!} {m}%-6s{!} %s {s}(used by default){!}", "files", "f",
 		"Files and directories",
 	)
 	fmtc.Printfn(
-		"  {m}%-8s{!} {s}or{!} {m}%-6s{!} %s", "requires", "reqs",
+		"  {m}%-11s{!} {s}or{!} {m}%-6s{!} %s", "requires", "reqs",
 		"Required dependencies",
 	)
 	fmtc.Printfn(
-		"  {m}%-8s{!} {s}or{!} {m}%-6s{!} %s", "provides", "provs",
+		"  {m}%-11s{!} {s}or{!} {m}%-6s{!} %s", "provides", "provs",
 		"Provided dependencies",
 	)
+	fmtc.Printfn(
+		"  {m}%-11s{!} {s}or{!} {m}%-6s{!} %s", "conflicts", "confs",
+		"Conflicting packages",
+	)
+	fmtc.Printfn(
+		"  {m}%-11s{!} {s}or{!} {m}%-6s{!} %s", "obsoletes", "obs",
+		"Obsoleted packages",
+	)
+	fmtc.Printfn(
+		"  {m}%-11s{!} {s}or{!} {m}%-6s{!} %s", "recommends", "recs",
+		"Recommended (weak) dependencies",
+	)
+	fmtc.Printfn(
+		"  {m}%-11s{!} {s}or{!} {m}%-6s{!} %s", "suggests", "sugs",
+		"Suggested (weak) dependencies",
+	)
+	fmtc.Printfn(
+		"  {m}%-11s{!} {s}or{!} {m}%-6s{!} %s", "enhances", "enhs",
+		"Enhanced packages (weak reverse dependencies)",
+	)
+	fmtc.Printfn(
+		"  {m}%-11s{!} {s}or{!} {m}%-6s{!} %s", "supplements", "sups",
+		"Supplemented packages (weak reverse dependencies)",
+	)
 	fmtc.NewLine()
 	help.Shortcut()
 	help.Options()
@@ -470,20 +557,23 @@ func helpPayload() {
 
 // helpCleanup shows help content about "cleanup" command
 func helpCleanup() {
+	info := genUsage()
 	help := &commandHelp{
 		command:  COMMAND_CLEANUP,
 		shortcut: COMMAND_SHORT_CLEANUP,
-		info:     genUsage(),
+		info:     info,
 		examples: []commandExample{
 			{"5", "Remove outdated packages except the 5 latest versions"},
 			{"10", "Remove outdated packages except the 10 latest versions"},
 			{"5 nginx", "Remove outdated nginx packages except the 5 latest versions"},
+			{info.GetOption(OPT_KEEP_SOURCE).String() + " 5", "Remove all packages built from outdated source packages except the 5 latest builds"},
 		},
 	}
 
 	help.Usage()
 	help.Paragraph("Remove old versions of packages. Note that the number of versions only counts different versions, so different releases of the same version count as one version.")
 	help.Paragraph("You can also specify part of the source package name to filter the results and clean up outdated versions of only one package.")
+	help.Paragraph("With " + info.GetOption(OPT_KEEP_SOURCE).String() + " option packages are grouped by source package, and the number of versions counts every build {s-}(version and release){!} of the source package. All binary packages built from one source package are removed together.")
 	help.Shortcut()
 	help.Options()
 	help.Examples()
@@ -491,19 +581,23 @@ func helpCleanup() {
 
 // helpCheck shows help content about "check" command
 func helpCheck() {
+	info := genUsage()
 	help := &commandHelp{
 		command:  COMMAND_CHECK,
 		shortcut: COMMAND_SHORT_CHECK,
-		info:     genUsage(),
+		info:     info,
 		examples: []commandExample{
 			{"", "Check the release and testing repository for consistency"},
 			{"100", "Check the release and testing repository for consistency and print the first 100 errors"},
+			{info.GetOption(OPT_FIX).String(), "Check the release and testing repository for consistency and fix wrong owner and permissions of files"},
 		},
 	}
 
 	help.Usage()
 	help.Paragraph("Check repositories consistency.")
+	help.Paragraph("With option {?opt}" + info.GetOption(OPT_FIX).String() + "{!} the command sets owner and permissions defined in repository configuration file for files and directories with wrong owner or permissions. Without option {?opt}" + info.GetOption(OPT_FORCE).String() + "{!} the command asks for confirmation before fixing.")
 	help.Shortcut()
+	help.Options()
 	help.Examples()
 }
 
@@ -519,25 +613,30 @@ func helpSign() {
 	}
 
 	help.Usage()
-	help.Paragraph("Add GPG signature to RPM file or files.")
+	help.Paragraph("Add GPG signature to RPM file or files. If more than one file is given, files are signed in parallel (one worker per CPU core). Failure on one file doesn't stop signing of other files, all errors and summary are shown at the end.")
 	help.Shortcut()
 	help.Options()
 	help.Examples()
 }
 
-// helpSign shows help content about "resign" command
+// helpResign shows help content about "resign" command
 func helpResign() {
+	info := genUsage()
 	help := &commandHelp{
 		command:  COMMAND_RESIGN,
 		shortcut: COMMAND_SHORT_RESIGN,
-		info:     genUsage(),
+		info:     info,
 		examples: []commandExample{
 			{"", "Re-sign all packages"},
+			{"d:3d", "Re-sign all packages added in the last 3 days"},
+			{"s:redis-6.0.4-0.el7.src", "Re-sign all packages built from the given source package"},
+			{info.GetOption(OPT_ARCH).String() + " aarch64", "Re-sign all packages for aarch64 architecture"},
 		},
 	}
 
 	help.Usage()
-	help.Paragraph("Re-sign all packages in testing and release repositories.")
+	help.Paragraph("Re-sign all packages in testing and release repositories. If search query or architecture is given, only matching packages will be re-signed.")
+	help.Paragraph("The command uses search query syntax for package selection. For more information about query syntax, see \"rep {?cmd}" + COMMAND_HELP + "{!} {?arg}" + COMMAND_FIND + "{!}\".")
 	help.Shortcut()
 	help.Options()
 	help.Examples()
@@ -554,12 +653,14 @@ func helpAdd() {
 			{"*.rpm", "Add all RPM packages in the current directory"},
 			{info.GetOption(OPT_MOVE).String() + " *.rpm", "Add all RPM packages in the current directory and remove them after success"},
 			{info.GetOption(OPT_NO_SOURCE).String() + " *.rpm", "Add all RPM packages in the current directory except source packages"},
+			{info.GetOption(OPT_RELEASE).String() + " *.rpm", "Add all RPM packages in the current directory directly to the release repository"},
+			{info.GetOption(OPT_DRY_RUN).String() + " *.rpm", "Show which RPM packages in the current directory would be added"},
 		},
 		isGlobal: false,
 	}
 
 	help.Usage()
-	help.Paragraph("Add RPM file or files to the testing repository.")
+	help.Paragraph("Add RPM file or files to the testing repository. With option {?opt}" + info.GetOption(OPT_RELEASE).String() + "{!} packages will be added directly to the release repository {s}(use it only for urgent hotfixes){!}.")
 	help.Shortcut()
 	help.Options()
 	help.Examples()
@@ -576,6 +677,7 @@ func helpRemove() {
 			{"n:nginx v:1.21.3", "Remove all packages from the testing repository with a specific name and version"},
 			{"s:redis-6.0.4-0.el7.src", "Remove all packages from the testing repository built from the given source package"},
 			{info.GetOption(OPT_ALL).String() + " n:nginx v:1.21.3", "Remove all packages from testing and release repositories with specific name and version"},
+			{info.GetOption(OPT_DRY_RUN).String() + " n:nginx v:1.21.3", "Show which packages would be removed from the testing repository"},
 		},
 		isGlobal: false,
 	}
@@ -590,13 +692,16 @@ func helpRemove() {
 
 // helpRelease shows help content about "release" command
 func helpRelease() {
+	info := genUsage()
 	help := &commandHelp{
 		command:  COMMAND_RELEASE,
 		shortcut: COMMAND_SHORT_RELEASE,
-		info:     genUsage(),
+		info:     info,
 		examples: []commandExample{
 			{"d:3d", "Release all packages added in the last 3 days"},
 			{"s:redis-6.0.4-0.el7.src", "Release all packages built from the given source package"},
+			{info.GetOption(OPT_ATOMIC).String() + " s:redis-6.0.4-0.el7.src", "Release all packages built from the given source package or none of them if any fails"},
+			{info.GetOption(OPT_DRY_RUN).String() + " d:3d", "Show which packages added in the last 3 days would be released"},
 		},
 	}
 
@@ -641,12 +746,15 @@ func helpReindex() {
 			{"", "Regenerate index for testing and release repositories"},
 			{info.GetOption(OPT_TESTING).String(), "Regenerate index only for the testing repository"},
 			{info.GetOption(OPT_FULL).String(), "Generate index for testing and release repositories from scratch"},
+			{info.GetOption(OPT_ARCH).String() + " x86_64", "Regenerate index only for x86_64 architecture"},
+			{info.GetOption(OPT_SYNC_NOARCH).String(), "Copy missing noarch packages to all architecture directories and regenerate index"},
 		},
 		isGlobal: false,
 	}
 
 	help.Usage()
-	help.Paragraph("Generate repository index with createrepo utility.")
+	help.Paragraph("Generate repository index with createrepo utility. Index is generated only if it is outdated (packages, index options or groupfile were changed since the last reindex, or some metadata files are missing). Use --full option to force index regeneration.")
+	help.Paragraph("Reindex doesn't change package files. If some architectures were added to repository after noarch packages, use --sync-noarch option to copy these packages to architecture directories which don't contain them before reindex.")
 	help.Shortcut()
 	help.Options()
 	help.Examples()
@@ -662,11 +770,15 @@ func helpPurgeCache() {
 		examples: []commandExample{
 			{"", "Remove cached SQLite databases for testing and release repositories"},
 			{info.GetOption(OPT_TESTING).String(), "Remove cached SQLite databases only for the testing repository"},
+			{info.GetOption(OPT_RELEASE).String(), "Remove cached SQLite databases only for the release repository"},
+			{info.GetOption(OPT_ALL_REPOS).String(), "Remove cached SQLite databases for all configured repositories"},
 		},
 	}
 
 	help.Usage()
 	help.Paragraph("Remove all cached SQLite databases.")
+	help.Paragraph("With option {?opt}" + info.GetOption(OPT_ALL_REPOS).String() + "{!} repository name can be omitted:")
+	help.Paragraph("  rep " + COMMAND_PURGE_CACHE + " " + info.GetOption(OPT_ALL_REPOS).String())
 	help.Shortcut()
 	help.Options()
 	help.Examples()
@@ -682,12 +794,192 @@ func helpStats() {
 		examples: []commandExample{
 			{"", "Show statistic information about testing and release repositories"},
 			{info.GetOption(OPT_TESTING).String(), "Show statistic information only about the testing repository"},
+			{info.GetOption(OPT_SAVE).String(), "Show statistic information and save snapshot for later comparison"},
+			{info.GetOption(OPT_DIFF).String(), "Show statistic information and changes since the previous saved snapshot"},
+			{info.GetOption(OPT_ARCH).String() + " aarch64 " + info.GetOption(OPT_DIFF).String(), "Show statistic information and changes only for aarch64 architecture"},
+			{info.GetOption(OPT_ALL_REPOS).String(), "Show statistic information about all configured repositories with totals"},
+			{info.GetOption(OPT_ALL_REPOS).String() + " " + info.GetOption(OPT_JSON).String(), "Print statistic information about all repositories in JSON format"},
 		},
 		isGlobal: false,
 	}
 
 	help.Usage()
 	help.Paragraph("Show repository statistics.")
+	help.Paragraph("With option {?opt}" + info.GetOption(OPT_ALL_REPOS).String() + "{!} statistics are shown for all configured repositories along with total number of packages and their size. Snapshots used by {?opt}" + info.GetOption(OPT_DIFF).String() + "{!} option are saved only if {?opt}" + info.GetOption(OPT_DIFF).String() + "{!} or {?opt}" + info.GetOption(OPT_SAVE).String() + "{!} option is set, so scraping stats doesn't affect the comparison.")
+	help.Shortcut()
+	help.Options()
+	help.Examples()
+}
+
+// helpDepGraph shows help content about "dep-graph" command
+func helpDepGraph() {
+	info := genUsage()
+	help := &commandHelp{
+		command:  COMMAND_DEP_GRAPH,
+		shortcut: COMMAND_SHORT_DEP_GRAPH,
+		info:     info,
+		examples: []commandExample{
+			{"", "Export graph of dependencies between packages in release repository in DOT format"},
+			{"json", "Export graph of dependencies between packages in release repository in JSON format"},
+			{info.GetOption(OPT_TESTING).String() + " dot | dot -Tsvg -o deps.svg", "Render graph for testing repository to SVG image with Graphviz"},
+		},
+		isGlobal: false,
+	}
+
+	help.Usage()
+	help.Paragraph("Export graph of dependencies between packages within the repository. Package A depends on package B if A requires a capability or file provided by B. Supported formats: dot {s}(default){!} and json. Graph is built for the release repository unless option {?opt}" + info.GetOption(OPT_TESTING).String() + "{!} is set.")
+	help.Shortcut()
+	help.Options()
+	help.Examples()
+}
+
+// helpExport shows help content about "export" command
+func helpExport() {
+	info := genUsage()
+	help := &commandHelp{
+		command:  COMMAND_EXPORT,
+		shortcut: COMMAND_SHORT_EXPORT,
+		info:     info,
+		examples: []commandExample{
+			{"/tmp/repo.tar.gz", "Export release repository to tarball"},
+			{info.GetOption(OPT_TESTING).String() + " /tmp/repo-testing.tar.gz", "Export testing repository to tarball"},
+			{info.GetOption(OPT_NO_SOURCE).String() + " /tmp/repo.tar.gz", "Export release repository without source packages"},
+		},
+		isGlobal: false,
+	}
+
+	help.Usage()
+	help.Paragraph("Export all packages and metadata of sub-repository to gzip compressed tarball. Archive contains all architecture directories with their repodata, so it can be unpacked and used as a self-contained repository. By default, release repository is exported.")
+	help.Shortcut()
+	help.Options()
+	help.Examples()
+}
+
+// helpImport shows help content about "import" command
+func helpImport() {
+	info := genUsage()
+	help := &commandHelp{
+		command:  COMMAND_IMPORT,
+		shortcut: COMMAND_SHORT_IMPORT,
+		info:     info,
+		examples: []commandExample{
+			{"/srv/old-repo/x86_64", "Import all packages from existing repository"},
+			{info.GetOption(OPT_NO_SOURCE).String() + " /srv/old-repo/x86_64", "Import all packages from existing repository except source packages"},
+		},
+		isGlobal: false,
+	}
+
+	help.Usage()
+	help.Paragraph("Import packages from existing repository created with createrepo to testing repository. The list of packages is read from primary SQLite database of the given repository. Packages which already present in testing repository are skipped. Repository will be reindexed once after all packages are imported.")
+	help.Shortcut()
+	help.Options()
+	help.Examples()
+}
+
+// helpDiff shows help content about "diff" command
+func helpDiff() {
+	info := genUsage()
+	help := &commandHelp{
+		command:  COMMAND_DIFF,
+		shortcut: COMMAND_SHORT_DIFF,
+		info:     info,
+		examples: []commandExample{
+			{"", "Show differences between testing and release repositories"},
+			{info.GetOption(OPT_JSON).String(), "Show differences between repositories in JSON format"},
+		},
+		isGlobal: false,
+	}
+
+	help.Usage()
+	help.Paragraph("Show differences between testing and release repositories: packages which present only in testing repository, packages which present only in release repository and packages which present in both repositories but with different versions.")
+	help.Shortcut()
+	help.Options()
+	help.Examples()
+}
+
+// helpDownload shows help content about "download" command
+func helpDownload() {
+	info := genUsage()
+	help := &commandHelp{
+		command:  COMMAND_DOWNLOAD,
+		shortcut: COMMAND_SHORT_DOWNLOAD,
+		info:     info,
+		examples: []commandExample{
+			{"my-package", "Download all files of the latest version of package from testing repository"},
+			{info.GetOption(OPT_RELEASE).String() + " my-package", "Download all files of the latest version of package from release repository"},
+			{info.GetOption(OPT_ARCH).String() + " src my-package", "Download only source package"},
+			{info.GetOption(OPT_FORCE).String() + " n:my-package v:1.0.0", "Download files of package with given version and overwrite existing files"},
+		},
+		isGlobal: false,
+	}
+
+	help.Usage()
+	help.Paragraph("Copy package files from the repository to the current directory with their original names. By default, packages are downloaded from testing repository. Existing files in the current directory will not be overwritten without " + info.GetOption(OPT_FORCE).String() + " option.")
+	help.Shortcut()
+	help.Options()
+	help.Examples()
+}
+
+// helpTag shows help content about "tag" command
+func helpTag() {
+	help := &commandHelp{
+		command:  COMMAND_TAG,
+		shortcut: COMMAND_SHORT_TAG,
+		info:     genUsage(),
+		examples: []commandExample{
+			{TAG_ACTION_ADD + " security-fix n:openssl v:3.0.7", "Add tag \"security-fix\" to all openssl 3.0.7 packages"},
+			{TAG_ACTION_REMOVE + " ticket-1234 my-package", "Remove tag \"ticket-1234\" from my-package packages"},
+			{TAG_ACTION_LIST, "Show all tagged packages"},
+			{TAG_ACTION_LIST + " n:openssl", "Show tags of openssl packages"},
+		},
+		isGlobal: false,
+	}
+
+	help.Usage()
+	help.Paragraph("Manage labels attached to packages. Tags are stored in the repository data directory and attached to every file of the package {s}(name, epoch, version, release and architecture){!}. Supported actions: " + TAG_ACTION_ADD + ", " + TAG_ACTION_REMOVE + ", and " + TAG_ACTION_LIST + ".")
+	help.Paragraph("You can use {y}--tag{!} option with {y}" + COMMAND_LIST + "{!} and {y}" + COMMAND_FIND + "{!} commands to show only packages with the given tag.")
+	help.Paragraph("The command uses search query syntax for package selection. For more information about query syntax, see \"rep {?cmd}" + COMMAND_HELP + "{!} {?arg}" + COMMAND_FIND + "{!}\".")
+	help.Shortcut()
+	help.Options()
+	help.Examples()
+}
+
+// helpMetrics shows help content about "metrics" command
+func helpMetrics() {
+	help := &commandHelp{
+		command:  COMMAND_METRICS,
+		shortcut: COMMAND_SHORT_METRICS,
+		info:     genUsage(),
+		examples: []commandExample{
+			{"", "Print metrics for all configured repositories"},
+		},
+		isGlobal: false,
+	}
+
+	help.Usage()
+	help.Paragraph("Print number of packages, size of packages and index age for all configured repositories as gauges in Prometheus text-based exposition format. Output can be saved to file and exposed with textfile collector of node_exporter:")
+	help.Paragraph("  rep metrics > /var/lib/node_exporter/rep.prom.$$ && mv /var/lib/node_exporter/rep.prom.$$ /var/lib/node_exporter/rep.prom")
+	help.Shortcut()
+	help.Examples()
+}
+
+// helpVerify shows help content about "verify" command
+func helpVerify() {
+	info := genUsage()
+	help := &commandHelp{
+		command:  COMMAND_VERIFY,
+		shortcut: COMMAND_SHORT_VERIFY,
+		info:     info,
+		examples: []commandExample{
+			{"", "Verify signatures of all packages in testing and release repositories"},
+			{info.GetOption(OPT_RELEASE).String(), "Verify signatures of packages only in release repository"},
+			{info.GetOption(OPT_JSON).String(), "Verify signatures and print result in JSON format"},
+		},
+		isGlobal: false,
+	}
+
+	help.Usage()
+	help.Paragraph("Verify that all packages in repository are signed with repository signing key or one of trusted keys. Unlike {y}" + COMMAND_CHECK + "{!} command, this command checks only signatures and never asks for confirmation, so it can be used in CI. Command exits with non-zero exit code if any package is not signed or signed with untrusted key.")
 	help.Shortcut()
 	help.Options()
 	help.Examples()
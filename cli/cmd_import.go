@@ -0,0 +1,257 @@
+package cli
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2024 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/essentialkaos/ek/v13/fmtc"
+	"github.com/essentialkaos/ek/v13/fsutil"
+	"github.com/essentialkaos/ek/v13/options"
+	"github.com/essentialkaos/ek/v13/path"
+	"github.com/essentialkaos/ek/v13/pluralize"
+	"github.com/essentialkaos/ek/v13/spinner"
+	"github.com/essentialkaos/ek/v13/terminal"
+
+	"github.com/essentialkaos/rep/v3/repo"
+	"github.com/essentialkaos/rep/v3/repo/meta"
+	"github.com/essentialkaos/rep/v3/repo/sign"
+	"github.com/essentialkaos/rep/v3/repo/storage/utils"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// cmdImport is 'import' command handler
+func cmdImport(ctx *context, args options.Arguments) bool {
+	dir := args.Get(0).Clean().String()
+
+	files, err := getImportPackages(ctx, dir)
+
+	if err != nil {
+		terminal.Error(err)
+		return false
+	}
+
+	files = filterRPMPackages(ctx, files)
+
+	if len(files) == 0 {
+		terminal.Warn("There are no RPM packages to import")
+		return false
+	}
+
+	r := ctx.Repo.Testing
+
+	if !isSignRequired(r, files) {
+		return importRPMFiles(ctx, r, files, nil)
+	}
+
+	signingKey, ok := getRepoSigningKey(ctx.Repo)
+
+	if !ok {
+		return false
+	}
+
+	return importRPMFiles(ctx, r, files, signingKey)
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// getImportPackages returns paths to all packages referenced in primary DB of
+// repository in given directory
+func getImportPackages(ctx *context, dir string) ([]string, error) {
+	metaFile := path.Join(dir, "repodata/repomd.xml")
+
+	if !fsutil.CheckPerms("FRS", metaFile) {
+		return nil, fmt.Errorf("Directory %s doesn't contain repository metadata", dir)
+	}
+
+	metaIndex, err := meta.Read(metaFile)
+
+	if err != nil {
+		return nil, fmt.Errorf("Can't read repository metadata: %w", err)
+	}
+
+	dbInfo := metaIndex.Get(meta.TYPE_PRIMARY_DB)
+
+	if dbInfo == nil {
+		return nil, fmt.Errorf("Repository in %s doesn't contain primary SQLite database", dir)
+	}
+
+	tmpDir, err := ctx.Temp.MkDir("rep")
+
+	if err != nil {
+		return nil, fmt.Errorf("Can't create temporary directory: %w", err)
+	}
+
+	dbFile := path.Join(tmpDir, "primary.sqlite")
+	dbHREF, err := getImportFilePath(dir, dbInfo.Location.HREF)
+
+	if err != nil {
+		return nil, fmt.Errorf("Invalid primary database location: %w", err)
+	}
+
+	err = utils.UnpackDB(dbHREF, dbFile)
+
+	if err != nil {
+		return nil, fmt.Errorf("Can't unpack primary database: %w", err)
+	}
+
+	db, err := sql.Open("sqlite3", dbFile)
+
+	if err != nil {
+		return nil, fmt.Errorf("Can't open primary database: %w", err)
+	}
+
+	defer db.Close()
+
+	rows, err := db.Query("SELECT location_href, location_base FROM packages;")
+
+	if err != nil {
+		return nil, fmt.Errorf("Can't read packages list from primary database: %w", err)
+	}
+
+	defer rows.Close()
+
+	var result []string
+
+	for rows.Next() {
+		var href string
+		var base sql.NullString
+
+		err = rows.Scan(&href, &base)
+
+		if err != nil {
+			return nil, fmt.Errorf("Can't parse packages list from primary database: %w", err)
+		}
+
+		// Packages with location base are stored outside of repository
+		// directory (e.g. on another server), so we can't import them
+		if base.String != "" {
+			return nil, fmt.Errorf("Package %s has location base %q, only packages stored in repository directory can be imported", href, base.String)
+		}
+
+		file, err := getImportFilePath(dir, href)
+
+		if err != nil {
+			return nil, fmt.Errorf("Invalid package location: %w", err)
+		}
+
+		result = append(result, file)
+	}
+
+	return result, rows.Err()
+}
+
+// importRPMFiles adds given RPM files to given sub-repository skipping packages
+// which already present in it
+func importRPMFiles(ctx *context, r *repo.SubRepository, files []string, signingKey *sign.Key) bool {
+	tmpDir, err := ctx.Temp.MkDir("rep")
+
+	if err != nil {
+		terminal.Error("Can't create temporary directory: %v", err)
+		return false
+	}
+
+	var hasErrors bool
+	var skipped int
+	var pkgFiles []string
+
+	isCancelProtected = true
+
+	for _, file := range files {
+		if isCanceled.Load() {
+			isCancelProtected = false
+			return false
+		}
+
+		fileName := path.Base(file)
+
+		if r.HasPackageFile(fileName) {
+			spinner.Show("{s}Skip %s (already present in repository){!}", fileName)
+			spinner.Skip()
+			skipped++
+			continue
+		}
+
+		pkgFile, ok := prepareRPMFile(ctx, r, file, tmpDir, signingKey)
+
+		if !ok {
+			hasErrors = true
+			continue
+		}
+
+		if pkgFile == "" {
+			skipped++
+			continue
+		}
+
+		pkgFiles = append(pkgFiles, pkgFile)
+	}
+
+	addedFiles := make(map[string]bool)
+
+	if len(pkgFiles) != 0 {
+		spinner.Show("Importing packages to {*}{?repo}%s{!}", r.Name)
+
+		added, err := r.AddPackages(pkgFiles)
+
+		for _, relPath := range added {
+			addedFiles[path.Base(relPath)] = true
+		}
+
+		spinner.Update(
+			"%s imported to {*}{?repo}%s{!}",
+			pluralize.PS(pluralize.En, "%d %s", len(addedFiles), "package", "packages"),
+			r.Name,
+		)
+		spinner.Done(err == nil)
+
+		if err != nil {
+			hasErrors = true
+
+			for _, line := range strings.Split(err.Error(), "\n") {
+				terminal.Error("   %s", line)
+			}
+		}
+
+		for _, pkgFile := range pkgFiles {
+			if addedFiles[path.Base(pkgFile)] {
+				ctx.Logger.Get(r.Name).Print("Imported package %s", path.Base(pkgFile))
+			}
+		}
+	}
+
+	fmtc.NewLine()
+	fmtc.Printfn(
+		"{*}%s{!} imported to {*}{?repo}%s{!}, %s skipped",
+		pluralize.PS(pluralize.En, "%d %s", len(addedFiles), "package", "packages"),
+		r.Name, pluralize.PS(pluralize.En, "%d %s", skipped, "package", "packages"),
+	)
+
+	if len(addedFiles) != 0 {
+		autoReindexRepositories(ctx, nil, r)
+	}
+
+	isCancelProtected = false
+
+	return hasErrors == false
+}
+
+// getImportFilePath returns path to file with given location in repository
+// directory. Location is read from repository metadata, so it can't be trusted.
+func getImportFilePath(dir, href string) (string, error) {
+	file := path.Join(dir, href)
+
+	if !strings.HasPrefix(file, strings.TrimRight(path.Clean(dir), "/")+"/") {
+		return "", fmt.Errorf("Location %q points outside of repository directory", href)
+	}
+
+	return file, nil
+}
@@ -18,6 +18,7 @@ import (
 	"github.com/essentialkaos/ek/v13/hash"
 	"github.com/essentialkaos/ek/v13/lscolors"
 	"github.com/essentialkaos/ek/v13/options"
+	"github.com/essentialkaos/ek/v13/path"
 	"github.com/essentialkaos/ek/v13/strutil"
 	"github.com/essentialkaos/ek/v13/terminal"
 	"github.com/essentialkaos/ek/v13/timeutil"
@@ -38,6 +39,10 @@ func cmdInfo(ctx *context, args options.Arguments) bool {
 	pkgName := args.Get(0).String()
 	pkgArch := options.GetS(OPT_ARCH)
 
+	if options.GetB(OPT_FILE) {
+		return printPackageFileInfo(pkgName)
+	}
+
 	pkg, releaseDate, err := ctx.Repo.Info(pkgName, pkgArch)
 
 	if err != nil {
@@ -45,6 +50,10 @@ func cmdInfo(ctx *context, args options.Arguments) bool {
 		return false
 	}
 
+	if options.GetB(OPT_JSON) {
+		return printJSON(pkg)
+	}
+
 	printPackageInfo(ctx.Repo, pkg, releaseDate)
 
 	return true
@@ -52,6 +61,24 @@ func cmdInfo(ctx *context, args options.Arguments) bool {
 
 // ////////////////////////////////////////////////////////////////////////////////// //
 
+// printPackageFileInfo prints info about package read directly from RPM file
+func printPackageFileInfo(rpmFile string) bool {
+	pkg, err := repo.ReadPackageFile(rpmFile)
+
+	if err != nil {
+		terminal.Error(err.Error())
+		return false
+	}
+
+	if options.GetB(OPT_JSON) {
+		return printJSON(pkg)
+	}
+
+	printPackageInfo(nil, pkg, time.Time{})
+
+	return true
+}
+
 // printPackageInfo prints all info about package
 func printPackageInfo(r *repo.Repository, pkg *repo.Package, releaseDate time.Time) {
 	fmtutil.Separator(true, "PACKAGE INFO")
@@ -59,8 +86,14 @@ func printPackageInfo(r *repo.Repository, pkg *repo.Package, releaseDate time.Ti
 
 	printPackageBasicInfo(r, pkg, releaseDate)
 	printPackagePayloadInfo(pkg.Info.Payload)
-	printPackageRequiresInfo(pkg.Info.Requires)
-	printPackageProvidesInfo(pkg.Info.Provides)
+	printPackageDepsInfo("Requires", pkg.Info.Requires)
+	printPackageDepsInfo("Provides", pkg.Info.Provides)
+	printPackageDepsInfo("Conflicts", pkg.Info.Conflicts)
+	printPackageDepsInfo("Obsoletes", pkg.Info.Obsoletes)
+	printPackageDepsInfo("Recommends", pkg.Info.Recommends)
+	printPackageDepsInfo("Suggests", pkg.Info.Suggests)
+	printPackageDepsInfo("Enhances", pkg.Info.Enhances)
+	printPackageDepsInfo("Supplements", pkg.Info.Supplements)
 	printPackageChangelogInfo(pkg.Info.Changelog)
 
 	fmtutil.Separator(true)
@@ -95,25 +128,24 @@ func printPackageBasicInfo(r *repo.Repository, pkg *repo.Package, releaseDate ti
 
 	fmtc.NewLine()
 
-	if !releaseDate.IsZero() {
-		fmtc.Printfn("{*}%-16s{!}testing release", "Repository")
-	} else {
-		fmtc.Printfn("{*}%-16s{!}testing", "Repository")
+	if r != nil {
+		fmtc.Printfn("{*}%-16s{!}%s", "Repository", getPackageRepoStatus(r, pkg, releaseDate))
+		fmtc.NewLine()
 	}
 
-	fmtc.NewLine()
-
 	fmtc.Printfn(
 		"{*}%-16s{!}%s {s-}(%s){!}", "Built",
 		timeutil.Format(pkg.Info.DateBuild, "%d/%m/%Y %H:%M"),
 		getDaysSinceDate(pkg.Info.DateBuild),
 	)
 
-	fmtc.Printfn(
-		"{*}%-16s{!}%s {s-}(%s){!}", "Added",
-		timeutil.Format(pkg.Info.DateAdded, "%d/%m/%Y %H:%M"),
-		getDaysSinceDate(pkg.Info.DateAdded),
-	)
+	if !pkg.Info.DateAdded.IsZero() {
+		fmtc.Printfn(
+			"{*}%-16s{!}%s {s-}(%s){!}", "Added",
+			timeutil.Format(pkg.Info.DateAdded, "%d/%m/%Y %H:%M"),
+			getDaysSinceDate(pkg.Info.DateAdded),
+		)
+	}
 
 	if !releaseDate.IsZero() {
 		fmtc.Printfn(
@@ -125,7 +157,13 @@ func printPackageBasicInfo(r *repo.Repository, pkg *repo.Package, releaseDate ti
 
 	fmtc.NewLine()
 
-	if len(pkg.Files) != 0 {
+	if len(pkg.Files) != 0 && r == nil {
+		fmtc.Printfn("{*}%-16s{!}%s", "RPM File", pkg.Files[0].Path)
+		fmtc.Printfn("{*}%-16s{!}%s", "Checksum", pkg.Files[0].CRC)
+		fmtc.NewLine()
+	}
+
+	if len(pkg.Files) != 0 && r != nil {
 		fmtc.Printfn(
 			"{*}%-16s{!}%s", "RPM File",
 			getPackageFileInfoWithMark(r, pkg.Files[0], !releaseDate.IsZero()),
@@ -183,32 +221,16 @@ func printPackagePayloadInfo(payload repo.PackagePayload) {
 	fmtc.NewLine()
 }
 
-// printPackageRequiresInfo prints info about required packages and binaries
-func printPackageRequiresInfo(reqs []data.Dependency) {
-	if len(reqs) == 0 {
-		return
-	}
-
-	for i, dep := range reqs {
-		if i == 0 {
-			fmtc.Printfn("{*}%-16s{!}%s", "Requires", formatDepName(dep, true))
-		} else {
-			fmtc.Printfn("{*}%-16s{!}%s", "", formatDepName(dep, true))
-		}
-	}
-
-	fmtc.NewLine()
-}
-
-// printPackageProvidesInfo prints info about provided packages and binaries
-func printPackageProvidesInfo(provs []data.Dependency) {
-	if len(provs) == 0 {
+// printPackageDepsInfo prints info about package dependencies of given type
+// (requires, provides, obsoletes…)
+func printPackageDepsInfo(name string, deps []data.Dependency) {
+	if len(deps) == 0 {
 		return
 	}
 
-	for i, dep := range provs {
+	for i, dep := range deps {
 		if i == 0 {
-			fmtc.Printfn("{*}%-16s{!}%s", "Provides", formatDepName(dep, true))
+			fmtc.Printfn("{*}%-16s{!}%s", name, formatDepName(dep, true))
 		} else {
 			fmtc.Printfn("{*}%-16s{!}%s", "", formatDepName(dep, true))
 		}
@@ -309,6 +331,32 @@ func formatPayloadPath(path string) string {
 	return lscolors.ColorizePath(path)
 }
 
+// getPackageRepoStatus returns info about presence of package in testing and
+// release sub-repositories
+func getPackageRepoStatus(r *repo.Repository, pkg *repo.Package, releaseDate time.Time) string {
+	if len(pkg.Files) == 0 {
+		if !releaseDate.IsZero() {
+			return data.REPO_TESTING + " " + data.REPO_RELEASE
+		}
+
+		return data.REPO_TESTING
+	}
+
+	var result []string
+
+	fileName := path.Base(pkg.Files[0].Path)
+
+	for _, subRepo := range []*repo.SubRepository{r.Testing, r.Release} {
+		if subRepo.HasPackageFile(fileName) {
+			result = append(result, fmtc.Sprintf("%s {g}✔ {!}", subRepo.Name))
+		} else {
+			result = append(result, fmtc.Sprintf("{s-}%s{!} {r}✖ {!}", subRepo.Name))
+		}
+	}
+
+	return strings.Join(result, " ")
+}
+
 // getPackageFileInfoWithMark returns status mark for package file
 func getPackageFileInfoWithMark(r *repo.Repository, pkgFile repo.PackageFile, isReleased bool) string {
 	testingFile := r.Testing.GetFullPackagePath(pkgFile)
@@ -10,11 +10,15 @@ package cli
 import (
 	"fmt"
 	"regexp"
+	"slices"
+	"sort"
 	"strings"
 
 	"github.com/essentialkaos/ek/v13/fmtc"
 	"github.com/essentialkaos/ek/v13/fmtutil"
 	"github.com/essentialkaos/ek/v13/options"
+	"github.com/essentialkaos/ek/v13/path"
+	"github.com/essentialkaos/ek/v13/strutil"
 	"github.com/essentialkaos/ek/v13/terminal"
 
 	"github.com/essentialkaos/rep/v3/repo"
@@ -23,8 +27,44 @@ import (
 
 // ////////////////////////////////////////////////////////////////////////////////// //
 
+// Fields for raw output
+const (
+	FIELD_NAME    = "name"
+	FIELD_VERSION = "version"
+	FIELD_RELEASE = "release"
+	FIELD_EPOCH   = "epoch"
+	FIELD_ARCH    = "arch"
+	FIELD_SOURCE  = "source"
+	FIELD_PATH    = "path"
+	FIELD_CRC     = "crc"
+)
+
+// Sort orders
+const (
+	SORT_NAME       = "name"
+	SORT_SIZE       = "size"
+	SORT_DATE_ADD   = "date-add"
+	SORT_DATE_BUILD = "date-build"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
 // filterValidationRegex is regex for filter value validation
-var filterValidationRegex = regexp.MustCompile(`^[\w\-\.+]+$`)
+var filterValidationRegex = regexp.MustCompile(`^[\w\-\.+*?\[\]]+$`)
+
+// outputFields is a slice with all supported raw output fields
+var outputFields = []string{
+	FIELD_NAME, FIELD_VERSION, FIELD_RELEASE, FIELD_EPOCH,
+	FIELD_ARCH, FIELD_SOURCE, FIELD_PATH, FIELD_CRC,
+}
+
+// rawFields is a slice with fields selected for raw output
+var rawFields []string
+
+// sortOrders is a slice with all supported sort orders
+var sortOrders = []string{
+	SORT_NAME, SORT_SIZE, SORT_DATE_ADD, SORT_DATE_BUILD,
+}
 
 // ////////////////////////////////////////////////////////////////////////////////// //
 
@@ -32,10 +72,15 @@ var filterValidationRegex = regexp.MustCompile(`^[\w\-\.+]+$`)
 func cmdList(ctx *context, args options.Arguments) bool {
 	filter := args.Get(0).String()
 
-	if !isFilterValueValid(filter) {
+	if !isFilterValueValid(filter) || !parseFieldsOption() ||
+		!validateSourceOptions() || !validateSortOption() {
 		return false
 	}
 
+	if options.GetB(OPT_RELEASE_ONLY) {
+		return listReleaseOnlyPackages(ctx.Repo, filter)
+	}
+
 	all := !options.GetB(OPT_RELEASE) && !options.GetB(OPT_TESTING)
 
 	if all || options.GetB(OPT_RELEASE) {
@@ -54,6 +99,10 @@ func cmdList(ctx *context, args options.Arguments) bool {
 		}
 	}
 
+	if options.GetB(OPT_JSON) {
+		return printJSON(jsonStacks)
+	}
+
 	if !rawOutput {
 		fmtutil.Separator(true)
 	}
@@ -72,19 +121,154 @@ func listPackages(r *repo.SubRepository, filter string) bool {
 		return false
 	}
 
-	printPackageList(r, stack, filter)
+	stack, err = filterPackageStackByTag(r, stack)
+
+	if err != nil {
+		terminal.Error(err.Error())
+		return false
+	}
+
+	stack = sortPackageStack(filterPackageStackBySource(stack))
+
+	showPackageList(r, stack, filter)
+
+	return true
+}
+
+// listReleaseOnlyPackages prints listing of packages which present only in
+// release repository
+func listReleaseOnlyPackages(r *repo.Repository, filter string) bool {
+	stack, err := r.ReleaseOnly()
+
+	if err != nil {
+		terminal.Error(err.Error())
+		return false
+	}
+
+	if filter != "" {
+		stack = filterPackageStack(stack, filter)
+	}
+
+	stack, err = filterPackageStackByTag(r.Release, stack)
+
+	if err != nil {
+		terminal.Error(err.Error())
+		return false
+	}
+
+	stack = sortPackageStack(filterPackageStackBySource(stack))
+
+	showPackageList(r.Release, stack, filter)
+
+	if options.GetB(OPT_JSON) {
+		return printJSON(jsonStacks)
+	}
+
+	if !rawOutput {
+		fmtutil.Separator(true)
+	}
 
 	return true
 }
 
+// filterPackageStack returns stack only with packages which full name contains
+// given filter value or matches given glob pattern
+func filterPackageStack(stack repo.PackageStack, filter string) repo.PackageStack {
+	result := repo.PackageStack{}
+	isGlob := path.IsGlob(filter)
+
+	for _, bundle := range stack {
+		var filteredBundle repo.PackageBundle
+
+		for _, pkg := range bundle {
+			if isGlob && isPackageMatchGlob(pkg, filter) || !isGlob && strings.Contains(pkg.FullName(), filter) {
+				filteredBundle = append(filteredBundle, pkg)
+			}
+		}
+
+		if len(filteredBundle) != 0 {
+			result = append(result, filteredBundle)
+		}
+	}
+
+	return result
+}
+
+// filterPackageStackBySource returns stack with or without source packages
+// depending on --only-src and --no-source options
+func filterPackageStackBySource(stack repo.PackageStack) repo.PackageStack {
+	onlySrc, noSrc := options.GetB(OPT_ONLY_SOURCE), options.GetB(OPT_NO_SOURCE)
+
+	if stack.IsEmpty() || (!onlySrc && !noSrc) {
+		return stack
+	}
+
+	result := repo.PackageStack{}
+
+	for _, bundle := range stack {
+		var filteredBundle repo.PackageBundle
+
+		for _, pkg := range bundle {
+			if pkg == nil {
+				continue
+			}
+
+			var files repo.PackageFiles
+			var archFlags data.ArchFlag
+
+			for _, file := range pkg.Files {
+				if (file.ArchFlag == data.ARCH_FLAG_SRC) == onlySrc {
+					files = append(files, file)
+					archFlags |= file.ArchFlag
+				}
+			}
+
+			if len(files) == 0 {
+				continue
+			}
+
+			filteredPkg := *pkg
+			filteredPkg.Files = files
+			filteredPkg.ArchFlags = archFlags
+
+			filteredBundle = append(filteredBundle, &filteredPkg)
+		}
+
+		if len(filteredBundle) != 0 {
+			result = append(result, filteredBundle)
+		}
+	}
+
+	return result
+}
+
+// isPackageMatchGlob returns true if package name or full name matches given
+// glob pattern
+func isPackageMatchGlob(pkg *repo.Package, pattern string) bool {
+	nameMatch, _ := path.Match(pattern, pkg.Name)
+	fullNameMatch, _ := path.Match(pattern, pkg.FullName())
+
+	return nameMatch || fullNameMatch
+}
+
 // printPackageList prints package listing for given sub-repository
 func printPackageList(r *repo.SubRepository, stack repo.PackageStack, filter string) {
-	if !rawOutput {
+	summary := options.GetB(OPT_SUMMARY)
+
+	switch {
+	case !rawOutput && summary:
+		fmtutil.Separator(true, strings.ToUpper(r.Name))
+		fmtc.NewLine()
+		printPackageStackSummary(r, stack)
+		fmtc.NewLine()
+	case !rawOutput:
 		fmtutil.Separator(true, strings.ToUpper(r.Name))
 		fmtc.NewLine()
 		printPackageStack(r, stack, filter)
 		fmtc.NewLine()
-	} else {
+	case summary:
+		printRawPackageStackSummary(r, stack)
+	default:
 		printRawPackageStack(r, stack)
 	}
 }
@@ -103,13 +287,162 @@ func printPackageStack(r *repo.SubRepository, stack repo.PackageStack, filter st
 			printPackageBundle(r, bundle, archList, stack.HasMultiBundles(), filter)
 		}
 	}
+
+	printPackageStackArchCounts(stack)
+}
+
+// printPackageStackArchCounts prints number of package files provided by every
+// sub-repository arch if stack contains files from more than one arch
+func printPackageStackArchCounts(stack repo.PackageStack) {
+	counts := make(map[data.ArchFlag]int)
+
+	for _, file := range stack.FlattenFiles() {
+		counts[file.BaseArchFlag]++
+	}
+
+	if len(counts) < 2 {
+		return
+	}
+
+	var info []string
+
+	for _, arch := range data.ArchList {
+		num := counts[data.SupportedArchs[arch].Flag]
+
+		if num == 0 {
+			continue
+		}
+
+		color := archColors[arch]
+
+		if fmtc.Is256ColorsSupported() {
+			color = archColorsExt[arch]
+		}
+
+		info = append(info, fmt.Sprintf(color+"%s{!}{s}:{!} %s", arch, fmtutil.PrettyNum(num)))
+	}
+
+	fmtc.NewLine()
+	fmtc.Println("{s-}Packages per arch:{!} " + strings.Join(info, " {s-}•{!} "))
+}
+
+// printPackageStackSummary prints number of files and total size for every
+// bundle in stack
+func printPackageStackSummary(r *repo.SubRepository, stack repo.PackageStack) {
+	if stack.IsEmpty() {
+		fmtc.Println("{s-}-- empty --{!}")
+		return
+	}
+
+	var maxNameSize int
+
+	for _, bundle := range stack {
+		maxNameSize = max(maxNameSize, strutil.Len(getBundleName(bundle)))
+	}
+
+	for _, bundle := range stack {
+		files := bundle.FlattenFiles()
+
+		if len(files) == 0 {
+			continue
+		}
+
+		name := getBundleName(bundle)
+
+		fmtc.Printfn(
+			"%s%s {s-}│{!} %s {s-}│{!} %s",
+			name, strings.Repeat(" ", maxNameSize-strutil.Len(name)),
+			fmtutil.PrettyNum(len(files)),
+			fmtutil.PrettySize(r.GetPackageFilesSize(files), " "),
+		)
+	}
+}
+
+// printRawPackageStackSummary prints number of files and total size for every
+// bundle in stack in raw format
+func printRawPackageStackSummary(r *repo.SubRepository, stack repo.PackageStack) {
+	for _, bundle := range stack {
+		files := bundle.FlattenFiles()
+
+		if len(files) == 0 {
+			continue
+		}
+
+		fmt.Printf(
+			"%s\t%d\t%d\n", getBundleName(bundle),
+			len(files), r.GetPackageFilesSize(files),
+		)
+	}
+}
+
+// getBundleName returns name of source package used for building packages
+// in bundle
+func getBundleName(bundle repo.PackageBundle) string {
+	for _, pkg := range bundle {
+		if pkg == nil {
+			continue
+		}
+
+		if pkg.Src != "" {
+			return strings.TrimSuffix(pkg.Src, ".src.rpm")
+		}
+
+		return pkg.FullName()
+	}
+
+	return ""
 }
 
 // printRawPackageStack prints info about packages in stack
 func printRawPackageStack(r *repo.SubRepository, stack repo.PackageStack) {
-	for _, file := range stack.FlattenFiles() {
-		fmt.Println(r.GetFullPackagePath(file))
+	if len(rawFields) == 0 {
+		for _, file := range stack.FlattenFiles() {
+			fmt.Println(r.GetFullPackagePath(file))
+		}
+
+		return
 	}
+
+	for _, bundle := range stack {
+		for _, pkg := range bundle {
+			if pkg == nil {
+				continue
+			}
+
+			for _, file := range pkg.Files {
+				fmt.Println(strings.Join(getRawFieldsValues(r, pkg, file), "\t"))
+			}
+		}
+	}
+}
+
+// getRawFieldsValues returns values of selected raw output fields for given
+// package file
+func getRawFieldsValues(r *repo.SubRepository, pkg *repo.Package, file repo.PackageFile) []string {
+	var result []string
+
+	for _, field := range rawFields {
+		switch field {
+		case FIELD_NAME:
+			result = append(result, pkg.Name)
+		case FIELD_VERSION:
+			result = append(result, pkg.Version)
+		case FIELD_RELEASE:
+			result = append(result, pkg.Release)
+		case FIELD_EPOCH:
+			result = append(result, pkg.Epoch)
+		case FIELD_ARCH:
+			result = append(result, file.ArchFlag.String())
+		case FIELD_SOURCE:
+			result = append(result, pkg.Src)
+		case FIELD_PATH:
+			result = append(result, r.GetFullPackagePath(file))
+		case FIELD_CRC:
+			result = append(result, file.CRC)
+		}
+	}
+
+	return result
 }
 
 // printPackageBundle prints info about packages in bundle
@@ -134,7 +467,37 @@ func printPackageBundle(r *repo.SubRepository, bundle repo.PackageBundle, archLi
 			groupSym = " "
 		}
 
-		fmtc.Println(genListArchInfo(pkg, archList) + groupSym + genListPkgName(r, pkg, filter))
+		archInfo := genListArchInfo(pkg, archList)
+
+		fmtc.Println(archInfo + groupSym + genListPkgName(r, pkg, filter))
+
+		if len(pkg.Matches) != 0 {
+			printPackageMatches(pkg, strutil.Len(fmtc.Clean(archInfo+groupSym)))
+		}
+	}
+}
+
+// printPackageMatches prints info about search terms matched by package
+func printPackageMatches(pkg *repo.Package, indent int) {
+	var values []string
+
+	for i, match := range pkg.Matches {
+		values = append(values, match.Value)
+
+		if i+1 < len(pkg.Matches) && pkg.Matches[i+1].Term == match.Term {
+			continue
+		}
+
+		if len(values) > 3 {
+			values = append(values[:3], fmt.Sprintf("+%d more", len(values)-3))
+		}
+
+		fmtc.Printfn(
+			"%s{s-}↳ %s: %s{!}", strings.Repeat(" ", indent),
+			match.Term.PrettyName(), strings.Join(values, ", "),
+		)
+
+		values = nil
 	}
 }
 
@@ -176,7 +539,7 @@ func genListPkgName(r *repo.SubRepository, pkg *repo.Package, filter string) str
 		pkgName = "{s-}" + pkg.Epoch + ":{!}" + pkgName
 	}
 
-	if filter != "" {
+	if filter != "" && !path.IsGlob(filter) {
 		pkgName = getPkgNameWithFilter(pkgName, filter)
 	}
 
@@ -225,3 +588,86 @@ func isFilterValueValid(filter string) bool {
 
 	return true
 }
+
+// validateSourceOptions checks that options for source packages filtering
+// are not used together
+func validateSourceOptions() bool {
+	if options.GetB(OPT_ONLY_SOURCE) && options.GetB(OPT_NO_SOURCE) {
+		terminal.Error(
+			"Options %s and %s can't be used together",
+			options.F(OPT_ONLY_SOURCE), options.F(OPT_NO_SOURCE),
+		)
+		return false
+	}
+
+	return true
+}
+
+// parseFieldsOption parses and validates list of fields for raw output
+func parseFieldsOption() bool {
+	if !options.Has(OPT_FIELDS) {
+		return true
+	}
+
+	rawFields = nil
+
+	for _, field := range strings.Split(options.GetS(OPT_FIELDS), ",") {
+		field = strings.ToLower(strings.TrimSpace(field))
+
+		if field == "" {
+			continue
+		}
+
+		if !slices.Contains(outputFields, field) {
+			terminal.Error(
+				"Unknown field %q (supported fields: %s)",
+				field, strings.Join(outputFields, ", "),
+			)
+			return false
+		}
+
+		rawFields = append(rawFields, field)
+	}
+
+	if len(rawFields) == 0 {
+		terminal.Error("List of fields can't be empty")
+		return false
+	}
+
+	return true
+}
+
+// validateSortOption validates sort order option value
+func validateSortOption() bool {
+	if !options.Has(OPT_SORT) {
+		return true
+	}
+
+	sortOrder := strings.ToLower(options.GetS(OPT_SORT))
+
+	if !slices.Contains(sortOrders, sortOrder) {
+		terminal.Error(
+			"Unknown sort order %q (supported orders: %s)",
+			options.GetS(OPT_SORT), strings.Join(sortOrders, ", "),
+		)
+		return false
+	}
+
+	return true
+}
+
+// sortPackageStack sorts package stack using order from --sort option
+func sortPackageStack(stack repo.PackageStack) repo.PackageStack {
+	stack = stack.Compact()
+
+	switch strings.ToLower(options.GetS(OPT_SORT)) {
+	case SORT_SIZE:
+		sort.Stable(repo.PackageStackBySize{PackageStack: stack})
+	case SORT_DATE_ADD:
+		sort.Stable(repo.PackageStackByDateAdd{PackageStack: stack})
+	case SORT_DATE_BUILD:
+		sort.Stable(repo.PackageStackByDateBuild{PackageStack: stack})
+	}
+
+	return stack
+}
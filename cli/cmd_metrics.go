@@ -0,0 +1,181 @@
+package cli
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2024 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/essentialkaos/ek/v13/options"
+	"github.com/essentialkaos/ek/v13/terminal"
+
+	"github.com/essentialkaos/rep/v3/repo"
+	"github.com/essentialkaos/rep/v3/repo/data"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Names of exported metrics
+const (
+	METRIC_PACKAGES  = "rep_packages_total"
+	METRIC_SIZE      = "rep_size_bytes"
+	METRIC_INDEX_AGE = "rep_index_age_seconds"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// metric contains info about metric with all its samples
+type metric struct {
+	Name    string
+	Help    string
+	Samples []string
+}
+
+// repoMetrics contains all metrics exported for repositories
+type repoMetrics struct {
+	Packages *metric
+	Size     *metric
+	IndexAge *metric
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// cmdMetrics is 'metrics' command handler (context can be nil, because metrics
+// are collected for all configured repositories)
+func cmdMetrics(ctx *context, args options.Arguments) bool {
+	if globalConfig.GetB(INDEX_NO_DATABASE) {
+		terminal.Error("Can't collect metrics: SQLite databases are required, but their generation is disabled (%s)", INDEX_NO_DATABASE)
+		return false
+	}
+
+	metrics := &repoMetrics{
+		Packages: &metric{Name: METRIC_PACKAGES, Help: "Number of packages in repository"},
+		Size:     &metric{Name: METRIC_SIZE, Help: "Total size of packages in repository in bytes"},
+		IndexAge: &metric{Name: METRIC_INDEX_AGE, Help: "Number of seconds since the latest repository index update"},
+	}
+
+	now := time.Now()
+
+	for _, repoName := range getRepoNames() {
+		if isCanceled.Load() {
+			return false
+		}
+
+		r, err := getRepo(configs[repoName])
+
+		if err != nil {
+			terminal.Error("Can't collect metrics for %q: %v", repoName, err)
+			return false
+		}
+
+		warmUpCache(r)
+
+		err = collectRepoMetrics(r, metrics, now)
+
+		r.InvalidateCache()
+
+		if err != nil {
+			terminal.Error("Can't collect metrics for %q: %v", repoName, err)
+			return false
+		}
+	}
+
+	fmt.Print(formatMetrics(metrics.Packages, metrics.Size, metrics.IndexAge))
+
+	return true
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// collectRepoMetrics collects metrics samples for given repository
+func collectRepoMetrics(r *repo.Repository, metrics *repoMetrics, now time.Time) error {
+	indexDates := make(map[string]time.Time)
+
+	for _, subRepo := range []*repo.SubRepository{r.Release, r.Testing} {
+		stats, err := subRepo.Stats()
+
+		if err != nil {
+			return err
+		}
+
+		for _, arch := range data.ArchList {
+			if !r.HasArch(arch) || data.SupportedArchs[arch].Dir == "" {
+				continue
+			}
+
+			labels := formatMetricLabels("repo", r.Name, "arch", arch, "type", subRepo.Name)
+
+			metrics.Packages.Samples = append(metrics.Packages.Samples, fmt.Sprintf(
+				"%s%s %d", METRIC_PACKAGES, labels, stats.Packages[arch],
+			))
+
+			metrics.Size.Samples = append(metrics.Size.Samples, fmt.Sprintf(
+				"%s%s %d", METRIC_SIZE, labels, stats.Sizes[arch],
+			))
+
+			modTime, err := subRepo.GetModTime(arch)
+
+			if err != nil {
+				return err
+			}
+
+			if modTime.After(indexDates[arch]) {
+				indexDates[arch] = modTime
+			}
+		}
+	}
+
+	for _, arch := range data.ArchList {
+		modTime, ok := indexDates[arch]
+
+		if !ok || modTime.IsZero() {
+			continue
+		}
+
+		metrics.IndexAge.Samples = append(metrics.IndexAge.Samples, fmt.Sprintf(
+			"%s%s %d", METRIC_INDEX_AGE,
+			formatMetricLabels("repo", r.Name, "arch", arch),
+			int64(now.Sub(modTime).Seconds()),
+		))
+	}
+
+	return nil
+}
+
+// formatMetrics formats metrics using Prometheus text-based exposition format
+func formatMetrics(metrics ...*metric) string {
+	var buf strings.Builder
+
+	for _, m := range metrics {
+		if len(m.Samples) == 0 {
+			continue
+		}
+
+		fmt.Fprintf(&buf, "# HELP %s %s\n", m.Name, m.Help)
+		fmt.Fprintf(&buf, "# TYPE %s gauge\n", m.Name)
+
+		for _, sample := range m.Samples {
+			buf.WriteString(sample + "\n")
+		}
+	}
+
+	return buf.String()
+}
+
+// formatMetricLabels formats metric labels from given name/value pairs
+func formatMetricLabels(pairs ...string) string {
+	var labels []string
+
+	for i := 0; i+1 < len(pairs); i += 2 {
+		value := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(pairs[i+1])
+		labels = append(labels, fmt.Sprintf(`%s="%s"`, pairs[i], value))
+	}
+
+	return "{" + strings.Join(labels, ",") + "}"
+}
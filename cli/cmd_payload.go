@@ -33,10 +33,9 @@ func cmdPayload(ctx *context, args options.Arguments) bool {
 	payloadType := "files"
 
 	if args.Has(1) {
-		switch args.Get(1).String() {
-		case "files", "file", "f", "requires", "req", "reqs", "provides", "prov", "provs":
-			payloadType = args.Get(1).String()
-		default:
+		payloadType = getPayloadType(args.Get(1).String())
+
+		if payloadType == "" {
 			terminal.Error("Unknown payload type %q", args.Get(1).String())
 			return false
 		}
@@ -75,29 +74,18 @@ func printPackagePayload(pkg *repo.Package, payloadType string) {
 		fmtutil.Separator(false)
 	}
 
-	switch payloadType {
-	case "files", "file", "f":
+	if payloadType == "files" {
 		if rawOutput {
 			printRawPackagePayload(pkg)
 		} else {
 			printPackageFilesTree(pkg)
 		}
-
-	case "requires", "req", "reqs":
-		for _, req := range pkg.Info.Requires {
-			if rawOutput {
-				fmt.Println(formatDepName(req, false))
-			} else {
-				fmt.Printf(" %s\n", formatDepName(req, true))
-			}
-		}
-
-	case "provides", "prov", "provs":
-		for _, prov := range pkg.Info.Provides {
+	} else {
+		for _, dep := range getPayloadDeps(pkg, payloadType) {
 			if rawOutput {
-				fmt.Println(formatDepName(prov, false))
+				fmt.Println(formatDepName(dep, false))
 			} else {
-				fmt.Printf(" %s\n", formatDepName(prov, true))
+				fmt.Printf(" %s\n", formatDepName(dep, true))
 			}
 		}
 	}
@@ -108,6 +96,56 @@ func printPackagePayload(pkg *repo.Package, payloadType string) {
 	}
 }
 
+// getPayloadType returns normalized payload type or empty string if type is unknown
+func getPayloadType(typ string) string {
+	switch typ {
+	case "files", "file", "f":
+		return "files"
+	case "requires", "req", "reqs":
+		return "requires"
+	case "provides", "prov", "provs":
+		return "provides"
+	case "conflicts", "conf", "confs":
+		return "conflicts"
+	case "obsoletes", "obs":
+		return "obsoletes"
+	case "recommends", "rec", "recs":
+		return "recommends"
+	case "suggests", "sug", "sugs":
+		return "suggests"
+	case "enhances", "enh", "enhs":
+		return "enhances"
+	case "supplements", "sup", "sups":
+		return "supplements"
+	}
+
+	return ""
+}
+
+// getPayloadDeps returns package dependencies of given payload type
+func getPayloadDeps(pkg *repo.Package, payloadType string) []data.Dependency {
+	switch payloadType {
+	case "requires":
+		return pkg.Info.Requires
+	case "provides":
+		return pkg.Info.Provides
+	case "conflicts":
+		return pkg.Info.Conflicts
+	case "obsoletes":
+		return pkg.Info.Obsoletes
+	case "recommends":
+		return pkg.Info.Recommends
+	case "suggests":
+		return pkg.Info.Suggests
+	case "enhances":
+		return pkg.Info.Enhances
+	case "supplements":
+		return pkg.Info.Supplements
+	}
+
+	return nil
+}
+
 // printRawPackagePayload prints raw package payload
 func printRawPackagePayload(pkg *repo.Package) {
 	payload := pkg.Info.Payload
@@ -9,14 +9,25 @@ package cli
 
 import (
 	"github.com/essentialkaos/ek/v13/fmtc"
+	"github.com/essentialkaos/ek/v13/knf"
 	"github.com/essentialkaos/ek/v13/options"
 	"github.com/essentialkaos/ek/v13/terminal"
+
+	"github.com/essentialkaos/rep/v3/repo"
 )
 
 // ////////////////////////////////////////////////////////////////////////////////// //
 
 // cmdPurgeCache is 'purge-cache' command handler
 func cmdPurgeCache(ctx *context, args options.Arguments) bool {
+	if options.GetB(OPT_ALL_REPOS) {
+		return purgeAllReposCache(ctx)
+	}
+
+	if options.GetB(OPT_RELEASE) || options.GetB(OPT_TESTING) {
+		return purgeSubReposCache(ctx)
+	}
+
 	isCancelProtected = true
 
 	err := ctx.Repo.PurgeCache()
@@ -32,3 +43,109 @@ func cmdPurgeCache(ctx *context, args options.Arguments) bool {
 
 	return true
 }
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// purgeSubReposCache removes cached data only for sub-repositories selected
+// with options
+func purgeSubReposCache(ctx *context) bool {
+	var subRepos []*repo.SubRepository
+
+	if options.GetB(OPT_RELEASE) {
+		subRepos = append(subRepos, ctx.Repo.Release)
+	}
+
+	if options.GetB(OPT_TESTING) {
+		subRepos = append(subRepos, ctx.Repo.Testing)
+	}
+
+	for _, r := range subRepos {
+		isCancelProtected = true
+
+		err := r.PurgeCache()
+
+		isCancelProtected = false
+
+		if err != nil {
+			terminal.Error("Can't clean cached data: %v", err)
+			return false
+		}
+
+		fmtc.Printfn("{g}Cached data for {*}%s{!*} repository successfully deleted{!}", r.Name)
+	}
+
+	return true
+}
+
+// purgeAllReposCache removes cached data for all configured repositories. Context
+// is nil if command is executed without repository name.
+func purgeAllReposCache(ctx *context) bool {
+	hasErrors := false
+
+	for _, repoName := range getRepoNames() {
+		if isCanceled.Load() {
+			return false
+		}
+
+		// Lock for current repository already acquired by command runner
+		if !isCurrentRepo(ctx, repoName) && !checkForLock(getLockName(repoName)) {
+			fmtc.Printfn("{s-}%s{!} {r}✖ {!}", repoName)
+			terminal.Error("Can't clean cached data for %q due to lock", repoName)
+			hasErrors = true
+			continue
+		}
+
+		isCancelProtected = true
+
+		err := purgeRepoCacheWithLock(ctx, repoName)
+
+		isCancelProtected = false
+
+		if err != nil {
+			fmtc.Printfn("{s-}%s{!} {r}✖ {!}", repoName)
+			terminal.Error("Can't clean cached data for %q: %v", repoName, err)
+			hasErrors = true
+			continue
+		}
+
+		fmtc.Printfn("%s {g}✔ {!}", repoName)
+	}
+
+	if hasErrors {
+		return false
+	}
+
+	fmtc.NewLine()
+	fmtc.Println("{g}All cached data for all repositories successfully deleted{!}")
+
+	return true
+}
+
+// purgeRepoCacheWithLock removes cached data for repository with given name
+// holding repository lock
+func purgeRepoCacheWithLock(ctx *context, repoName string) error {
+	if !isCurrentRepo(ctx, repoName) {
+		lockName := getLockName(repoName)
+
+		createLock(lockName)
+		defer removeLock(lockName)
+	}
+
+	return purgeRepoCache(configs[repoName])
+}
+
+// isCurrentRepo returns true if given repository is repository of command context
+func isCurrentRepo(ctx *context, repoName string) bool {
+	return ctx != nil && ctx.Repo.Name == repoName
+}
+
+// purgeRepoCache removes cached data for repository with given configuration
+func purgeRepoCache(repoCfg *knf.Config) error {
+	r, err := getRepo(repoCfg)
+
+	if err != nil {
+		return err
+	}
+
+	return r.PurgeCache()
+}
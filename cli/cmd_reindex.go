@@ -8,39 +8,90 @@ package cli
 // ////////////////////////////////////////////////////////////////////////////////// //
 
 import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/essentialkaos/ek/v13/fmtc"
+	"github.com/essentialkaos/ek/v13/fmtutil"
 	"github.com/essentialkaos/ek/v13/options"
+	"github.com/essentialkaos/ek/v13/path"
+	"github.com/essentialkaos/ek/v13/pluralize"
 	"github.com/essentialkaos/ek/v13/spinner"
 	"github.com/essentialkaos/ek/v13/terminal"
+	"github.com/essentialkaos/ek/v13/timeutil"
 
 	"github.com/essentialkaos/rep/v3/repo"
 	"github.com/essentialkaos/rep/v3/repo/data"
+	"github.com/essentialkaos/rep/v3/repo/index"
 )
 
 // ////////////////////////////////////////////////////////////////////////////////// //
 
+// MAINTENANCE_FLAG is name of maintenance flag file
+const MAINTENANCE_FLAG = ".maintenance"
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
 // cmdReindex is 'reindex' command handler
 func cmdReindex(ctx *context, args options.Arguments) bool {
 	reindexAll := !options.GetB(OPT_RELEASE) && !options.GetB(OPT_TESTING)
 	full := options.GetB(OPT_FULL)
+	syncNoarch := options.GetB(OPT_SYNC_NOARCH)
+	logInfo := fmt.Sprintf("full: %t, sync-noarch: %t", full, syncNoarch)
+
+	var archs []string
+
+	if options.Has(OPT_ARCH) {
+		arch := options.GetS(OPT_ARCH)
+		archInfo, ok := data.SupportedArchs[arch]
+
+		if !ok || archInfo.Dir == "" {
+			terminal.Error("Unknown or unsupported architecture %q", arch)
+			return false
+		}
+
+		archs = []string{arch}
+		logInfo += ", arch: " + arch
+	}
+
+	if globalConfig.GetB(STORAGE_MAINTENANCE_FLAG) {
+		err := createMaintenanceFlag(ctx.Repo.Name)
+
+		if err != nil {
+			terminal.Error(err)
+			return false
+		}
+
+		defer removeMaintenanceFlag()
+	}
 
 	if reindexAll || options.GetB(OPT_RELEASE) {
-		if !reindexRepository(ctx, ctx.Repo.Release, full) {
+		if syncNoarch && !syncNoarchPackages(ctx, ctx.Repo.Release) {
+			return false
+		}
+
+		if !reindexRepositoryArchs(ctx, ctx.Repo.Release, archs, full) {
 			return false
 		}
 
-		ctx.Logger.Get(data.REPO_RELEASE).Print("Repository reindexed (full: %t)", full)
+		ctx.Logger.Get(data.REPO_RELEASE).Print("Repository reindexed (%s)", logInfo)
 	}
 
-	if isCanceled {
+	if isCanceled.Load() {
 		return false
 	}
 
 	if reindexAll || options.GetB(OPT_TESTING) {
-		if !reindexRepository(ctx, ctx.Repo.Testing, full) {
+		if syncNoarch && !syncNoarchPackages(ctx, ctx.Repo.Testing) {
 			return false
 		}
 
-		ctx.Logger.Get(data.REPO_TESTING).Print("Repository reindexed (full: %t)", full)
+		if !reindexRepositoryArchs(ctx, ctx.Repo.Testing, archs, full) {
+			return false
+		}
+
+		ctx.Logger.Get(data.REPO_TESTING).Print("Repository reindexed (%s)", logInfo)
 	}
 
 	return true
@@ -50,15 +101,25 @@ func cmdReindex(ctx *context, args options.Arguments) bool {
 
 // reindexRepository starts repository reindex
 func reindexRepository(ctx *context, r *repo.SubRepository, full bool) bool {
+	return reindexRepositoryArchs(ctx, r, nil, full)
+}
+
+// reindexRepositoryArchs starts repository reindex only for given architectures
+// (or for all architectures if list is empty)
+func reindexRepositoryArchs(ctx *context, r *repo.SubRepository, archs []string, full bool) bool {
 	spinner.Show("Indexing {*}{?repo}%s{!} repository", r.Name)
 
 	isCancelProtected = true
 
 	ch := make(chan string, len(data.SupportedArchs))
+	progress := make(chan index.Progress, 1)
+	done := make(chan struct{})
 
-	go updateReindexStatus(ch, r.Name)
+	go updateReindexStatus(ch, progress, done, r.Name)
 
-	err := r.Reindex(full, ch)
+	stats, err := r.ReindexArchs(cancelCtx, archs, full, ch, progress)
+
+	<-done
 
 	if err == nil {
 		spinner.Update("Index for {*}{?repo}%s{!} repository successfully built", r.Name)
@@ -75,12 +136,144 @@ func reindexRepository(ctx *context, r *repo.SubRepository, full bool) bool {
 		return false
 	}
 
+	printReindexStats(stats)
+
+	return true
+}
+
+// autoReindexRepositories reindexes given sub-repositories after changes if
+// automatic reindex is enabled and reindex wasn't postponed. It returns true if
+// all sub-repositories were successfully reindexed.
+func autoReindexRepositories(ctx *context, archs []string, repos ...*repo.SubRepository) bool {
+	if isReindexPostponed(ctx) {
+		for _, r := range repos {
+			ctx.Logger.Get(r.Name).Print("Repository reindex postponed")
+		}
+
+		return false
+	}
+
+	fmtc.NewLine()
+
+	ok := true
+
+	for _, r := range repos {
+		if !reindexRepositoryArchs(ctx, r, archs, false) {
+			ok = false
+			continue
+		}
+
+		ctx.Logger.Get(r.Name).Print("Repository reindexed after changes")
+	}
+
+	return ok
+}
+
+// isReindexPostponed returns true if repository must not be reindexed after
+// changes
+func isReindexPostponed(ctx *context) bool {
+	return options.GetB(OPT_POSTPONE_INDEX) ||
+		!configs[ctx.Repo.Name].GetB(REPOSITORY_AUTO_REINDEX, true)
+}
+
+// syncNoarchPackages copies noarch packages to all binary arch directories of
+// sub-repository which don't contain them (e.g. added after package was added)
+func syncNoarchPackages(ctx *context, r *repo.SubRepository) bool {
+	added, err := r.SyncNoarchPackages()
+
+	for _, file := range added {
+		ctx.Logger.Get(r.Name).Print("Added missing noarch package copy %s", file)
+	}
+
+	if len(added) != 0 {
+		fmtc.Printfn(
+			"{g}✔ {!}%s copied to missing {?repo}%s{!} architecture directories",
+			pluralize.PS(pluralize.En, "%d noarch %s", len(added), "package", "packages"), r.Name,
+		)
+	}
+
+	if err != nil {
+		terminal.Error("Can't sync noarch packages in %s repository: %v", r.Name, err)
+		return false
+	}
+
 	return true
 }
 
+// printReindexStats prints index generation summary for every arch
+func printReindexStats(stats repo.ReindexStats) {
+	for _, arch := range data.ArchList {
+		archStats, ok := stats[arch]
+
+		if !ok {
+			continue
+		}
+
+		if archStats == nil {
+			fmtc.Printfn("   {s-}%-9s  index is up to date, reindex skipped (use --full to force){!}", arch)
+			continue
+		}
+
+		fmtc.Printfn(
+			"   {s-}%-9s  %s, %s of metadata, took %s{!}", arch,
+			pluralize.PS(pluralize.En, "%d %s", archStats.Packages, "package", "packages"),
+			fmtutil.PrettySize(archStats.MetaSize),
+			timeutil.PrettyDuration(archStats.Duration),
+		)
+	}
+}
+
 // updateReindexStatus updates spinner status
-func updateReindexStatus(ch chan string, name string) {
-	for arch := range ch {
-		spinner.Update("Indexing {*}{?repo}%s{!} {s-}(%s){!} repository", name, arch)
+func updateReindexStatus(ch chan string, progress chan index.Progress, done chan struct{}, name string) {
+	var arch string
+
+	defer close(done)
+
+	for {
+		select {
+		case a, ok := <-ch:
+			if !ok {
+				return
+			}
+
+			arch = a
+			spinner.Update("Indexing {*}{?repo}%s{!} {s-}(%s){!} repository", name, arch)
+
+		case p := <-progress:
+			if p.Total == 0 {
+				continue
+			}
+
+			spinner.Update(
+				"Indexing {*}{?repo}%s{!} {s-}(%s: %d/%d){!} repository",
+				name, arch, p.Done, p.Total,
+			)
+		}
+	}
+}
+
+// createMaintenanceFlag creates maintenance flag file in repository data directory
+func createMaintenanceFlag(repoName string) error {
+	flagFile := path.Join(globalConfig.GetS(STORAGE_DATA), repoName, MAINTENANCE_FLAG)
+	flagData := fmt.Sprintf("%d %s\n", os.Getpid(), time.Now().Format(time.RFC3339))
+
+	err := os.WriteFile(flagFile, []byte(flagData), configs[repoName].GetM(PERMISSIONS_FILE, 0644))
+
+	if err != nil {
+		return fmt.Errorf("Can't create maintenance flag file: %w", err)
+	}
+
+	maintenanceFlag = flagFile
+
+	return nil
+}
+
+// removeMaintenanceFlag removes maintenance flag file if it was created
+func removeMaintenanceFlag() {
+	if maintenanceFlag == "" {
+		return
 	}
+
+	os.Remove(maintenanceFlag)
+	maintenanceFlag = ""
 }
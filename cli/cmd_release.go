@@ -12,6 +12,7 @@ import (
 	"github.com/essentialkaos/ek/v13/fmtutil"
 	"github.com/essentialkaos/ek/v13/options"
 	"github.com/essentialkaos/ek/v13/path"
+	"github.com/essentialkaos/ek/v13/pluralize"
 	"github.com/essentialkaos/ek/v13/spinner"
 	"github.com/essentialkaos/ek/v13/terminal"
 	"github.com/essentialkaos/ek/v13/terminal/input"
@@ -43,6 +44,12 @@ func cmdRelease(ctx *context, args options.Arguments) bool {
 
 // releasePackages copies packages from testing to release repository
 func releasePackages(ctx *context, stack repo.PackageStack, filter string) bool {
+	if options.GetB(OPT_DRY_RUN) {
+		printDryRunFiles("Would be copied to", ctx.Repo.Release, stack.FlattenFiles())
+		printDryRunNotice()
+		return true
+	}
+
 	if !options.GetB(OPT_FORCE) {
 		printPackageList(ctx.Repo.Testing, stack, filter)
 
@@ -56,6 +63,10 @@ func releasePackages(ctx *context, stack repo.PackageStack, filter string) bool
 		}
 	}
 
+	if options.GetB(OPT_ATOMIC) {
+		return releasePackagesFilesAtomic(ctx, stack.FlattenFiles())
+	}
+
 	return releasePackagesFiles(ctx, stack.FlattenFiles())
 }
 
@@ -68,7 +79,7 @@ func releasePackagesFiles(ctx *context, files []repo.PackageFile) bool {
 	for _, file := range files {
 		ok := releasePackageFile(ctx, file)
 
-		if isCanceled {
+		if isCanceled.Load() {
 			return false
 		}
 
@@ -80,9 +91,8 @@ func releasePackagesFiles(ctx *context, files []repo.PackageFile) bool {
 		released = true
 	}
 
-	if released && !options.GetB(OPT_POSTPONE_INDEX) {
-		fmtc.NewLine()
-		reindexRepository(ctx, ctx.Repo.Release, false)
+	if released {
+		autoReindexRepositories(ctx, nil, ctx.Repo.Release)
 	}
 
 	isCancelProtected = false
@@ -90,6 +100,43 @@ func releasePackagesFiles(ctx *context, files []repo.PackageFile) bool {
 	return hasErrors == false
 }
 
+// releasePackagesFilesAtomic copies packages files from testing to release repository
+// with all-or-nothing semantics
+func releasePackagesFilesAtomic(ctx *context, files []repo.PackageFile) bool {
+	isCancelProtected = true
+
+	spinner.Show("Releasing %s", pluralize.P("%d %s", len(files), "package", "packages"))
+
+	err := ctx.Repo.CopyPackages(cancelCtx, ctx.Repo.Testing, ctx.Repo.Release, files)
+
+	if err != nil {
+		spinner.Update("Can't release packages, all changes were reverted")
+		spinner.Done(false)
+		terminal.Error("   %v", err)
+		isCancelProtected = false
+		return false
+	}
+
+	spinner.Update("%s released", pluralize.P("%d %s", len(files), "package", "packages"))
+	spinner.Done(true)
+
+	for _, file := range files {
+		fileName := path.Base(file.Path)
+
+		if file.ArchFlag == data.ARCH_FLAG_NOARCH {
+			ctx.Logger.Get(data.REPO_RELEASE).Print("Released package %s (%s)", fileName, file.BaseArchFlag.String())
+		} else {
+			ctx.Logger.Get(data.REPO_RELEASE).Print("Released package %s", fileName)
+		}
+	}
+
+	autoReindexRepositories(ctx, nil, ctx.Repo.Release)
+
+	isCancelProtected = false
+
+	return true
+}
+
 // releasePackageFile copies package file from testing to release repository
 func releasePackageFile(ctx *context, file repo.PackageFile) bool {
 	fileName := path.Base(file.Path)
@@ -56,6 +56,13 @@ func cmdRemove(ctx *context, args options.Arguments) bool {
 
 // removePackages removes packages from testing or all sub-repositories
 func removePackages(ctx *context, releaseStack, testingStack repo.PackageStack, filter string) bool {
+	if options.GetB(OPT_DRY_RUN) {
+		printDryRunFiles("Would be removed from", ctx.Repo.Release, releaseStack.FlattenFiles())
+		printDryRunFiles("Would be removed from", ctx.Repo.Testing, testingStack.FlattenFiles())
+		printDryRunNotice()
+		return true
+	}
+
 	if !options.GetB(OPT_FORCE) {
 		if !releaseStack.IsEmpty() {
 			printPackageList(ctx.Repo.Release, releaseStack, filter)
@@ -91,7 +98,7 @@ func removePackagesFiles(ctx *context, releaseFiles, testingFiles []repo.Package
 	for _, file = range releaseFiles {
 		ok := removePackageFile(ctx, ctx.Repo.Release, file)
 
-		if isCanceled {
+		if isCanceled.Load() {
 			return false
 		}
 
@@ -111,7 +118,7 @@ func removePackagesFiles(ctx *context, releaseFiles, testingFiles []repo.Package
 			continue
 		}
 
-		if isCanceled {
+		if isCanceled.Load() {
 			return false
 		}
 
@@ -120,16 +127,18 @@ func removePackagesFiles(ctx *context, releaseFiles, testingFiles []repo.Package
 
 	isCancelProtected = false
 
-	if (releaseRemoved || testingRemoved) && !options.GetB(OPT_POSTPONE_INDEX) {
-		fmtc.NewLine()
+	var changed []*repo.SubRepository
 
-		if releaseRemoved {
-			reindexRepository(ctx, ctx.Repo.Release, false)
-		}
+	if releaseRemoved {
+		changed = append(changed, ctx.Repo.Release)
+	}
 
-		if testingRemoved {
-			reindexRepository(ctx, ctx.Repo.Testing, false)
-		}
+	if testingRemoved {
+		changed = append(changed, ctx.Repo.Testing)
+	}
+
+	if len(changed) != 0 {
+		autoReindexRepositories(ctx, nil, changed...)
 	}
 
 	return hasErrors == false
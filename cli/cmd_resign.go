@@ -25,8 +25,31 @@ import (
 
 // ////////////////////////////////////////////////////////////////////////////////// //
 
-// cmdSign is 'resign' command handler
+// cmdResign is 'resign' command handler
 func cmdResign(ctx *context, args options.Arguments) bool {
+	archFlag := data.ARCH_FLAG_UNKNOWN
+
+	if options.Has(OPT_ARCH) {
+		arch := options.GetS(OPT_ARCH)
+		archFlag = data.SupportedArchs[arch].Flag
+
+		if archFlag == data.ARCH_FLAG_UNKNOWN {
+			terminal.Error("Unknown or unsupported architecture %q", arch)
+			return false
+		}
+	}
+
+	if len(args) == 0 && archFlag == data.ARCH_FLAG_UNKNOWN {
+		return resignAll(ctx)
+	}
+
+	return resignPackages(ctx, args, archFlag)
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// resignAll asks for confirmation and re-signs all packages in repository
+func resignAll(ctx *context) bool {
 	if !options.GetB(OPT_FORCE) {
 		panel.Warn(
 			"Command can take a lot of time",
@@ -54,7 +77,89 @@ how many packages you have and how big they are).`,
 	return resignAllPackages(ctx, key)
 }
 
-// ////////////////////////////////////////////////////////////////////////////////// //
+// resignPackages re-signs packages which match given search query and arch
+func resignPackages(ctx *context, args options.Arguments, archFlag data.ArchFlag) bool {
+	var err error
+	var filter string
+	var testingStack, releaseStack repo.PackageStack
+
+	if len(args) == 0 {
+		testingStack, err = ctx.Repo.Testing.List("", true)
+	} else {
+		testingStack, filter, err = smartPackageSearch(ctx.Repo.Testing, args)
+	}
+
+	if err != nil {
+		terminal.Error(err.Error())
+		return false
+	}
+
+	if len(args) == 0 {
+		releaseStack, err = ctx.Repo.Release.List("", true)
+	} else {
+		releaseStack, _, err = smartPackageSearch(ctx.Repo.Release, args)
+	}
+
+	if err != nil {
+		terminal.Error(err.Error())
+		return false
+	}
+
+	testingFiles := getResignFiles(testingStack, archFlag)
+	releaseFiles := getResignFiles(releaseStack, archFlag)
+
+	if len(testingFiles) == 0 && len(releaseFiles) == 0 {
+		terminal.Warn("No packages found")
+		return false
+	}
+
+	if !options.GetB(OPT_FORCE) {
+		if !testingStack.IsEmpty() {
+			printPackageList(ctx.Repo.Testing, testingStack, filter)
+		}
+
+		if !releaseStack.IsEmpty() {
+			printPackageList(ctx.Repo.Release, releaseStack, filter)
+		}
+
+		fmtutil.Separator(true)
+		fmtc.NewLine()
+
+		ok, err := input.ReadAnswer("Do you really want to re-sign these packages?", "n")
+
+		if err != nil || !ok {
+			return false
+		}
+	}
+
+	key, ok := getRepoSigningKey(ctx.Repo)
+
+	if !ok {
+		return false
+	}
+
+	if len(testingFiles) != 0 {
+		if !resignRepoFiles(ctx, key, ctx.Repo.Testing, testingFiles) {
+			ctx.Logger.Get(data.REPO_TESTING).Print("Packages re-signing finished with error")
+			return false
+		}
+
+		fmtc.NewLine()
+		reindexRepository(ctx, ctx.Repo.Testing, false)
+	}
+
+	if len(releaseFiles) != 0 {
+		if !resignRepoFiles(ctx, key, ctx.Repo.Release, releaseFiles) {
+			ctx.Logger.Get(data.REPO_RELEASE).Print("Packages re-signing finished with error")
+			return false
+		}
+
+		fmtc.NewLine()
+		reindexRepository(ctx, ctx.Repo.Release, false)
+	}
+
+	return true
+}
 
 // resignAllPackages re-singes all packages in testing and release repositories
 func resignAllPackages(ctx *context, key *sign.Key) bool {
@@ -88,8 +193,6 @@ func resignAllPackages(ctx *context, key *sign.Key) bool {
 func resignRepoPackages(ctx *context, key *sign.Key, r *repo.SubRepository) bool {
 	stack, err := r.List("", true)
 
-	ctx.Logger.Get(r.Name).Print("Started packages re-signing")
-
 	if err != nil {
 		terminal.Error(err.Error())
 		return false
@@ -100,6 +203,13 @@ func resignRepoPackages(ctx *context, key *sign.Key, r *repo.SubRepository) bool
 		return true
 	}
 
+	return resignRepoFiles(ctx, key, r, stack.FlattenFiles())
+}
+
+// resignRepoFiles re-signs given package files in given repository
+func resignRepoFiles(ctx *context, key *sign.Key, r *repo.SubRepository, files repo.PackageFiles) bool {
+	ctx.Logger.Get(r.Name).Print("Started packages re-signing")
+
 	tmpDir, err := ctx.Temp.MkDir("rep")
 
 	if err != nil {
@@ -107,8 +217,6 @@ func resignRepoPackages(ctx *context, key *sign.Key, r *repo.SubRepository) bool
 		return false
 	}
 
-	files := stack.FlattenFiles()
-
 	fmtc.Printf(
 		"Re-signing %s %s in {*}{?repo}%s{!} repository…\n",
 		fmtutil.PrettyNum(len(files)),
@@ -144,7 +252,7 @@ func resignRepoPackages(ctx *context, key *sign.Key, r *repo.SubRepository) bool
 
 		pb.Add(1)
 
-		if isCanceled {
+		if isCanceled.Load() {
 			pb.Finish()
 			return false
 		}
@@ -158,3 +266,26 @@ func resignRepoPackages(ctx *context, key *sign.Key, r *repo.SubRepository) bool
 
 	return true
 }
+
+// getResignFiles returns unique package files from stack with given arch
+// (ARCH_FLAG_UNKNOWN means any arch)
+func getResignFiles(stack repo.PackageStack, archFlag data.ArchFlag) repo.PackageFiles {
+	var result repo.PackageFiles
+
+	added := make(map[string]bool)
+
+	for _, file := range stack.FlattenFiles() {
+		if added[file.Path] {
+			continue
+		}
+
+		if archFlag != data.ARCH_FLAG_UNKNOWN && file.ArchFlag != archFlag {
+			continue
+		}
+
+		added[file.Path] = true
+		result = append(result, file)
+	}
+
+	return result
+}
@@ -10,10 +10,15 @@ package cli
 import (
 	"fmt"
 	"os"
+	"runtime"
+	"sync/atomic"
 
+	"github.com/essentialkaos/ek/v13/fmtc"
+	"github.com/essentialkaos/ek/v13/fmtutil"
 	"github.com/essentialkaos/ek/v13/fsutil"
 	"github.com/essentialkaos/ek/v13/options"
 	"github.com/essentialkaos/ek/v13/path"
+	"github.com/essentialkaos/ek/v13/progress"
 	"github.com/essentialkaos/ek/v13/spinner"
 	"github.com/essentialkaos/ek/v13/terminal"
 
@@ -56,6 +61,10 @@ func signRPMFiles(files []string, ctx *context, key *sign.Key) bool {
 		return false
 	}
 
+	if len(files) > 1 {
+		return signRPMFilesParallel(files, tmpDir, ctx, key)
+	}
+
 	isCancelProtected = true
 
 	var hasErrors bool
@@ -63,7 +72,7 @@ func signRPMFiles(files []string, ctx *context, key *sign.Key) bool {
 	for _, file := range files {
 		ok := signRPMFile(file, tmpDir, ctx, key)
 
-		if isCanceled {
+		if isCanceled.Load() {
 			return false
 		}
 
@@ -77,65 +86,137 @@ func signRPMFiles(files []string, ctx *context, key *sign.Key) bool {
 	return hasErrors == false
 }
 
+// signRPMFilesParallel signs given RPM files using a pool of workers
+func signRPMFilesParallel(files []string, tmpDir string, ctx *context, key *sign.Key) bool {
+	var signed, skipped int32
+
+	isCancelProtected = true
+
+	pb := progress.New(int64(len(files)), "Signing")
+	pb.Start()
+
+	errs := runParallel(pb, runtime.NumCPU(), len(files), func(i int) []error {
+		if isCanceled.Load() {
+			return nil
+		}
+
+		// Files with the same name can be placed in different directories, so
+		// we use index for making temporary file names unique
+		tmpFile := path.Join(tmpDir, fmt.Sprintf("%d-%s", i, path.Base(files[i])))
+		isSigned, err := signRPMFileQuiet(files[i], tmpFile, ctx, key)
+
+		switch {
+		case err != nil:
+			return []error{fmt.Errorf("%s: %v", files[i], err)}
+		case isSigned:
+			atomic.AddInt32(&signed, 1)
+		default:
+			atomic.AddInt32(&skipped, 1)
+		}
+
+		return nil
+	})
+
+	pb.Finish()
+
+	isCancelProtected = false
+
+	if isCanceled.Load() {
+		return false
+	}
+
+	if !errs.IsEmpty() {
+		fmtc.NewLine()
+
+		for _, err := range errs.All() {
+			terminal.Error(err.Error())
+		}
+	}
+
+	failedColor := "{!}"
+
+	if !errs.IsEmpty() {
+		failedColor = "{r}"
+	}
+
+	fmtc.NewLine()
+	fmtc.Printfn(
+		"{*}Signed:{!} %s {s}|{!} {*}Already signed:{!} %s {s}|{!} {*}Failed:{!} "+failedColor+"%s{!}",
+		fmtutil.PrettyNum(signed), fmtutil.PrettyNum(skipped), fmtutil.PrettyNum(errs.Num()),
+	)
+
+	return errs.IsEmpty()
+}
+
 // signRPMFile signs given RPM file
 func signRPMFile(file, tmpDir string, ctx *context, key *sign.Key) bool {
-	var err error
-
 	fileName := path.Base(file)
 
 	spinner.Show("Signing {?package}%s{!}", file)
 
+	isSigned, err := signRPMFileQuiet(file, path.Join(tmpDir, fileName), ctx, key)
+
+	if err != nil {
+		printSpinnerSignError(fileName, err.Error())
+		return false
+	}
+
+	if !isSigned {
+		spinner.Update("Package {?package}%s{!} already signed with this key", file)
+		spinner.Done(true)
+		return true
+	}
+
+	spinner.Update("Package {?package}%s{!} signed", file)
+	spinner.Done(true)
+
+	return true
+}
+
+// signRPMFileQuiet signs given RPM file without printing anything and returns
+// false if file is already signed with given key
+func signRPMFileQuiet(file, tmpFile string, ctx *context, key *sign.Key) (bool, error) {
+	fileName := path.Base(file)
+
 	if !options.GetB(OPT_IGNORE_FILTER) {
 		matchFilePattern, err := path.Match(ctx.Repo.FileFilter, fileName)
 
 		if err != nil {
-			printSpinnerSignError(fileName, fmt.Sprintf("Can't parse file filter pattern: %v", err))
-			return false
+			return false, fmt.Errorf("Can't parse file filter pattern: %v", err)
 		}
 
 		if !matchFilePattern {
-			printSpinnerSignError(fileName, fmt.Sprintf("File doesn't match repository filter (%s)", ctx.Repo.FileFilter))
-			return false
+			return false, fmt.Errorf("File doesn't match repository filter (%s)", ctx.Repo.FileFilter)
 		}
 	}
 
 	if !rpm.IsRPM(file) {
-		printSpinnerSignError(fileName, "File is not an RPM package")
-		return false
+		return false, fmt.Errorf("File is not an RPM package")
 	}
 
 	isSignValid, err := sign.IsPackageSignatureValid(file, key)
 
 	if err != nil {
-		printSpinnerSignError(fileName, err.Error())
-		return false
+		return false, err
 	}
 
 	if isSignValid {
-		spinner.Update("Package {?package}%s{!} already signed with this key", file)
-		spinner.Done(true)
-		return true
+		return false, nil
 	}
 
-	tmpFile := path.Join(tmpDir, fileName)
 	err = sign.SignPackage(file, tmpFile, key)
 
 	if err != nil {
-		printSpinnerSignError(fileName, err.Error())
-		return false
+		return false, err
 	}
 
 	err = replaceSignedRPMFile(file, tmpFile)
 
 	if err != nil {
-		printSpinnerSignError(fileName, err.Error())
-		return false
+		return false, err
 	}
 
-	spinner.Update("Package {?package}%s{!} signed", file)
-	spinner.Done(true)
-
-	return true
+	return true, nil
 }
 
 // replaceSignedRPMFile replaces original file with the signed one
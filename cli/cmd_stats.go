@@ -8,11 +8,18 @@ package cli
 // ////////////////////////////////////////////////////////////////////////////////// //
 
 import (
+	"encoding/json"
+	"fmt"
+	"os"
 	"strings"
+	"time"
 
 	"github.com/essentialkaos/ek/v13/fmtc"
 	"github.com/essentialkaos/ek/v13/fmtutil"
+	"github.com/essentialkaos/ek/v13/fsutil"
+	"github.com/essentialkaos/ek/v13/mathutil"
 	"github.com/essentialkaos/ek/v13/options"
+	"github.com/essentialkaos/ek/v13/path"
 	"github.com/essentialkaos/ek/v13/terminal"
 	"github.com/essentialkaos/ek/v13/timeutil"
 
@@ -22,46 +29,214 @@ import (
 
 // ////////////////////////////////////////////////////////////////////////////////// //
 
+// statsSnapshot contains stats data saved on the previous command run
+type statsSnapshot struct {
+	Date  time.Time             `json:"date"`
+	Stats *repo.RepositoryStats `json:"stats"`
+}
+
+// statsReport contains stats data for JSON output
+type statsReport struct {
+	Repos map[string]map[string]*statsReportData `json:"repos"` // repo → sub-repo → stats
+	Total *statsReportData                       `json:"total"`
+}
+
+// statsReportData contains stats data of one or more sub-repositories
+type statsReportData struct {
+	Packages      map[string]int   `json:"packages"`
+	Sizes         map[string]int64 `json:"sizes"`
+	TotalPackages int              `json:"total_packages"`
+	TotalSize     int64            `json:"total_size"`
+	Updated       int64            `json:"updated"`
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
 // cmdStats is 'stats' command handler
 func cmdStats(ctx *context, args options.Arguments) bool {
-	showAll := !options.GetB(OPT_RELEASE) && !options.GetB(OPT_TESTING)
+	if options.Has(OPT_ARCH) {
+		arch := options.GetS(OPT_ARCH)
 
-	if showAll || options.GetB(OPT_RELEASE) {
-		stats, err := ctx.Repo.Release.Stats()
+		if data.SupportedArchs[arch].Flag == data.ARCH_FLAG_UNKNOWN {
+			terminal.Error("Unknown or unsupported architecture %q", arch)
+			return false
+		}
+	}
 
-		if err != nil {
-			terminal.Error(err.Error())
+	if options.GetB(OPT_ALL_REPOS) {
+		return showAllReposStats(ctx)
+	}
+
+	total := &repo.RepositoryStats{}
+	report := &statsReport{Repos: make(map[string]map[string]*statsReportData)}
+
+	if !collectRepoStats(ctx.Repo, report, total) {
+		return false
+	}
+
+	if options.GetB(OPT_JSON) {
+		report.Total = convertStatsToReportData(total)
+		return printJSON(report)
+	}
+
+	fmtutil.Separator(true)
+
+	return true
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// showAllReposStats shows combined stats for all configured repositories
+func showAllReposStats(ctx *context) bool {
+	total := &repo.RepositoryStats{}
+	report := &statsReport{Repos: make(map[string]map[string]*statsReportData)}
+
+	for _, repoName := range getRepoNames() {
+		if isCanceled.Load() {
 			return false
 		}
 
-		printRepoStats(ctx.Repo.Release, stats)
+		r := ctx.Repo
 
-		fmtc.NewLine()
+		if repoName != ctx.Repo.Name {
+			var err error
+
+			r, err = getRepo(configs[repoName])
+
+			if err != nil {
+				terminal.Error("Can't read stats for %q: %v", repoName, err)
+				return false
+			}
+
+			warmUpCache(r)
+		}
+
+		ok := collectRepoStats(r, report, total)
+
+		if r != ctx.Repo {
+			r.InvalidateCache()
+		}
+
+		if !ok {
+			return false
+		}
+	}
+
+	if options.GetB(OPT_JSON) {
+		report.Total = convertStatsToReportData(total)
+		return printJSON(report)
+	}
+
+	printRepoStats("Total", total)
+
+	fmtc.NewLine()
+	fmtutil.Separator(true)
+
+	return true
+}
+
+// collectRepoStats reads stats of sub-repositories selected with options, prints
+// them (if JSON output is not required) and adds them to given report and total
+func collectRepoStats(r *repo.Repository, report *statsReport, total *repo.RepositoryStats) bool {
+	showAll := !options.GetB(OPT_RELEASE) && !options.GetB(OPT_TESTING)
+	isMultiRepo := options.GetB(OPT_ALL_REPOS)
+
+	var subRepos []*repo.SubRepository
+
+	if showAll || options.GetB(OPT_RELEASE) {
+		subRepos = append(subRepos, r.Release)
 	}
 
 	if showAll || options.GetB(OPT_TESTING) {
-		stats, err := ctx.Repo.Testing.Stats()
+		subRepos = append(subRepos, r.Testing)
+	}
+
+	report.Repos[r.Name] = make(map[string]*statsReportData)
+
+	for _, subRepo := range subRepos {
+		fullStats, err := subRepo.Stats()
 
 		if err != nil {
 			terminal.Error(err.Error())
 			return false
 		}
 
-		printRepoStats(ctx.Repo.Testing, stats)
+		stats := filterStatsByArch(fullStats, options.GetS(OPT_ARCH))
+
+		report.Repos[r.Name][subRepo.Name] = convertStatsToReportData(stats)
+		total.Merge(stats)
+
+		if options.GetB(OPT_JSON) {
+			if options.GetB(OPT_SAVE) {
+				storeStatsSnapshot(subRepo, fullStats)
+			}
+
+			continue
+		}
+
+		title := subRepo.Name
+
+		if isMultiRepo {
+			title = r.Name + "/" + subRepo.Name
+		}
+
+		printRepoStats(title, stats)
+		processStatsSnapshot(subRepo, fullStats)
 
 		fmtc.NewLine()
 	}
 
-	fmtutil.Separator(true)
-
 	return true
 }
 
+// filterStatsByArch returns stats only for given arch (or unchanged stats if
+// arch is empty)
+func filterStatsByArch(stats *repo.RepositoryStats, arch string) *repo.RepositoryStats {
+	if arch == "" || stats == nil {
+		return stats
+	}
+
+	result := &repo.RepositoryStats{
+		Packages: make(map[string]int),
+		Sizes:    make(map[string]int64),
+		Updated:  stats.Updated,
+	}
+
+	count, ok := stats.Packages[arch]
+
+	if !ok {
+		return result
+	}
+
+	result.Packages[arch] = count
+	result.Sizes[arch] = stats.Sizes[arch]
+	result.TotalPackages = count
+	result.TotalSize = stats.Sizes[arch]
+
+	return result
+}
+
+// convertStatsToReportData converts repository stats to report data
+func convertStatsToReportData(stats *repo.RepositoryStats) *statsReportData {
+	result := &statsReportData{
+		Packages:      stats.Packages,
+		Sizes:         stats.Sizes,
+		TotalPackages: stats.TotalPackages,
+		TotalSize:     stats.TotalSize,
+	}
+
+	if !stats.Updated.IsZero() {
+		result.Updated = stats.Updated.Unix()
+	}
+
+	return result
+}
+
 // ////////////////////////////////////////////////////////////////////////////////// //
 
 // printRepoStats prints repo stats
-func printRepoStats(r *repo.SubRepository, stats *repo.RepositoryStats) {
-	fmtutil.Separator(true, strings.ToUpper(r.Name))
+func printRepoStats(title string, stats *repo.RepositoryStats) {
+	fmtutil.Separator(true, strings.ToUpper(title))
 	fmtc.NewLine()
 
 	if stats.TotalPackages == 0 {
@@ -104,3 +279,153 @@ func printRepoStats(r *repo.SubRepository, stats *repo.RepositoryStats) {
 		timeutil.Format(stats.Updated, "%Y/%m/%d %H:%M"),
 	)
 }
+
+// processStatsSnapshot prints difference with the previous stats snapshot (if
+// required) and saves the current one (if required). Snapshot always contains
+// stats for all architectures.
+func processStatsSnapshot(r *repo.SubRepository, stats *repo.RepositoryStats) {
+	if options.GetB(OPT_DIFF) {
+		prevSnapshot, err := readStatsSnapshot(getStatsSnapshotPath(r))
+
+		fmtc.NewLine()
+
+		if err != nil {
+			terminal.Warn("Can't compare stats: %v", err)
+		} else {
+			arch := options.GetS(OPT_ARCH)
+			prevSnapshot.Stats = filterStatsByArch(prevSnapshot.Stats, arch)
+			printRepoStatsDiff(prevSnapshot, filterStatsByArch(stats, arch))
+		}
+	}
+
+	if options.GetB(OPT_DIFF) || options.GetB(OPT_SAVE) {
+		storeStatsSnapshot(r, stats)
+	}
+}
+
+// storeStatsSnapshot saves stats snapshot for given sub-repository
+func storeStatsSnapshot(r *repo.SubRepository, stats *repo.RepositoryStats) {
+	err := saveStatsSnapshot(getStatsSnapshotPath(r), stats)
+
+	if err != nil {
+		terminal.Warn("Can't save stats snapshot: %v", err)
+	}
+}
+
+// printRepoStatsDiff prints difference between previous and current stats
+func printRepoStatsDiff(prevSnapshot *statsSnapshot, stats *repo.RepositoryStats) {
+	prev := prevSnapshot.Stats
+
+	fmtc.Printf(
+		"{*}Changes since %s:{!}\n\n",
+		timeutil.Format(prevSnapshot.Date, "%Y/%m/%d %H:%M"),
+	)
+
+	if prev.TotalPackages == stats.TotalPackages && prev.TotalSize == stats.TotalSize {
+		fmtc.Println("{s-}-- no changes --{!}")
+		return
+	}
+
+	fmtc.Printf(
+		"{*}Packages:{!}  %s\n",
+		formatStatsDelta(stats.TotalPackages-prev.TotalPackages, stats.TotalSize-prev.TotalSize),
+	)
+
+	fmtc.NewLine()
+
+	for _, arch := range data.ArchList {
+		countDelta := stats.Packages[arch] - prev.Packages[arch]
+		sizeDelta := stats.Sizes[arch] - prev.Sizes[arch]
+
+		if countDelta == 0 && sizeDelta == 0 {
+			continue
+		}
+
+		color := archColors[arch]
+
+		if fmtc.Is256ColorsSupported() {
+			color = archColorsExt[arch]
+		}
+
+		fmtc.Printf(color+"%-9s{!}  %s\n", arch, formatStatsDelta(countDelta, sizeDelta))
+	}
+}
+
+// formatStatsDelta formats difference in number of packages and size
+func formatStatsDelta(count int, size int64) string {
+	countSign, sizeSign := "+", "+"
+	countColor := "{g}"
+
+	if count < 0 {
+		countSign, countColor = "-", "{r}"
+	} else if count == 0 {
+		countSign, countColor = "", "{s}"
+	}
+
+	if size < 0 {
+		sizeSign = "-"
+	}
+
+	return fmtc.Sprintf(
+		"%s%s%s{!} {s}(%s%s){!}",
+		countColor, countSign, fmtutil.PrettyNum(mathutil.Abs(count)),
+		sizeSign, fmtutil.PrettySize(mathutil.Abs(size)),
+	)
+}
+
+// getStatsSnapshotPath returns path to file with stats snapshot for given
+// sub-repository
+func getStatsSnapshotPath(r *repo.SubRepository) string {
+	return path.Join(globalConfig.GetS(STORAGE_CACHE), r.Parent.Name, "stats-"+r.Name+".json")
+}
+
+// readStatsSnapshot reads stats snapshot from given file
+func readStatsSnapshot(file string) (*statsSnapshot, error) {
+	if !fsutil.IsExist(file) {
+		return nil, fmt.Errorf("There is no saved stats from the previous run")
+	}
+
+	snapshotData, err := os.ReadFile(file)
+
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := &statsSnapshot{}
+	err = json.Unmarshal(snapshotData, snapshot)
+
+	if err != nil {
+		return nil, fmt.Errorf("Can't decode stats snapshot: %w", err)
+	}
+
+	if snapshot.Stats == nil {
+		return nil, fmt.Errorf("Stats snapshot is empty")
+	}
+
+	return snapshot, nil
+}
+
+// saveStatsSnapshot saves stats snapshot to given file
+func saveStatsSnapshot(file string, stats *repo.RepositoryStats) error {
+	snapshotData, err := json.Marshal(&statsSnapshot{time.Now(), stats})
+
+	if err != nil {
+		return err
+	}
+
+	tmpFile := file + ".tmp"
+	err = os.WriteFile(tmpFile, snapshotData, 0600)
+
+	if err != nil {
+		return err
+	}
+
+	err = os.Rename(tmpFile, file)
+
+	if err != nil {
+		os.Remove(tmpFile)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,305 @@
+package cli
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2024 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/essentialkaos/ek/v13/fmtc"
+	"github.com/essentialkaos/ek/v13/fmtutil"
+	"github.com/essentialkaos/ek/v13/options"
+	"github.com/essentialkaos/ek/v13/pluralize"
+	"github.com/essentialkaos/ek/v13/terminal"
+
+	"github.com/essentialkaos/rep/v3/cli/tags"
+	"github.com/essentialkaos/rep/v3/repo"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Tag command actions
+const (
+	TAG_ACTION_ADD    = "add"
+	TAG_ACTION_REMOVE = "remove"
+	TAG_ACTION_LIST   = "list"
+)
+
+// TAGS_DATA_NAME is name of auxiliary data with packages tags
+const TAGS_DATA_NAME = "tags.json"
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// cmdTag is 'tag' command handler
+func cmdTag(ctx *context, args options.Arguments) bool {
+	action := strings.ToLower(args.Get(0).String())
+
+	switch action {
+	case TAG_ACTION_LIST:
+		return listPackagesTags(ctx, args[1:])
+	case TAG_ACTION_ADD, TAG_ACTION_REMOVE:
+		// continue
+	default:
+		terminal.Error("Unknown tag action %q", action)
+		return false
+	}
+
+	if len(args) < 3 {
+		terminal.Error("You must define tag and search query")
+		return false
+	}
+
+	tag := args.Get(1).String()
+	err := tags.ValidateTag(tag)
+
+	if err != nil {
+		terminal.Error("Tag %q is invalid: %v", tag, err)
+		return false
+	}
+
+	return updatePackagesTags(ctx, action, tag, args[2:])
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// updatePackagesTags adds or removes tag for packages found by given query
+func updatePackagesTags(ctx *context, action, tag string, args options.Arguments) bool {
+	repoTags, err := readRepoTags(ctx.Repo)
+
+	if err != nil {
+		terminal.Error(err.Error())
+		return false
+	}
+
+	pkgs, err := findTaggablePackages(ctx.Repo, args)
+
+	if err != nil {
+		terminal.Error(err.Error())
+		return false
+	}
+
+	if len(pkgs) == 0 {
+		terminal.Warn("No packages found")
+		return false
+	}
+
+	var updated int
+
+	for _, pkg := range pkgs {
+		var ok bool
+
+		for _, nevra := range getPackageNEVRAs(pkg) {
+			if action == TAG_ACTION_ADD {
+				ok = repoTags.Add(nevra, tag) || ok
+			} else {
+				ok = repoTags.Remove(nevra, tag) || ok
+			}
+		}
+
+		if ok {
+			updated++
+		}
+	}
+
+	if updated == 0 {
+		terminal.Warn("Nothing to update")
+		return true
+	}
+
+	err = writeRepoTags(ctx.Repo, repoTags)
+
+	if err != nil {
+		terminal.Error(err.Error())
+		return false
+	}
+
+	if action == TAG_ACTION_ADD {
+		fmtc.Printfn(
+			"{g}Tag {*}%s{!*} added to %s{!}", tag,
+			pluralize.P("%d %s", updated, "package", "packages"),
+		)
+	} else {
+		fmtc.Printfn(
+			"{g}Tag {*}%s{!*} removed from %s{!}", tag,
+			pluralize.P("%d %s", updated, "package", "packages"),
+		)
+	}
+
+	return true
+}
+
+// listPackagesTags prints tags of packages found by given query or all tagged
+// packages if query is empty
+func listPackagesTags(ctx *context, args options.Arguments) bool {
+	repoTags, err := readRepoTags(ctx.Repo)
+
+	if err != nil {
+		terminal.Error(err.Error())
+		return false
+	}
+
+	nevras := repoTags.Packages()
+
+	if len(args) != 0 {
+		pkgs, err := findTaggablePackages(ctx.Repo, args)
+
+		if err != nil {
+			terminal.Error(err.Error())
+			return false
+		}
+
+		var foundNEVRAs []string
+
+		for _, pkg := range pkgs {
+			foundNEVRAs = append(foundNEVRAs, getPackageNEVRAs(pkg)...)
+		}
+
+		nevras = slices.DeleteFunc(nevras, func(nevra string) bool {
+			return !slices.Contains(foundNEVRAs, nevra)
+		})
+	}
+
+	if rawOutput {
+		for _, nevra := range nevras {
+			fmt.Printf("%s\t%s\n", nevra, strings.Join(repoTags.Get(nevra), ","))
+		}
+
+		return true
+	}
+
+	if len(nevras) == 0 {
+		terminal.Warn("No tagged packages found")
+		return true
+	}
+
+	fmtutil.Separator(true, "TAGS")
+	fmtc.NewLine()
+
+	for _, nevra := range nevras {
+		fmtc.Printfn("%s {s-}→{!} {c}%s{!}", nevra, strings.Join(repoTags.Get(nevra), "{s-},{!} "))
+	}
+
+	fmtc.NewLine()
+	fmtutil.Separator(true)
+
+	return true
+}
+
+// findTaggablePackages finds packages in testing and release repositories
+func findTaggablePackages(r *repo.Repository, args options.Arguments) ([]*repo.Package, error) {
+	var result []*repo.Package
+
+	for _, subRepo := range []*repo.SubRepository{r.Testing, r.Release} {
+		stack, _, err := smartPackageSearch(subRepo, args)
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, bundle := range stack {
+			for _, pkg := range bundle {
+				if pkg != nil {
+					result = append(result, pkg)
+				}
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// filterPackageStackByTag returns stack only with packages which have tag defined
+// with --tag option
+func filterPackageStackByTag(r *repo.SubRepository, stack repo.PackageStack) (repo.PackageStack, error) {
+	if !options.Has(OPT_TAG) || stack.IsEmpty() {
+		return stack, nil
+	}
+
+	repoTags, err := readRepoTags(r.Parent)
+
+	if err != nil {
+		return nil, err
+	}
+
+	tag := options.GetS(OPT_TAG)
+	result := repo.PackageStack{}
+
+	for _, bundle := range stack {
+		var filteredBundle repo.PackageBundle
+
+		for _, pkg := range bundle {
+			if pkg != nil && isPackageHasTag(repoTags, pkg, tag) {
+				filteredBundle = append(filteredBundle, pkg)
+			}
+		}
+
+		if len(filteredBundle) != 0 {
+			result = append(result, filteredBundle)
+		}
+	}
+
+	return result, nil
+}
+
+// isPackageHasTag returns true if any of package files has given tag
+func isPackageHasTag(repoTags *tags.Tags, pkg *repo.Package, tag string) bool {
+	for _, nevra := range getPackageNEVRAs(pkg) {
+		if repoTags.Has(nevra, tag) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// getPackageNEVRAs returns slice with NEVRA of every package file
+func getPackageNEVRAs(pkg *repo.Package) []string {
+	var result []string
+
+	epoch := pkg.Epoch
+
+	if epoch == "" {
+		epoch = "0"
+	}
+
+	for _, file := range pkg.Files {
+		nevra := fmt.Sprintf(
+			"%s-%s:%s-%s.%s", pkg.Name, epoch,
+			pkg.Version, pkg.Release, file.ArchFlag.String(),
+		)
+
+		if !slices.Contains(result, nevra) {
+			result = append(result, nevra)
+		}
+	}
+
+	return result
+}
+
+// readRepoTags reads tags of packages in given repository
+func readRepoTags(r *repo.Repository) (*tags.Tags, error) {
+	tagsData, err := r.ReadAuxData(TAGS_DATA_NAME)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return tags.Decode(tagsData)
+}
+
+// writeRepoTags saves tags of packages in given repository
+func writeRepoTags(r *repo.Repository, repoTags *tags.Tags) error {
+	tagsData, err := repoTags.Encode()
+
+	if err != nil {
+		return err
+	}
+
+	return r.WriteAuxData(TAGS_DATA_NAME, tagsData)
+}
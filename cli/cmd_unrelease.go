@@ -43,6 +43,11 @@ func cmdUnrelease(ctx *context, args options.Arguments) bool {
 
 // unreleasePackages removes packages from release sub-repository
 func unreleasePackages(ctx *context, stack repo.PackageStack, filter string) bool {
+	if options.GetB(OPT_DRY_RUN) {
+		unreleasePackagesDryRun(ctx, stack.FlattenFiles())
+		return true
+	}
+
 	if !options.GetB(OPT_FORCE) {
 		printPackageList(ctx.Repo.Release, stack, filter)
 
@@ -61,6 +66,22 @@ func unreleasePackages(ctx *context, stack repo.PackageStack, filter string) boo
 	return unreleasePackagesFiles(ctx, files)
 }
 
+// unreleasePackagesDryRun prints list of packages files which would be removed
+// from release sub-repository and restored in testing sub-repository
+func unreleasePackagesDryRun(ctx *context, files []repo.PackageFile) {
+	var restoreFiles []repo.PackageFile
+
+	for _, file := range files {
+		if !ctx.Repo.Testing.HasPackageFile(path.Base(file.Path)) {
+			restoreFiles = append(restoreFiles, file)
+		}
+	}
+
+	printDryRunFiles("Would be removed from", ctx.Repo.Release, files)
+	printDryRunFiles("Would be moved to", ctx.Repo.Testing, restoreFiles)
+	printDryRunNotice()
+}
+
 // unreleasePackagesFiles removes packages files from release sub-repository
 func unreleasePackagesFiles(ctx *context, files []repo.PackageFile) bool {
 	var hasErrors, unreleased, restored bool
@@ -70,7 +91,7 @@ func unreleasePackagesFiles(ctx *context, files []repo.PackageFile) bool {
 	for _, file := range files {
 		ok, testingRestored := unreleasePackageFile(ctx, file)
 
-		if isCanceled {
+		if isCanceled.Load() {
 			return false
 		}
 
@@ -86,14 +107,10 @@ func unreleasePackagesFiles(ctx *context, files []repo.PackageFile) bool {
 		unreleased = true
 	}
 
-	if unreleased && !options.GetB(OPT_POSTPONE_INDEX) {
-		fmtc.NewLine()
-
-		reindexRepository(ctx, ctx.Repo.Release, false)
-
-		if restored {
-			reindexRepository(ctx, ctx.Repo.Testing, false)
-		}
+	if unreleased && restored {
+		autoReindexRepositories(ctx, nil, ctx.Repo.Release, ctx.Repo.Testing)
+	} else if unreleased {
+		autoReindexRepositories(ctx, nil, ctx.Repo.Release)
 	}
 
 	isCancelProtected = false
@@ -0,0 +1,182 @@
+package cli
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2024 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"github.com/essentialkaos/ek/v13/fmtc"
+	"github.com/essentialkaos/ek/v13/fmtutil"
+	"github.com/essentialkaos/ek/v13/options"
+	"github.com/essentialkaos/ek/v13/terminal"
+
+	"github.com/essentialkaos/rep/v3/repo"
+	"github.com/essentialkaos/rep/v3/repo/sign"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Types of problems found by verification
+const (
+	VERIFY_PROBLEM_UNSIGNED  = "unsigned"
+	VERIFY_PROBLEM_WRONG_KEY = "wrong-key"
+	VERIFY_PROBLEM_ERROR     = "error"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// verifyReport contains results of signatures verification
+type verifyReport struct {
+	Total    int              `json:"total"`
+	Failed   int              `json:"failed"`
+	Failures []*verifyFailure `json:"failures"`
+}
+
+// verifyFailure contains info about package file which failed verification
+type verifyFailure struct {
+	Repo    string `json:"repo"`
+	Arch    string `json:"arch"`
+	File    string `json:"file"`
+	Problem string `json:"problem"`
+	Error   string `json:"error,omitempty"`
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// cmdVerify is 'verify' command handler
+func cmdVerify(ctx *context, args options.Arguments) bool {
+	keys, err := ctx.Repo.ReadVerificationKeys()
+
+	if err != nil {
+		terminal.Error("Can't read signing keys: %v", err)
+		return false
+	}
+
+	if len(keys) == 0 {
+		terminal.Error("No signing key defined in configuration file")
+		return false
+	}
+
+	showAll := !options.GetB(OPT_RELEASE) && !options.GetB(OPT_TESTING)
+	report := &verifyReport{Failures: []*verifyFailure{}}
+
+	if showAll || options.GetB(OPT_RELEASE) {
+		if !verifySubRepoSignatures(ctx.Repo.Release, keys, report) {
+			return false
+		}
+	}
+
+	if showAll || options.GetB(OPT_TESTING) {
+		if !verifySubRepoSignatures(ctx.Repo.Testing, keys, report) {
+			return false
+		}
+	}
+
+	report.Failed = len(report.Failures)
+
+	if options.GetB(OPT_JSON) {
+		printJSON(report)
+	} else {
+		printVerifyReport(report)
+	}
+
+	return report.Failed == 0
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// verifySubRepoSignatures checks signatures of all package files in given
+// sub-repository and adds results to report
+func verifySubRepoSignatures(r *repo.SubRepository, keys []*sign.Key, report *verifyReport) bool {
+	stack, err := r.List("", true)
+
+	if err != nil {
+		terminal.Error(err.Error())
+		return false
+	}
+
+	checked := make(map[string]bool)
+
+	for _, file := range stack.FlattenFiles() {
+		if isCanceled.Load() {
+			return false
+		}
+
+		// Noarch packages have copies in all arch directories, so every copy
+		// must be checked
+		fileID := file.BaseArchFlag.String() + "/" + file.Path
+
+		if checked[fileID] {
+			continue
+		}
+
+		checked[fileID] = true
+		report.Total++
+
+		failure := verifyPackageFile(r, file, keys)
+
+		if failure != nil {
+			report.Failures = append(report.Failures, failure)
+		}
+	}
+
+	return true
+}
+
+// verifyPackageFile checks signature of given package file
+func verifyPackageFile(r *repo.SubRepository, file repo.PackageFile, keys []*sign.Key) *verifyFailure {
+	problem, err := checkPackageSignature(r.GetFullPackagePath(file), keys)
+
+	if problem == "" {
+		return nil
+	}
+
+	failure := &verifyFailure{
+		Repo:    r.Name,
+		Arch:    file.BaseArchFlag.String(),
+		File:    file.Path,
+		Problem: problem,
+	}
+
+	if err != nil {
+		failure.Error = err.Error()
+	}
+
+	return failure
+}
+
+// printVerifyReport prints signatures verification results
+func printVerifyReport(report *verifyReport) {
+	for _, failure := range report.Failures {
+		switch failure.Problem {
+		case VERIFY_PROBLEM_UNSIGNED:
+			fmtc.Printfn("{r}✖ {!}{s}%s/%s/{!}%s {s-}—{!} package is not signed", failure.Repo, failure.Arch, failure.File)
+		case VERIFY_PROBLEM_WRONG_KEY:
+			fmtc.Printfn("{r}✖ {!}{s}%s/%s/{!}%s {s-}—{!} package signed with untrusted key", failure.Repo, failure.Arch, failure.File)
+		default:
+			fmtc.Printfn("{r}✖ {!}{s}%s/%s/{!}%s {s-}—{!} %s", failure.Repo, failure.Arch, failure.File, failure.Error)
+		}
+	}
+
+	if report.Failed != 0 {
+		fmtc.NewLine()
+		terminal.Error(
+			"%s of %s packages failed signature verification",
+			fmtutil.PrettyNum(report.Failed), fmtutil.PrettyNum(report.Total),
+		)
+		return
+	}
+
+	if report.Total == 0 {
+		terminal.Warn("There are no packages to verify")
+		return
+	}
+
+	fmtc.Printfn(
+		"{g}✔ {!}All %s packages have valid signatures",
+		fmtutil.PrettyNum(report.Total),
+	)
+}
@@ -8,6 +8,7 @@ package cli
 // ////////////////////////////////////////////////////////////////////////////////// //
 
 import (
+	"fmt"
 	"strings"
 
 	"github.com/essentialkaos/ek/v13/fmtc"
@@ -18,16 +19,26 @@ import (
 
 	"github.com/essentialkaos/rep/v3/repo"
 	"github.com/essentialkaos/rep/v3/repo/data"
+	"github.com/essentialkaos/rep/v3/repo/search"
 )
 
 // ////////////////////////////////////////////////////////////////////////////////// //
 
 // cmdWhichSource is 'which-source' command handler
 func cmdWhichSource(ctx *context, args options.Arguments) bool {
+	if options.GetB(OPT_FILE) {
+		return findFileSources(args)
+	}
+
 	showAll := !options.GetB(OPT_RELEASE) && !options.GetB(OPT_TESTING)
+	findFunc := findSources
+
+	if options.GetB(OPT_BINARIES) {
+		findFunc = findBinaries
+	}
 
 	if options.GetB(OPT_RELEASE) || showAll {
-		status := findSources(ctx.Repo.Release, args)
+		status := findFunc(ctx.Repo.Release, args)
 
 		if status != true {
 			return false
@@ -35,13 +46,17 @@ func cmdWhichSource(ctx *context, args options.Arguments) bool {
 	}
 
 	if options.GetB(OPT_TESTING) || showAll {
-		status := findSources(ctx.Repo.Testing, args)
+		status := findFunc(ctx.Repo.Testing, args)
 
 		if status != true {
 			return false
 		}
 	}
 
+	if options.GetB(OPT_JSON) {
+		return printJSON(jsonStacks)
+	}
+
 	fmtutil.Separator(true)
 
 	return true
@@ -51,9 +66,6 @@ func cmdWhichSource(ctx *context, args options.Arguments) bool {
 
 // findSources tries to find source package name
 func findSources(r *repo.SubRepository, args options.Arguments) bool {
-	fmtutil.Separator(true, strings.ToUpper(r.Name))
-	fmtc.NewLine()
-
 	stack, _, err := smartPackageSearch(r, args)
 
 	if err != nil {
@@ -61,12 +73,118 @@ func findSources(r *repo.SubRepository, args options.Arguments) bool {
 		return false
 	}
 
+	if options.GetB(OPT_JSON) {
+		jsonStacks[r.Name] = stack.Compact()
+		return true
+	}
+
+	fmtutil.Separator(true, strings.ToUpper(r.Name))
+	fmtc.NewLine()
+
 	printPackageStackSources(r, stack)
 	fmtc.NewLine()
 
 	return true
 }
 
+// findBinaries tries to find binary packages built from given source packages
+func findBinaries(r *repo.SubRepository, args options.Arguments) bool {
+	var stack repo.PackageStack
+
+	for _, arg := range args {
+		bundles, err := findSourceBinaries(r, arg.String())
+
+		if err != nil {
+			terminal.Error(err.Error())
+			return false
+		}
+
+		stack = append(stack, bundles...)
+	}
+
+	if options.GetB(OPT_JSON) {
+		jsonStacks[r.Name] = stack.Compact()
+		return true
+	}
+
+	fmtutil.Separator(true, strings.ToUpper(r.Name))
+	fmtc.NewLine()
+
+	printPackageStackSources(r, stack)
+	fmtc.NewLine()
+
+	return true
+}
+
+// findSourceBinaries returns bundles with packages built from source package with
+// given name or source RPM file name
+func findSourceBinaries(r *repo.SubRepository, src string) (repo.PackageStack, error) {
+	isFileName := strings.HasSuffix(src, ".src.rpm")
+	term := search.TermSource(src)
+
+	if !isFileName {
+		term = search.TermSource(src + "-*")
+	}
+
+	stack, err := r.Find(search.Query{term})
+
+	if err != nil {
+		return nil, err
+	}
+
+	var result repo.PackageStack
+
+	for _, bundle := range stack {
+		if len(bundle) == 0 || bundle[0] == nil {
+			continue
+		}
+
+		// All packages in bundle are built from the same source package
+		pkgSrc := bundle[0].Src
+
+		if isFileName && pkgSrc == src ||
+			!isFileName && getSourcePackageName(pkgSrc) == src {
+			result = append(result, bundle)
+		}
+	}
+
+	return result, nil
+}
+
+// findFileSources reads source package name directly from RPM files
+func findFileSources(args options.Arguments) bool {
+	var stack repo.PackageStack
+
+	for _, arg := range args {
+		pkg, err := repo.ReadPackageFile(arg.Clean().String())
+
+		if err != nil {
+			terminal.Error(err.Error())
+			return false
+		}
+
+		if pkg.Src == "" {
+			pkg.Src = fmt.Sprintf("%s-%s-%s.src.rpm", pkg.Name, pkg.Version, pkg.Release)
+		}
+
+		stack = append(stack, repo.PackageBundle{pkg})
+	}
+
+	if options.GetB(OPT_JSON) {
+		return printJSON(map[string]repo.PackageStack{"files": stack})
+	}
+
+	fmtutil.Separator(true, "FILES")
+	fmtc.NewLine()
+
+	printPackageStackSources(nil, stack)
+
+	fmtc.NewLine()
+	fmtutil.Separator(true)
+
+	return true
+}
+
 // printPackageStackSources prints list of packages with info about source package
 func printPackageStackSources(r *repo.SubRepository, stack repo.PackageStack) {
 	if len(stack) == 0 {
@@ -102,7 +220,7 @@ func printPackageStackSources(r *repo.SubRepository, stack repo.PackageStack) {
 				pkgInfo += "{s-}" + pkg.Epoch + ":{!}"
 			}
 
-			if options.GetB(OPT_STATUS) && r.Is(data.REPO_TESTING) {
+			if options.GetB(OPT_STATUS) && r != nil && r.Is(data.REPO_TESTING) {
 				isReleased, _, _ := r.Parent.IsPackageReleased(pkg)
 
 				if isReleased {
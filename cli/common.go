@@ -8,20 +8,28 @@ package cli
 // ////////////////////////////////////////////////////////////////////////////////// //
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/essentialkaos/ek/v13/errors"
 	"github.com/essentialkaos/ek/v13/fmtc"
 	"github.com/essentialkaos/ek/v13/fsutil"
 	"github.com/essentialkaos/ek/v13/knf"
 	"github.com/essentialkaos/ek/v13/lock"
+	"github.com/essentialkaos/ek/v13/mathutil"
 	"github.com/essentialkaos/ek/v13/options"
 	"github.com/essentialkaos/ek/v13/path"
+	"github.com/essentialkaos/ek/v13/progress"
 	"github.com/essentialkaos/ek/v13/secstr"
+	"github.com/essentialkaos/ek/v13/sortutil"
+	"github.com/essentialkaos/ek/v13/strutil"
 	"github.com/essentialkaos/ek/v13/terminal"
 	"github.com/essentialkaos/ek/v13/terminal/input"
+	"github.com/essentialkaos/ek/v13/timeutil"
 	"github.com/essentialkaos/ek/v13/tmp"
 
 	"github.com/essentialkaos/rep/v3/cli/logger"
@@ -32,6 +40,7 @@ import (
 	"github.com/essentialkaos/rep/v3/repo/sign"
 	"github.com/essentialkaos/rep/v3/repo/storage"
 	"github.com/essentialkaos/rep/v3/repo/storage/fs"
+	"github.com/essentialkaos/rep/v3/repo/storage/s3"
 )
 
 // ////////////////////////////////////////////////////////////////////////////////// //
@@ -76,6 +85,8 @@ var archColors = map[string]string{
 	data.ARCH_PPC64LE: "{y*}",
 	data.ARCH_ARM:     "{g*}",
 	data.ARCH_ARMV7HL: "{g*}",
+	data.ARCH_S390X:   "{b*}",
+	data.ARCH_RISCV64: "{r*}",
 }
 
 // archColorsExt contains archs colors tags for terminals with 256-colors support
@@ -91,6 +102,8 @@ var archColorsExt = map[string]string{
 	data.ARCH_PPC64LE: "{*}{#105}",
 	data.ARCH_ARM:     "{*}{#76}",
 	data.ARCH_ARMV7HL: "{*}{#78}",
+	data.ARCH_S390X:   "{*}{#33}",
+	data.ARCH_RISCV64: "{*}{#203}",
 }
 
 // commands is map [long command → {handler + min args + options}]
@@ -113,6 +126,14 @@ var commands = map[string]command{
 	COMMAND_REINDEX:      {cmdReindex, 0, FLAG_REQUIRE_LOCK},
 	COMMAND_PURGE_CACHE:  {cmdPurgeCache, 0, FLAG_REQUIRE_LOCK},
 	COMMAND_STATS:        {cmdStats, 0, FLAG_REQUIRE_CACHE},
+	COMMAND_DEP_GRAPH:    {cmdDepGraph, 0, FLAG_REQUIRE_CACHE},
+	COMMAND_EXPORT:       {cmdExport, 1, FLAG_REQUIRE_CACHE | FLAG_REQUIRE_LOCK},
+	COMMAND_IMPORT:       {cmdImport, 1, FLAG_REQUIRE_LOCK},
+	COMMAND_DIFF:         {cmdDiff, 0, FLAG_REQUIRE_CACHE},
+	COMMAND_DOWNLOAD:     {cmdDownload, 1, FLAG_REQUIRE_CACHE},
+	COMMAND_TAG:          {cmdTag, 1, FLAG_REQUIRE_CACHE | FLAG_REQUIRE_LOCK},
+	COMMAND_METRICS:      {cmdMetrics, 0, FLAG_NONE},
+	COMMAND_VERIFY:       {cmdVerify, 0, FLAG_REQUIRE_CACHE},
 	COMMAND_HELP:         {cmdHelp, 0, FLAG_NONE},
 
 	"": {cmdList, 0, FLAG_REQUIRE_CACHE}, // default command
@@ -136,6 +157,14 @@ var commandsShortcurts = map[string]string{
 	COMMAND_SHORT_REINDEX:      COMMAND_REINDEX,
 	COMMAND_SHORT_PURGE_CACHE:  COMMAND_PURGE_CACHE,
 	COMMAND_SHORT_STATS:        COMMAND_STATS,
+	COMMAND_SHORT_DEP_GRAPH:    COMMAND_DEP_GRAPH,
+	COMMAND_SHORT_EXPORT:       COMMAND_EXPORT,
+	COMMAND_SHORT_IMPORT:       COMMAND_IMPORT,
+	COMMAND_SHORT_DIFF:         COMMAND_DIFF,
+	COMMAND_SHORT_DOWNLOAD:     COMMAND_DOWNLOAD,
+	COMMAND_SHORT_TAG:          COMMAND_TAG,
+	COMMAND_SHORT_METRICS:      COMMAND_METRICS,
+	COMMAND_SHORT_VERIFY:       COMMAND_VERIFY,
 	COMMAND_SHORT_HELP:         COMMAND_HELP,
 }
 
@@ -160,8 +189,12 @@ func runCommand(repoCfg *knf.Config, cmdName string, cmdArgs options.Arguments)
 		return false
 	}
 
-	defer ctx.Temp.Clean()   // Clean temporary data
-	defer ctx.Logger.Flush() // Flush logs
+	setActiveContext(ctx)
+
+	defer setActiveContext(nil)
+	defer ctx.Temp.Clean()           // Clean temporary data
+	defer ctx.Logger.Flush()         // Flush logs
+	defer ctx.Repo.InvalidateCache() // Close DBs and remove in-memory cache
 
 	cmd, ok := commands[cmdName]
 
@@ -177,14 +210,29 @@ func runCommand(repoCfg *knf.Config, cmdName string, cmdArgs options.Arguments)
 		}
 	}
 
+	// Check command modifies repository data only if fixing is enabled
+	if cmdName == COMMAND_CHECK && options.GetB(OPT_FIX) {
+		cmd.Flags |= FLAG_REQUIRE_LOCK
+	}
+
+	if cmd.RequireCache() && globalConfig.GetB(INDEX_NO_DATABASE) {
+		terminal.Error(
+			"Can't run command: SQLite databases are required, but their generation is disabled (%s)\n",
+			INDEX_NO_DATABASE,
+		)
+		return false
+	}
+
 	if cmd.RequireLock() {
-		if !checkForLock() {
+		lockName := getLockName(ctx.Repo.Name)
+
+		if !checkForLock(lockName) {
 			terminal.Error("Can't run command due to lock\n")
 			return false
 		}
 
-		lock.Create(APP)
-		defer lock.Remove(APP)
+		createLock(lockName)
+		defer removeLock(lockName)
 	}
 
 	if cmd.RequireCache() {
@@ -250,32 +298,90 @@ func warmUpCache(r *repo.Repository) {
 	}
 
 	if warmupTesting {
-		fmtc.If(!rawOutput && !options.GetB(OPT_PAGER)).TPrintf("{s-}Warming up testing repository cache (it can take a while)…{!}")
-		r.Testing.WarmupCache()
+		warmUpSubRepoCache(r.Testing)
 	}
 
 	if warmupRelease {
-		fmtc.If(!rawOutput && !options.GetB(OPT_PAGER)).TPrintf("{s-}Warming up release repository cache (it can take a while)…{!}")
-		r.Release.WarmupCache()
+		warmUpSubRepoCache(r.Release)
 	}
+}
 
-	fmtc.If(!rawOutput && !options.GetB(OPT_PAGER)).TPrintf("")
+// warmUpSubRepoCache warms up sub-repository cache and shows how long it took
+// in verbose mode
+func warmUpSubRepoCache(r *repo.SubRepository) {
+	showInfo := !rawOutput && !options.GetB(OPT_PAGER)
+
+	fmtc.If(showInfo).TPrintf("{s-}Warming up %s repository cache (it can take a while)…{!}", r.Name)
+
+	start := time.Now()
+	err := r.WarmupCache()
+
+	fmtc.If(showInfo).TPrintf("")
+
+	if err == nil && options.GetB(OPT_VERBOSE) {
+		fmtc.If(showInfo).Printfn(
+			"{s-}Warming up %s repository cache took %s{!}",
+			r.Name, timeutil.PrettyDuration(time.Since(start)),
+		)
+	}
+}
+
+// getLockName returns name of lock for repository with given name
+func getLockName(repoName string) string {
+	return APP + "-" + repoName
 }
 
-// checkForLock check for lock file
-func checkForLock() bool {
-	if !lock.Has(APP) {
+// createLock creates lock file with given name. Created lock will be removed
+// on shutdown if command is interrupted.
+func createLock(name string) {
+	activeMu.Lock()
+	defer activeMu.Unlock()
+
+	lock.Create(name)
+	activeLocks[name] = true
+}
+
+// removeLock removes lock file with given name
+func removeLock(name string) {
+	activeMu.Lock()
+	defer activeMu.Unlock()
+
+	lock.Remove(name)
+	delete(activeLocks, name)
+}
+
+// removeActiveLocks removes all lock files created by app
+func removeActiveLocks() {
+	activeMu.Lock()
+	defer activeMu.Unlock()
+
+	for name := range activeLocks {
+		lock.Remove(name)
+		delete(activeLocks, name)
+	}
+}
+
+// setActiveContext sets context of running command
+func setActiveContext(ctx *context) {
+	activeMu.Lock()
+	activeCtx = ctx
+	activeMu.Unlock()
+}
+
+// checkForLock check for lock file with given name
+func checkForLock(name string) bool {
+	if !lock.Has(name) {
 		return true
 	}
 
-	if lock.IsExpired(APP, 5*time.Minute) {
-		lock.Remove(APP) // Remove outdated lock file
+	if lock.IsExpired(name, globalConfig.GetTD(LOCK_TIMEOUT, LOCK_TIMEOUT_DEFAULT)) {
+		lock.Remove(name) // Remove outdated lock file
 		return true
 	}
 
 	fmtc.If(!rawOutput && !options.GetB(OPT_PAGER)).TPrintf("{s-}Found lock file, waiting for lock to release…{!}")
 
-	ok := lock.Wait(APP, time.Now().Add(5*time.Minute))
+	ok := lock.Wait(name, time.Now().Add(globalConfig.GetTD(LOCK_WAIT, LOCK_WAIT_DEFAULT)))
 
 	fmtc.If(!rawOutput && !options.GetB(OPT_PAGER)).TPrintf("")
 
@@ -286,7 +392,7 @@ func checkForLock() bool {
 
 // getRepoContext generates repository context based on given repository configuration
 func getRepoContext(repoCfg *knf.Config) (*context, error) {
-	repoStorage, err := getRepoStorage(knf.GetS(STORAGE_TYPE), repoCfg)
+	repoStorage, err := getRepoStorage(globalConfig.GetS(STORAGE_TYPE), repoCfg)
 
 	if err != nil {
 		return nil, err
@@ -300,8 +406,19 @@ func getRepoContext(repoCfg *knf.Config) (*context, error) {
 
 	repo.FileFilter = repoCfg.GetS(REPOSITORY_FILE_FILTER)
 	repo.Replace = repoCfg.GetB(REPOSITORY_REPLACE, true)
+	repo.CacheInfo = repoCfg.GetB(REPOSITORY_CACHE_INFO)
+	repo.TempDir = globalConfig.GetS(TEMP_DIR)
+	repo.Context = cancelCtx
 
-	if repoCfg.HasProp(SIGN_KEY) {
+	switch {
+	case repoCfg.GetS(SIGN_MODE) == SIGN_MODE_AGENT:
+		err = repo.ReadAgentSigningKey(repoCfg.GetS(SIGN_KEY_ID))
+
+		if err != nil {
+			return nil, err
+		}
+
+	case repoCfg.HasProp(SIGN_KEY):
 		err = repo.ReadSigningKey(repoCfg.GetS(SIGN_KEY))
 
 		if err != nil {
@@ -309,7 +426,15 @@ func getRepoContext(repoCfg *knf.Config) (*context, error) {
 		}
 	}
 
-	temp, err := tmp.NewTemp(knf.GetS(TEMP_DIR))
+	if repoCfg.HasProp(SIGN_TRUSTED_KEYS) {
+		err = repo.ReadTrustedKeys(strutil.Fields(repoCfg.GetS(SIGN_TRUSTED_KEYS))...)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	temp, err := tmp.NewTemp(globalConfig.GetS(TEMP_DIR))
 
 	if err != nil {
 		return nil, err
@@ -324,10 +449,47 @@ func getRepoContext(repoCfg *knf.Config) (*context, error) {
 	return &context{repo, temp, logger}, nil
 }
 
+// getRepo creates repository instance for given configuration without
+// creating full command context
+func getRepo(repoCfg *knf.Config) (*repo.Repository, error) {
+	repoStorage, err := getRepoStorage(globalConfig.GetS(STORAGE_TYPE), repoCfg)
+
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := repo.NewRepository(repoCfg.GetS(REPOSITORY_NAME), repoStorage)
+
+	if err != nil {
+		return nil, fmt.Errorf("Can't create repository instance: %w", err)
+	}
+
+	r.TempDir = globalConfig.GetS(TEMP_DIR)
+	r.Context = cancelCtx
+
+	return r, nil
+}
+
+// getRepoNames returns sorted slice with names of all configured repositories
+func getRepoNames() []string {
+	var result []string
+
+	for repoName := range configs {
+		result = append(result, repoName)
+	}
+
+	sortutil.StringsNatural(result)
+
+	return result
+}
+
 // getRepoStorage configures repository storage
 func getRepoStorage(typ string, repoCfg *knf.Config) (storage.Storage, error) {
-	if typ == storage.TYPE_FS {
+	switch typ {
+	case storage.TYPE_FS:
 		return getRepoFSStorage(repoCfg)
+	case storage.TYPE_S3:
+		return getRepoS3Storage(repoCfg)
 	}
 
 	return nil, fmt.Errorf("Unknown storage type %q", typ)
@@ -335,54 +497,94 @@ func getRepoStorage(typ string, repoCfg *knf.Config) (storage.Storage, error) {
 
 // getRepoFSStorage configures new filesystem storage
 func getRepoFSStorage(repoCfg *knf.Config) (*fs.Storage, error) {
-	return fs.NewStorage(
-		&fs.Options{
-			DataDir:    path.Join(knf.GetS(STORAGE_DATA), repoCfg.GetS(REPOSITORY_NAME)),
-			CacheDir:   path.Join(knf.GetS(STORAGE_CACHE), repoCfg.GetS(REPOSITORY_NAME)),
-			SplitFiles: knf.GetB(STORAGE_SPLIT_FILES, false),
-			User:       repoCfg.GetS(PERMISSIONS_USER),
-			Group:      repoCfg.GetS(PERMISSIONS_GROUP),
-			DirPerms:   repoCfg.GetM(PERMISSIONS_DIR),
-			FilePerms:  repoCfg.GetM(PERMISSIONS_FILE),
-		},
-		&index.Options{
-			User:           repoCfg.GetS(PERMISSIONS_USER),
-			Group:          repoCfg.GetS(PERMISSIONS_GROUP),
-			DirPerms:       repoCfg.GetM(PERMISSIONS_DIR),
-			FilePerms:      repoCfg.GetM(PERMISSIONS_FILE),
-			Pretty:         knf.GetB(INDEX_PRETTY),
-			Update:         knf.GetB(INDEX_UPDATE),
-			Split:          knf.GetB(INDEX_SPLIT),
-			SkipSymlinks:   knf.GetB(INDEX_SKIP_SYMLINKS),
-			Deltas:         knf.GetB(INDEX_DELTAS),
-			NumDeltas:      knf.GetI(INDEX_NUM_DELTAS),
-			MDFilenames:    knf.GetS(INDEX_MD_FILENAMES, index.MDF_SIMPLE),
-			CheckSum:       knf.GetS(INDEX_CHECKSUM, index.CHECKSUM_SHA256),
-			ChangelogLimit: knf.GetI(INDEX_CHANGELOG_LIMIT),
-			Distro:         knf.GetS(INDEX_DISTRO),
-			Content:        knf.GetS(INDEX_CONTENT),
-			Revision:       knf.GetS(INDEX_REVISION),
-			Workers:        knf.GetI(INDEX_WORKERS, 0),
-			CompressType:   knf.GetS(INDEX_COMPRESSION_TYPE, index.COMPRESSION_BZ2),
+	return fs.NewStorage(getFSDataOptions(globalConfig, repoCfg), getIndexOptions(globalConfig, repoCfg))
+}
+
+// getRepoS3Storage configures new S3 storage
+func getRepoS3Storage(repoCfg *knf.Config) (*s3.Storage, error) {
+	return s3.NewStorage(
+		&s3.Options{
+			Endpoint:  globalConfig.GetS(S3_ENDPOINT),
+			Region:    globalConfig.GetS(S3_REGION),
+			Bucket:    globalConfig.GetS(S3_BUCKET),
+			Prefix:    path.Join(globalConfig.GetS(S3_PREFIX), repoCfg.GetS(REPOSITORY_NAME)),
+			AccessKey: globalConfig.GetS(S3_ACCESS_KEY),
+			SecretKey: globalConfig.GetS(S3_SECRET_KEY),
 		},
+		getFSDataOptions(globalConfig, repoCfg),
+		getIndexOptions(globalConfig, repoCfg),
 	)
 }
 
+// getFSDataOptions returns options for filesystem data storage
+func getFSDataOptions(globalCfg knf.IConfig, repoCfg *knf.Config) *fs.Options {
+	return &fs.Options{
+		DataDir:     path.Join(globalCfg.GetS(STORAGE_DATA), repoCfg.GetS(REPOSITORY_NAME)),
+		CacheDir:    path.Join(globalCfg.GetS(STORAGE_CACHE), repoCfg.GetS(REPOSITORY_NAME)),
+		SplitFiles:  globalCfg.GetB(STORAGE_SPLIT_FILES, false),
+		SplitDepth:  globalCfg.GetI(STORAGE_SPLIT_DEPTH, fs.SPLIT_DEPTH_DEFAULT),
+		Layout:      globalCfg.GetS(STORAGE_LAYOUT, fs.LAYOUT_NESTED),
+		NestedCache: globalCfg.GetB(STORAGE_NESTED_CACHE, false),
+		HardLink:    globalCfg.GetB(STORAGE_HARD_LINK, false),
+		VerifyCopy:  globalCfg.GetB(STORAGE_VERIFY_COPY),
+		SkipDBs:     strutil.Fields(globalCfg.GetS(STORAGE_SKIP_DBS)),
+		User:        repoCfg.GetS(PERMISSIONS_USER),
+		Group:       repoCfg.GetS(PERMISSIONS_GROUP),
+		DirPerms:    repoCfg.GetM(PERMISSIONS_DIR),
+		FilePerms:   repoCfg.GetM(PERMISSIONS_FILE),
+
+		MinFreeSpace: globalCfg.GetSZ(STORAGE_MIN_FREE_SPACE),
+		AttrsRetries: globalCfg.GetI(STORAGE_ATTRS_RETRIES, 3),
+
+		MemCache:        globalCfg.GetB(STORAGE_MEM_CACHE, false),
+		MemCacheMaxSize: globalCfg.GetSZ(STORAGE_MEM_CACHE_MAX),
+	}
+}
+
+// getIndexOptions returns options for index generation
+func getIndexOptions(globalCfg knf.IConfig, repoCfg *knf.Config) *index.Options {
+	return &index.Options{
+		User:           repoCfg.GetS(PERMISSIONS_USER),
+		Group:          repoCfg.GetS(PERMISSIONS_GROUP),
+		DirPerms:       repoCfg.GetM(PERMISSIONS_DIR),
+		FilePerms:      repoCfg.GetM(PERMISSIONS_FILE),
+		GroupFile:      repoCfg.GetS(REPOSITORY_GROUP_FILE),
+		Pretty:         globalCfg.GetB(INDEX_PRETTY),
+		Update:         globalCfg.GetB(INDEX_UPDATE),
+		Split:          globalCfg.GetB(INDEX_SPLIT),
+		SkipSymlinks:   globalCfg.GetB(INDEX_SKIP_SYMLINKS),
+		Deltas:         globalCfg.GetB(INDEX_DELTAS),
+		NumDeltas:      globalCfg.GetI(INDEX_NUM_DELTAS),
+		MDFilenames:    globalCfg.GetS(INDEX_MD_FILENAMES, index.MDF_SIMPLE),
+		CheckSum:       globalCfg.GetS(INDEX_CHECKSUM, index.CHECKSUM_SHA256),
+		ChangelogLimit: globalCfg.GetI(INDEX_CHANGELOG_LIMIT),
+		RetainOldMD:    globalCfg.GetI(INDEX_RETAIN_OLD_MD),
+		Distro:         globalCfg.GetS(INDEX_DISTRO),
+		Content:        globalCfg.GetS(INDEX_CONTENT),
+		Revision:       globalCfg.GetS(INDEX_REVISION),
+		LocationPrefix: globalCfg.GetS(INDEX_LOCATION_PREFIX),
+		Workers:        globalCfg.GetI(INDEX_WORKERS, 0),
+		CompressType:   globalCfg.GetS(INDEX_COMPRESSION_TYPE, index.COMPRESSION_BZ2),
+		Zchunk:         globalCfg.GetB(INDEX_ZCHUNK),
+		NoDatabase:     globalCfg.GetB(INDEX_NO_DATABASE),
+	}
+}
+
 // getCLILogger returns logger for CLI
 func getCLILogger(repoName string) (*logger.Logger, error) {
 	var err error
 
-	logDir := path.Join(knf.GetS(LOG_DIR), repoName)
+	logDir := path.Join(globalConfig.GetS(LOG_DIR), repoName)
 
 	if !fsutil.IsExist(logDir) {
-		err = os.Mkdir(logDir, knf.GetM(LOG_DIR_PERMS, 0755))
+		err = os.Mkdir(logDir, globalConfig.GetM(LOG_DIR_PERMS, 0755))
 
 		if err != nil {
 			return nil, fmt.Errorf("Can't create directory for logs: %w", err)
 		}
 	}
 
-	l := logger.New(logDir, knf.GetM(LOG_FILE_PERMS, 0644))
+	l := logger.New(logDir, globalConfig.GetM(LOG_FILE_PERMS, 0644))
 
 	err = l.Add(data.REPO_RELEASE)
 
@@ -427,28 +629,48 @@ func isSignRequired(r *repo.SubRepository, files []string) bool {
 		return false
 	}
 
-	// We don't decrypt key, because we can check signature without decrypting
-	key, err := r.Parent.SigningKey.Read(nil)
+	// We don't decrypt keys, because we can check signature without decrypting
+	keys, err := r.Parent.ReadVerificationKeys()
 
 	if err != nil {
 		return true
 	}
 
 	for _, file := range files {
-		isSigned, err := sign.IsPackageSigned(file)
+		problem, err := checkPackageSignature(file, keys)
 
-		if err != nil || !isSigned {
+		if err != nil || problem != "" {
 			return true
 		}
+	}
 
-		isSignValid, err := sign.IsPackageSignatureValid(file, key)
+	return false
+}
 
-		if err != nil || !isSignValid {
-			return true
-		}
+// checkPackageSignature checks signature of given package file and returns
+// type of found problem (VERIFY_PROBLEM_*) or empty string if signature is valid
+func checkPackageSignature(file string, keys []*sign.Key) (string, error) {
+	hasSign, err := sign.IsPackageSigned(file)
+
+	if err != nil {
+		return VERIFY_PROBLEM_ERROR, err
 	}
 
-	return false
+	if !hasSign {
+		return VERIFY_PROBLEM_UNSIGNED, nil
+	}
+
+	isSignValid, err := sign.IsPackageSignatureValidWithKeys(file, keys...)
+
+	if err != nil {
+		return VERIFY_PROBLEM_ERROR, err
+	}
+
+	if !isSignValid {
+		return VERIFY_PROBLEM_WRONG_KEY, nil
+	}
+
+	return "", nil
 }
 
 // getRepoSigningKey reads password and decrypts repository private key
@@ -500,6 +722,10 @@ func smartPackageSearch(r *repo.SubRepository, args options.Arguments) (repo.Pac
 		}
 
 		stack, err = findPackages(r, searchRequest)
+
+		if err == nil {
+			stack = stack.Slice(searchRequest.Offset, searchRequest.Limit)
+		}
 	} else {
 		filter = args.Get(0).String()
 		stack, err = r.List(filter, true)
@@ -526,6 +752,11 @@ func printQueryDebug(searchRequest *query.Request) {
 	for index, term := range searchRequest.Query {
 		db, qrs := term.SQL()
 
+		if term.IsGroup() {
+			fmtc.Printfn("{s-}{%d|group} %s{!}", index, term)
+			continue
+		}
+
 		for _, qr := range qrs {
 			fmtc.Printfn("{s-}{%d|%s} %s → %s{!}", index, db, term, qr)
 		}
@@ -534,6 +765,105 @@ func printQueryDebug(searchRequest *query.Request) {
 	fmtc.NewLine()
 }
 
+// showPackageList prints package listing for given sub-repository or saves it
+// for printing in JSON format
+func showPackageList(r *repo.SubRepository, stack repo.PackageStack, filter string) {
+	if options.GetB(OPT_JSON) {
+		jsonStacks[r.Name] = stack.Compact()
+		return
+	}
+
+	printPackageList(r, stack, filter)
+}
+
+// printJSON prints given data encoded as JSON
+func printJSON(v any) bool {
+	jsonData, err := json.MarshalIndent(v, "", "  ")
+
+	if err != nil {
+		terminal.Error("Can't encode data to JSON: %v", err)
+		return false
+	}
+
+	fmt.Println(string(jsonData))
+
+	return true
+}
+
+// printDryRunFiles prints list of package files which would be affected by
+// command in dry-run mode
+func printDryRunFiles(action string, r *repo.SubRepository, files []repo.PackageFile) {
+	if len(files) == 0 {
+		return
+	}
+
+	fmtc.Printfn("{*}%s {?repo}%s{!}{*}:{!}", action, r.Name)
+
+	for _, file := range files {
+		archTag := fmtc.If(file.ArchFlag == data.ARCH_FLAG_NOARCH).Sprintf(
+			" {s}[%s]{!}", file.BaseArchFlag.String(),
+		)
+
+		fmtc.Printfn("{s-}•{!} {?package}%s{!}%s", path.Base(file.Path), archTag)
+	}
+
+	fmtc.NewLine()
+}
+
+// printDryRunNotice prints notice about dry-run mode
+func printDryRunNotice() {
+	dryRunOption, _ := options.ParseOptionName(OPT_DRY_RUN)
+	terminal.Warn("No changes were made (--%s mode)", dryRunOption)
+}
+
+// runParallel runs given function for every item index in range [0, num) using
+// given number of workers. Progress bar is updated after every processed item
+// under mutex, so one bar can be shared by all workers. Errors are returned in
+// the same order as items.
+func runParallel(pb *progress.Bar, workers, num int, fn func(index int) []error) *errors.Bundle {
+	errs := errors.NewBundle()
+
+	if num <= 0 {
+		return errs
+	}
+
+	var wg sync.WaitGroup
+	var pbMu sync.Mutex
+
+	results := make([][]error, num)
+	queue := make(chan int)
+	workers = mathutil.Between(workers, 1, num)
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for index := range queue {
+				results[index] = fn(index)
+
+				pbMu.Lock()
+				pb.Add(1)
+				pbMu.Unlock()
+			}
+		}()
+	}
+
+	for i := 0; i < num; i++ {
+		queue <- i
+	}
+
+	close(queue)
+	wg.Wait()
+
+	for _, itemErrs := range results {
+		errs.Add(itemErrs)
+	}
+
+	return errs
+}
+
 // ////////////////////////////////////////////////////////////////////////////////// //
 
 // RequireCache returns true if command requires warm cache
@@ -0,0 +1,443 @@
+package cli
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2024 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/essentialkaos/ek/v13/fsutil"
+	"github.com/essentialkaos/ek/v13/knf"
+	"github.com/essentialkaos/ek/v13/secstr"
+	"github.com/essentialkaos/ek/v13/sliceutil"
+
+	"github.com/essentialkaos/rep/v3/repo"
+	"github.com/essentialkaos/rep/v3/repo/data"
+	"github.com/essentialkaos/rep/v3/repo/sign"
+
+	. "github.com/essentialkaos/check"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func Test(t *testing.T) { TestingT(t) }
+
+type CLISuite struct{}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+var _ = Suite(&CLISuite{})
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func (s *CLISuite) TestIndexOptionsZchunk(c *C) {
+	repoCfg := loadTestConfigs(c, "[index]\n  zchunk: true\n")
+	args := getIndexOptions(globalConfig, repoCfg).ToArgs()
+
+	c.Assert(sliceutil.Contains(args, "--zck"), Equals, true)
+
+	repoCfg = loadTestConfigs(c, "[index]\n  zchunk: false\n")
+	args = getIndexOptions(globalConfig, repoCfg).ToArgs()
+
+	c.Assert(sliceutil.Contains(args, "--zck"), Equals, false)
+}
+
+func (s *CLISuite) TestIndexOptionsCompression(c *C) {
+	repoCfg := loadTestConfigs(c, "[index]\n  revision: c5af8a1\n  compression-type: xz\n")
+	opts := getIndexOptions(globalConfig, repoCfg)
+	args := opts.ToArgs()
+
+	c.Assert(opts.Revision, Equals, "c5af8a1")
+	c.Assert(sliceutil.Contains(args, "--compress-type=xz"), Equals, true)
+	c.Assert(sliceutil.Contains(args, "--revision=c5af8a1"), Equals, true)
+}
+
+func (s *CLISuite) TestIndexOptionsGroupFile(c *C) {
+	loadTestConfigs(c, "[index]\n  update: true\n")
+
+	repoCfg, err := knf.Parse([]byte("[repository]\n  name: test\n  group-file: /opt/rep/comps.xml\n"))
+
+	c.Assert(err, IsNil)
+
+	args := getIndexOptions(globalConfig, repoCfg).ToArgs()
+
+	c.Assert(sliceutil.Contains(args, "--groupfile=/opt/rep/comps.xml"), Equals, true)
+}
+
+func (s *CLISuite) TestCleanupSources(c *C) {
+	c.Assert(getSourcePackageName("redis-6.0.4-0.el7.src.rpm"), Equals, "redis")
+	c.Assert(getSourcePackageName("python-six-1.16.0-1.el8.src.rpm"), Equals, "python-six")
+	c.Assert(getSourcePackageName("test"), Equals, "test")
+
+	stack := repo.PackageStack{
+		repo.PackageBundle{
+			&repo.Package{Name: "redis", Version: "6.0.4", Release: "0.el7", Src: "redis-6.0.4-0.el7.src.rpm"},
+			&repo.Package{Name: "redis-devel", Version: "6.0.4", Release: "0.el7", Src: "redis-6.0.4-0.el7.src.rpm"},
+		},
+		repo.PackageBundle{
+			&repo.Package{Name: "redis-devel", Version: "6.0.10", Release: "0.el7", Src: "redis-6.0.10-0.el7.src.rpm"},
+		},
+		repo.PackageBundle{
+			&repo.Package{Name: "redis", Version: "6.0.4", Release: "1.el7", Src: "redis-6.0.4-1.el7.src.rpm"},
+		},
+		repo.PackageBundle{
+			&repo.Package{Name: "nginx", Version: "1.20.0", Release: "0.el7", Src: "nginx-1.20.0-0.el7.src.rpm"},
+		},
+	}
+
+	result := extractSourcesToCleanup(stack, 2, "")
+
+	c.Assert(result, HasLen, 1)
+	c.Assert(result[0], HasLen, 2)
+	c.Assert(result[0][0].Src, Equals, "redis-6.0.4-0.el7.src.rpm")
+
+	c.Assert(extractSourcesToCleanup(stack, 1, "nginx"), HasLen, 0)
+	c.Assert(extractSourcesToCleanup(stack, 1, "redis"), HasLen, 2)
+}
+
+func (s *CLISuite) TestPackagesArchs(c *C) {
+	c.Assert(getPackagesArchs(nil), HasLen, 0)
+	c.Assert(getPackagesArchs([]string{
+		"x86_64/t/test-package-1.0.0-0.el7.x86_64.rpm",
+		"x86_64/g/git-all-2.27.0-0.el7.noarch.rpm",
+		"aarch64/g/git-all-2.27.0-0.el7.noarch.rpm",
+		"SRPMS/t/test-package-1.0.0-0.el7.src.rpm",
+		"test-package-1.0.0-0.el7.x86_64.rpm",
+	}), DeepEquals, []string{data.ARCH_X64, data.ARCH_AARCH64, data.ARCH_SRC})
+}
+
+func (s *CLISuite) TestPayloadType(c *C) {
+	c.Assert(getPayloadType("f"), Equals, "files")
+	c.Assert(getPayloadType("reqs"), Equals, "requires")
+	c.Assert(getPayloadType("obs"), Equals, "obsoletes")
+	c.Assert(getPayloadType("sups"), Equals, "supplements")
+	c.Assert(getPayloadType("unknown"), Equals, "")
+
+	pkg := &repo.Package{Info: &repo.PackageInfo{
+		Obsoletes: []data.Dependency{{Name: "test-package-old"}},
+	}}
+
+	c.Assert(getPayloadDeps(pkg, "obsoletes"), HasLen, 1)
+	c.Assert(getPayloadDeps(pkg, "conflicts"), HasLen, 0)
+	c.Assert(getPayloadDeps(pkg, "files"), IsNil)
+}
+
+func (s *CLISuite) TestDurationValidator(c *C) {
+	loadTestConfigs(c, "[lock]\n  timeout: 15m\n  wait: 5 minutes\n")
+
+	errs := globalConfig.Validate(knf.Validators{{LOCK_TIMEOUT, validateDuration, nil}})
+	c.Assert(errs.IsEmpty(), Equals, true)
+
+	errs = globalConfig.Validate(knf.Validators{{LOCK_WAIT, validateDuration, nil}})
+	c.Assert(errs.IsEmpty(), Equals, false)
+
+	c.Assert(globalConfig.GetTD(LOCK_TIMEOUT, LOCK_TIMEOUT_DEFAULT), Equals, 15*time.Minute)
+}
+
+func (s *CLISuite) TestKeyFilesValidator(c *C) {
+	loadTestConfigs(c, "[sign]\n  trusted-keys: ../testdata/reptest.public ../testdata/reptest.private\n  key: ../testdata/unknown.public\n")
+
+	errs := globalConfig.Validate(knf.Validators{{SIGN_TRUSTED_KEYS, validateKeyFiles, nil}})
+	c.Assert(errs.IsEmpty(), Equals, true)
+
+	errs = globalConfig.Validate(knf.Validators{{SIGN_KEY, validateKeyFiles, nil}})
+	c.Assert(errs.IsEmpty(), Equals, false)
+}
+
+func (s *CLISuite) TestResignFiles(c *C) {
+	stack := repo.PackageStack{
+		repo.PackageBundle{
+			&repo.Package{Name: "redis", Files: repo.PackageFiles{
+				{Path: "x86_64/redis-6.0.4-0.el7.x86_64.rpm", ArchFlag: data.ARCH_FLAG_X64},
+				{Path: "aarch64/redis-6.0.4-0.el7.aarch64.rpm", ArchFlag: data.ARCH_FLAG_AARCH64},
+			}},
+			&repo.Package{Name: "redis-docs", Files: repo.PackageFiles{
+				{Path: "noarch/redis-docs-6.0.4-0.el7.noarch.rpm", ArchFlag: data.ARCH_FLAG_NOARCH},
+				{Path: "noarch/redis-docs-6.0.4-0.el7.noarch.rpm", ArchFlag: data.ARCH_FLAG_NOARCH},
+			}},
+		},
+	}
+
+	c.Assert(getResignFiles(stack, data.ARCH_FLAG_UNKNOWN), HasLen, 3)
+	c.Assert(getResignFiles(stack, data.ARCH_FLAG_X64), HasLen, 1)
+	c.Assert(getResignFiles(stack, data.ARCH_FLAG_SRC), HasLen, 0)
+	c.Assert(getResignFiles(nil, data.ARCH_FLAG_UNKNOWN), HasLen, 0)
+}
+
+func (s *CLISuite) TestPackageSignatureCheck(c *C) {
+	srcPkg := "../testdata/test-package-1.0.0-0.el7.x86_64.rpm"
+	trgPkg := c.MkDir() + "/test-package-1.0.0-0.el7.x86_64.rpm"
+
+	password, _ := secstr.NewSecureString("test1234TEST")
+
+	armKey, err := sign.ReadKey("../testdata/reptest.private")
+	c.Assert(err, IsNil)
+	key, err := armKey.Read(password)
+	c.Assert(err, IsNil)
+
+	armKey, err = sign.ReadKey("../testdata/reptest-old.private")
+	c.Assert(err, IsNil)
+	oldKey, err := armKey.Read(password)
+	c.Assert(err, IsNil)
+
+	c.Assert(sign.SignPackage(srcPkg, trgPkg, key), IsNil)
+
+	problem, err := checkPackageSignature(srcPkg, []*sign.Key{key})
+	c.Assert(err, IsNil)
+	c.Assert(problem, Equals, VERIFY_PROBLEM_UNSIGNED)
+
+	problem, err = checkPackageSignature(trgPkg, []*sign.Key{oldKey})
+	c.Assert(err, IsNil)
+	c.Assert(problem, Equals, VERIFY_PROBLEM_WRONG_KEY)
+
+	problem, err = checkPackageSignature(trgPkg, []*sign.Key{oldKey, key})
+	c.Assert(err, IsNil)
+	c.Assert(problem, Equals, "")
+
+	problem, err = checkPackageSignature("_unknown_", []*sign.Key{key})
+	c.Assert(err, NotNil)
+	c.Assert(problem, Equals, VERIFY_PROBLEM_ERROR)
+}
+
+func (s *CLISuite) TestStatsArchFilter(c *C) {
+	stats := &repo.RepositoryStats{
+		Packages:      map[string]int{data.ARCH_X64: 10, data.ARCH_AARCH64: 5},
+		Sizes:         map[string]int64{data.ARCH_X64: 1000, data.ARCH_AARCH64: 500},
+		TotalPackages: 15,
+		TotalSize:     1500,
+	}
+
+	c.Assert(filterStatsByArch(stats, ""), Equals, stats)
+	c.Assert(filterStatsByArch(nil, data.ARCH_X64), IsNil)
+
+	fs := filterStatsByArch(stats, data.ARCH_AARCH64)
+	c.Assert(fs.Packages, DeepEquals, map[string]int{data.ARCH_AARCH64: 5})
+	c.Assert(fs.TotalPackages, Equals, 5)
+	c.Assert(fs.TotalSize, Equals, int64(500))
+
+	fs = filterStatsByArch(stats, data.ARCH_PPC64LE)
+	c.Assert(fs.TotalPackages, Equals, 0)
+	c.Assert(fs.Packages, HasLen, 0)
+}
+
+func (s *CLISuite) TestRepositoriesDiff(c *C) {
+	releaseStack := repo.PackageStack{
+		repo.PackageBundle{
+			&repo.Package{Name: "redis", Version: "6.0.4", Release: "0.el7"},
+			&repo.Package{Name: "redis-devel", Version: "6.0.4", Release: "0.el7"},
+		},
+		repo.PackageBundle{
+			&repo.Package{Name: "nginx", Version: "1.20.0", Release: "0.el7"},
+		},
+		repo.PackageBundle{
+			&repo.Package{Name: "zlib", Version: "1.3", Release: "0.el7"},
+		},
+		repo.PackageBundle{
+			&repo.Package{Name: "curl", Version: "8.1.0", Release: "0.el7"},
+		},
+	}
+
+	testingStack := repo.PackageStack{
+		repo.PackageBundle{
+			&repo.Package{Name: "redis", Version: "6.0.4", Release: "0.el7"},
+			&repo.Package{Name: "redis-devel", Version: "6.0.4", Release: "0.el7"},
+		},
+		repo.PackageBundle{
+			&repo.Package{Name: "nginx", Version: "1.20.0", Release: "0.el7"},
+			&repo.Package{Name: "nginx", Version: "1.22.1", Release: "0.el7"},
+		},
+		repo.PackageBundle{
+			&repo.Package{Name: "zlib", Epoch: "1", Version: "1.3", Release: "0.el7"},
+		},
+		repo.PackageBundle{
+			&repo.Package{Name: "htop", Version: "3.2.2", Release: "0.el7"},
+		},
+	}
+
+	diff := getRepositoriesDiff(releaseStack, testingStack)
+
+	c.Assert(diff.OnlyTesting, HasLen, 1)
+	c.Assert(diff.OnlyTesting[0].Name, Equals, "htop")
+	c.Assert(diff.OnlyRelease, HasLen, 1)
+	c.Assert(diff.OnlyRelease[0].Name, Equals, "curl")
+	c.Assert(diff.Changed, DeepEquals, []*repoDiffChange{
+		{Name: "nginx", Testing: []string{"1.22.1-0.el7"}, Release: []string{}},
+		{Name: "zlib", Testing: []string{"1:1.3-0.el7"}, Release: []string{"1.3-0.el7"}},
+	})
+
+	diff = getRepositoriesDiff(releaseStack, releaseStack)
+
+	c.Assert(diff.OnlyTesting, HasLen, 0)
+	c.Assert(diff.OnlyRelease, HasLen, 0)
+	c.Assert(diff.Changed, HasLen, 0)
+
+	c.Assert(formatDiffVersions(nil), Equals, "—")
+	c.Assert(formatDiffVersions([]string{"1.0-0", "1:1.1-0"}), Equals, "1.0-0, 1:1.1-0")
+	c.Assert(getDiffVersionsFormat(nil), Equals, "{s-}%s{!}")
+	c.Assert(getDiffVersionsFormat([]string{"1.0-0"}), Equals, "%s")
+}
+
+func (s *CLISuite) TestStatsSnapshot(c *C) {
+	snapshotFile := c.MkDir() + "/stats-testing.json"
+
+	_, err := readStatsSnapshot(snapshotFile)
+	c.Assert(err, ErrorMatches, "There is no saved stats from the previous run")
+
+	stats := &repo.RepositoryStats{
+		Packages:      map[string]int{data.ARCH_X64: 10, data.ARCH_AARCH64: 5},
+		Sizes:         map[string]int64{data.ARCH_X64: 1000, data.ARCH_AARCH64: 500},
+		TotalPackages: 15,
+		TotalSize:     1500,
+	}
+
+	c.Assert(saveStatsSnapshot(snapshotFile, stats), IsNil)
+	c.Assert(fsutil.IsExist(snapshotFile+".tmp"), Equals, false)
+
+	snapshot, err := readStatsSnapshot(snapshotFile)
+
+	c.Assert(err, IsNil)
+	c.Assert(snapshot.Stats, DeepEquals, stats)
+	c.Assert(snapshot.Date.IsZero(), Equals, false)
+
+	c.Assert(saveStatsSnapshot(c.MkDir()+"/unknown/stats.json", stats), NotNil)
+
+	c.Assert(os.WriteFile(snapshotFile, []byte("{}"), 0600), IsNil)
+	_, err = readStatsSnapshot(snapshotFile)
+	c.Assert(err, ErrorMatches, "Stats snapshot is empty")
+
+	c.Assert(os.WriteFile(snapshotFile, []byte("{"), 0600), IsNil)
+	_, err = readStatsSnapshot(snapshotFile)
+	c.Assert(err, ErrorMatches, "Can't decode stats snapshot: .*")
+}
+
+func (s *CLISuite) TestStatsDiff(c *C) {
+	c.Assert(formatStatsDelta(3, 1024), Matches, ".*\\+3.*\\(\\+1KB\\).*")
+	c.Assert(formatStatsDelta(-2, -2048), Matches, ".*-2.*\\(-2KB\\).*")
+	c.Assert(formatStatsDelta(0, 100), Matches, ".*0.*\\(\\+100B\\).*")
+
+	prev := &statsSnapshot{
+		Date: time.Now().Add(-time.Hour),
+		Stats: &repo.RepositoryStats{
+			Packages:      map[string]int{data.ARCH_X64: 10},
+			Sizes:         map[string]int64{data.ARCH_X64: 1000},
+			TotalPackages: 10,
+			TotalSize:     1000,
+		},
+	}
+
+	printRepoStatsDiff(prev, prev.Stats)
+	printRepoStatsDiff(prev, &repo.RepositoryStats{
+		Packages:      map[string]int{data.ARCH_X64: 12, data.ARCH_AARCH64: 1},
+		Sizes:         map[string]int64{data.ARCH_X64: 1200, data.ARCH_AARCH64: 100},
+		TotalPackages: 13,
+		TotalSize:     1300,
+	})
+}
+
+func (s *CLISuite) TestGetRepositories(c *C) {
+	loadTestConfigs(c, "[storage]\n  data: /opt/rep/global\n")
+
+	configDir := c.MkDir()
+	globalCfgFile := c.MkDir() + "/rep.knf"
+
+	c.Assert(os.WriteFile(globalCfgFile, []byte("[storage]\n  data: /opt/rep/data\n  cache: /opt/rep/cache\n"), 0644), IsNil)
+	c.Assert(os.WriteFile(configDir+"/el9.knf", []byte("[repository]\n  name: el9\n"), 0644), IsNil)
+	c.Assert(os.WriteFile(configDir+"/el8.knf", []byte("[repository]\n  name: el8\n"), 0644), IsNil)
+
+	prevConfigs := configs
+	configs = nil
+
+	repos, err := getRepositories(globalCfgFile, configDir)
+
+	c.Assert(err, IsNil)
+	c.Assert(repos, DeepEquals, []RepositoryInfo{
+		{Name: "el8", ConfigFile: configDir + "/el8.knf", DataDir: "/opt/rep/data/el8", CacheDir: "/opt/rep/cache/el8"},
+		{Name: "el9", ConfigFile: configDir + "/el9.knf", DataDir: "/opt/rep/data/el9", CacheDir: "/opt/rep/cache/el9"},
+	})
+
+	// Global state must stay untouched
+	c.Assert(configs, IsNil)
+	c.Assert(globalConfig.GetS(STORAGE_DATA), Equals, "/opt/rep/global")
+
+	configs = prevConfigs
+
+	repos, err = getRepositories(globalCfgFile, c.MkDir())
+
+	c.Assert(err, IsNil)
+	c.Assert(repos, HasLen, 0)
+
+	_, err = getRepositories(c.MkDir()+"/unknown.knf", configDir)
+	c.Assert(err, ErrorMatches, "Can't load global coniguration: .*")
+
+	c.Assert(os.WriteFile(configDir+"/el9-copy.knf", []byte("[repository]\n  name: el9\n"), 0644), IsNil)
+
+	_, err = getRepositories(globalCfgFile, configDir)
+	c.Assert(err, ErrorMatches, `Repository name "el9" is used in more than one configuration file .*`)
+
+	configDir = c.MkDir()
+
+	c.Assert(os.WriteFile(configDir+"/help.knf", []byte("[repository]\n  name: help\n"), 0644), IsNil)
+
+	_, err = getRepositories(globalCfgFile, configDir)
+	c.Assert(err, ErrorMatches, `Error while repository configuration file validation \(.*/help.knf\): Repository name "help" is reserved for command`)
+}
+
+func (s *CLISuite) TestImportFilePath(c *C) {
+	file, err := getImportFilePath("/opt/repo", "Packages/t/test.rpm")
+
+	c.Assert(err, IsNil)
+	c.Assert(file, Equals, "/opt/repo/Packages/t/test.rpm")
+
+	file, err = getImportFilePath("/opt/repo/", "./Packages/../test.rpm")
+
+	c.Assert(err, IsNil)
+	c.Assert(file, Equals, "/opt/repo/test.rpm")
+
+	_, err = getImportFilePath("/opt/repo", "../../etc/test.rpm")
+	c.Assert(err, ErrorMatches, `Location "../../etc/test.rpm" points outside of repository directory`)
+
+	_, err = getImportFilePath("/opt/repo", "Packages/../../repo2/test.rpm")
+	c.Assert(err, ErrorMatches, `Location .* points outside of repository directory`)
+
+	_, err = getImportFilePath("/opt/repo", "")
+	c.Assert(err, NotNil)
+}
+
+func (s *CLISuite) TestMetricsFormatting(c *C) {
+	c.Assert(formatMetricLabels("repo", "el9", "arch", "x86_64"), Equals, `{repo="el9",arch="x86_64"}`)
+	c.Assert(formatMetricLabels("repo", `a"b\c`), Equals, `{repo="a\"b\\c"}`)
+	c.Assert(formatMetricLabels(), Equals, "{}")
+
+	m1 := &metric{Name: "test_total", Help: "Test metric", Samples: []string{`test_total{repo="el9"} 1`}}
+	m2 := &metric{Name: "test_empty", Help: "Empty metric"}
+
+	c.Assert(formatMetrics(m1, m2), Equals, "# HELP test_total Test metric\n# TYPE test_total gauge\ntest_total{repo=\"el9\"} 1\n")
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// loadTestConfigs loads given data as global configuration and returns
+// repository configuration
+func loadTestConfigs(c *C, globalData string) *knf.Config {
+	globalCfgFile := c.MkDir() + "/rep.knf"
+
+	err := os.WriteFile(globalCfgFile, []byte(globalData), 0644)
+
+	c.Assert(err, IsNil)
+
+	globalConfig, err = knf.Read(globalCfgFile)
+
+	c.Assert(err, IsNil)
+
+	repoCfg, err := knf.Parse([]byte("[repository]\n  name: test\n"))
+
+	c.Assert(err, IsNil)
+
+	return repoCfg
+}
@@ -10,6 +10,7 @@ package query
 import (
 	"fmt"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
@@ -48,6 +49,8 @@ const (
 	TERM_SHORT_BUILD_HOST  = "h"
 	TERM_SHORT_SIZE        = "S"
 	TERM_SHORT_PAYLOAD     = "@"
+	TERM_SHORT_REGEX       = "re"
+	TERM_SHORT_PACKAGER    = "pk"
 
 	TERM_NAME        = "name"
 	TERM_VERSION     = "version"
@@ -72,12 +75,32 @@ const (
 	TERM_BUILD_HOST  = "host"
 	TERM_SIZE        = "size"
 	TERM_PAYLOAD     = "payload"
+	TERM_REGEX       = "regex"
+	TERM_PACKAGER    = "packager"
 )
 
 const (
 	TERM_SHORT_RELEASED = "^"
+	TERM_SHORT_DEBUG    = "dg"
 
 	TERM_RELEASED = "released"
+	TERM_DEBUG    = "debug"
+	TERM_LIMIT    = "limit"
+	TERM_OFFSET   = "offset"
+)
+
+// TERM_MOD_IGNORE_CASE is term name suffix for case-insensitive matching
+const TERM_MOD_IGNORE_CASE = "~"
+
+const (
+	GROUP_START     = "("
+	GROUP_END       = ")"
+	GROUP_SEPARATOR = "|"
+)
+
+const (
+	DATE_FORMAT          = "2006-01-02"
+	DATE_RANGE_SEPARATOR = ".."
 )
 
 const (
@@ -86,12 +109,21 @@ const (
 	FILTER_FLAG_UNRELEASED uint8 = 2
 )
 
+const (
+	DEBUG_FLAG_NONE    uint8 = 0
+	DEBUG_FLAG_ONLY    uint8 = 1 // Show only debug packages
+	DEBUG_FLAG_EXCLUDE uint8 = 2 // Hide debug packages
+)
+
 // ////////////////////////////////////////////////////////////////////////////////// //
 
 // Request contains parsed query data
 type Request struct {
 	Query      search.Query
 	FilterFlag uint8
+	DebugFlag  uint8
+	Limit      int // Maximum number of bundles in result (0 = no limit)
+	Offset     int // Number of bundles to skip
 }
 
 // ////////////////////////////////////////////////////////////////////////////////// //
@@ -120,6 +152,8 @@ var terms = map[string]uint8{
 	TERM_SHORT_SIZE:        search.TERM_SIZE,
 	TERM_SHORT_ARCH:        search.TERM_ARCH,
 	TERM_SHORT_PAYLOAD:     search.TERM_PAYLOAD,
+	TERM_SHORT_REGEX:       search.TERM_REGEX,
+	TERM_SHORT_PACKAGER:    search.TERM_PACKAGER,
 
 	TERM_NAME:        search.TERM_NAME,
 	TERM_VERSION:     search.TERM_VERSION,
@@ -144,11 +178,17 @@ var terms = map[string]uint8{
 	TERM_SIZE:        search.TERM_SIZE,
 	TERM_ARCH:        search.TERM_ARCH,
 	TERM_PAYLOAD:     search.TERM_PAYLOAD,
+	TERM_REGEX:       search.TERM_REGEX,
+	TERM_PACKAGER:    search.TERM_PACKAGER,
 }
 
 var extTerm = map[string]bool{
 	TERM_SHORT_RELEASED: true,
 	TERM_RELEASED:       true,
+	TERM_SHORT_DEBUG:    true,
+	TERM_DEBUG:          true,
+	TERM_LIMIT:          true,
+	TERM_OFFSET:         true,
 }
 
 var depRegex = regexp.MustCompile(`([a-zA-Z0-9\._\-:\(\)\*]+)(>=|<=|>|<|=)?([0-9]:)?([0-9a-z\.\*]+)?-?(.*)?`)
@@ -158,43 +198,146 @@ var depRegex = regexp.MustCompile(`([a-zA-Z0-9\._\-:\(\)\*]+)(>=|<=|>|<|=)?([0-9
 // Parse parses string with data and creates search query
 func Parse(q []string) (*Request, error) {
 	result := &Request{}
+	query, _, _, err := parseTokens(splitGroupTokens(q), result, false)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if query == nil {
+		return nil, nil
+	}
 
-	for _, rawTerm := range q {
-		if len(rawTerm) == 0 {
+	result.Query = query
+
+	return result, nil
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// parseTokens parses query tokens until the end of tokens or the end of current
+// group alternative. It returns parsed query, number of processed tokens and
+// token which finished alternative.
+func parseTokens(tokens []string, req *Request, inGroup bool) (search.Query, int, string, error) {
+	var query search.Query
+
+	for i := 0; i < len(tokens); i++ {
+		token := tokens[i]
+
+		switch token {
+		case "":
 			continue
+
+		case GROUP_START:
+			term, n, err := parseGroup(tokens[i+1:], req)
+
+			if err != nil {
+				return nil, 0, "", err
+			}
+
+			query = append(query, term)
+			i += n
+			continue
+
+		case GROUP_END, GROUP_SEPARATOR:
+			if !inGroup {
+				return nil, 0, "", fmt.Errorf("Unexpected %q in query", token)
+			}
+
+			return query, i + 1, token, nil
 		}
 
-		termName, _, _ := extractTermInfo(rawTerm)
+		termName, _, _ := extractTermInfo(token)
 
 		if extTerm[termName] {
-			err := parseExtTerm(rawTerm, result)
+			if inGroup {
+				return nil, 0, "", fmt.Errorf("Query term %q can't be used in group", termName)
+			}
+
+			err := parseExtTerm(token, req)
 
 			if err != nil {
-				return nil, err
+				return nil, 0, "", err
 			}
 		} else {
-			term, err := parseTerm(rawTerm)
+			term, err := parseTerm(token)
 
 			if err != nil {
-				return nil, err
+				return nil, 0, "", err
 			}
 
-			result.Query = append(result.Query, term)
+			query = append(query, term)
 		}
 	}
 
-	if result.Query == nil {
-		return nil, nil
+	if inGroup {
+		return nil, 0, "", fmt.Errorf("Query group is not closed")
 	}
 
-	return result, nil
+	return query, len(tokens), "", nil
 }
 
-// ////////////////////////////////////////////////////////////////////////////////// //
+// parseGroup parses group of alternative queries and returns number of processed
+// tokens (including closing bracket)
+func parseGroup(tokens []string, req *Request) (*search.Term, int, error) {
+	var pos int
+	var queries []search.Query
+
+	for {
+		query, n, endToken, err := parseTokens(tokens[pos:], req, true)
+
+		if err != nil {
+			return nil, 0, err
+		}
+
+		if len(query) == 0 {
+			return nil, 0, fmt.Errorf("Query group contains empty alternative")
+		}
+
+		pos += n
+		queries = append(queries, query)
+
+		if endToken == GROUP_END {
+			break
+		}
+	}
+
+	return search.TermOr(queries...), pos, nil
+}
+
+// splitGroupTokens splits brackets glued to terms into separate tokens
+func splitGroupTokens(q []string) []string {
+	var result []string
+
+	for _, token := range q {
+		for len(token) > 1 && strings.HasPrefix(token, GROUP_START) {
+			result = append(result, GROUP_START)
+			token = token[1:]
+		}
+
+		var closing int
+
+		// Brackets are also used in dependencies names, e.g. libc.so.6()(64bit)
+		for len(token) > 1 && strings.HasSuffix(token, GROUP_END) &&
+			strings.Count(token, GROUP_END) > strings.Count(token, GROUP_START) {
+			token = token[:len(token)-1]
+			closing++
+		}
+
+		result = append(result, token)
+
+		for i := 0; i < closing; i++ {
+			result = append(result, GROUP_END)
+		}
+	}
+
+	return result
+}
 
 // parseTerm parses query term
 func parseTerm(rawTerm string) (*search.Term, error) {
 	name, value, isNegative := extractTermInfo(rawTerm)
+	name, ignoreCase := strings.CutSuffix(name, TERM_MOD_IGNORE_CASE)
 	termType, mod := terms[name], uint8(0)
 
 	if name != "" {
@@ -206,7 +349,11 @@ func parseTerm(rawTerm string) (*search.Term, error) {
 	}
 
 	if isNegative {
-		mod = search.TERM_MOD_NEGATIVE
+		mod |= search.TERM_MOD_NEGATIVE
+	}
+
+	if ignoreCase {
+		mod |= search.TERM_MOD_IGNORE_CASE
 	}
 
 	switch termType {
@@ -248,12 +395,16 @@ func parseTerm(rawTerm string) (*search.Term, error) {
 		return search.TermGroup(value, mod), nil
 	case search.TERM_BUILD_HOST:
 		return search.TermBuildHost(value, mod), nil
+	case search.TERM_PACKAGER:
+		return search.TermPackager(value, mod), nil
 	case search.TERM_DATE_ADD, search.TERM_DATE_BUILD:
 		return parseDateTermValue(termType, value, mod)
 	case search.TERM_SIZE:
 		return parseSizeTermValue(value, mod)
 	case search.TERM_PAYLOAD:
 		return search.TermPayload(value, mod), nil
+	case search.TERM_REGEX:
+		return parseRegexTermValue(value, mod)
 	default:
 		return search.TermName(value+"*", mod), nil
 	}
@@ -263,7 +414,8 @@ func parseTerm(rawTerm string) (*search.Term, error) {
 func parseExtTerm(rawTerm string, searchResult *Request) error {
 	name, value, isNegative := extractTermInfo(rawTerm)
 
-	if name == TERM_RELEASED || name == TERM_SHORT_RELEASED {
+	switch name {
+	case TERM_RELEASED, TERM_SHORT_RELEASED:
 		v, err := parseBoolTermValue(value, isNegative)
 
 		if err != nil {
@@ -275,6 +427,37 @@ func parseExtTerm(rawTerm string, searchResult *Request) error {
 		} else {
 			searchResult.FilterFlag = FILTER_FLAG_UNRELEASED
 		}
+
+	case TERM_DEBUG, TERM_SHORT_DEBUG:
+		v, err := parseBoolTermValue(value, isNegative)
+
+		if err != nil {
+			return err
+		}
+
+		if v {
+			searchResult.DebugFlag = DEBUG_FLAG_ONLY
+		} else {
+			searchResult.DebugFlag = DEBUG_FLAG_EXCLUDE
+		}
+
+	case TERM_LIMIT:
+		v, err := parseNumTermValue(value, isNegative)
+
+		if err != nil {
+			return err
+		}
+
+		searchResult.Limit = v
+
+	case TERM_OFFSET:
+		v, err := parseNumTermValue(value, isNegative)
+
+		if err != nil {
+			return err
+		}
+
+		searchResult.Offset = v
 	}
 
 	return nil
@@ -282,6 +465,10 @@ func parseExtTerm(rawTerm string, searchResult *Request) error {
 
 // parseDateTermValue parses date term value
 func parseDateTermValue(termType uint8, value string, mod uint8) (*search.Term, error) {
+	if strings.Contains(value, DATE_RANGE_SEPARATOR) {
+		return parseDateRangeTermValue(termType, value, mod)
+	}
+
 	dur, err := timeutil.ParseDuration(value, 'd')
 
 	if err != nil {
@@ -295,6 +482,34 @@ func parseDateTermValue(termType uint8, value string, mod uint8) (*search.Term,
 	return &search.Term{Type: termType, Value: search.Range{from, to}, Modificator: mod}, nil
 }
 
+// parseDateRangeTermValue parses date term value with range of dates
+// (e.g. 2023-01-01..2023-06-01)
+func parseDateRangeTermValue(termType uint8, value string, mod uint8) (*search.Term, error) {
+	startValue, endValue, _ := strings.Cut(value, DATE_RANGE_SEPARATOR)
+
+	start, err := time.ParseInLocation(DATE_FORMAT, startValue, time.Local)
+
+	if err != nil {
+		return nil, fmt.Errorf("Can't parse %q as date (must be in YYYY-MM-DD format)", startValue)
+	}
+
+	end, err := time.ParseInLocation(DATE_FORMAT, endValue, time.Local)
+
+	if err != nil {
+		return nil, fmt.Errorf("Can't parse %q as date (must be in YYYY-MM-DD format)", endValue)
+	}
+
+	if start.After(end) {
+		return nil, fmt.Errorf("Date range %s→%s is invalid", startValue, endValue)
+	}
+
+	// End date is inclusive, so we use the last second of the day as range end
+	from := start.Unix()
+	to := end.AddDate(0, 0, 1).Unix() - 1
+
+	return &search.Term{Type: termType, Value: search.Range{from, to}, Modificator: mod}, nil
+}
+
 // parseBoolTermValue parses boolean term value
 func parseBoolTermValue(value string, isNegative bool) (bool, error) {
 	var result bool
@@ -317,6 +532,25 @@ func parseBoolTermValue(value string, isNegative bool) (bool, error) {
 	return result, nil
 }
 
+// parseNumTermValue parses numeric term value
+func parseNumTermValue(value string, isNegative bool) (int, error) {
+	if value == "" {
+		return 0, fmt.Errorf("Query term value can not be empty")
+	}
+
+	if isNegative {
+		return 0, fmt.Errorf("Query term with numeric value can not be negative")
+	}
+
+	v, err := strconv.Atoi(value)
+
+	if err != nil || v < 0 {
+		return 0, fmt.Errorf("Unsupported query term value %q (must be a number greater or equal to zero)", value)
+	}
+
+	return v, nil
+}
+
 // parseSizeTermValue parses size term value
 func parseSizeTermValue(value string, mod uint8) (*search.Term, error) {
 	var from, to uint64
@@ -348,6 +582,21 @@ func parseSizeTermValue(value string, mod uint8) (*search.Term, error) {
 	return search.TermSize(int64(from), int64(to), mod), nil
 }
 
+// parseRegexTermValue parses term with regular expression
+func parseRegexTermValue(value string, mod uint8) (*search.Term, error) {
+	if value == "" {
+		return nil, fmt.Errorf("Query term value can not be empty")
+	}
+
+	_, err := regexp.Compile(value)
+
+	if err != nil {
+		return nil, fmt.Errorf("Can't parse %q as regular expression: %v", value, err)
+	}
+
+	return search.TermRegex(value, mod), nil
+}
+
 // parseDepTermValue parses term with dependency info (used for requires/provides)
 func parseDepTermValue(termType uint8, value string, mod uint8) (*search.Term, error) {
 	dep := extractDepInfo(value)
@@ -9,6 +9,7 @@ package query
 
 import (
 	"testing"
+	"time"
 
 	"github.com/essentialkaos/rep/v3/repo/data"
 	"github.com/essentialkaos/rep/v3/repo/search"
@@ -66,10 +67,94 @@ func (s *QueryParserSuite) TestFlagsParser(c *C) {
 	c.Assert(sr.Query, HasLen, 1)
 	c.Assert(sr.FilterFlag, Equals, FILTER_FLAG_UNRELEASED)
 
+	sr, err = Parse([]string{"n:test", "debug:no"})
+
+	c.Assert(err, IsNil)
+	c.Assert(sr, NotNil)
+	c.Assert(sr.Query, HasLen, 1)
+	c.Assert(sr.DebugFlag, Equals, DEBUG_FLAG_EXCLUDE)
+
+	sr, err = Parse([]string{"n:test", "dg:yes"})
+
+	c.Assert(err, IsNil)
+	c.Assert(sr, NotNil)
+	c.Assert(sr.DebugFlag, Equals, DEBUG_FLAG_ONLY)
+
+	sr, err = Parse([]string{"n:test", "debug:test"})
+
+	c.Assert(err, NotNil)
+	c.Assert(sr, IsNil)
+
 	sr, err = Parse([]string{"n:test", "^:test"})
 
 	c.Assert(err, NotNil)
 	c.Assert(sr, IsNil)
+
+	sr, err = Parse([]string{"n:test", "limit:50", "offset:100"})
+
+	c.Assert(err, IsNil)
+	c.Assert(sr, NotNil)
+	c.Assert(sr.Query, HasLen, 1)
+	c.Assert(sr.Limit, Equals, 50)
+	c.Assert(sr.Offset, Equals, 100)
+
+	_, err = Parse([]string{"n:test", "limit:abc"})
+	c.Assert(err, NotNil)
+
+	_, err = Parse([]string{"n:test", "limit:-1"})
+	c.Assert(err, NotNil)
+
+	_, err = Parse([]string{"n:test", "offset::10"})
+	c.Assert(err, NotNil)
+
+	_, err = Parse([]string{"n:test", "offset:"})
+	c.Assert(err, NotNil)
+}
+
+func (s *QueryParserSuite) TestGroupParser(c *C) {
+	sr, err := Parse([]string{"(", "n:nginx", "|", "n:haproxy", "v:2.*", ")", "a:x64"})
+
+	c.Assert(err, IsNil)
+	c.Assert(sr, NotNil)
+	c.Assert(sr.Query, HasLen, 2)
+	c.Assert(sr.Query[0].Type, Equals, search.TERM_OR)
+	c.Assert(sr.Query[0].Alternatives(), HasLen, 2)
+	c.Assert(sr.Query[0].Alternatives()[0], HasLen, 1)
+	c.Assert(sr.Query[0].Alternatives()[1], HasLen, 2)
+	c.Assert(sr.Query[1].Type, Equals, search.TERM_ARCH)
+
+	sr, err = Parse([]string{"(n:nginx", "|", "(n:haproxy", "|", "P:libc.so.6()(64bit)))"})
+
+	c.Assert(err, IsNil)
+	c.Assert(sr, NotNil)
+	c.Assert(sr.Query, HasLen, 1)
+	c.Assert(sr.Query[0].Alternatives(), HasLen, 2)
+	c.Assert(sr.Query[0].Alternatives()[1][0].Type, Equals, search.TERM_OR)
+	c.Assert(sr.Query[0].Alternatives()[1][0].Alternatives()[1][0].Value.(data.Dependency).Name, Equals, "libc.so.6()(64bit)")
+
+	sr, err = Parse([]string{"n:nginx", "v:'1.19.6|1.21.3'"})
+
+	c.Assert(err, IsNil)
+	c.Assert(sr.Query, HasLen, 2)
+	c.Assert(sr.Query[1].Type, Equals, search.TERM_VERSION)
+
+	_, err = Parse([]string{"(", "n:nginx", "|", "n:haproxy"})
+	c.Assert(err, NotNil)
+
+	_, err = Parse([]string{"n:nginx", ")"})
+	c.Assert(err, NotNil)
+
+	_, err = Parse([]string{"n:nginx", "|", "n:haproxy"})
+	c.Assert(err, NotNil)
+
+	_, err = Parse([]string{"(", "n:nginx", "|", ")"})
+	c.Assert(err, NotNil)
+
+	_, err = Parse([]string{"(", "n:nginx", "|", "^:yes", ")"})
+	c.Assert(err, NotNil)
+
+	_, err = Parse([]string{"(", "k:nginx", ")"})
+	c.Assert(err, NotNil)
 }
 
 func (s *QueryParserSuite) TestTermParser(c *C) {
@@ -102,6 +187,8 @@ func (s *QueryParserSuite) TestTermParser(c *C) {
 	checkTermParser(c, TERM_SHORT_SIZE+":1mb", search.TERM_SIZE)
 	checkTermParser(c, TERM_SHORT_VENDOR+":test", search.TERM_VENDOR)
 	checkTermParser(c, TERM_SHORT_PAYLOAD+":/test/file.log", search.TERM_PAYLOAD)
+	checkTermParser(c, TERM_SHORT_REGEX+":^test-[0-9]+$", search.TERM_REGEX)
+	checkTermParser(c, TERM_SHORT_PACKAGER+":test", search.TERM_PACKAGER)
 
 	checkTermParser(c, TERM_NAME+":test", search.TERM_NAME)
 	checkTermParser(c, TERM_VERSION+":test", search.TERM_VERSION)
@@ -125,10 +212,58 @@ func (s *QueryParserSuite) TestTermParser(c *C) {
 	checkTermParser(c, TERM_BUILD_HOST+":test", search.TERM_BUILD_HOST)
 	checkTermParser(c, TERM_SIZE+":1mb", search.TERM_SIZE)
 	checkTermParser(c, TERM_PAYLOAD+":/test/file.log", search.TERM_PAYLOAD)
+	checkTermParser(c, TERM_REGEX+":^test-[0-9]+$", search.TERM_REGEX)
+	checkTermParser(c, TERM_PACKAGER+":test", search.TERM_PACKAGER)
 
 	checkTermParser(c, TERM_SHORT_NAME+"::test", search.TERM_NAME)
 }
 
+func (s *QueryParserSuite) TestCaseInsensitiveTermParser(c *C) {
+	t, err := parseTerm("n~:NGINX")
+
+	c.Assert(t, NotNil)
+	c.Assert(err, IsNil)
+	c.Assert(t.Type, Equals, search.TERM_NAME)
+	c.Assert(t.Value, Equals, "NGINX")
+	c.Assert(t.IsCaseInsensitive(), Equals, true)
+	c.Assert(t.IsNegative(), Equals, false)
+
+	_, qs := t.SQL()
+	c.Assert(qs, DeepEquals, []string{`SELECT pkgKey FROM packages WHERE lower(name) = "nginx";`})
+
+	t, err = parseTerm("name~::NgInX-*")
+
+	c.Assert(t, NotNil)
+	c.Assert(err, IsNil)
+	c.Assert(t.IsCaseInsensitive(), Equals, true)
+	c.Assert(t.IsNegative(), Equals, true)
+
+	_, qs = t.SQL()
+	c.Assert(qs, DeepEquals, []string{`SELECT pkgKey FROM packages WHERE lower(name) NOT GLOB "nginx-*";`})
+
+	t, err = parseTerm("~:NGINX")
+
+	c.Assert(t, NotNil)
+	c.Assert(err, IsNil)
+	c.Assert(t.Type, Equals, search.TERM_NAME)
+	c.Assert(t.Value, Equals, "NGINX*")
+	c.Assert(t.IsCaseInsensitive(), Equals, true)
+
+	t, err = parseTerm("n:NGINX")
+
+	c.Assert(t, NotNil)
+	c.Assert(err, IsNil)
+	c.Assert(t.IsCaseInsensitive(), Equals, false)
+
+	sr, err := Parse([]string{"S~:1mb"})
+
+	c.Assert(err, IsNil)
+	c.Assert(sr.Query.Validate(), HasLen, 1)
+
+	_, err = parseTerm("k~:test")
+	c.Assert(err, NotNil)
+}
+
 func (s *QueryParserSuite) TestDateTermParser(c *C) {
 	t, err := parseTerm("d:1w")
 
@@ -144,6 +279,88 @@ func (s *QueryParserSuite) TestDateTermParser(c *C) {
 
 	c.Assert(t, IsNil)
 	c.Assert(err, NotNil)
+
+	t, err = parseTerm("D:2023-01-01..2023-06-01")
+
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.Local).Unix()
+	end := time.Date(2023, 6, 2, 0, 0, 0, 0, time.Local).Unix() - 1
+
+	c.Assert(t, NotNil)
+	c.Assert(err, IsNil)
+	c.Assert(t.Type, Equals, search.TERM_DATE_BUILD)
+	c.Assert(t.Value.(search.Range).Start, Equals, start)
+	c.Assert(t.Value.(search.Range).End, Equals, end)
+
+	t, err = parseTerm("d:2023-01-01..2023-01-01")
+
+	c.Assert(t, NotNil)
+	c.Assert(err, IsNil)
+	c.Assert(t.Value.(search.Range).End-t.Value.(search.Range).Start, Equals, int64(24*3600-1))
+
+	t, err = parseTerm("d:2023-06-01..2023-01-01")
+
+	c.Assert(t, IsNil)
+	c.Assert(err, ErrorMatches, `Date range 2023-06-01→2023-01-01 is invalid`)
+
+	t, err = parseTerm("d:2023-13-01..2023-06-01")
+
+	c.Assert(t, IsNil)
+	c.Assert(err, ErrorMatches, `Can't parse "2023-13-01" as date .*`)
+
+	t, err = parseTerm("d:2023-01-01..")
+
+	c.Assert(t, IsNil)
+	c.Assert(err, ErrorMatches, `Can't parse "" as date .*`)
+}
+
+func (s *QueryParserSuite) TestBuildHostTermParser(c *C) {
+	t, err := parseTerm("h:buildhost.example.com")
+
+	c.Assert(t, NotNil)
+	c.Assert(err, IsNil)
+	c.Assert(t.Type, Equals, search.TERM_BUILD_HOST)
+	c.Assert(t.Value, Equals, "buildhost.example.com")
+	c.Assert(t.IsNegative(), Equals, false)
+
+	db, qrs := t.SQL()
+
+	c.Assert(db, Equals, data.DB_PRIMARY)
+	c.Assert(qrs, DeepEquals, []string{`SELECT pkgKey FROM packages WHERE rpm_buildhost = "buildhost.example.com";`})
+
+	t, err = parseTerm("host::*.example.com")
+
+	c.Assert(t, NotNil)
+	c.Assert(err, IsNil)
+	c.Assert(t.Type, Equals, search.TERM_BUILD_HOST)
+	c.Assert(t.IsNegative(), Equals, true)
+
+	_, qrs = t.SQL()
+
+	c.Assert(qrs, DeepEquals, []string{`SELECT pkgKey FROM packages WHERE rpm_buildhost NOT GLOB "*.example.com";`})
+}
+
+func (s *QueryParserSuite) TestRegexTermParser(c *C) {
+	t, err := parseTerm("re:^nginx(-module)?$")
+
+	c.Assert(t, NotNil)
+	c.Assert(err, IsNil)
+	c.Assert(t.Value, Equals, "^nginx(-module)?$")
+
+	t, err = parseTerm("re::^nginx")
+
+	c.Assert(t, NotNil)
+	c.Assert(err, IsNil)
+	c.Assert(t.IsNegative(), Equals, true)
+
+	t, err = parseTerm("re:^nginx(-module$")
+
+	c.Assert(t, IsNil)
+	c.Assert(err, NotNil)
+
+	t, err = parseTerm("re:")
+
+	c.Assert(t, IsNil)
+	c.Assert(err, NotNil)
 }
 
 func (s *QueryParserSuite) TestSizeTermParser(c *C) {
@@ -255,6 +472,9 @@ func (s *QueryParserSuite) TestArchFormatter(c *C) {
 	c.Assert(formatArchValue("x32"), Equals, "i386")
 	c.Assert(formatArchValue("ppc64le"), Equals, "ppc64le")
 	c.Assert(formatArchValue("arm"), Equals, "arm")
+	c.Assert(formatArchValue("s390x"), Equals, "s390x")
+	c.Assert(formatArchValue("rv64"), Equals, "riscv64")
+	c.Assert(formatArchValue("riscv64"), Equals, "riscv64")
 	c.Assert(formatArchValue("src"), Equals, "src")
 }
 
@@ -0,0 +1,149 @@
+package tags
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2024 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+
+	"github.com/essentialkaos/ek/v13/sortutil"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Tags contains packages tags
+type Tags struct {
+	data map[string][]string // NEVRA → tags
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+var (
+	ErrEmptyTag   = fmt.Errorf("Tag can't be empty")
+	ErrInvalidTag = fmt.Errorf("Tag contains invalid symbols")
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// tagValidationRegex is regex for tag validation
+var tagValidationRegex = regexp.MustCompile(`^[\w\-\.]+$`)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Decode decodes tags from given JSON data (empty data means that there are no
+// tags yet)
+func Decode(tagsData []byte) (*Tags, error) {
+	t := &Tags{data: make(map[string][]string)}
+
+	if len(tagsData) == 0 {
+		return t, nil
+	}
+
+	err := json.Unmarshal(tagsData, &t.data)
+
+	if err != nil {
+		return nil, fmt.Errorf("Can't decode tags data: %w", err)
+	}
+
+	return t, nil
+}
+
+// ValidateTag validates tag
+func ValidateTag(tag string) error {
+	switch {
+	case tag == "":
+		return ErrEmptyTag
+	case !tagValidationRegex.MatchString(tag):
+		return ErrInvalidTag
+	}
+
+	return nil
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Add adds tag to package with given NEVRA
+func (t *Tags) Add(nevra, tag string) bool {
+	if t == nil || nevra == "" || tag == "" || t.Has(nevra, tag) {
+		return false
+	}
+
+	t.data[nevra] = append(t.data[nevra], tag)
+	sortutil.StringsNatural(t.data[nevra])
+
+	return true
+}
+
+// Remove removes tag from package with given NEVRA
+func (t *Tags) Remove(nevra, tag string) bool {
+	if t == nil || !t.Has(nevra, tag) {
+		return false
+	}
+
+	t.data[nevra] = slices.DeleteFunc(t.data[nevra], func(v string) bool {
+		return v == tag
+	})
+
+	if len(t.data[nevra]) == 0 {
+		delete(t.data, nevra)
+	}
+
+	return true
+}
+
+// Has returns true if package with given NEVRA has given tag
+func (t *Tags) Has(nevra, tag string) bool {
+	if t == nil {
+		return false
+	}
+
+	return slices.Contains(t.data[nevra], tag)
+}
+
+// Get returns slice with tags of package with given NEVRA
+func (t *Tags) Get(nevra string) []string {
+	if t == nil {
+		return nil
+	}
+
+	return t.data[nevra]
+}
+
+// Packages returns sorted slice with NEVRA of all tagged packages
+func (t *Tags) Packages() []string {
+	if t == nil {
+		return nil
+	}
+
+	var result []string
+
+	for nevra := range t.data {
+		result = append(result, nevra)
+	}
+
+	sortutil.StringsNatural(result)
+
+	return result
+}
+
+// Encode encodes tags to JSON
+func (t *Tags) Encode() ([]byte, error) {
+	if t == nil {
+		return nil, fmt.Errorf("Tags struct is nil")
+	}
+
+	tagsData, err := json.MarshalIndent(t.data, "", "  ")
+
+	if err != nil {
+		return nil, fmt.Errorf("Can't encode tags data: %w", err)
+	}
+
+	return tagsData, nil
+}
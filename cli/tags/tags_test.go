@@ -0,0 +1,74 @@
+package tags
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2024 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"testing"
+
+	. "github.com/essentialkaos/check"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func Test(t *testing.T) { TestingT(t) }
+
+type TagsSuite struct{}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+var _ = Suite(&TagsSuite{})
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func (s *TagsSuite) TestTags(c *C) {
+	t, err := Decode(nil)
+	c.Assert(err, IsNil)
+	c.Assert(t, NotNil)
+	c.Assert(t.Packages(), IsNil)
+
+	c.Assert(t.Add("test-0:1.0.0-0.el7.x86_64", "security-fix"), Equals, true)
+	c.Assert(t.Add("test-0:1.0.0-0.el7.x86_64", "security-fix"), Equals, false)
+	c.Assert(t.Add("test-0:1.0.0-0.el7.x86_64", "ticket-1234"), Equals, true)
+	c.Assert(t.Add("test-0:1.0.0-0.el7.src", "ticket-1234"), Equals, true)
+	c.Assert(t.Add("", "ticket-1234"), Equals, false)
+	c.Assert(t.Add("test-0:1.0.0-0.el7.src", ""), Equals, false)
+
+	c.Assert(t.Has("test-0:1.0.0-0.el7.x86_64", "security-fix"), Equals, true)
+	c.Assert(t.Has("test-0:1.0.0-0.el7.src", "security-fix"), Equals, false)
+	c.Assert(t.Get("test-0:1.0.0-0.el7.x86_64"), DeepEquals, []string{"security-fix", "ticket-1234"})
+
+	c.Assert(t.Remove("test-0:1.0.0-0.el7.src", "ticket-1234"), Equals, true)
+	c.Assert(t.Remove("test-0:1.0.0-0.el7.src", "ticket-1234"), Equals, false)
+	c.Assert(t.Packages(), DeepEquals, []string{"test-0:1.0.0-0.el7.x86_64"})
+
+	tagsData, err := t.Encode()
+	c.Assert(err, IsNil)
+
+	t, err = Decode(tagsData)
+	c.Assert(err, IsNil)
+	c.Assert(t.Get("test-0:1.0.0-0.el7.x86_64"), DeepEquals, []string{"security-fix", "ticket-1234"})
+}
+
+func (s *TagsSuite) TestErrors(c *C) {
+	_, err := Decode([]byte("{"))
+	c.Assert(err, ErrorMatches, "Can't decode tags data: .*")
+
+	c.Assert(ValidateTag(""), Equals, ErrEmptyTag)
+	c.Assert(ValidateTag("abc d"), Equals, ErrInvalidTag)
+	c.Assert(ValidateTag("ticket-1234"), IsNil)
+
+	var t *Tags
+
+	c.Assert(t.Add("test", "test"), Equals, false)
+	c.Assert(t.Remove("test", "test"), Equals, false)
+	c.Assert(t.Has("test", "test"), Equals, false)
+	c.Assert(t.Get("test"), IsNil)
+	c.Assert(t.Packages(), IsNil)
+	_, err = t.Encode()
+	c.Assert(err, ErrorMatches, "Tags struct is nil")
+}
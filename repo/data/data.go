@@ -8,6 +8,7 @@ package data
 // ////////////////////////////////////////////////////////////////////////////////// //
 
 import (
+	"encoding/json"
 	"strings"
 )
 
@@ -26,7 +27,8 @@ const (
 	DB_FILELISTS = "filelists"
 )
 
-// Arch flags
+// Arch flags (typed constants, so compiler rejects flags which don't fit
+// into ArchFlag type)
 const (
 	ARCH_FLAG_UNKNOWN ArchFlag = 0
 	ARCH_FLAG_SRC     ArchFlag = 1 << iota
@@ -40,6 +42,8 @@ const (
 	ARCH_FLAG_PPC64LE
 	ARCH_FLAG_ARM
 	ARCH_FLAG_ARMV7HL
+	ARCH_FLAG_S390X
+	ARCH_FLAG_RISCV64
 )
 
 // Arch names
@@ -55,6 +59,8 @@ const (
 	ARCH_PPC64LE = "ppc64le"
 	ARCH_ARM     = "arm"
 	ARCH_ARMV7HL = "armv7hl"
+	ARCH_S390X   = "s390x"
+	ARCH_RISCV64 = "riscv64"
 )
 
 // Comparison flags
@@ -74,15 +80,15 @@ type CompFlag uint8
 
 // Dependency contains info about dependency
 type Dependency struct {
-	Name    string
-	Epoch   string
-	Version string
-	Release string
-	Flag    CompFlag
+	Name    string   `json:"name"`
+	Epoch   string   `json:"epoch,omitempty"`
+	Version string   `json:"version,omitempty"`
+	Release string   `json:"release,omitempty"`
+	Flag    CompFlag `json:"flag,omitempty"`
 }
 
-// ArchInfo is arch flag
-type ArchFlag uint16
+// ArchFlag is arch flag (bitmask with one bit per supported arch)
+type ArchFlag uint32
 
 // ArchInfo contains info about specific arch
 type ArchInfo struct {
@@ -106,6 +112,8 @@ var SupportedArchs = map[string]ArchInfo{
 	ARCH_PPC64LE: {"ppc64le", "p64l", ARCH_FLAG_PPC64LE},
 	ARCH_ARM:     {"arm", "arm", ARCH_FLAG_ARM},
 	ARCH_ARMV7HL: {"armv7hl", "arm7", ARCH_FLAG_ARMV7HL},
+	ARCH_S390X:   {"s390x", "s390x", ARCH_FLAG_S390X},
+	ARCH_RISCV64: {"riscv64", "rv64", ARCH_FLAG_RISCV64},
 }
 
 // ArchList is a slice with supported archs
@@ -121,6 +129,8 @@ var ArchList = []string{
 	ARCH_PPC64LE,
 	ARCH_ARM,
 	ARCH_ARMV7HL,
+	ARCH_S390X,
+	ARCH_RISCV64,
 }
 
 // BinArchList is a slice with supported binary archs
@@ -134,6 +144,8 @@ var BinArchList = []string{
 	ARCH_PPC64LE,
 	ARCH_ARM,
 	ARCH_ARMV7HL,
+	ARCH_S390X,
+	ARCH_RISCV64,
 }
 
 // DBList is a slice with names of databases
@@ -167,6 +179,19 @@ func (f ArchFlag) String() string {
 	return strings.Join(result, "/")
 }
 
+// MarshalJSON encodes arch flag as JSON array with arch names
+func (f ArchFlag) MarshalJSON() ([]byte, error) {
+	result := []string{}
+
+	for _, arch := range ArchList {
+		if f.Has(SupportedArchs[arch].Flag) {
+			result = append(result, arch)
+		}
+	}
+
+	return json.Marshal(result)
+}
+
 // String returns string representation of comparison flag
 func (f CompFlag) String() string {
 	switch f {
@@ -185,6 +210,11 @@ func (f CompFlag) String() string {
 	}
 }
 
+// MarshalJSON encodes comparison flag as JSON string
+func (f CompFlag) MarshalJSON() ([]byte, error) {
+	return json.Marshal(f.String())
+}
+
 // ////////////////////////////////////////////////////////////////////////////////// //
 
 // ParseComp parses text value of flag
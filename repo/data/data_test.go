@@ -8,7 +8,10 @@ package data
 // ////////////////////////////////////////////////////////////////////////////////// //
 
 import (
+	"encoding/json"
+	"math/bits"
 	"testing"
+	"unsafe"
 
 	. "github.com/essentialkaos/check"
 )
@@ -98,6 +101,29 @@ func (s *DataSuite) TestPkgKeyIndex(c *C) {
 	c.Assert(index.HasData(), Equals, false)
 }
 
+func (s *DataSuite) TestPkgKeyMap(c *C) {
+	km1 := NewPkgKeyMap()
+	km2 := NewPkgKeyMap()
+
+	km1.Set(1)
+	km1.Set(2)
+	km2.Set(2)
+	km2.Set(3)
+
+	km1.Union(km2)
+
+	c.Assert(km1, HasLen, 3)
+	c.Assert(km1[1], Equals, true)
+	c.Assert(km1[3], Equals, true)
+
+	km1.Intersect(km2)
+
+	c.Assert(km1, HasLen, 2)
+	c.Assert(km1[1], Equals, false)
+	c.Assert(km1[2], Equals, true)
+	c.Assert(km1[3], Equals, true)
+}
+
 func (s *DataSuite) TestArchFlag(c *C) {
 	var f ArchFlag
 
@@ -109,6 +135,49 @@ func (s *DataSuite) TestArchFlag(c *C) {
 	c.Assert(f.Has(ARCH_FLAG_X64), Equals, true)
 
 	c.Assert(f.String(), Equals, "noarch/i386/x86_64")
+
+	f = ARCH_FLAG_S390X | ARCH_FLAG_RISCV64
+
+	c.Assert(f.Has(ARCH_FLAG_ARMV7HL), Equals, false)
+	c.Assert(f.String(), Equals, "s390x/riscv64")
+}
+
+func (s *DataSuite) TestArchFlagBits(c *C) {
+	var mask ArchFlag
+
+	c.Assert(len(ArchList) < int(unsafe.Sizeof(mask))*8, Equals, true)
+	c.Assert(SupportedArchs, HasLen, len(ArchList))
+
+	for _, arch := range ArchList {
+		info, ok := SupportedArchs[arch]
+
+		c.Assert(ok, Equals, true, Commentf("Arch %s is not supported", arch))
+		c.Assert(bits.OnesCount32(uint32(info.Flag)), Equals, 1, Commentf("Arch %s flag must have exactly one bit", arch))
+		c.Assert(mask&info.Flag, Equals, ARCH_FLAG_UNKNOWN, Commentf("Arch %s flag overlaps with other flag", arch))
+
+		mask |= info.Flag
+	}
+
+	for _, arch := range BinArchList {
+		_, ok := SupportedArchs[arch]
+		c.Assert(ok, Equals, true, Commentf("Arch %s is not supported", arch))
+	}
+}
+
+func (s *DataSuite) TestJSON(c *C) {
+	f := ARCH_FLAG_I386 | ARCH_FLAG_X64
+
+	d, err := json.Marshal(f)
+	c.Assert(err, IsNil)
+	c.Assert(string(d), Equals, `["i386","x86_64"]`)
+
+	d, err = json.Marshal(ArchFlag(0))
+	c.Assert(err, IsNil)
+	c.Assert(string(d), Equals, `[]`)
+
+	d, err = json.Marshal(Dependency{Name: "bash", Version: "4.2", Flag: COMP_FLAG_GE})
+	c.Assert(err, IsNil)
+	c.Assert(string(d), Equals, `{"name":"bash","version":"4.2","flag":"GE"}`)
 }
 
 // ////////////////////////////////////////////////////////////////////////////////// //
@@ -39,6 +39,22 @@ func (m PkgKeyMap) Set(key int) {
 	m[key] = true
 }
 
+// Union adds all keys from given map
+func (m PkgKeyMap) Union(src PkgKeyMap) {
+	for k := range src {
+		m[k] = true
+	}
+}
+
+// Intersect removes all keys which are absent in given map
+func (m PkgKeyMap) Intersect(src PkgKeyMap) {
+	for k := range m {
+		if !src[k] {
+			delete(m, k)
+		}
+	}
+}
+
 // ////////////////////////////////////////////////////////////////////////////////// //
 
 // HasData returns true if index contains any data
@@ -38,7 +38,8 @@ func GuessFileArch(fileName string) string {
 	switch fileName[index+1:] {
 	case data.ARCH_SRC, data.ARCH_NOARCH, data.ARCH_I386, data.ARCH_I586,
 		data.ARCH_I686, data.ARCH_X64, data.ARCH_AARCH64, data.ARCH_PPC64,
-		data.ARCH_PPC64LE, data.ARCH_ARM, data.ARCH_ARMV7HL:
+		data.ARCH_PPC64LE, data.ARCH_ARM, data.ARCH_ARMV7HL, data.ARCH_S390X,
+		data.ARCH_RISCV64:
 		return fileName[index+1:]
 	}
 
@@ -47,22 +48,56 @@ func GuessFileArch(fileName string) string {
 
 // ExtractPackageArch reads package arch tag from header
 func ExtractPackageArch(rpmFile string) (string, error) {
-	fd, err := os.OpenFile(rpmFile, os.O_RDONLY, 0)
+	header, err := readPackageHeader(rpmFile)
 
 	if err != nil {
 		return "", err
 	}
 
-	defer fd.Close()
+	return getHeaderArch(header)
+}
+
+// ExtractPackageFileName builds canonical package file name
+// (name-version-release.arch.rpm) using data from package header
+func ExtractPackageFileName(rpmFile string) (string, error) {
+	header, err := readPackageHeader(rpmFile)
 
-	r := bufio.NewReader(fd)
+	if err != nil {
+		return "", err
+	}
 
-	header, err := rpmutils.ReadHeader(r)
+	nevra, err := header.GetNEVRA()
 
 	if err != nil {
 		return "", err
 	}
 
+	arch, err := getHeaderArch(header)
+
+	if err != nil {
+		return "", err
+	}
+
+	return nevra.Name + "-" + nevra.Version + "-" + nevra.Release + "." + arch + ".rpm", nil
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// readPackageHeader reads RPM package header
+func readPackageHeader(rpmFile string) (*rpmutils.RpmHeader, error) {
+	fd, err := os.OpenFile(rpmFile, os.O_RDONLY, 0)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer fd.Close()
+
+	return rpmutils.ReadHeader(bufio.NewReader(fd))
+}
+
+// getHeaderArch returns package arch from header
+func getHeaderArch(header *rpmutils.RpmHeader) (string, error) {
 	if !header.HasTag(rpmutils.SOURCERPM) {
 		return data.ARCH_SRC, nil
 	}
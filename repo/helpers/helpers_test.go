@@ -39,6 +39,8 @@ func (s *HelpersSuite) TestGuessFileArch(c *C) {
 	c.Assert(GuessFileArch("test-package-1.0.0-0.el7.i386.rpm"), Equals, data.ARCH_I386)
 	c.Assert(GuessFileArch("test-package-1.0.0-0.el7.i686.rpm"), Equals, data.ARCH_I686)
 	c.Assert(GuessFileArch("test-package-1.0.0-0.el7.aarch64.rpm"), Equals, data.ARCH_AARCH64)
+	c.Assert(GuessFileArch("test-package-1.0.0-0.el9.s390x.rpm"), Equals, data.ARCH_S390X)
+	c.Assert(GuessFileArch("test-package-1.0.0-0.el9.riscv64.rpm"), Equals, data.ARCH_RISCV64)
 }
 
 func (s *HelpersSuite) TestExtractPackageArch(c *C) {
@@ -59,4 +61,32 @@ func (s *HelpersSuite) TestExtractPackageArch(c *C) {
 	arch, err = ExtractPackageArch("../../testdata/git-all-2.27.0-0.el7.noarch.rpm")
 	c.Assert(err, IsNil)
 	c.Assert(arch, Equals, "noarch")
+
+	arch, err = ExtractPackageArch("../../testdata/test-package-1.0.0-0.el7.s390x.rpm")
+	c.Assert(err, IsNil)
+	c.Assert(arch, Equals, data.ARCH_S390X)
+
+	arch, err = ExtractPackageArch("../../testdata/test-package-1.0.0-0.el7.riscv64.rpm")
+	c.Assert(err, IsNil)
+	c.Assert(arch, Equals, data.ARCH_RISCV64)
+}
+
+func (s *HelpersSuite) TestExtractPackageFileName(c *C) {
+	_, err := ExtractPackageFileName("/_unknown_")
+	c.Assert(err, ErrorMatches, `open /_unknown_: no such file or directory`)
+
+	_, err = ExtractPackageFileName("../../testdata/comps.xml.gz")
+	c.Assert(err, ErrorMatches, `file is not an RPM`)
+
+	fileName, err := ExtractPackageFileName("../../testdata/test-package-1.0.0-0.el7.x86_64.rpm")
+	c.Assert(err, IsNil)
+	c.Assert(fileName, Equals, "test-package-1.0.0-0.el7.x86_64.rpm")
+
+	fileName, err = ExtractPackageFileName("../../testdata/test-package-1.0.0-0.el7.src.rpm")
+	c.Assert(err, IsNil)
+	c.Assert(fileName, Equals, "test-package-1.0.0-0.el7.src.rpm")
+
+	fileName, err = ExtractPackageFileName("../../testdata/git-all-2.27.0-0.el7.noarch.rpm")
+	c.Assert(err, IsNil)
+	c.Assert(fileName, Equals, "git-all-2.27.0-0.el7.noarch.rpm")
 }
@@ -8,12 +8,18 @@ package index
 // ////////////////////////////////////////////////////////////////////////////////// //
 
 import (
+	"bufio"
 	"bytes"
+	"context"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"regexp"
 	"strconv"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/essentialkaos/ek/v13/fsutil"
 	"github.com/essentialkaos/ek/v13/sliceutil"
@@ -43,6 +49,13 @@ const (
 	COMPRESSION_ZSTD = "zstd"
 )
 
+// TEMP_DIR is name of directory used by createrepo_c for generating new metadata
+const TEMP_DIR = ".repodata"
+
+// STOP_DELAY is time given to createrepo_c to stop after cancellation before
+// killing it
+const STOP_DELAY = 15 * time.Second
+
 const (
 	PERMS_DIR  os.FileMode = 0755 // Default permissions for directories
 	PERMS_FILE os.FileMode = 0644 // Default permissions for files
@@ -64,8 +77,10 @@ type Options struct {
 	Distro         string // Distro tag and optional CPE ID
 	Content        string // Tags for the content in the repository
 	Revision       string // User-specified revision for repository
+	LocationPrefix string // Prefix added before location_href of every package
 	NumDeltas      int    // The number of older versions to make deltas against
 	ChangelogLimit int    // Only import the last N changelog entries
+	RetainOldMD    int    // Keep N latest copies of old repodata
 	Workers        int    // Number of workers to spawn to read rpms
 	Pretty         bool   // Make sure all xml generated is formatted
 	Update         bool   // Use the existing repodata to speed up creation of new repository
@@ -73,6 +88,20 @@ type Options struct {
 	SkipSymlinks   bool   // Ignore symlinks of packages
 	Deltas         bool   // Create delta rpms and metadata
 	Zchunk         bool   // Generate zchunk files as well as the standard repodata
+	NoDatabase     bool   // Don't generate SQLite databases
+}
+
+// Progress contains info about index generation progress
+type Progress struct {
+	Done  int // Number of processed packages
+	Total int // Total number of packages
+}
+
+// GenerateStats contains index generation statistics
+type GenerateStats struct {
+	Duration time.Duration // Index generation duration
+	Packages int           // Number of processed packages
+	MetaSize int64         // Total size of generated metadata
 }
 
 // ////////////////////////////////////////////////////////////////////////////////// //
@@ -114,6 +143,14 @@ var DefaultOptions = &Options{
 var chownFunc = os.Chown
 var chmodFunc = os.Chmod
 
+// packagesCountRegex is regex for extracting number of packages from
+// createrepo_c output
+var packagesCountRegex = regexp.MustCompile(`Directory walk done - ([0-9]+) packages`)
+
+// packageProcessingRegex is regex for finding info about processed package in
+// createrepo_c verbose output
+var packageProcessingRegex = regexp.MustCompile(`Processing: .+\.rpm`)
+
 // ////////////////////////////////////////////////////////////////////////////////// //
 
 // IsCreaterepoInstalled returns true if createrepo_c utility is installed on the
@@ -123,16 +160,19 @@ func IsCreaterepoInstalled() bool {
 	return err == nil
 }
 
-// Generate creates repository index using createrepo_c utility
-func Generate(path string, options *Options, full bool) error {
+// Generate creates repository index using createrepo_c utility. If progress
+// channel is not nil, info about generation progress will be sent to it. If
+// context is canceled, createrepo_c will be stopped and existing index won't be
+// changed.
+func Generate(ctx context.Context, path string, options *Options, full bool, progress chan<- Progress) (*GenerateStats, error) {
 	if !IsCreaterepoInstalled() {
-		return fmt.Errorf("Can't generate index: createrepo_c not installed")
+		return nil, fmt.Errorf("Can't generate index: createrepo_c not installed")
 	}
 
 	err := options.Validate()
 
 	if err != nil {
-		return fmt.Errorf("Error while options validation: %w", err)
+		return nil, fmt.Errorf("Error while options validation: %w", err)
 	}
 
 	if full && options.Update {
@@ -142,28 +182,86 @@ func Generate(path string, options *Options, full bool) error {
 
 	var stdErrBuf bytes.Buffer
 
-	cmd := exec.Command("createrepo_c", options.ToArgs()...)
+	cmd := exec.CommandContext(ctx, "createrepo_c", options.ToArgs()...)
+
+	// createrepo_c generates metadata in temporary directory and replaces
+	// repodata directory only after successful generation, so on SIGTERM
+	// it stops and removes temporary data
+	cmd.Cancel = func() error { return cmd.Process.Signal(syscall.SIGTERM) }
+	cmd.WaitDelay = STOP_DELAY
+
+	// createrepo_c looks for older versions of packages only in the
+	// given directories, so without it no drpms will be created
+	if options.Deltas {
+		cmd.Args = append(cmd.Args, "--oldpackagedirs="+path)
+	}
+
+	// createrepo_c prints info about every processed package only in
+	// verbose mode
+	if progress != nil {
+		cmd.Args = append(cmd.Args, "--verbose")
+	}
+
 	cmd.Args = append(cmd.Args, path)
 	cmd.Stderr = &stdErrBuf
 
-	if cmd.Run() != nil {
+	stdout, err := cmd.StdoutPipe()
+
+	if err != nil {
+		return nil, fmt.Errorf("Error while executing createrepo_c: %w", err)
+	}
+
+	tempDir := path + "/" + TEMP_DIR
+	hasTempDir := fsutil.IsExist(tempDir)
+	start := time.Now()
+
+	err = cmd.Start()
+
+	if err != nil {
+		return nil, fmt.Errorf("Error while executing createrepo_c: %w", err)
+	}
+
+	packages := readOutput(stdout, progress)
+
+	if cmd.Wait() != nil {
+		// Remove temporary directory left by killed createrepo_c, otherwise
+		// all subsequent runs will fail
+		if !hasTempDir {
+			os.RemoveAll(tempDir)
+		}
+
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("Index generation canceled: %w", context.Cause(ctx))
+		}
+
 		errorMessage := strings.TrimRight(stdErrBuf.String(), "\r\n")
-		return fmt.Errorf("Error while executing createrepo_c: %s", errorMessage)
+		return nil, fmt.Errorf("Error while executing createrepo_c: %s", errorMessage)
+	}
+
+	stats := &GenerateStats{
+		Duration: time.Since(start),
+		Packages: packages,
 	}
 
 	if options.User != "" || options.Group != "" {
 		err = updateIndexOwner(path, options)
 
 		if err != nil {
-			return err
+			return nil, err
 		}
 	}
 
 	if options.DirPerms != 0 || options.FilePerms != 0 {
-		return updateIndexPerms(path, options)
+		err = updateIndexPerms(path, options)
+
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	return nil
+	stats.MetaSize = getMetaSize(path)
+
+	return stats, nil
 }
 
 // ////////////////////////////////////////////////////////////////////////////////// //
@@ -183,8 +281,10 @@ func (o *Options) Clone() *Options {
 		Distro:         o.Distro,
 		Content:        o.Content,
 		Revision:       o.Revision,
+		LocationPrefix: o.LocationPrefix,
 		NumDeltas:      o.NumDeltas,
 		ChangelogLimit: o.ChangelogLimit,
+		RetainOldMD:    o.RetainOldMD,
 		Workers:        o.Workers,
 		Pretty:         o.Pretty,
 		Update:         o.Update,
@@ -192,6 +292,7 @@ func (o *Options) Clone() *Options {
 		SkipSymlinks:   o.SkipSymlinks,
 		Deltas:         o.Deltas,
 		Zchunk:         o.Zchunk,
+		NoDatabase:     o.NoDatabase,
 	}
 }
 
@@ -227,6 +328,10 @@ func (o *Options) Validate() error {
 		return fmt.Errorf("ChangelogLimit can't be less than 0")
 	}
 
+	if o.RetainOldMD < 0 {
+		return fmt.Errorf("RetainOldMD can't be less than 0")
+	}
+
 	if o.CheckSum != "" && !sliceutil.Contains(CheckSumMethods, o.CheckSum) {
 		return fmt.Errorf("Unsupported CheckSum method \"%s\"", o.CheckSum)
 	}
@@ -246,7 +351,13 @@ func (o *Options) Validate() error {
 func (o *Options) ToArgs() []string {
 	var args []string
 
-	args = append(args, "--database")
+	// createrepo_c generates SQLite databases by default, so we have to disable
+	// it explicitly
+	if o.NoDatabase {
+		args = append(args, "--no-database")
+	} else {
+		args = append(args, "--database")
+	}
 
 	if o.GroupFile != "" {
 		args = append(args, "--groupfile="+o.GroupFile)
@@ -274,12 +385,20 @@ func (o *Options) ToArgs() []string {
 
 	if o.Deltas {
 		args = append(args, "--deltas")
+
+		if o.NumDeltas > 0 {
+			args = append(args, "--num-deltas="+strconv.Itoa(o.NumDeltas))
+		}
 	}
 
 	if o.ChangelogLimit > 0 {
 		args = append(args, "--changelog-limit="+strconv.Itoa(o.ChangelogLimit))
 	}
 
+	if o.RetainOldMD > 0 {
+		args = append(args, "--retain-old-md="+strconv.Itoa(o.RetainOldMD))
+	}
+
 	if o.Distro != "" {
 		args = append(args, "--distro="+o.Distro)
 	}
@@ -292,25 +411,18 @@ func (o *Options) ToArgs() []string {
 		args = append(args, "--revision="+o.Revision)
 	}
 
-	if o.NumDeltas > 0 {
-		args = append(args, "--num-deltas="+strconv.Itoa(o.NumDeltas))
+	if o.LocationPrefix != "" {
+		args = append(args, "--location-prefix="+o.LocationPrefix)
 	}
 
 	if o.Workers > 1 {
 		args = append(args, "--workers="+strconv.Itoa(o.Workers))
 	}
 
-	if o.CompressType != "" {
-		args = append(args,
-			"--compress-type="+o.CompressType,
-			"--general-compress-type="+o.CompressType,
-		)
-	} else {
-		args = append(args,
-			"--compress-type="+COMPRESSION_BZ2,
-			"--general-compress-type="+COMPRESSION_BZ2,
-		)
-	}
+	args = append(args,
+		"--compress-type="+o.getCompressType(),
+		"--general-compress-type="+o.getCompressType(),
+	)
 
 	if o.Zchunk {
 		args = append(args, "--zck")
@@ -345,6 +457,91 @@ func (o *Options) GetFilePerms() os.FileMode {
 
 // ////////////////////////////////////////////////////////////////////////////////// //
 
+// getCompressType returns compression type or default compression type if it
+// is not set
+func (o *Options) getCompressType() string {
+	if o.CompressType == "" {
+		return COMPRESSION_BZ2
+	}
+
+	return o.CompressType
+}
+
+// parsePackagesCount extracts number of processed packages from createrepo_c
+// output
+func parsePackagesCount(output string) int {
+	match := packagesCountRegex.FindStringSubmatch(output)
+
+	if len(match) != 2 {
+		return 0
+	}
+
+	count, _ := strconv.Atoi(match[1])
+
+	return count
+}
+
+// readOutput reads createrepo_c output, sends info about generation progress
+// to given channel and returns number of packages found by createrepo_c
+func readOutput(r io.Reader, progress chan<- Progress) int {
+	var done, total int
+
+	scanner := bufio.NewScanner(r)
+
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if total == 0 {
+			total = parsePackagesCount(line)
+
+			if total != 0 {
+				sendProgress(progress, Progress{Total: total})
+			}
+
+			continue
+		}
+
+		if progress != nil && packageProcessingRegex.MatchString(line) {
+			done = min(done+1, total)
+			sendProgress(progress, Progress{Done: done, Total: total})
+		}
+	}
+
+	// Read the rest of output if scanner failed, otherwise createrepo_c
+	// will be blocked on writing to the pipe
+	io.Copy(io.Discard, r)
+
+	return total
+}
+
+// sendProgress sends progress info to given channel without blocking
+func sendProgress(ch chan<- Progress, p Progress) {
+	if ch == nil {
+		return
+	}
+
+	select {
+	case ch <- p:
+	default:
+	}
+}
+
+// getMetaSize returns total size of files in repodata directory
+func getMetaSize(path string) int64 {
+	var size int64
+
+	repodataPath := path + "/repodata"
+
+	objects := fsutil.List(repodataPath, true, fsutil.ListingFilter{Perms: "F"})
+	fsutil.ListToAbsolute(repodataPath, objects)
+
+	for _, obj := range objects {
+		size += fsutil.GetSize(obj)
+	}
+
+	return size
+}
+
 // updateIndexOwner updates owner for repodata directory and files in it
 func updateIndexOwner(path string, options *Options) error {
 	repodataPath := path + "/repodata"
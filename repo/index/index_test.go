@@ -8,8 +8,10 @@ package index
 // ////////////////////////////////////////////////////////////////////////////////// //
 
 import (
+	"context"
 	"errors"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/essentialkaos/ek/v13/fsutil"
@@ -45,13 +47,16 @@ func (s *IndexSuite) TestClone(c *C) {
 		Deltas:         true,
 		CheckSum:       CHECKSUM_SHA384,
 		ChangelogLimit: 17,
+		RetainOldMD:    2,
 		MDFilenames:    MDF_UNIQUE,
 		Distro:         "cpeid,textname",
 		Revision:       "c5af8a1",
+		LocationPrefix: "/el7/",
 		NumDeltas:      8,
 		Workers:        11,
 		CompressType:   COMPRESSION_XZ,
 		Zchunk:         true,
+		NoDatabase:     true,
 
 		User:  "nobody",
 		Group: "nobody",
@@ -93,6 +98,9 @@ func (s *IndexSuite) TestValidate(c *C) {
 
 	c.Assert(opts.Validate(), IsNil)
 
+	opts.CompressType = COMPRESSION_ZSTD
+	c.Assert(opts.Validate(), IsNil)
+
 	opts.CompressType = "unknown"
 	c.Assert(opts.Validate(), NotNil)
 
@@ -105,6 +113,9 @@ func (s *IndexSuite) TestValidate(c *C) {
 	opts.ChangelogLimit = -1
 	c.Assert(opts.Validate(), NotNil)
 
+	opts.RetainOldMD = -1
+	c.Assert(opts.Validate(), NotNil)
+
 	opts.Workers = -1
 	c.Assert(opts.Validate(), NotNil)
 
@@ -135,6 +146,7 @@ func (s *IndexSuite) TestToArg(c *C) {
 		Content:        "test",
 		Distro:         "cpeid,textname",
 		Revision:       "c5af8a1",
+		LocationPrefix: "/el7/",
 		NumDeltas:      8,
 		Workers:        11,
 		CompressType:   COMPRESSION_XZ,
@@ -152,11 +164,12 @@ func (s *IndexSuite) TestToArg(c *C) {
 		"--split",
 		"--skip-symlinks",
 		"--deltas",
+		"--num-deltas=8",
 		"--changelog-limit=17",
 		"--distro=cpeid,textname",
 		"--content=test",
 		"--revision=c5af8a1",
-		"--num-deltas=8",
+		"--location-prefix=/el7/",
 		"--workers=11",
 		"--compress-type=xz",
 		"--general-compress-type=xz",
@@ -178,17 +191,65 @@ func (s *IndexSuite) TestToArg(c *C) {
 		"--split",
 		"--skip-symlinks",
 		"--deltas",
+		"--num-deltas=8",
 		"--changelog-limit=17",
 		"--distro=cpeid,textname",
 		"--content=test",
 		"--revision=c5af8a1",
-		"--num-deltas=8",
+		"--location-prefix=/el7/",
 		"--workers=11",
 		"--compress-type=bz2",
 		"--general-compress-type=bz2",
 		"--zck",
 		"--simple-md-filenames",
 	})
+
+	opts.CompressType = COMPRESSION_ZSTD
+	opts.Zchunk = false
+
+	args = opts.ToArgs()
+
+	c.Assert(args[len(args)-3:], DeepEquals, []string{
+		"--compress-type=zstd",
+		"--general-compress-type=zstd",
+		"--simple-md-filenames",
+	})
+}
+
+func (s *IndexSuite) TestRetainOldMDArgs(c *C) {
+	opts := &Options{RetainOldMD: 4}
+
+	c.Assert(opts.ToArgs()[:2], DeepEquals, []string{
+		"--database", "--retain-old-md=4",
+	})
+
+	opts.RetainOldMD = 0
+
+	c.Assert(opts.ToArgs()[:2], DeepEquals, []string{
+		"--database", "--compress-type=bz2",
+	})
+}
+
+func (s *IndexSuite) TestNoDatabaseArgs(c *C) {
+	opts := &Options{NoDatabase: true}
+
+	c.Assert(opts.ToArgs()[0], Equals, "--no-database")
+
+	opts.NoDatabase = false
+
+	c.Assert(opts.ToArgs()[0], Equals, "--database")
+}
+
+func (s *IndexSuite) TestDeltasArgs(c *C) {
+	opts := &Options{Deltas: true, NumDeltas: 3}
+
+	c.Assert(opts.ToArgs()[:3], DeepEquals, []string{
+		"--database", "--deltas", "--num-deltas=3",
+	})
+
+	opts.Deltas = false
+
+	c.Assert(opts.ToArgs()[1], Equals, "--compress-type=bz2")
 }
 
 func (s *IndexSuite) TestCreaterepo(c *C) {
@@ -201,9 +262,13 @@ func (s *IndexSuite) TestCreaterepo(c *C) {
 		repoDir+"/test-package-1.0.0-0.el7.x86_64.rpm",
 	)
 
-	err := Generate(repoDir, DefaultOptions, true)
+	stats, err := Generate(context.Background(), repoDir, DefaultOptions, true, nil)
 
 	c.Assert(err, IsNil)
+	c.Assert(stats, NotNil)
+	c.Assert(stats.Packages, Equals, 1)
+	c.Assert(stats.Duration > 0, Equals, true)
+	c.Assert(stats.MetaSize > 0, Equals, true)
 	c.Assert(fsutil.IsExist(repoDir+"/repodata/filelists.sqlite.bz2"), Equals, true)
 	c.Assert(fsutil.IsExist(repoDir+"/repodata/filelists.xml.bz2"), Equals, true)
 	c.Assert(fsutil.IsExist(repoDir+"/repodata/other.sqlite.bz2"), Equals, true)
@@ -223,13 +288,13 @@ func (s *IndexSuite) TestCreaterepoWithChown(c *C) {
 		repoDir+"/test-package-1.0.0-0.el7.x86_64.rpm",
 	)
 
-	Generate(repoDir, &Options{
+	Generate(context.Background(), repoDir, &Options{
 		Update:       true,
 		MDFilenames:  MDF_SIMPLE,
 		CompressType: COMPRESSION_BZ2,
 		User:         "nobody",
 		Group:        "nobody",
-	}, false)
+	}, false, nil)
 
 	chownFunc = func(name string, uid, gid int) error {
 		return nil
@@ -264,13 +329,13 @@ func (s *IndexSuite) TestCreaterepoWithChmod(c *C) {
 		repoDir+"/test-package-1.0.0-0.el7.x86_64.rpm",
 	)
 
-	Generate(repoDir, &Options{
+	Generate(context.Background(), repoDir, &Options{
 		Update:       true,
 		MDFilenames:  MDF_SIMPLE,
 		CompressType: COMPRESSION_BZ2,
 		DirPerms:     0700,
 		FilePerms:    0600,
-	}, false)
+	}, false, nil)
 
 	err := updateIndexPerms(repoDir, &Options{})
 	c.Assert(err, IsNil)
@@ -301,11 +366,59 @@ func (s *IndexSuite) TestCreaterepoWithChmod(c *C) {
 }
 
 func (s *IndexSuite) TestCreaterepoErrors(c *C) {
-	err := Generate("/unknown", &Options{GroupFile: "/unknown"}, false)
+	_, err := Generate(context.Background(), "/unknown", &Options{GroupFile: "/unknown"}, false, nil)
 	c.Assert(err, NotNil)
 
-	err = Generate("/unknown", DefaultOptions.Clone(), false)
+	_, err = Generate(context.Background(), "/unknown", DefaultOptions.Clone(), false, nil)
 	c.Assert(err, NotNil)
+
+	repoDir := c.MkDir()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	cancel()
+
+	_, err = Generate(ctx, repoDir, DefaultOptions.Clone(), false, nil)
+	c.Assert(err, NotNil)
+	c.Assert(fsutil.IsExist(repoDir+"/"+TEMP_DIR), Equals, false)
+	c.Assert(fsutil.IsExist(repoDir+"/repodata"), Equals, false)
+}
+
+func (s *IndexSuite) TestReadOutput(c *C) {
+	output := "Directory walk started\nDirectory walk done - 3 packages\n" +
+		"Processing: a-1.0.0-0.el7.x86_64.rpm\n" +
+		"Processing: b-1.0.0-0.el7.x86_64.rpm\n" +
+		"Processing: c-1.0.0-0.el7.noarch.rpm\n" +
+		"Processing: d-1.0.0-0.el7.noarch.rpm\n" +
+		"Pool finished\n"
+
+	progress := make(chan Progress, 10)
+
+	c.Assert(readOutput(strings.NewReader(output), progress), Equals, 3)
+
+	close(progress)
+
+	var updates []Progress
+
+	for p := range progress {
+		updates = append(updates, p)
+	}
+
+	c.Assert(updates, DeepEquals, []Progress{
+		{Done: 0, Total: 3}, {Done: 1, Total: 3}, {Done: 2, Total: 3},
+		{Done: 3, Total: 3}, {Done: 3, Total: 3},
+	})
+
+	c.Assert(readOutput(strings.NewReader(output), nil), Equals, 3)
+	c.Assert(readOutput(strings.NewReader(""), nil), Equals, 0)
+
+	// Updates must not block if nobody reads them
+	c.Assert(readOutput(strings.NewReader(output), make(chan Progress)), Equals, 3)
+}
+
+func (s *IndexSuite) TestParsePackagesCount(c *C) {
+	c.Assert(parsePackagesCount(""), Equals, 0)
+	c.Assert(parsePackagesCount("Directory walk started\nDirectory walk done - 42 packages\nPool finished\n"), Equals, 42)
+	c.Assert(getMetaSize("/unknown"), Equals, int64(0))
 }
 
 func (s *IndexSuite) TestOptionsHelpers(c *C) {
@@ -8,14 +8,22 @@ package repo
 // ////////////////////////////////////////////////////////////////////////////////// //
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"io"
+	"os"
 	"regexp"
+	"slices"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/essentialkaos/ek/v13/fsutil"
+	"github.com/essentialkaos/ek/v13/hash"
 	"github.com/essentialkaos/ek/v13/path"
 	"github.com/essentialkaos/ek/v13/sortutil"
 	"github.com/essentialkaos/ek/v13/strutil"
@@ -23,6 +31,8 @@ import (
 
 	"github.com/essentialkaos/rep/v3/repo/data"
 	"github.com/essentialkaos/rep/v3/repo/helpers"
+	"github.com/essentialkaos/rep/v3/repo/index"
+	"github.com/essentialkaos/rep/v3/repo/meta"
 	"github.com/essentialkaos/rep/v3/repo/rpm"
 	"github.com/essentialkaos/rep/v3/repo/search"
 	"github.com/essentialkaos/rep/v3/repo/sign"
@@ -32,17 +42,40 @@ import (
 // ////////////////////////////////////////////////////////////////////////////////// //
 
 const (
-	_SQL_LIST_ALL       = `SELECT pkgId,name,arch,version,release,epoch,rpm_sourcerpm,location_href FROM packages;`
-	_SQL_LIST_LATEST    = `SELECT pkgId,name,arch,version,release,epoch,rpm_sourcerpm,location_href FROM packages GROUP BY name HAVING MAX(pkgKey);`
-	_SQL_LIST_BY_NAME   = `SELECT pkgId,name,arch,version,release,epoch,rpm_sourcerpm,location_href FROM packages WHERE (name || "-" || version || "-" || release) LIKE @filter ORDER BY rpm_sourcerpm;`
-	_SQL_FIND_BY_KEYS   = `SELECT pkgId,name,arch,version,release,epoch,rpm_sourcerpm,location_href FROM packages WHERE pkgKey in (%s);`
-	_SQL_EXIST          = `SELECT time_file FROM packages WHERE name = @name AND version = @version AND release = @release AND epoch = @epoch;`
-	_SQL_STATS          = `SELECT SUM(size_package),COUNT(*) FROM packages;`
-	_SQL_INFO_BASE      = `SELECT pkgId,name,arch,version,release,epoch,rpm_sourcerpm,location_href,summary,description,url,time_file,time_build,rpm_license,rpm_vendor,rpm_group,size_package,size_installed FROM packages WHERE (name || "-" || version || "-" || release) LIKE @name GROUP BY name HAVING MAX(time_build) LIMIT 1;`
-	_SQL_INFO_FILES     = `SELECT f.dirname,f.filenames,f.filetypes FROM filelist f INNER JOIN packages p ON f.pkgKey = p.pkgKey WHERE p.pkgId = @id ORDER BY f.dirname,f.filenames;`
-	_SQL_INFO_REQUIRES  = `SELECT r.name,r.flags,r.epoch,r.version,r.release FROM requires r INNER JOIN packages p ON r.pkgKey = p.pkgKey WHERE p.pkgId = @id ORDER BY r.name;`
-	_SQL_INFO_PROVIDES  = `SELECT r.name,r.flags,r.epoch,r.version,r.release FROM provides r INNER JOIN packages p ON r.pkgKey = p.pkgKey WHERE p.pkgId = @id ORDER BY r.name;`
-	_SQL_INFO_CHANGELOG = `SELECT c.author,c.date,c.changelog FROM changelog c INNER JOIN packages p ON c.pkgKey = p.pkgKey WHERE p.pkgId = @id AND c.author LIKE @version ORDER BY c.date DESC LIMIT 1;`
+	_SQL_LIST_ALL         = `SELECT pkgId,name,arch,version,release,epoch,rpm_sourcerpm,location_href,size_package,time_file,time_build FROM packages;`
+	_SQL_LIST_LATEST      = `SELECT pkgId,name,arch,version,release,epoch,rpm_sourcerpm,location_href,size_package,time_file,time_build FROM packages GROUP BY name HAVING MAX(pkgKey);`
+	_SQL_LIST_BY_NAME     = `SELECT pkgId,name,arch,version,release,epoch,rpm_sourcerpm,location_href,size_package,time_file,time_build FROM packages WHERE (name || "-" || version || "-" || release) LIKE @filter ORDER BY rpm_sourcerpm;`
+	_SQL_LIST_BY_GLOB     = `SELECT pkgId,name,arch,version,release,epoch,rpm_sourcerpm,location_href,size_package,time_file,time_build FROM packages WHERE name GLOB @filter OR (name || "-" || version || "-" || release) GLOB @filter ORDER BY rpm_sourcerpm;`
+	_SQL_FIND_BY_KEYS     = `SELECT pkgId,name,arch,version,release,epoch,rpm_sourcerpm,location_href,size_package,time_file,time_build FROM packages WHERE pkgKey in (%s);`
+	_SQL_FIND_IDS         = `SELECT pkgKey,pkgId FROM packages WHERE pkgKey in (%s);`
+	_SQL_EXIST            = `SELECT time_file FROM packages WHERE name = @name AND version = @version AND release = @release AND COALESCE(NULLIF(epoch, ''), '0') = @epoch;`
+	_SQL_STATS            = `SELECT SUM(size_package),COUNT(*) FROM packages;`
+	_SQL_INFO_BASE        = `SELECT pkgId,name,arch,version,release,epoch,rpm_sourcerpm,location_href,summary,description,url,time_file,time_build,rpm_license,rpm_vendor,rpm_group,rpm_packager,size_package,size_installed FROM packages WHERE (name || "-" || version || "-" || release) LIKE @name GROUP BY name HAVING MAX(time_build) LIMIT 1;`
+	_SQL_INFO_FILES       = `SELECT f.dirname,f.filenames,f.filetypes FROM filelist f INNER JOIN packages p ON f.pkgKey = p.pkgKey WHERE p.pkgId = @id ORDER BY f.dirname,f.filenames;`
+	_SQL_INFO_REQUIRES    = `SELECT r.name,r.flags,r.epoch,r.version,r.release FROM requires r INNER JOIN packages p ON r.pkgKey = p.pkgKey WHERE p.pkgId = @id ORDER BY r.name;`
+	_SQL_INFO_PROVIDES    = `SELECT r.name,r.flags,r.epoch,r.version,r.release FROM provides r INNER JOIN packages p ON r.pkgKey = p.pkgKey WHERE p.pkgId = @id ORDER BY r.name;`
+	_SQL_INFO_CONFLICTS   = `SELECT r.name,r.flags,r.epoch,r.version,r.release FROM conflicts r INNER JOIN packages p ON r.pkgKey = p.pkgKey WHERE p.pkgId = @id ORDER BY r.name;`
+	_SQL_INFO_OBSOLETES   = `SELECT r.name,r.flags,r.epoch,r.version,r.release FROM obsoletes r INNER JOIN packages p ON r.pkgKey = p.pkgKey WHERE p.pkgId = @id ORDER BY r.name;`
+	_SQL_INFO_RECOMMENDS  = `SELECT r.name,r.flags,r.epoch,r.version,r.release FROM recommends r INNER JOIN packages p ON r.pkgKey = p.pkgKey WHERE p.pkgId = @id ORDER BY r.name;`
+	_SQL_INFO_SUGGESTS    = `SELECT r.name,r.flags,r.epoch,r.version,r.release FROM suggests r INNER JOIN packages p ON r.pkgKey = p.pkgKey WHERE p.pkgId = @id ORDER BY r.name;`
+	_SQL_INFO_ENHANCES    = `SELECT r.name,r.flags,r.epoch,r.version,r.release FROM enhances r INNER JOIN packages p ON r.pkgKey = p.pkgKey WHERE p.pkgId = @id ORDER BY r.name;`
+	_SQL_INFO_SUPPLEMENTS = `SELECT r.name,r.flags,r.epoch,r.version,r.release FROM supplements r INNER JOIN packages p ON r.pkgKey = p.pkgKey WHERE p.pkgId = @id ORDER BY r.name;`
+	_SQL_PROVIDES_DUPS    = `SELECT r.name,GROUP_CONCAT(DISTINCT p.name) FROM provides r INNER JOIN packages p ON r.pkgKey = p.pkgKey GROUP BY r.name HAVING COUNT(DISTINCT p.name) > 1 ORDER BY r.name;`
+	_SQL_DEP_NODES        = `SELECT DISTINCT name FROM packages ORDER BY name;`
+	_SQL_DEP_EDGES        = `SELECT DISTINCT rp.name,pp.name FROM requires r INNER JOIN provides pr ON r.name = pr.name INNER JOIN packages rp ON r.pkgKey = rp.pkgKey INNER JOIN packages pp ON pr.pkgKey = pp.pkgKey WHERE rp.name != pp.name UNION SELECT DISTINCT rp.name,pp.name FROM requires r INNER JOIN files f ON r.name = f.name INNER JOIN packages rp ON r.pkgKey = rp.pkgKey INNER JOIN packages pp ON f.pkgKey = pp.pkgKey WHERE rp.name != pp.name;`
+	_SQL_INFO_CHANGELOG   = `SELECT c.author,c.date,c.changelog FROM changelog c INNER JOIN packages p ON c.pkgKey = p.pkgKey WHERE p.pkgId = @id AND c.author LIKE @version ORDER BY c.date DESC LIMIT 1;`
+	_SQL_META_FIELDS      = `SELECT name,version,release,arch,%s FROM packages;`
+)
+
+// Package metadata fields which can be checked for emptiness
+const (
+	META_FIELD_SUMMARY     = "summary"
+	META_FIELD_DESCRIPTION = "description"
+	META_FIELD_URL         = "url"
+	META_FIELD_LICENSE     = "license"
+	META_FIELD_VENDOR      = "vendor"
+	META_FIELD_GROUP       = "group"
+	META_FIELD_PACKAGER    = "packager"
 )
 
 // ////////////////////////////////////////////////////////////////////////////////// //
@@ -53,8 +86,14 @@ type Repository struct {
 	DefaultArch string
 	FileFilter  string
 	Replace     bool
+	CacheInfo   bool   // Enable in-memory cache for packages info
+	TempDir     string // Directory for temporary data (system temporary directory by default)
+
+	// Context is used for canceling long DB queries (context.Background() by default)
+	Context context.Context
 
-	SigningKey *sign.ArmoredKey
+	SigningKey  *sign.ArmoredKey   // Primary key used for signing packages
+	TrustedKeys []*sign.ArmoredKey // Additional keys used only for signature verification
 
 	Testing *SubRepository // Testing sub-repository (with unstable packages)
 	Release *SubRepository // Release sub-repository (with stable packages)
@@ -66,6 +105,15 @@ type Repository struct {
 type SubRepository struct {
 	Name   string      // Sub-repository name
 	Parent *Repository // Pointer to parent repository
+
+	infoCache     map[string]*packageInfoCache // arch → packages info cache
+	infoCacheLock sync.Mutex
+}
+
+// packageInfoCache contains cached packages info for one arch
+type packageInfoCache struct {
+	Revision int64               // Index revision
+	Packages map[string]*Package // NEVRA → package info
 }
 
 // RepositoryStats contains repository stats data
@@ -77,17 +125,26 @@ type RepositoryStats struct {
 	Updated       time.Time
 }
 
+// ReindexStats contains index generation statistics for every reindexed arch
+// (arch → stats, nil if reindex for arch was skipped)
+type ReindexStats map[string]*index.GenerateStats
+
 // Package contains info about package
 type Package struct {
-	Name      string        // Name
-	Version   string        // Version
-	Release   string        // Release
-	Epoch     string        // Epoch
-	ArchFlags data.ArchFlag // Archs flag
-	Src       string        // Source package name
-	Files     PackageFiles  // RPM files list
+	Name      string        `json:"name"`    // Name
+	Version   string        `json:"version"` // Version
+	Release   string        `json:"release"` // Release
+	Epoch     string        `json:"epoch"`   // Epoch
+	ArchFlags data.ArchFlag `json:"archs"`   // Archs flag
+	Src       string        `json:"source"`  // Source package name
+	Files     PackageFiles  `json:"files"`   // RPM files list
+
+	Size      uint64    `json:"-"` // Total size of all package files in bytes
+	DateAdded time.Time `json:"-"` // Date when the latest package file was added
+	DateBuild time.Time `json:"-"` // Build date of the latest package file
 
-	Info *PackageInfo // Additional info
+	Info    *PackageInfo   `json:"info,omitempty"` // Additional info
+	Matches PackageMatches `json:"-"`              // Search terms matches (only for FindWithMatches)
 }
 
 // PackageFiles is slice with package files
@@ -95,21 +152,27 @@ type PackageFiles []PackageFile
 
 // PackageInfo contains additional information about package
 type PackageInfo struct {
-	Summary       string            // Summary
-	Desc          string            // Description
-	URL           string            // URL
-	Vendor        string            // Vendor
-	Packager      string            // Packager
-	Group         string            // Group
-	License       string            // License
-	SizePackage   uint64            // Size of package in bytes
-	SizeInstalled uint64            // Size of installed data in bytes
-	DateAdded     time.Time         // Add date as unix timestamp
-	DateBuild     time.Time         // Build date as unix timestamp
-	Changelog     *PackageChangelog // Changelog records
-	Requires      []data.Dependency // Requires
-	Provides      []data.Dependency // Provides
-	Payload       PackagePayload    // Files and directories
+	Summary       string            `json:"summary"`               // Summary
+	Desc          string            `json:"description"`           // Description
+	URL           string            `json:"url"`                   // URL
+	Vendor        string            `json:"vendor"`                // Vendor
+	Packager      string            `json:"packager"`              // Packager
+	Group         string            `json:"group"`                 // Group
+	License       string            `json:"license"`               // License
+	SizePackage   uint64            `json:"size_package"`          // Size of package in bytes
+	SizeInstalled uint64            `json:"size_installed"`        // Size of installed data in bytes
+	DateAdded     time.Time         `json:"date_added"`            // Add date as unix timestamp
+	DateBuild     time.Time         `json:"date_build"`            // Build date as unix timestamp
+	Changelog     *PackageChangelog `json:"changelog,omitempty"`   // Changelog records
+	Requires      []data.Dependency `json:"requires,omitempty"`    // Requires
+	Provides      []data.Dependency `json:"provides,omitempty"`    // Provides
+	Conflicts     []data.Dependency `json:"conflicts,omitempty"`   // Conflicts
+	Obsoletes     []data.Dependency `json:"obsoletes,omitempty"`   // Obsoletes
+	Recommends    []data.Dependency `json:"recommends,omitempty"`  // Recommends (weak dependency)
+	Suggests      []data.Dependency `json:"suggests,omitempty"`    // Suggests (weak dependency)
+	Enhances      []data.Dependency `json:"enhances,omitempty"`    // Enhances (weak reverse dependency)
+	Supplements   []data.Dependency `json:"supplements,omitempty"` // Supplements (weak reverse dependency)
+	Payload       PackagePayload    `json:"payload,omitempty"`     // Files and directories
 }
 
 // PackagePayload is a slice with info about package files or directories
@@ -117,31 +180,82 @@ type PackagePayload []PayloadObject
 
 // PackageChangelog contains changelog data
 type PackageChangelog struct {
-	Records []string
-	Author  string
-	Date    time.Time
+	Records []string  `json:"records"`
+	Author  string    `json:"author"`
+	Date    time.Time `json:"date"`
 }
 
 // PackageFile contains info about package file
 type PackageFile struct {
-	CRC          string        // File checksum (first 7 symbols)
-	Path         string        // Path to file
-	ArchFlag     data.ArchFlag // Package arch flag
-	BaseArchFlag data.ArchFlag // Sub-repo (i.e. directory arch) flag
+	CRC          string        `json:"crc"`       // File checksum (first 7 symbols)
+	Path         string        `json:"path"`      // Path to file
+	ArchFlag     data.ArchFlag `json:"arch"`      // Package arch flag
+	BaseArchFlag data.ArchFlag `json:"base_arch"` // Sub-repo (i.e. directory arch) flag
 }
 
 // PayloadObject contains info about file or directory
 type PayloadObject struct {
-	IsDir bool
-	Path  string
+	IsDir bool   `json:"is_dir"`
+	Path  string `json:"path"`
+}
+
+// PackageMatch contains info about search term satisfied by package
+type PackageMatch struct {
+	Term  *search.Term // Search term
+	Value string       // Matched value
 }
 
+// PackageMatches is slice with search terms matches
+type PackageMatches []PackageMatch
+
 // PackageBundle is slice of packages built from one source RPM
 type PackageBundle []*Package
 
 // PackageStack is slice with package bundles
 type PackageStack []PackageBundle
 
+// PackageStackBySize is package stack sorted by bundles size (biggest first)
+type PackageStackBySize struct{ PackageStack }
+
+// PackageStackByDateAdd is package stack sorted by date when bundles were added
+// to repository (newest first)
+type PackageStackByDateAdd struct{ PackageStack }
+
+// PackageStackByDateBuild is package stack sorted by bundles build date
+// (newest first)
+type PackageStackByDateBuild struct{ PackageStack }
+
+// ProvidesCollision contains info about capability provided by more than one package
+type ProvidesCollision struct {
+	Name      string        // Capability name
+	Packages  []string      // Names of packages which provide capability
+	ArchFlags data.ArchFlag // Archs flag
+}
+
+// ProvidesCollisions is slice with provides collisions
+type ProvidesCollisions []*ProvidesCollision
+
+// EmptyMetaFields contains info about package with empty metadata fields
+type EmptyMetaFields struct {
+	Package string   // Package full name with arch
+	Fields  []string // Names of empty fields
+}
+
+// EmptyMetaFieldsList is slice with info about packages with empty metadata fields
+type EmptyMetaFieldsList []*EmptyMetaFields
+
+// DepGraph contains graph of dependencies between packages in repository
+type DepGraph struct {
+	Nodes []string  `json:"nodes"` // Packages names
+	Edges []DepEdge `json:"edges"` // Dependencies between packages
+}
+
+// DepEdge contains info about dependency between packages
+type DepEdge struct {
+	From string `json:"from"` // Name of package which requires capability
+	To   string `json:"to"`   // Name of package which provides capability
+}
+
 // ////////////////////////////////////////////////////////////////////////////////// //
 
 // packageStackBuilder contains packages info for data grouping
@@ -162,6 +276,25 @@ var (
 
 // ////////////////////////////////////////////////////////////////////////////////// //
 
+// MetaFields is slice with names of all metadata fields which can be checked
+var MetaFields = []string{
+	META_FIELD_SUMMARY, META_FIELD_DESCRIPTION, META_FIELD_URL, META_FIELD_LICENSE,
+	META_FIELD_VENDOR, META_FIELD_GROUP, META_FIELD_PACKAGER,
+}
+
+// metaFieldColumns is map [metadata field] → [primary DB column]
+var metaFieldColumns = map[string]string{
+	META_FIELD_SUMMARY:     "summary",
+	META_FIELD_DESCRIPTION: "description",
+	META_FIELD_URL:         "url",
+	META_FIELD_LICENSE:     "rpm_license",
+	META_FIELD_VENDOR:      "rpm_vendor",
+	META_FIELD_GROUP:       "rpm_group",
+	META_FIELD_PACKAGER:    "rpm_packager",
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
 // repoNameValidationRegex is regex pattern for repository name validation
 var repoNameValidationRegex = regexp.MustCompile(`[0-9a-zA-Z_\-]+`)
 
@@ -197,8 +330,101 @@ func NewSubRepository(name string) *SubRepository {
 	return &SubRepository{Name: name}
 }
 
+// ReadPackageFile reads info about package directly from RPM file
+func ReadPackageFile(rpmFile string) (*Package, error) {
+	if rpmFile == "" {
+		return nil, ErrEmptyPath
+	}
+
+	if !rpm.IsRPM(rpmFile) {
+		return nil, fmt.Errorf("File %s is not an RPM package", rpmFile)
+	}
+
+	info, err := rpm.ReadInfo(rpmFile)
+
+	if err != nil {
+		return nil, fmt.Errorf("Can't read package info: %w", err)
+	}
+
+	pkg := &Package{
+		Name:      info.Name,
+		Version:   info.Version,
+		Release:   info.Release,
+		Epoch:     info.Epoch,
+		ArchFlags: data.SupportedArchs[info.Arch].Flag,
+		Src:       info.Src,
+		Files: PackageFiles{PackageFile{
+			CRC:      strutil.Head(hash.FileHash(rpmFile), 7),
+			Path:     rpmFile,
+			ArchFlag: data.SupportedArchs[info.Arch].Flag,
+		}},
+		Info: &PackageInfo{
+			Summary:       info.Summary,
+			Desc:          info.Desc,
+			URL:           info.URL,
+			Vendor:        info.Vendor,
+			Packager:      info.Packager,
+			Group:         info.Group,
+			License:       info.License,
+			SizePackage:   uint64(fsutil.GetSize(rpmFile)),
+			SizeInstalled: info.SizeInstalled,
+			DateBuild:     info.DateBuild,
+			Requires:      info.Requires,
+			Provides:      info.Provides,
+		},
+	}
+
+	for _, obj := range info.Payload {
+		pkg.Info.Payload = append(pkg.Info.Payload, PayloadObject{obj.IsDir, obj.Path})
+	}
+
+	if info.Changelog != nil {
+		pkg.Info.Changelog = &PackageChangelog{
+			Records: strings.Split(info.Changelog.Text, "\n"),
+			Author:  info.Changelog.Author,
+			Date:    info.Changelog.Date,
+		}
+	}
+
+	return pkg, nil
+}
+
 // ////////////////////////////////////////////////////////////////////////////////// //
 
+// clone returns deep copy of package
+func (p *Package) clone() *Package {
+	if p == nil {
+		return nil
+	}
+
+	pkg := *p
+	pkg.Files = slices.Clone(p.Files)
+	pkg.Matches = slices.Clone(p.Matches)
+
+	if p.Info != nil {
+		info := *p.Info
+		info.Requires = slices.Clone(p.Info.Requires)
+		info.Provides = slices.Clone(p.Info.Provides)
+		info.Conflicts = slices.Clone(p.Info.Conflicts)
+		info.Obsoletes = slices.Clone(p.Info.Obsoletes)
+		info.Recommends = slices.Clone(p.Info.Recommends)
+		info.Suggests = slices.Clone(p.Info.Suggests)
+		info.Enhances = slices.Clone(p.Info.Enhances)
+		info.Supplements = slices.Clone(p.Info.Supplements)
+		info.Payload = slices.Clone(p.Info.Payload)
+
+		if p.Info.Changelog != nil {
+			changelog := *p.Info.Changelog
+			changelog.Records = slices.Clone(p.Info.Changelog.Records)
+			info.Changelog = &changelog
+		}
+
+		pkg.Info = &info
+	}
+
+	return &pkg
+}
+
 // FullName returns full package name
 func (p *Package) FullName() string {
 	if p == nil {
@@ -251,6 +477,58 @@ func (b PackageBundle) Size() int {
 	return size
 }
 
+// TotalSize returns total size of all packages files in bundle
+func (b PackageBundle) TotalSize() uint64 {
+	var size uint64
+
+	for _, pkg := range b {
+		if pkg != nil {
+			size += pkg.Size
+		}
+	}
+
+	return size
+}
+
+// LatestDateAdded returns the latest date when package from bundle was added
+func (b PackageBundle) LatestDateAdded() time.Time {
+	var date time.Time
+
+	for _, pkg := range b {
+		if pkg != nil {
+			date = latestDate(date, pkg.DateAdded)
+		}
+	}
+
+	return date
+}
+
+// LatestDateBuild returns the latest build date of packages in bundle
+func (b PackageBundle) LatestDateBuild() time.Time {
+	var date time.Time
+
+	for _, pkg := range b {
+		if pkg != nil {
+			date = latestDate(date, pkg.DateBuild)
+		}
+	}
+
+	return date
+}
+
+// FlattenFiles returns slice with all packages files in bundle
+func (b PackageBundle) FlattenFiles() PackageFiles {
+	var result PackageFiles
+
+	for _, pkg := range b {
+		if pkg != nil {
+			result = append(result, pkg.Files...)
+		}
+	}
+
+	return result
+}
+
 // ////////////////////////////////////////////////////////////////////////////////// //
 
 // HasMultiBundles returns true if stack contains bundle with more than 1 package
@@ -333,6 +611,49 @@ func (s PackageStack) FlattenFiles() PackageFiles {
 	return result
 }
 
+// Compact returns copy of stack without empty bundles and nil packages
+func (s PackageStack) Compact() PackageStack {
+	result := PackageStack{}
+
+	for _, bundle := range s {
+		var compactBundle PackageBundle
+
+		for _, pkg := range bundle {
+			if pkg != nil {
+				compactBundle = append(compactBundle, pkg)
+			}
+		}
+
+		if len(compactBundle) != 0 {
+			result = append(result, compactBundle)
+		}
+	}
+
+	return result
+}
+
+// Slice returns part of stack with given number of non-empty bundles starting
+// from given offset (limit 0 means no limit)
+func (s PackageStack) Slice(offset, limit int) PackageStack {
+	result := s.Compact()
+
+	if offset <= 0 && limit <= 0 {
+		return result
+	}
+
+	if offset >= len(result) {
+		return PackageStack{}
+	}
+
+	result = result[max(offset, 0):]
+
+	if limit > 0 && limit < len(result) {
+		result = result[:limit]
+	}
+
+	return result
+}
+
 // IsEmpty returns true if package stack is empty
 func (s PackageStack) IsEmpty() bool {
 	for _, bundle := range s {
@@ -348,6 +669,38 @@ func (s PackageStack) IsEmpty() bool {
 
 // ////////////////////////////////////////////////////////////////////////////////// //
 
+// Merge adds stats data from other stats to the current one
+func (s *RepositoryStats) Merge(other *RepositoryStats) {
+	if s == nil || other == nil {
+		return
+	}
+
+	if s.Packages == nil {
+		s.Packages = make(map[string]int)
+	}
+
+	if s.Sizes == nil {
+		s.Sizes = make(map[string]int64)
+	}
+
+	for arch, count := range other.Packages {
+		s.Packages[arch] += count
+	}
+
+	for arch, size := range other.Sizes {
+		s.Sizes[arch] += size
+	}
+
+	s.TotalPackages += other.TotalPackages
+	s.TotalSize += other.TotalSize
+
+	if other.Updated.After(s.Updated) {
+		s.Updated = other.Updated
+	}
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
 // Initialize initializes the new repository and creates all required directories
 func (r *Repository) Initialize(archList []string) error {
 	return r.storage.Initialize(
@@ -391,7 +744,54 @@ func (r *Repository) Info(name, arch string) (*Package, time.Time, error) {
 	return pkg, releaseDate, nil
 }
 
-// CopyPackage copies packages between sub-repositories
+// ReleaseOnly returns stack with packages which present in release repository,
+// but absent in testing repository
+func (r *Repository) ReleaseOnly() (PackageStack, error) {
+	if !r.storage.IsInitialized() {
+		return nil, ErrNotInitialized
+	}
+
+	releaseStack, err := r.Release.List("", true)
+
+	if err != nil {
+		return nil, err
+	}
+
+	testingStack, err := r.Testing.List("", true)
+
+	if err != nil {
+		return nil, err
+	}
+
+	testingIndex := make(map[string]bool)
+
+	for _, bundle := range testingStack {
+		for _, pkg := range bundle {
+			testingIndex[pkg.FullName()] = true
+		}
+	}
+
+	result := PackageStack{}
+
+	for _, bundle := range releaseStack {
+		var releaseBundle PackageBundle
+
+		for _, pkg := range bundle {
+			if !testingIndex[pkg.FullName()] {
+				releaseBundle = append(releaseBundle, pkg)
+			}
+		}
+
+		if len(releaseBundle) != 0 {
+			result = append(result, releaseBundle)
+		}
+	}
+
+	return result, nil
+}
+
+// CopyPackage copies packages between sub-repositories. Sub-repositories may
+// belong to different repositories with different storages.
 func (r *Repository) CopyPackage(source, target *SubRepository, packageFile PackageFile) error {
 	if !r.storage.IsInitialized() {
 		return ErrNotInitialized
@@ -406,12 +806,91 @@ func (r *Repository) CopyPackage(source, target *SubRepository, packageFile Pack
 		return ErrEmptyPath
 	}
 
-	return r.storage.CopyPackage(
+	return storage.Copy(
+		source.Parent.storage, target.Parent.storage,
 		source.Name, target.Name,
 		packageFile.BaseArchFlag.String(), packageFile.Path,
+		r.TempDir,
 	)
 }
 
+// CopyPackages copies all given package files from one sub-repository to another
+// with all-or-nothing semantics. All files are staged to temporary directory first
+// and added to target sub-repository only if all of them were staged. Files which
+// are replaced in target sub-repository are backed up, so if adding of any file
+// fails or context is canceled, target sub-repository is restored to its initial
+// state.
+// Important: This method DO NOT run repository reindex
+func (r *Repository) CopyPackages(ctx context.Context, source, target *SubRepository, files PackageFiles) error {
+	if !r.storage.IsInitialized() {
+		return ErrNotInitialized
+	}
+
+	switch {
+	case source == nil:
+		return fmt.Errorf("Source sub-repository is nil")
+	case target == nil:
+		return fmt.Errorf("Target sub-repository is nil")
+	}
+
+	tmpDir, err := os.MkdirTemp(r.TempDir, "rep-")
+
+	if err != nil {
+		return fmt.Errorf("Can't create staging directory: %w", err)
+	}
+
+	defer os.RemoveAll(tmpDir)
+
+	staged := make([]string, len(files))
+	backups := make([]string, len(files))
+
+	for i, file := range files {
+		if ctx.Err() != nil {
+			return fmt.Errorf("Can't copy packages: %w", context.Cause(ctx))
+		}
+
+		staged[i] = path.Join(tmpDir, "stage", strconv.Itoa(i), path.Base(file.Path))
+		err = savePackageFile(source, file, staged[i])
+
+		if err != nil {
+			return fmt.Errorf("Can't stage package %s: %w", file.Path, err)
+		}
+
+		if !target.Parent.storage.HasPackage(target.Name, file.BaseArchFlag.String(), path.Base(file.Path)) {
+			continue
+		}
+
+		backups[i] = path.Join(tmpDir, "backup", strconv.Itoa(i), path.Base(file.Path))
+		err = savePackageFile(target, file, backups[i])
+
+		if err != nil {
+			return fmt.Errorf("Can't back up package %s: %w", file.Path, err)
+		}
+	}
+
+	for i, file := range files {
+		err = context.Cause(ctx)
+
+		if err == nil {
+			err = target.Parent.storage.AddPackage(target.Name, staged[i])
+		}
+
+		if err != nil {
+			// Adding of the current file could be partially done (e.g. noarch
+			// package copied only to some arch directories), so roll it back too
+			rollbackErr := rollbackCopying(target, files[:i+1], backups[:i+1])
+
+			if rollbackErr != nil {
+				return fmt.Errorf("Can't copy package %s: %w (rollback failed: %v)", file.Path, err, rollbackErr)
+			}
+
+			return fmt.Errorf("Can't copy package %s: %w", file.Path, err)
+		}
+	}
+
+	return nil
+}
+
 // IsPackageReleased checks if package was released
 func (r *Repository) IsPackageReleased(pkg *Package) (bool, time.Time, error) {
 	if !r.storage.IsInitialized() {
@@ -464,69 +943,174 @@ func (r *Repository) ReadSigningKey(file string) error {
 	return err
 }
 
-// IsSigningRequired returns true if signing key is set and repository
-// requires package signing
-func (r *Repository) IsSigningRequired() bool {
-	return r.SigningKey != nil
-}
+// ReadAgentSigningKey reads public part of signing key with given ID from GPG
+// keyring, private key is used through GPG agent
+func (r *Repository) ReadAgentSigningKey(keyID string) error {
+	var err error
 
-// HasArch returns true if release and testing repositories have given arch
-func (r *Repository) HasArch(arch string) bool {
-	return r.Testing.HasArch(arch) && r.Release.HasArch(arch)
+	r.SigningKey, err = sign.ReadAgentKey(keyID)
+
+	return err
 }
 
-// PurgeCache removes all cached data
-func (r *Repository) PurgeCache() error {
-	err := r.storage.PurgeCache()
+// ReadTrustedKeys reads additional keys used only for packages signature
+// verification (e.g. previous signing keys after key rotation)
+func (r *Repository) ReadTrustedKeys(files ...string) error {
+	for _, file := range files {
+		key, err := sign.ReadPublicKey(file)
 
-	if err != nil {
-		return err
+		if err != nil {
+			return fmt.Errorf("Can't read trusted key %s: %w", file, err)
+		}
+
+		r.TrustedKeys = append(r.TrustedKeys, key)
 	}
 
-	return r.storage.InvalidateCache()
+	return nil
 }
 
-// ////////////////////////////////////////////////////////////////////////////////// //
+// ReadVerificationKeys reads public parts of signing key and all trusted keys
+// which can be used for packages signature verification
+func (r *Repository) ReadVerificationKeys() ([]*sign.Key, error) {
+	var keys []*sign.Key
 
-// AddPackage copies given file into sub-repository storage
-// Important: This method DO NOT run repository reindex
-func (r *SubRepository) AddPackage(rpmFilePath string) error {
-	switch {
-	case rpmFilePath == "":
-		return fmt.Errorf("Can't add package to repository: %w", ErrEmptyPath)
-	case !r.Parent.storage.IsInitialized():
-		return fmt.Errorf("Can't add package to repository: %w", ErrNotInitialized)
-	}
+	if r.SigningKey != nil {
+		key, err := r.SigningKey.Read(nil)
 
-	err := fsutil.ValidatePerms("FRS", rpmFilePath)
+		if err != nil {
+			return nil, err
+		}
 
-	if err != nil {
-		return fmt.Errorf("Can't add package to repository: %w", err)
+		keys = append(keys, key)
 	}
 
-	if !rpm.IsRPM(rpmFilePath) {
-		return fmt.Errorf("Can't add file to repository: %s is not an RPM package", rpmFilePath)
-	}
-
-	if r.Parent.SigningKey != nil {
-		key, err := r.Parent.SigningKey.Read(nil)
+	for _, armoredKey := range r.TrustedKeys {
+		key, err := armoredKey.Read(nil)
 
 		if err != nil {
-			return fmt.Errorf("Can't add file to repository: %w", err)
+			return nil, err
 		}
 
-		isSignValid, err := sign.IsPackageSignatureValid(rpmFilePath, key)
+		keys = append(keys, key)
+	}
+
+	return keys, nil
+}
+
+// IsSigningRequired returns true if signing key is set and repository
+// requires package signing
+func (r *Repository) IsSigningRequired() bool {
+	return r.SigningKey != nil
+}
+
+// HasArch returns true if release and testing repositories have given arch
+func (r *Repository) HasArch(arch string) bool {
+	return r.Testing.HasArch(arch) && r.Release.HasArch(arch)
+}
+
+// ReadAuxData reads auxiliary data (e.g. packages tags) with given name. If
+// data doesn't exist, nil is returned.
+func (r *Repository) ReadAuxData(name string) ([]byte, error) {
+	return r.storage.ReadAuxData(name)
+}
+
+// WriteAuxData atomically writes auxiliary data (e.g. packages tags) with
+// given name
+func (r *Repository) WriteAuxData(name string, data []byte) error {
+	return r.storage.WriteAuxData(name, data)
+}
+
+// InvalidateCache closes all DB connections and removes in-memory cached data
+func (r *Repository) InvalidateCache() error {
+	return r.storage.InvalidateCache()
+}
+
+// PurgeCache removes all cached data
+func (r *Repository) PurgeCache() error {
+	err := r.storage.PurgeCache()
+
+	if err != nil {
+		return err
+	}
+
+	return r.storage.InvalidateCache()
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// AddPackage copies given file into sub-repository storage
+// Important: This method DO NOT run repository reindex
+func (r *SubRepository) AddPackage(rpmFilePath string) error {
+	switch {
+	case rpmFilePath == "":
+		return fmt.Errorf("Can't add package to repository: %w", ErrEmptyPath)
+	case !r.Parent.storage.IsInitialized():
+		return fmt.Errorf("Can't add package to repository: %w", ErrNotInitialized)
+	}
+
+	keys, err := r.readVerificationKeys()
+
+	if err != nil {
+		return fmt.Errorf("Can't add file to repository: %w", err)
+	}
+
+	err = checkPackageFile(rpmFilePath, keys)
+
+	if err != nil {
+		return err
+	}
+
+	return r.Parent.storage.AddPackage(r.Name, rpmFilePath)
+}
+
+// AddPackages copies given files into sub-repository storage and returns paths
+// (relative to repository directory) of added files
+// Important: This method DO NOT run repository reindex
+func (r *SubRepository) AddPackages(rpmFilePaths []string) ([]string, error) {
+	switch {
+	case len(rpmFilePaths) == 0:
+		return nil, fmt.Errorf("Can't add packages to repository: %w", ErrEmptyPath)
+	case !r.Parent.storage.IsInitialized():
+		return nil, fmt.Errorf("Can't add packages to repository: %w", ErrNotInitialized)
+	}
+
+	keys, err := r.readVerificationKeys()
+
+	if err != nil {
+		return nil, fmt.Errorf("Can't add packages to repository: %w", err)
+	}
+
+	var files []string
+	var errs []error
+
+	for _, rpmFilePath := range rpmFilePaths {
+		err = checkPackageFile(rpmFilePath, keys)
 
 		if err != nil {
-			return fmt.Errorf("Can't add file to repository: %w", err)
+			errs = append(errs, fmt.Errorf("%s: %w", path.Base(rpmFilePath), err))
+			continue
 		}
 
-		if !isSignValid {
-			return fmt.Errorf("Can't add file to repository: Repository allows only signed packages")
-		}
+		files = append(files, rpmFilePath)
 	}
 
-	return r.Parent.storage.AddPackage(r.Name, rpmFilePath)
+	if len(files) == 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	added, err := r.Parent.storage.AddPackages(r.Name, files)
+
+	return added, errors.Join(append(errs, err)...)
+}
+
+// readVerificationKeys reads keys for checking signature of added packages if
+// repository requires package signing
+func (r *SubRepository) readVerificationKeys() ([]*sign.Key, error) {
+	if !r.Parent.IsSigningRequired() {
+		return nil, nil
+	}
+
+	return r.Parent.ReadVerificationKeys()
 }
 
 // RemovePackage removes package with given relative path from sub-repository storage
@@ -631,6 +1215,11 @@ func (r *SubRepository) List(filter string, all bool) (PackageStack, error) {
 		psb, err = r.listPackages(_SQL_LIST_ALL)
 	case !all && filter == "":
 		psb, err = r.listPackages(_SQL_LIST_LATEST)
+	case path.IsGlob(filter):
+		psb, err = r.listPackages(
+			_SQL_LIST_BY_GLOB,
+			sql.Named("filter", strutil.ReplaceAll(filter, "'\"", "")),
+		)
 	default:
 		psb, err = r.listPackages(
 			_SQL_LIST_BY_NAME,
@@ -647,60 +1236,64 @@ func (r *SubRepository) List(filter string, all bool) (PackageStack, error) {
 
 // Find tries to find packages by given search query
 func (r *SubRepository) Find(query search.Query) (PackageStack, error) {
-	if !r.Parent.storage.IsInitialized() {
-		return nil, ErrNotInitialized
-	}
-
-	if len(query) == 0 {
-		return PackageStack{}, nil
-	}
-
-	errs := query.Validate()
-
-	if len(errs) != 0 {
-		return nil, errs[0]
-	}
+	return r.find(query, false)
+}
 
-	psb, err := r.searchPackages(query)
+// FindWithMatches tries to find packages by given search query and collects info
+// about values matched by every query term
+func (r *SubRepository) FindWithMatches(query search.Query) (PackageStack, error) {
+	return r.find(query, true)
+}
 
-	if err != nil {
-		return nil, err
-	}
+// Reindex generates repository metadata and returns index generation statistics
+// for every arch
+func (r *SubRepository) Reindex(full bool, ch chan string) (ReindexStats, error) {
+	return r.ReindexArchs(context.Background(), nil, full, ch, nil)
+}
 
-	if psb == nil {
-		return PackageStack{}, nil
+// ReindexArchs generates repository metadata only for given architectures (or
+// for all architectures if list is empty) and returns index generation statistics
+// for every arch. Channel ch receives name of every arch before processing and
+// closed after reindex, progress channel receives index generation progress.
+// Reindex can be interrupted by canceling given context.
+func (r *SubRepository) ReindexArchs(ctx context.Context, archs []string, full bool, ch chan string, progress chan<- index.Progress) (ReindexStats, error) {
+	if ch != nil {
+		defer close(ch)
 	}
 
-	return psb.Data, nil
-}
-
-// Reindex generates repository metadata
-func (r *SubRepository) Reindex(full bool, ch chan string) error {
 	if !r.Parent.storage.IsInitialized() {
-		return ErrNotInitialized
+		return nil, ErrNotInitialized
 	}
 
+	stats := ReindexStats{}
+
 	for _, arch := range data.ArchList {
 		if !r.HasArch(arch) || data.SupportedArchs[arch].Dir == "" {
 			continue
 		}
 
+		if len(archs) != 0 && !slices.Contains(archs, arch) {
+			continue
+		}
+
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("Reindex canceled: %w", context.Cause(ctx))
+		}
+
 		if ch != nil {
 			ch <- arch
 		}
 
-		err := r.Parent.storage.Reindex(r.Name, arch, full)
+		archStats, err := r.Parent.storage.Reindex(ctx, r.Name, arch, full, progress)
 
 		if err != nil {
-			return err
+			return nil, err
 		}
-	}
 
-	if ch != nil {
-		close(ch)
+		stats[arch] = archStats
 	}
 
-	return nil
+	return stats, nil
 }
 
 // IsCacheValid returns true if cache for architectures is valid
@@ -743,11 +1336,77 @@ func (r *SubRepository) WarmupCache() error {
 	return nil
 }
 
+// PurgeCache removes cached data for all architectures
+func (r *SubRepository) PurgeCache() error {
+	if !r.Parent.storage.IsInitialized() {
+		return ErrNotInitialized
+	}
+
+	for _, arch := range data.ArchList {
+		if !r.HasArch(arch) || data.SupportedArchs[arch].Dir == "" {
+			continue
+		}
+
+		err := r.Parent.storage.PurgeCacheFor(r.Name, arch)
+
+		if err != nil {
+			return fmt.Errorf("Can't purge %s cache: %w", r.Name, err)
+		}
+	}
+
+	return nil
+}
+
 // GetFullPackagePath returns full path to package
 func (r *SubRepository) GetFullPackagePath(pkg PackageFile) string {
 	return r.Parent.storage.GetPackagePath(r.Name, pkg.BaseArchFlag.String(), pkg.Path)
 }
 
+// RepairPackageAttrs sets owner and permissions defined in storage options for
+// given package file and its directory
+func (r *SubRepository) RepairPackageAttrs(pkg PackageFile) error {
+	return r.Parent.storage.RepairPackageAttrs(r.Name, pkg.BaseArchFlag.String(), pkg.Path)
+}
+
+// GetMetaIndexPath returns path to index file (repomd.xml) for given arch
+func (r *SubRepository) GetMetaIndexPath(arch string) string {
+	return r.Parent.storage.GetMetaIndexPath(r.Name, arch)
+}
+
+// GetModTime returns date of index modification for given arch
+func (r *SubRepository) GetModTime(arch string) (time.Time, error) {
+	return r.Parent.storage.GetModTime(r.Name, arch)
+}
+
+// SyncNoarchPackages makes sure that every noarch package file is present in all
+// binary arch directories of sub-repository and returns paths (relative to
+// repository directory) of added files
+// Important: This method DO NOT run repository reindex
+func (r *SubRepository) SyncNoarchPackages() ([]string, error) {
+	if !r.Parent.storage.IsInitialized() {
+		return nil, fmt.Errorf("Can't sync noarch packages: %w", ErrNotInitialized)
+	}
+
+	return r.Parent.storage.SyncNoarchPackages(r.Name)
+}
+
+// FindDuplicates returns names of package files which exist in sub-repository
+// storage more than once with paths to all their copies
+func (r *SubRepository) FindDuplicates() (map[string][]string, error) {
+	return r.Parent.storage.FindDuplicates(r.Name)
+}
+
+// GetPackageFilesSize returns total size of given package files in bytes
+func (r *SubRepository) GetPackageFilesSize(files PackageFiles) int64 {
+	var size int64
+
+	for _, file := range files {
+		size += fsutil.GetSize(r.GetFullPackagePath(file))
+	}
+
+	return size
+}
+
 // HasArch returns true if sub-repository contains packages with given arch
 func (r *SubRepository) HasArch(arch string) bool {
 	return r.Parent.storage.HasArch(r.Name, arch)
@@ -763,6 +1422,191 @@ func (r *SubRepository) Is(name string) bool {
 	return r.Name == name
 }
 
+// ProvidesCollisions returns capabilities provided by more than one package
+func (r *SubRepository) ProvidesCollisions() (ProvidesCollisions, error) {
+	if !r.Parent.storage.IsInitialized() {
+		return nil, ErrNotInitialized
+	}
+
+	index := make(map[string]*ProvidesCollision)
+
+	for _, arch := range data.ArchList {
+		if !r.HasArch(arch) || data.SupportedArchs[arch].Dir == "" || r.IsEmpty(arch) {
+			continue
+		}
+
+		err := r.collectProvidesCollisions(index, arch)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var result ProvidesCollisions
+
+	for _, collision := range index {
+		sortutil.StringsNatural(collision.Packages)
+		result = append(result, collision)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+
+	return result, nil
+}
+
+// FindEmptyMetaFields returns info about packages with empty values of given
+// metadata fields
+func (r *SubRepository) FindEmptyMetaFields(fields []string) (EmptyMetaFieldsList, error) {
+	if !r.Parent.storage.IsInitialized() {
+		return nil, ErrNotInitialized
+	}
+
+	var columns []string
+
+	for _, field := range fields {
+		column, ok := metaFieldColumns[field]
+
+		if !ok {
+			return nil, fmt.Errorf("Unknown metadata field %q", field)
+		}
+
+		columns = append(columns, column)
+	}
+
+	if len(columns) == 0 {
+		return nil, nil
+	}
+
+	index := make(map[string]*EmptyMetaFields)
+	query := fmt.Sprintf(_SQL_META_FIELDS, strings.Join(columns, ","))
+
+	for _, arch := range data.ArchList {
+		if !r.HasArch(arch) || data.SupportedArchs[arch].Dir == "" || r.IsEmpty(arch) {
+			continue
+		}
+
+		err := r.collectEmptyMetaFields(index, fields, query, arch)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var result EmptyMetaFieldsList
+
+	for _, info := range index {
+		result = append(result, info)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Package < result[j].Package
+	})
+
+	return result, nil
+}
+
+// FindMissingMetaFiles returns map arch → metadata files which are referenced in
+// repository index but missing in storage
+func (r *SubRepository) FindMissingMetaFiles() (map[string][]string, error) {
+	if !r.Parent.storage.IsInitialized() {
+		return nil, ErrNotInitialized
+	}
+
+	result := make(map[string][]string)
+
+	for _, arch := range data.ArchList {
+		if !r.HasArch(arch) || data.SupportedArchs[arch].Dir == "" || r.IsEmpty(arch) {
+			continue
+		}
+
+		files, err := r.Parent.storage.FindMissingMetaFiles(r.Name, arch)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if len(files) != 0 {
+			result[arch] = files
+		}
+	}
+
+	return result, nil
+}
+
+// FindOrphanPackages returns map arch → package files which exist in storage
+// but aren't referenced in repository index
+func (r *SubRepository) FindOrphanPackages() (map[string][]string, error) {
+	if !r.Parent.storage.IsInitialized() {
+		return nil, ErrNotInitialized
+	}
+
+	result := make(map[string][]string)
+
+	for _, arch := range data.ArchList {
+		if !r.HasArch(arch) || data.SupportedArchs[arch].Dir == "" {
+			continue
+		}
+
+		files, err := r.Parent.storage.FindOrphanPackages(r.Name, arch)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if len(files) != 0 {
+			result[arch] = files
+		}
+	}
+
+	return result, nil
+}
+
+// DepGraph builds graph of dependencies between packages in sub-repository
+func (r *SubRepository) DepGraph() (*DepGraph, error) {
+	if !r.Parent.storage.IsInitialized() {
+		return nil, ErrNotInitialized
+	}
+
+	nodes := make(map[string]bool)
+	edges := make(map[DepEdge]bool)
+
+	for _, arch := range data.ArchList {
+		if !r.HasArch(arch) || data.SupportedArchs[arch].Dir == "" || r.IsEmpty(arch) {
+			continue
+		}
+
+		err := r.collectDepGraphData(nodes, edges, arch)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	graph := &DepGraph{}
+
+	for node := range nodes {
+		graph.Nodes = append(graph.Nodes, node)
+	}
+
+	for edge := range edges {
+		graph.Edges = append(graph.Edges, edge)
+	}
+
+	sortutil.StringsNatural(graph.Nodes)
+
+	sort.Slice(graph.Edges, func(i, j int) bool {
+		if graph.Edges[i].From == graph.Edges[j].From {
+			return graph.Edges[i].To < graph.Edges[j].To
+		}
+
+		return graph.Edges[i].From < graph.Edges[j].From
+	})
+
+	return graph, nil
+}
+
 // ////////////////////////////////////////////////////////////////////////////////// //
 
 // getRepoStats reads stats info from repository DB
@@ -787,7 +1631,140 @@ func (r *SubRepository) getRepoStats(arch string) (int, int64, error) {
 		return 0, 0, fmt.Errorf("Error while scanning rows with repository stats (arch: %s): %w", arch, err)
 	}
 
-	return int(count.Int64), size.Int64, nil
+	return int(count.Int64), size.Int64, nil
+}
+
+// collectProvidesCollisions reads info about provides collisions for given arch
+func (r *SubRepository) collectProvidesCollisions(index map[string]*ProvidesCollision, arch string) error {
+	rows, err := r.execQuery(data.DB_PRIMARY, arch, _SQL_PROVIDES_DUPS)
+
+	if err != nil {
+		return fmt.Errorf("Can't collect provides info (arch: %s): %w", arch, err)
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var name, pkgs string
+
+		err = rows.Scan(&name, &pkgs)
+
+		if err != nil {
+			return fmt.Errorf("Error while scanning rows with provides info (arch: %s): %w", arch, err)
+		}
+
+		collision := index[name]
+
+		if collision == nil {
+			collision = &ProvidesCollision{Name: name}
+			index[name] = collision
+		}
+
+		collision.ArchFlags |= data.SupportedArchs[arch].Flag
+
+		for _, pkg := range strings.Split(pkgs, ",") {
+			if !slices.Contains(collision.Packages, pkg) {
+				collision.Packages = append(collision.Packages, pkg)
+			}
+		}
+	}
+
+	return nil
+}
+
+// collectEmptyMetaFields reads info about packages with empty metadata fields
+// for given arch
+func (r *SubRepository) collectEmptyMetaFields(index map[string]*EmptyMetaFields, fields []string, query, arch string) error {
+	rows, err := r.execQuery(data.DB_PRIMARY, arch, query)
+
+	if err != nil {
+		return fmt.Errorf("Can't collect packages metadata (arch: %s): %w", arch, err)
+	}
+
+	defer rows.Close()
+
+	values := make([]sql.NullString, len(fields)+4)
+	valuesPtrs := make([]any, len(values))
+
+	for i := range values {
+		valuesPtrs[i] = &values[i]
+	}
+
+	for rows.Next() {
+		err = rows.Scan(valuesPtrs...)
+
+		if err != nil {
+			return fmt.Errorf("Error while scanning rows with packages metadata (arch: %s): %w", arch, err)
+		}
+
+		var emptyFields []string
+
+		for i, field := range fields {
+			if strings.TrimSpace(values[i+4].String) == "" {
+				emptyFields = append(emptyFields, field)
+			}
+		}
+
+		if len(emptyFields) == 0 {
+			continue
+		}
+
+		pkgName := fmt.Sprintf(
+			"%s-%s-%s.%s", values[0].String,
+			values[1].String, values[2].String, values[3].String,
+		)
+
+		if index[pkgName] == nil {
+			index[pkgName] = &EmptyMetaFields{Package: pkgName, Fields: emptyFields}
+		}
+	}
+
+	return nil
+}
+
+// collectDepGraphData reads packages and dependencies between them for given arch
+func (r *SubRepository) collectDepGraphData(nodes map[string]bool, edges map[DepEdge]bool, arch string) error {
+	rows, err := r.execQuery(data.DB_PRIMARY, arch, _SQL_DEP_NODES)
+
+	if err != nil {
+		return fmt.Errorf("Can't collect packages list (arch: %s): %w", arch, err)
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var name string
+
+		err = rows.Scan(&name)
+
+		if err != nil {
+			return fmt.Errorf("Error while scanning rows with packages list (arch: %s): %w", arch, err)
+		}
+
+		nodes[name] = true
+	}
+
+	depRows, err := r.execQuery(data.DB_PRIMARY, arch, _SQL_DEP_EDGES)
+
+	if err != nil {
+		return fmt.Errorf("Can't collect dependencies info (arch: %s): %w", arch, err)
+	}
+
+	defer depRows.Close()
+
+	for depRows.Next() {
+		var edge DepEdge
+
+		err = depRows.Scan(&edge.From, &edge.To)
+
+		if err != nil {
+			return fmt.Errorf("Error while scanning rows with dependencies info (arch: %s): %w", arch, err)
+		}
+
+		edges[edge] = true
+	}
+
+	return nil
 }
 
 // listPackages returns basic packages info
@@ -830,10 +1807,14 @@ func (r *SubRepository) listArchPackages(psb *packageStackBuilder, arch string,
 
 	var sourceRPM string
 	var pkgID, pkgName, pkgArch, pkgVer, pkgRel, pkgEpc, pkgSrc, pkgHREF sql.NullString
+	var pkgSize, pkgAddTS, pkgBuildTS sql.NullInt64
 
 ROWSLOOP:
 	for rows.Next() {
-		err = rows.Scan(&pkgID, &pkgName, &pkgArch, &pkgVer, &pkgRel, &pkgEpc, &pkgSrc, &pkgHREF)
+		err = rows.Scan(
+			&pkgID, &pkgName, &pkgArch, &pkgVer, &pkgRel, &pkgEpc, &pkgSrc, &pkgHREF,
+			&pkgSize, &pkgAddTS, &pkgBuildTS,
+		)
 
 		if err != nil {
 			return fmt.Errorf("Error while scanning rows with info about arch packages list (%s): %w", arch, err)
@@ -863,6 +1844,9 @@ ROWSLOOP:
 						data.SupportedArchs[pkgArch.String].Flag,
 						data.SupportedArchs[arch].Flag,
 					})
+					pkg.Size += uint64(pkgSize.Int64)
+					pkg.DateAdded = latestDate(pkg.DateAdded, time.Unix(pkgAddTS.Int64, 0))
+					pkg.DateBuild = latestDate(pkg.DateBuild, time.Unix(pkgBuildTS.Int64, 0))
 					continue ROWSLOOP
 				}
 			}
@@ -882,6 +1866,9 @@ ROWSLOOP:
 					data.SupportedArchs[pkgArch.String].Flag,
 					data.SupportedArchs[arch].Flag,
 				}},
+				Size:      uint64(pkgSize.Int64),
+				DateAdded: time.Unix(pkgAddTS.Int64, 0),
+				DateBuild: time.Unix(pkgBuildTS.Int64, 0),
 			},
 		)
 	}
@@ -889,19 +1876,66 @@ ROWSLOOP:
 	return nil
 }
 
+// find tries to find packages by given search query
+func (r *SubRepository) find(query search.Query, withMatches bool) (PackageStack, error) {
+	if !r.Parent.storage.IsInitialized() {
+		return nil, ErrNotInitialized
+	}
+
+	if len(query) == 0 {
+		return PackageStack{}, nil
+	}
+
+	errs := query.Validate()
+
+	if len(errs) != 0 {
+		return nil, errs[0]
+	}
+
+	psb, err := r.searchPackages(query, withMatches)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if psb == nil {
+		return PackageStack{}, nil
+	}
+
+	return psb.Data, nil
+}
+
 // searchPackages searches package with given search query
-func (r *SubRepository) searchPackages(query search.Query) (*packageStackBuilder, error) {
+func (r *SubRepository) searchPackages(query search.Query, withMatches bool) (*packageStackBuilder, error) {
+	var matches []map[string]map[int][]string
+
 	index := data.NewPkgKeyIndex()
+	terms := query.Terms()
 
-	for _, term := range query.Terms() {
+	if withMatches {
+		matches = make([]map[string]map[int][]string, len(terms))
+	}
+
+	for termIndex, term := range terms {
 		for _, arch := range data.ArchList {
 			if !r.HasArch(arch) || index.IgnoreArch(arch) ||
 				data.SupportedArchs[arch].Dir == "" || r.IsEmpty(arch) {
 				continue
 			}
 
-			targetDB, sqlQueries := term.SQL()
-			keyMap, err := r.searchArchPackages(arch, targetDB, sqlQueries)
+			var values map[int][]string
+
+			if withMatches {
+				values = make(map[int][]string)
+
+				if matches[termIndex] == nil {
+					matches[termIndex] = make(map[string]map[int][]string)
+				}
+
+				matches[termIndex][arch] = values
+			}
+
+			keyMap, err := r.searchArchTerm(arch, term, values)
 
 			if err != nil {
 				return nil, err
@@ -934,6 +1968,14 @@ func (r *SubRepository) searchPackages(query search.Query) (*packageStackBuilder
 		if err != nil {
 			return nil, err
 		}
+
+		if withMatches {
+			err = r.appendArchPackagesMatches(psb, arch, index, terms, matches)
+
+			if err != nil {
+				return nil, err
+			}
+		}
 	}
 
 	sortPackageStack(psb)
@@ -941,8 +1983,82 @@ func (r *SubRepository) searchPackages(query search.Query) (*packageStackBuilder
 	return psb, nil
 }
 
-// searchPackages searches package with given search query for some arch
-func (r *SubRepository) searchArchPackages(arch, targetDB string, queries []string) (data.PkgKeyMap, error) {
+// searchArchTerm searches packages matching given term in DB for given arch.
+// Results of group term alternatives are merged.
+func (r *SubRepository) searchArchTerm(arch string, term *search.Term, values map[int][]string) (data.PkgKeyMap, error) {
+	if !term.IsGroup() {
+		targetDB, sqlQueries := term.SQL()
+
+		if values != nil {
+			targetDB, sqlQueries = term.MatchSQL()
+		}
+
+		return r.searchArchPackages(arch, targetDB, sqlQueries, values)
+	}
+
+	keyMap := data.NewPkgKeyMap()
+
+	for _, query := range term.Alternatives() {
+		queryKeyMap, err := r.searchArchQuery(arch, query, values)
+
+		if err != nil {
+			return nil, err
+		}
+
+		keyMap.Union(queryKeyMap)
+	}
+
+	return keyMap, nil
+}
+
+// searchArchQuery searches packages matching all terms of given query in DB for
+// given arch
+func (r *SubRepository) searchArchQuery(arch string, query search.Query, values map[int][]string) (data.PkgKeyMap, error) {
+	var keyMap data.PkgKeyMap
+	var queryValues map[int][]string
+
+	if values != nil {
+		queryValues = make(map[int][]string)
+	}
+
+	for _, term := range query.Terms() {
+		termKeyMap, err := r.searchArchTerm(arch, term, queryValues)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if keyMap == nil {
+			keyMap = termKeyMap
+		} else {
+			keyMap.Intersect(termKeyMap)
+		}
+
+		if len(keyMap) == 0 {
+			break
+		}
+	}
+
+	// Keep only values of packages matched by the whole query
+	for pkgKey, pkgValues := range queryValues {
+		if !keyMap[pkgKey] {
+			continue
+		}
+
+		for _, value := range pkgValues {
+			if !slices.Contains(values[pkgKey], value) {
+				values[pkgKey] = append(values[pkgKey], value)
+			}
+		}
+	}
+
+	return keyMap, nil
+}
+
+// searchArchPackages searches packages in DB for given arch and returns map with
+// keys of found packages. If values map is not nil, it will be filled with matched
+// values for every package key.
+func (r *SubRepository) searchArchPackages(arch, targetDB string, queries []string, values map[int][]string) (data.PkgKeyMap, error) {
 	keyMap := data.NewPkgKeyMap()
 
 	for _, query := range queries {
@@ -953,9 +2069,14 @@ func (r *SubRepository) searchArchPackages(arch, targetDB string, queries []stri
 		}
 
 		var pkgKey int
+		var value sql.NullString
 
 		for rows.Next() {
-			err = rows.Scan(&pkgKey)
+			if values == nil {
+				err = rows.Scan(&pkgKey)
+			} else {
+				err = rows.Scan(&pkgKey, &value)
+			}
 
 			if err != nil {
 				rows.Close()
@@ -963,6 +2084,10 @@ func (r *SubRepository) searchArchPackages(arch, targetDB string, queries []stri
 			}
 
 			keyMap.Set(pkgKey)
+
+			if values != nil && !slices.Contains(values[pkgKey], value.String) {
+				values[pkgKey] = append(values[pkgKey], value.String)
+			}
 		}
 
 		rows.Close()
@@ -971,6 +2096,64 @@ func (r *SubRepository) searchArchPackages(arch, targetDB string, queries []stri
 	return keyMap, nil
 }
 
+// appendArchPackagesMatches appends info about search terms matches to found
+// packages with given arch
+func (r *SubRepository) appendArchPackagesMatches(psb *packageStackBuilder, arch string, index data.PkgKeyIndex, terms []*search.Term, matches []map[string]map[int][]string) error {
+	rows, err := r.execQuery(data.DB_PRIMARY, arch, fmt.Sprintf(_SQL_FIND_IDS, index.List(arch)))
+
+	if err != nil {
+		return fmt.Errorf("Can't collect packages IDs (%s): %w", arch, err)
+	}
+
+	defer rows.Close()
+
+	crcMatches := make(map[string]PackageMatches)
+
+	for rows.Next() {
+		var pkgKey int
+		var pkgID string
+
+		err = rows.Scan(&pkgKey, &pkgID)
+
+		if err != nil {
+			return fmt.Errorf("Error while scanning rows with packages IDs (%s): %w", arch, err)
+		}
+
+		crc := strutil.Head(pkgID, 7)
+
+		for termIndex, term := range terms {
+			for _, value := range matches[termIndex][arch][pkgKey] {
+				crcMatches[crc] = append(crcMatches[crc], PackageMatch{term, value})
+			}
+		}
+	}
+
+	archFlag := data.SupportedArchs[arch].Flag
+
+	for _, bundle := range psb.Data {
+		for _, pkg := range bundle {
+			for _, file := range pkg.Files {
+				if file.BaseArchFlag != archFlag {
+					continue
+				}
+
+				for _, match := range crcMatches[file.CRC] {
+					if !slices.Contains(pkg.Matches, match) {
+						pkg.Matches = append(pkg.Matches, match)
+					}
+				}
+			}
+
+			// Keep matches grouped by term in query order
+			sort.SliceStable(pkg.Matches, func(i, j int) bool {
+				return slices.Index(terms, pkg.Matches[i].Term) < slices.Index(terms, pkg.Matches[j].Term)
+			})
+		}
+	}
+
+	return nil
+}
+
 // hasPackage checks if package presented in repository
 func (r *SubRepository) hasPackage(pkg *Package, arch string) (bool, time.Time, error) {
 	rows, err := r.execQuery(
@@ -978,7 +2161,7 @@ func (r *SubRepository) hasPackage(pkg *Package, arch string) (bool, time.Time,
 		sql.Named("name", pkg.Name),
 		sql.Named("version", pkg.Version),
 		sql.Named("release", pkg.Release),
-		sql.Named("epoch", pkg.Epoch),
+		sql.Named("epoch", normalizeEpoch(pkg.Epoch)),
 	)
 
 	if err != nil {
@@ -1002,38 +2185,152 @@ func (r *SubRepository) hasPackage(pkg *Package, arch string) (bool, time.Time,
 	return true, time.Unix(pTimeFile.Int64, 0), nil
 }
 
-// getPackageInfo collects detailed info about package with given name
-func (r *SubRepository) getPackageInfo(name, arch string) (*Package, error) {
-	var err error
+// checkPackageFile checks that given file is an RPM package signed with any of
+// given keys
+func checkPackageFile(rpmFilePath string, keys []*sign.Key) error {
+	err := fsutil.ValidatePerms("FRS", rpmFilePath)
 
-	pkg, pkgID, err := r.collectPackageBasicInfo(name, arch)
+	if err != nil {
+		return fmt.Errorf("Can't add package to repository: %w", err)
+	}
+
+	if !rpm.IsRPM(rpmFilePath) {
+		return fmt.Errorf("Can't add file to repository: %s is not an RPM package", rpmFilePath)
+	}
+
+	if len(keys) == 0 {
+		return nil
+	}
+
+	isSignValid, err := sign.IsPackageSignatureValidWithKeys(rpmFilePath, keys...)
 
 	if err != nil {
+		return fmt.Errorf("Can't add file to repository: %w", err)
+	}
+
+	if !isSignValid {
+		return fmt.Errorf("Can't add file to repository: Repository allows only signed packages")
+	}
+
+	return nil
+}
+
+// normalizeEpoch returns epoch value suitable for comparison (empty epoch
+// is the same as 0)
+func normalizeEpoch(epoch string) string {
+	if epoch == "" {
+		return "0"
+	}
+
+	return epoch
+}
+
+// getPackageCacheKey returns key for packages info cache (NEVRA)
+func getPackageCacheKey(pkg *Package) string {
+	return fmt.Sprintf(
+		"%s-%s:%s-%s.%s", pkg.Name, normalizeEpoch(pkg.Epoch),
+		pkg.Version, pkg.Release, pkg.ArchFlags.String(),
+	)
+}
+
+// getPackageInfo returns detailed info about package with given name using
+// cache if it is enabled
+func (r *SubRepository) getPackageInfo(name, arch string) (*Package, error) {
+	pkg, pkgID, err := r.collectPackageBasicInfo(name, arch)
+
+	if err != nil || pkgID == "" {
 		return nil, err
 	}
 
-	if pkgID == "" {
-		return nil, nil
+	if !r.Parent.CacheInfo {
+		return r.collectPackageInfo(pkg, pkgID, arch)
 	}
 
-	pkg.Info.Payload, err = r.collectPackagePayloadInfo(pkgID, arch)
+	revision, err := r.getIndexRevision(arch)
 
 	if err != nil {
-		return nil, err
+		return r.collectPackageInfo(pkg, pkgID, arch)
+	}
+
+	r.infoCacheLock.Lock()
+	defer r.infoCacheLock.Unlock()
+
+	if r.infoCache == nil {
+		r.infoCache = map[string]*packageInfoCache{}
+	}
+
+	cache := r.infoCache[arch]
+
+	if cache == nil || cache.Revision != revision {
+		cache = &packageInfoCache{
+			Revision: revision,
+			Packages: map[string]*Package{},
+		}
+
+		r.infoCache[arch] = cache
+	}
+
+	key := getPackageCacheKey(pkg)
+	cachedPkg, ok := cache.Packages[key]
+
+	if ok {
+		return cachedPkg.clone(), nil
 	}
 
-	pkg.Info.Requires, err = r.collectPackageDepInfo(pkgID, arch, _SQL_INFO_REQUIRES)
+	pkg, err = r.collectPackageInfo(pkg, pkgID, arch)
 
 	if err != nil {
 		return nil, err
 	}
 
-	pkg.Info.Provides, err = r.collectPackageDepInfo(pkgID, arch, _SQL_INFO_PROVIDES)
+	cache.Packages[key] = pkg
+
+	return pkg.clone(), nil
+}
+
+// getIndexRevision returns revision of index for given arch
+func (r *SubRepository) getIndexRevision(arch string) (int64, error) {
+	metaIndex, err := meta.Read(r.Parent.storage.GetMetaIndexPath(r.Name, arch))
+
+	if err != nil {
+		return 0, err
+	}
+
+	return metaIndex.Revision, nil
+}
+
+// collectPackageInfo collects detailed info about package with given basic info
+func (r *SubRepository) collectPackageInfo(pkg *Package, pkgID, arch string) (*Package, error) {
+	var err error
+
+	pkg.Info.Payload, err = r.collectPackagePayloadInfo(pkgID, arch)
 
 	if err != nil {
 		return nil, err
 	}
 
+	deps := []struct {
+		target *[]data.Dependency
+		query  string
+	}{
+		{&pkg.Info.Requires, _SQL_INFO_REQUIRES},
+		{&pkg.Info.Provides, _SQL_INFO_PROVIDES},
+		{&pkg.Info.Conflicts, _SQL_INFO_CONFLICTS},
+		{&pkg.Info.Obsoletes, _SQL_INFO_OBSOLETES},
+		{&pkg.Info.Recommends, _SQL_INFO_RECOMMENDS},
+		{&pkg.Info.Suggests, _SQL_INFO_SUGGESTS},
+		{&pkg.Info.Enhances, _SQL_INFO_ENHANCES},
+		{&pkg.Info.Supplements, _SQL_INFO_SUPPLEMENTS},
+	}
+
+	for _, dep := range deps {
+		*dep.target, err = r.collectPackageDepInfo(pkgID, arch, dep.query)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	err = r.appendPackageChangelogInfo(pkg, pkgID, arch)
 
 	if err != nil {
@@ -1060,7 +2357,7 @@ func (r *SubRepository) collectPackageBasicInfo(name, arch string) (*Package, st
 
 	var pkgID, pkgName, pkgArch, pkgVer, pkgRel, pkgEpc, pkgSrc, pkgHREF sql.NullString
 	var pkgAddTS, pkgBuildTS, pkgSize, pkgSizeInst sql.NullInt64
-	var pkgSum, pkgDesc, pkgURL, pkgLic, pkgVend, pkgGroup sql.NullString
+	var pkgSum, pkgDesc, pkgURL, pkgLic, pkgVend, pkgGroup, pkgPackager sql.NullString
 
 	if !rows.Next() {
 		return nil, "", nil
@@ -1069,7 +2366,7 @@ func (r *SubRepository) collectPackageBasicInfo(name, arch string) (*Package, st
 	err = rows.Scan(
 		&pkgID, &pkgName, &pkgArch, &pkgVer, &pkgRel, &pkgEpc, &pkgSrc, &pkgHREF,
 		&pkgSum, &pkgDesc, &pkgURL, &pkgAddTS, &pkgBuildTS,
-		&pkgLic, &pkgVend, &pkgGroup, &pkgSize, &pkgSizeInst,
+		&pkgLic, &pkgVend, &pkgGroup, &pkgPackager, &pkgSize, &pkgSizeInst,
 	)
 
 	if err != nil {
@@ -1095,6 +2392,7 @@ func (r *SubRepository) collectPackageBasicInfo(name, arch string) (*Package, st
 			License:       pkgLic.String,
 			Vendor:        pkgVend.String,
 			Group:         pkgGroup.String,
+			Packager:      pkgPackager.String,
 			SizePackage:   uint64(pkgSize.Int64),
 			SizeInstalled: uint64(pkgSizeInst.Int64),
 			DateAdded:     time.Unix(pkgAddTS.Int64, 0),
@@ -1177,7 +2475,7 @@ func (r *SubRepository) collectPackagePayloadInfo(pkgID, arch string) ([]Payload
 	return result, nil
 }
 
-// collectPackageDepInfo collects requires/provides info
+// collectPackageDepInfo collects dependencies info (requires, provides, obsoletes…)
 func (r *SubRepository) collectPackageDepInfo(pkgID, arch, query string) ([]data.Dependency, error) {
 	rows, err := r.execQuery(
 		data.DB_PRIMARY, arch, query,
@@ -1185,7 +2483,7 @@ func (r *SubRepository) collectPackageDepInfo(pkgID, arch, query string) ([]data
 	)
 
 	if err != nil {
-		return nil, fmt.Errorf("Can't execute query for collecting requires/provides data: %w", err)
+		return nil, fmt.Errorf("Can't execute query for collecting dependencies data: %w", err)
 	}
 
 	defer rows.Close()
@@ -1197,7 +2495,7 @@ func (r *SubRepository) collectPackageDepInfo(pkgID, arch, query string) ([]data
 		err = rows.Scan(&pkgName, &pkgFlag, &pkgEpc, &pkgVer, &pkgRel)
 
 		if err != nil {
-			return nil, fmt.Errorf("Error while scanning rows with requires/provides data: %w", err)
+			return nil, fmt.Errorf("Error while scanning rows with dependencies data: %w", err)
 		}
 
 		dep := data.Dependency{
@@ -1234,13 +2532,19 @@ func (r *SubRepository) execQuery(dbType, arch, query string, args ...sql.NamedA
 		return nil, fmt.Errorf("Unknown or unsupported arch %q", arch)
 	}
 
-	db, err := r.Parent.storage.GetDB(r.Name, arch, dbType)
+	ctx := r.Parent.Context
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	rows, err := r.Parent.storage.Query(ctx, r.Name, arch, dbType, query, sqlArgToAny(args)...)
 
 	if err != nil {
 		return nil, fmt.Errorf("Can't get DB from storage: %w", err)
 	}
 
-	return db.Query(query, sqlArgToAny(args)...)
+	return rows, nil
 }
 
 // guessArch tries to guess real package arch
@@ -1276,6 +2580,70 @@ func sanitizeInput(data string) string {
 	return result
 }
 
+// rollbackCopying removes copied files from target sub-repository and restores
+// replaced files from backups
+func rollbackCopying(target *SubRepository, files PackageFiles, backups []string) error {
+	var errs []error
+
+	for i, file := range files {
+		var err error
+
+		switch {
+		case backups[i] != "":
+			err = target.Parent.storage.AddPackage(target.Name, backups[i])
+		case target.Parent.storage.HasPackage(target.Name, file.BaseArchFlag.String(), path.Base(file.Path)):
+			err = target.RemovePackage(file)
+		}
+
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// savePackageFile saves package file from sub-repository to given file
+func savePackageFile(r *SubRepository, file PackageFile, outputFile string) error {
+	pkgReader, err := r.Parent.storage.OpenPackage(r.Name, file.BaseArchFlag.String(), file.Path)
+
+	if err != nil {
+		return err
+	}
+
+	defer pkgReader.Close()
+
+	err = os.MkdirAll(path.Dir(outputFile), 0700)
+
+	if err != nil {
+		return err
+	}
+
+	fd, err := os.OpenFile(outputFile, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(fd, pkgReader)
+
+	if err != nil {
+		fd.Close()
+		return err
+	}
+
+	return fd.Close()
+}
+
+// latestDate returns the latest of two dates
+func latestDate(d1, d2 time.Time) time.Time {
+	if d2.After(d1) {
+		return d2
+	}
+
+	return d1
+}
+
 // sortPackageStack sort packages stack data
 func sortPackageStack(psb *packageStackBuilder) {
 	if len(psb.Data) <= 1 {
@@ -1360,6 +2728,42 @@ func (p PackageStack) Less(i, j int) bool {
 	return sortutil.NaturalLess(p[i][0].Release, p[j][0].Release)
 }
 
+// Less reports whether the element with index i
+// must sort before the element with index j
+func (p PackageStackBySize) Less(i, j int) bool {
+	s1, s2 := p.PackageStack[i].TotalSize(), p.PackageStack[j].TotalSize()
+
+	if s1 != s2 {
+		return s1 > s2
+	}
+
+	return p.PackageStack.Less(i, j)
+}
+
+// Less reports whether the element with index i
+// must sort before the element with index j
+func (p PackageStackByDateAdd) Less(i, j int) bool {
+	d1, d2 := p.PackageStack[i].LatestDateAdded(), p.PackageStack[j].LatestDateAdded()
+
+	if !d1.Equal(d2) {
+		return d1.After(d2)
+	}
+
+	return p.PackageStack.Less(i, j)
+}
+
+// Less reports whether the element with index i
+// must sort before the element with index j
+func (p PackageStackByDateBuild) Less(i, j int) bool {
+	d1, d2 := p.PackageStack[i].LatestDateBuild(), p.PackageStack[j].LatestDateBuild()
+
+	if !d1.Equal(d2) {
+		return d1.After(d2)
+	}
+
+	return p.PackageStack.Less(i, j)
+}
+
 // Len is the number of elements in the collection
 func (p PackagePayload) Len() int {
 	return len(p)
@@ -1387,5 +2791,3 @@ func (p PackagePayload) Less(i, j int) bool {
 
 	return p[i].Path < p[j].Path
 }
-
-// ////////////////////////////////////////////////////////////////////////////////// //
@@ -8,13 +8,17 @@ package repo
 // ////////////////////////////////////////////////////////////////////////////////// //
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"io"
 	"os"
 	"sort"
 	"testing"
 	"time"
 
+	"github.com/essentialkaos/ek/v13/secstr"
+
 	"github.com/essentialkaos/rep/v3/repo/data"
 	"github.com/essentialkaos/rep/v3/repo/index"
 	"github.com/essentialkaos/rep/v3/repo/search"
@@ -56,6 +60,23 @@ func (s *RepoSuite) TestNewRepository(c *C) {
 	c.Assert(r, NotNil)
 }
 
+func (s *RepoSuite) TestReadPackageFile(c *C) {
+	_, err := ReadPackageFile("")
+	c.Assert(err, DeepEquals, ErrEmptyPath)
+
+	_, err = ReadPackageFile("../testdata/comps.xml")
+	c.Assert(err, ErrorMatches, `File ../testdata/comps.xml is not an RPM package`)
+
+	pkg, err := ReadPackageFile("../testdata/test-package-1.0.0-0.el7.x86_64.rpm")
+	c.Assert(err, IsNil)
+	c.Assert(pkg, NotNil)
+	c.Assert(pkg.FullName(), Equals, "test-package-1.0.0-0.el7")
+	c.Assert(pkg.ArchFlags, Equals, data.ARCH_FLAG_X64)
+	c.Assert(pkg.Files, HasLen, 1)
+	c.Assert(pkg.Info, NotNil)
+	c.Assert(pkg.Info.SizePackage, Not(Equals), uint64(0))
+}
+
 func (s *RepoSuite) TestPackage(c *C) {
 	var p *Package
 
@@ -128,6 +149,29 @@ func (s *RepoSuite) TestPackageStack(c *C) {
 		PackageFile{"0000000", "test-package-1.0.0-0.el7.x86_64.rpm", data.ARCH_FLAG_X64, data.ARCH_FLAG_X64},
 		PackageFile{"0000000", "test-package-1.0.1-0.el7.x86_64.rpm", data.ARCH_FLAG_X64, data.ARCH_FLAG_X64},
 	})
+	c.Assert(ps[0].FlattenFiles(), HasLen, 3)
+	c.Assert(PackageBundle{nil}.FlattenFiles(), IsNil)
+
+	compactStack := PackageStack{nil, PackageBundle{nil, ps[0][1]}, PackageBundle{nil}}.Compact()
+
+	c.Assert(compactStack, HasLen, 1)
+	c.Assert(compactStack[0], HasLen, 1)
+	c.Assert(compactStack[0][0].Version, Equals, "1.0.1")
+	c.Assert(PackageStack{}.Compact(), HasLen, 0)
+
+	sliceStack := PackageStack{
+		PackageBundle{&Package{Name: "a"}}, nil,
+		PackageBundle{&Package{Name: "b"}, &Package{Name: "b"}},
+		PackageBundle{&Package{Name: "c"}},
+	}
+
+	c.Assert(sliceStack.Slice(0, 0), HasLen, 3)
+	c.Assert(sliceStack.Slice(0, 2), HasLen, 2)
+	c.Assert(sliceStack.Slice(1, 1), HasLen, 1)
+	c.Assert(sliceStack.Slice(1, 1)[0], HasLen, 2)
+	c.Assert(sliceStack.Slice(2, 10)[0][0].Name, Equals, "c")
+	c.Assert(sliceStack.Slice(3, 0), HasLen, 0)
+	c.Assert(PackageStack{}.Slice(1, 1), HasLen, 0)
 
 	ps = PackageStack{
 		PackageBundle{
@@ -150,6 +194,37 @@ func (s *RepoSuite) TestPackageStack(c *C) {
 	c.Assert(ps[1][0].FullName(), Equals, "b-1.0.0-0.el7")
 	c.Assert(ps[2][0].FullName(), Equals, "b-1.0.1-0.el7")
 	c.Assert(ps[3][0].FullName(), Equals, "b-1.0.1-1.el7")
+
+	ps = PackageStack{
+		PackageBundle{&Package{Name: "a", Size: 100, DateAdded: time.Unix(300, 0), DateBuild: time.Unix(100, 0)}},
+		PackageBundle{
+			&Package{Name: "b", Size: 200, DateAdded: time.Unix(100, 0), DateBuild: time.Unix(300, 0)},
+			&Package{Name: "b-devel", Size: 300, DateAdded: time.Unix(200, 0), DateBuild: time.Unix(200, 0)},
+		},
+		PackageBundle{&Package{Name: "c", Size: 100, DateAdded: time.Unix(300, 0), DateBuild: time.Unix(200, 0)}},
+	}
+
+	c.Assert(ps[1].TotalSize(), Equals, uint64(500))
+	c.Assert(ps[1].LatestDateAdded().Unix(), Equals, int64(200))
+	c.Assert(ps[1].LatestDateBuild().Unix(), Equals, int64(300))
+
+	sort.Sort(PackageStackBySize{ps})
+
+	c.Assert(ps[0][0].Name, Equals, "b")
+	c.Assert(ps[1][0].Name, Equals, "a")
+	c.Assert(ps[2][0].Name, Equals, "c")
+
+	sort.Sort(PackageStackByDateAdd{ps})
+
+	c.Assert(ps[0][0].Name, Equals, "a")
+	c.Assert(ps[1][0].Name, Equals, "c")
+	c.Assert(ps[2][0].Name, Equals, "b")
+
+	sort.Sort(PackageStackByDateBuild{ps})
+
+	c.Assert(ps[0][0].Name, Equals, "b")
+	c.Assert(ps[1][0].Name, Equals, "c")
+	c.Assert(ps[2][0].Name, Equals, "a")
 }
 
 func (s *RepoSuite) TestPackagePayload(c *C) {
@@ -214,6 +289,111 @@ func (s *RepoSuite) TestRepositoryCopyPackage(c *C) {
 
 	err = r.CopyPackage(r.Testing, r.Release, pkgFile)
 	c.Assert(err, IsNil)
+
+	r2, err := NewRepository("test2", makeFSStorage(c))
+	c.Assert(err, IsNil)
+
+	err = r.CopyPackage(r.Testing, r2.Release, pkgFile)
+	c.Assert(err, ErrorMatches, `Can't copy package between storages: Target storage is not initialized`)
+
+	err = r2.Initialize([]string{data.ARCH_X64})
+	c.Assert(err, IsNil)
+
+	r.TempDir = "/_unknown_"
+
+	err = r.CopyPackage(r.Testing, r2.Release, pkgFile)
+	c.Assert(err, ErrorMatches, `Can't copy package between storages: .* /_unknown_/rep-.*`)
+
+	r.TempDir = c.MkDir()
+
+	err = r.CopyPackage(r.Testing, r2.Release, pkgFile)
+	c.Assert(err, IsNil)
+	c.Assert(r2.Release.HasPackageFile(pkgFile.Path), Equals, true)
+}
+
+func (s *RepoSuite) TestRepositoryCopyPackages(c *C) {
+	r, err := NewRepository("test", makeFSStorage(c))
+	c.Assert(err, IsNil)
+	c.Assert(r, NotNil)
+
+	files := PackageFiles{
+		{"0000000", "test-package-1.0.0-0.el7.x86_64.rpm", data.ARCH_FLAG_X64, data.ARCH_FLAG_X64},
+		{"0000000", "git-all-2.27.0-0.el7.noarch.rpm", data.ARCH_FLAG_NOARCH, data.ARCH_FLAG_X64},
+	}
+
+	err = r.CopyPackages(context.Background(), r.Testing, r.Release, files)
+	c.Assert(err, DeepEquals, ErrNotInitialized)
+
+	err = r.Initialize([]string{data.ARCH_X64})
+	c.Assert(err, IsNil)
+
+	err = r.Testing.AddPackage("../testdata/test-package-1.0.0-0.el7.x86_64.rpm")
+	c.Assert(err, IsNil)
+	err = r.Testing.AddPackage("../testdata/git-all-2.27.0-0.el7.noarch.rpm")
+	c.Assert(err, IsNil)
+
+	badFiles := append(PackageFiles{}, files...)
+	badFiles = append(badFiles, PackageFile{"0000000", "unknown-1.0.0-0.el7.x86_64.rpm", data.ARCH_FLAG_X64, data.ARCH_FLAG_X64})
+
+	err = r.CopyPackages(context.Background(), r.Testing, r.Release, badFiles)
+	c.Assert(err, NotNil)
+	c.Assert(r.Release.HasPackageFile("test-package-1.0.0-0.el7.x86_64.rpm"), Equals, false)
+	c.Assert(r.Release.HasPackageFile("git-all-2.27.0-0.el7.noarch.rpm"), Equals, false)
+
+	canceledCtx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err = r.CopyPackages(canceledCtx, r.Testing, r.Release, files)
+	c.Assert(err, ErrorMatches, `Can't copy packages: context canceled`)
+	c.Assert(r.Release.HasPackageFile("test-package-1.0.0-0.el7.x86_64.rpm"), Equals, false)
+
+	err = r.CopyPackages(context.Background(), r.Testing, r.Release, files)
+	c.Assert(err, IsNil)
+	c.Assert(r.Release.HasPackageFile("test-package-1.0.0-0.el7.x86_64.rpm"), Equals, true)
+	c.Assert(r.Release.HasPackageFile("git-all-2.27.0-0.el7.noarch.rpm"), Equals, true)
+
+	// Replaced files must be restored if copying fails
+	err = rollbackCopying(r.Release, files, []string{"../testdata/test-package-1.0.0-0.el7.x86_64.rpm", ""})
+	c.Assert(err, IsNil)
+	c.Assert(r.Release.HasPackageFile("test-package-1.0.0-0.el7.x86_64.rpm"), Equals, true)
+	c.Assert(r.Release.HasPackageFile("git-all-2.27.0-0.el7.noarch.rpm"), Equals, false)
+
+	c.Assert(r.CopyPackages(context.Background(), nil, r.Release, files), ErrorMatches, `Source sub-repository is nil`)
+	c.Assert(r.CopyPackages(context.Background(), r.Testing, nil, files), ErrorMatches, `Target sub-repository is nil`)
+}
+
+func (s *RepoSuite) TestRepositoryReleaseOnly(c *C) {
+	r, err := NewRepository("test", makeFSStorage(c))
+	c.Assert(err, IsNil)
+	c.Assert(r, NotNil)
+
+	_, err = r.ReleaseOnly()
+	c.Assert(err, DeepEquals, ErrNotInitialized)
+
+	err = r.Initialize([]string{data.ARCH_X64})
+	c.Assert(err, IsNil)
+
+	err = r.Testing.AddPackage("../testdata/test-package-1.0.0-0.el7.x86_64.rpm")
+	c.Assert(err, IsNil)
+	err = r.Release.AddPackage("../testdata/test-package-1.0.0-0.el7.x86_64.rpm")
+	c.Assert(err, IsNil)
+	err = r.Release.AddPackage("../testdata/git-all-2.27.0-0.el7.noarch.rpm")
+	c.Assert(err, IsNil)
+
+	_, err = r.Testing.Reindex(false, nil)
+	c.Assert(err, IsNil)
+	_, err = r.Release.Reindex(false, nil)
+	c.Assert(err, IsNil)
+
+	stack, err := r.ReleaseOnly()
+	c.Assert(err, IsNil)
+	c.Assert(stack, HasLen, 1)
+	c.Assert(stack[0], HasLen, 1)
+	c.Assert(stack[0][0].Name, Equals, "git-all")
+
+	r.storage = &FailStorage{}
+	_, err = r.ReleaseOnly()
+	c.Assert(err, NotNil)
 }
 
 func (s *RepoSuite) TestRepositoryIsPackageReleased(c *C) {
@@ -247,9 +427,9 @@ func (s *RepoSuite) TestRepositoryIsPackageReleased(c *C) {
 	err = r.CopyPackage(r.Testing, r.Release, pkgFile)
 	c.Assert(err, IsNil)
 
-	err = r.Testing.Reindex(false, nil)
+	_, err = r.Testing.Reindex(false, nil)
 	c.Assert(err, IsNil)
-	err = r.Release.Reindex(false, nil)
+	_, err = r.Release.Reindex(false, nil)
 	c.Assert(err, IsNil)
 
 	_, _, err = r.IsPackageReleased(nil)
@@ -271,6 +451,25 @@ func (s *RepoSuite) TestRepositoryIsPackageReleased(c *C) {
 	c.Assert(err, IsNil)
 	c.Assert(ok, Equals, false)
 
+	p = &Package{
+		Name: "test-package", Version: "1.0.0", Release: "0.el7",
+		ArchFlags: data.ARCH_FLAG_X64,
+	}
+
+	ok, _, err = r.IsPackageReleased(p)
+	c.Assert(err, IsNil)
+	c.Assert(ok, Equals, true)
+
+	p.Epoch = "0"
+	ok, _, err = r.IsPackageReleased(p)
+	c.Assert(err, IsNil)
+	c.Assert(ok, Equals, true)
+
+	p.Epoch = "1"
+	ok, _, err = r.IsPackageReleased(p)
+	c.Assert(err, IsNil)
+	c.Assert(ok, Equals, false)
+
 	r.storage = &FailStorage{}
 	_, _, err = r.IsPackageReleased(p)
 	c.Assert(err, NotNil)
@@ -315,9 +514,9 @@ func (s *RepoSuite) TestRepositoryInfo(c *C) {
 	err = r.CopyPackage(r.Testing, r.Release, pkgFile)
 	c.Assert(err, IsNil)
 
-	err = r.Testing.Reindex(false, nil)
+	_, err = r.Testing.Reindex(false, nil)
 	c.Assert(err, IsNil)
-	err = r.Release.Reindex(false, nil)
+	_, err = r.Release.Reindex(false, nil)
 	c.Assert(err, IsNil)
 
 	_, _, err = r.Info("test.rpm", data.ARCH_X64)
@@ -328,6 +527,16 @@ func (s *RepoSuite) TestRepositoryInfo(c *C) {
 	c.Assert(err, IsNil)
 	c.Assert(pkg, NotNil)
 	c.Assert(mdt.IsZero(), Equals, false)
+	c.Assert(pkg.Info.Packager, Equals, "")
+
+	db, err := r.storage.GetDB(data.REPO_TESTING, data.ARCH_X64, data.DB_PRIMARY)
+	c.Assert(err, IsNil)
+	_, err = db.Exec(`UPDATE packages SET rpm_packager = "John Doe <john@domain.com>";`)
+	c.Assert(err, IsNil)
+
+	pkg, _, err = r.Info("test-package", data.ARCH_X64)
+	c.Assert(err, IsNil)
+	c.Assert(pkg.Info.Packager, Equals, "John Doe <john@domain.com>")
 
 	r.storage = &FailStorage{}
 	_, _, err = r.Info("test-package", data.ARCH_X64)
@@ -345,6 +554,48 @@ func (s *RepoSuite) TestRepositoryInfo(c *C) {
 	c.Assert(err, NotNil)
 }
 
+func (s *RepoSuite) TestRepositoryInfoCache(c *C) {
+	r, err := NewRepository("test", makeFSStorage(c))
+	c.Assert(err, IsNil)
+	c.Assert(r, NotNil)
+
+	r.CacheInfo = true
+
+	err = r.Initialize([]string{data.ARCH_X64})
+	c.Assert(err, IsNil)
+
+	err = r.Testing.AddPackage("../testdata/test-package-1.0.0-0.el7.x86_64.rpm")
+	c.Assert(err, IsNil)
+	_, err = r.Testing.Reindex(false, nil)
+	c.Assert(err, IsNil)
+
+	pkg1, _, err := r.Info("test-package", data.ARCH_X64)
+	c.Assert(err, IsNil)
+	c.Assert(pkg1, NotNil)
+
+	cache := r.Testing.infoCache[data.ARCH_X64]
+	c.Assert(cache, NotNil)
+	c.Assert(cache.Packages, HasLen, 1)
+	c.Assert(cache.Packages["test-package-0:1.0.0-0.el7.x86_64"], NotNil)
+
+	// Cached info must not be changed by caller
+	pkg1.Info.Summary = "CHANGED"
+
+	pkg2, _, err := r.Info("test-package", data.ARCH_X64)
+	c.Assert(err, IsNil)
+	c.Assert(pkg2 == pkg1, Equals, false)
+	c.Assert(pkg2.Info.Summary, Not(Equals), "CHANGED")
+	c.Assert(pkg2.FullName(), Equals, pkg1.FullName())
+
+	cache.Revision = 0
+
+	pkg3, _, err := r.Info("test-package", data.ARCH_X64)
+	c.Assert(err, IsNil)
+	c.Assert(pkg3.FullName(), Equals, pkg1.FullName())
+	c.Assert(r.Testing.infoCache[data.ARCH_X64] == cache, Equals, false)
+	c.Assert(r.Testing.infoCache[data.ARCH_X64].Revision, Not(Equals), int64(0))
+}
+
 func (s *RepoSuite) TestRepositorySigning(c *C) {
 	r, err := NewRepository("test", makeFSStorage(c))
 	c.Assert(err, IsNil)
@@ -360,6 +611,35 @@ func (s *RepoSuite) TestRepositorySigning(c *C) {
 	c.Assert(err, IsNil)
 }
 
+func (s *RepoSuite) TestRepositoryTrustedKeys(c *C) {
+	r, err := NewRepository("test", makeFSStorage(c))
+	c.Assert(err, IsNil)
+	c.Assert(r, NotNil)
+
+	keys, err := r.ReadVerificationKeys()
+	c.Assert(err, IsNil)
+	c.Assert(keys, HasLen, 0)
+
+	err = r.ReadTrustedKeys("../testdata/unknown.public")
+	c.Assert(err, ErrorMatches, `Can't read trusted key \.\./testdata/unknown\.public: open \.\./testdata/unknown\.public: no such file or directory`)
+
+	err = r.ReadTrustedKeys("../testdata/reptest.public", "../testdata/reptest.private")
+	c.Assert(err, IsNil)
+	c.Assert(r.TrustedKeys, HasLen, 2)
+	c.Assert(r.IsSigningRequired(), Equals, false)
+
+	err = r.ReadSigningKey("../testdata/reptest.private")
+	c.Assert(err, IsNil)
+
+	keys, err = r.ReadVerificationKeys()
+	c.Assert(err, IsNil)
+	c.Assert(keys, HasLen, 3)
+
+	r.TrustedKeys = append(r.TrustedKeys, &sign.ArmoredKey{})
+	_, err = r.ReadVerificationKeys()
+	c.Assert(err, NotNil)
+}
+
 func (s *RepoSuite) TestRepositoryPurgeCache(c *C) {
 	r, err := NewRepository("test", makeFSStorage(c))
 	c.Assert(err, IsNil)
@@ -374,6 +654,9 @@ func (s *RepoSuite) TestRepositoryPurgeCache(c *C) {
 
 	err = r.PurgeCache()
 	c.Assert(err, IsNil)
+
+	err = r.InvalidateCache()
+	c.Assert(err, IsNil)
 }
 
 func (s *RepoSuite) TestSubRepositoryAddPackage(c *C) {
@@ -419,6 +702,84 @@ func (s *RepoSuite) TestSubRepositoryAddPackage(c *C) {
 	c.Assert(err, IsNil)
 }
 
+func (s *RepoSuite) TestSubRepositoryAddPackageSignedWithTrustedKey(c *C) {
+	r, err := NewRepository("test", makeFSStorage(c))
+	c.Assert(err, IsNil)
+	c.Assert(r, NotNil)
+
+	err = r.Initialize([]string{data.ARCH_X64})
+	c.Assert(err, IsNil)
+
+	armKey, err := sign.ReadKey("../testdata/reptest-old.private")
+	c.Assert(err, IsNil)
+
+	password, _ := secstr.NewSecureString("test1234TEST")
+	oldKey, err := armKey.Read(password)
+	c.Assert(err, IsNil)
+
+	pkgFile := c.MkDir() + "/test-package-1.0.0-0.el7.x86_64.rpm"
+
+	err = sign.SignPackage("../testdata/test-package-1.0.0-0.el7.x86_64.rpm", pkgFile, oldKey)
+	c.Assert(err, IsNil)
+
+	err = r.ReadSigningKey("../testdata/reptest.private")
+	c.Assert(err, IsNil)
+
+	err = r.Testing.AddPackage(pkgFile)
+	c.Assert(err, ErrorMatches, `Can't add file to repository: Repository allows only signed packages`)
+
+	err = r.ReadTrustedKeys("../testdata/reptest-old.public")
+	c.Assert(err, IsNil)
+
+	err = r.Testing.AddPackage(pkgFile)
+	c.Assert(err, IsNil)
+
+	added, err := r.Release.AddPackages([]string{pkgFile})
+	c.Assert(err, IsNil)
+	c.Assert(added, HasLen, 1)
+}
+
+func (s *RepoSuite) TestSubRepositoryAddPackages(c *C) {
+	r, err := NewRepository("test", makeFSStorage(c))
+	c.Assert(err, IsNil)
+	c.Assert(r, NotNil)
+
+	_, err = r.Testing.AddPackages(nil)
+	c.Assert(err, ErrorMatches, `Can't add packages to repository: Path to file is empty`)
+
+	_, err = r.Testing.AddPackages([]string{"test.rpm"})
+	c.Assert(err, ErrorMatches, `Can't add packages to repository: Repository is not initialized`)
+
+	err = r.Initialize([]string{data.ARCH_X64})
+	c.Assert(err, IsNil)
+
+	added, err := r.Testing.AddPackages([]string{"../testdata/comps.xml"})
+	c.Assert(err, ErrorMatches, `comps.xml: Can't add file to repository: ../testdata/comps.xml is not an RPM package`)
+	c.Assert(added, HasLen, 0)
+
+	added, err = r.Testing.AddPackages([]string{
+		"../testdata/test-package-1.0.0-0.el7.x86_64.rpm",
+		"../testdata/git-all-2.27.0-0.el7.noarch.rpm",
+		"test.rpm",
+	})
+
+	c.Assert(err, ErrorMatches, `test.rpm: Can't add package to repository: File test.rpm doesn't exist or not accessible`)
+	c.Assert(added, DeepEquals, []string{
+		"x86_64/test-package-1.0.0-0.el7.x86_64.rpm",
+		"x86_64/git-all-2.27.0-0.el7.noarch.rpm",
+	})
+
+	r.SigningKey = &sign.ArmoredKey{}
+
+	_, err = r.Testing.AddPackages([]string{"../testdata/test-package-1.0.0-0.el7.x86_64.rpm"})
+	c.Assert(err, ErrorMatches, `Can't add packages to repository: Key is empty`)
+
+	r.SigningKey = nil
+
+	_, err = r.Testing.AddPackages([]string{"../testdata/test-package-1.0.0-0.el7.x86_64.rpm"})
+	c.Assert(err, IsNil)
+}
+
 func (s *RepoSuite) TestSubRepositoryRemovePackage(c *C) {
 	r, err := NewRepository("test", makeFSStorage(c))
 	c.Assert(err, IsNil)
@@ -481,7 +842,7 @@ func (s *RepoSuite) TestSubRepositoryStats(c *C) {
 	err = r.Testing.AddPackage("../testdata/test-package-1.0.0-0.el7.x86_64.rpm")
 	c.Assert(err, IsNil)
 
-	err = r.Testing.Reindex(false, nil)
+	_, err = r.Testing.Reindex(false, nil)
 	c.Assert(err, IsNil)
 
 	stats, err := r.Testing.Stats()
@@ -491,11 +852,205 @@ func (s *RepoSuite) TestSubRepositoryStats(c *C) {
 	c.Assert(stats.TotalPackages, Equals, 1)
 	c.Assert(stats.TotalSize, Equals, int64(2288))
 
+	modTime, err := r.Testing.GetModTime(data.ARCH_X64)
+	c.Assert(err, IsNil)
+	c.Assert(modTime.IsZero(), Equals, false)
+
 	r.storage = &FailStorage{}
 	_, err = r.Testing.Stats()
 	c.Assert(err, NotNil)
 }
 
+func (s *RepoSuite) TestRepositoryStatsMerge(c *C) {
+	var ns *RepositoryStats
+
+	ns.Merge(&RepositoryStats{TotalPackages: 1})
+
+	d1 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	d2 := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	stats := &RepositoryStats{}
+	stats.Merge(nil)
+
+	c.Assert(stats.TotalPackages, Equals, 0)
+
+	stats.Merge(&RepositoryStats{
+		Packages:      map[string]int{data.ARCH_X64: 2, data.ARCH_SRC: 1},
+		Sizes:         map[string]int64{data.ARCH_X64: 200, data.ARCH_SRC: 50},
+		TotalPackages: 3,
+		TotalSize:     250,
+		Updated:       d2,
+	})
+
+	stats.Merge(&RepositoryStats{
+		Packages:      map[string]int{data.ARCH_X64: 3, data.ARCH_NOARCH: 1},
+		Sizes:         map[string]int64{data.ARCH_X64: 300, data.ARCH_NOARCH: 10},
+		TotalPackages: 4,
+		TotalSize:     310,
+		Updated:       d1,
+	})
+
+	c.Assert(stats.TotalPackages, Equals, 7)
+	c.Assert(stats.TotalSize, Equals, int64(560))
+	c.Assert(stats.Packages[data.ARCH_X64], Equals, 5)
+	c.Assert(stats.Packages[data.ARCH_SRC], Equals, 1)
+	c.Assert(stats.Packages[data.ARCH_NOARCH], Equals, 1)
+	c.Assert(stats.Sizes[data.ARCH_X64], Equals, int64(500))
+	c.Assert(stats.Sizes[data.ARCH_NOARCH], Equals, int64(10))
+	c.Assert(stats.Updated.Equal(d2), Equals, true)
+}
+
+func (s *RepoSuite) TestSubRepositoryProvidesCollisions(c *C) {
+	r, err := NewRepository("test", makeFSStorage(c))
+	c.Assert(err, IsNil)
+	c.Assert(r, NotNil)
+
+	_, err = r.Testing.ProvidesCollisions()
+	c.Assert(err, NotNil)
+	c.Assert(err, DeepEquals, ErrNotInitialized)
+
+	err = r.Initialize([]string{data.ARCH_X64})
+	c.Assert(err, IsNil)
+
+	err = r.Testing.AddPackage("../testdata/test-package-1.0.0-0.el7.x86_64.rpm")
+	c.Assert(err, IsNil)
+	err = r.Testing.AddPackage("../testdata/git-all-2.27.0-0.el7.noarch.rpm")
+	c.Assert(err, IsNil)
+
+	_, err = r.Testing.Reindex(false, nil)
+	c.Assert(err, IsNil)
+
+	collisions, err := r.Testing.ProvidesCollisions()
+	c.Assert(err, IsNil)
+	c.Assert(collisions, HasLen, 0)
+
+	r.storage = &FailStorage{}
+	_, err = r.Testing.ProvidesCollisions()
+	c.Assert(err, NotNil)
+}
+
+func (s *RepoSuite) TestSubRepositoryFindEmptyMetaFields(c *C) {
+	r, err := NewRepository("test", makeFSStorage(c))
+	c.Assert(err, IsNil)
+	c.Assert(r, NotNil)
+
+	_, err = r.Testing.FindEmptyMetaFields(MetaFields)
+	c.Assert(err, NotNil)
+	c.Assert(err, DeepEquals, ErrNotInitialized)
+
+	err = r.Initialize([]string{data.ARCH_X64})
+	c.Assert(err, IsNil)
+
+	err = r.Testing.AddPackage("../testdata/test-package-1.0.0-0.el7.x86_64.rpm")
+	c.Assert(err, IsNil)
+
+	_, err = r.Testing.Reindex(false, nil)
+	c.Assert(err, IsNil)
+
+	_, err = r.Testing.FindEmptyMetaFields([]string{"unknown"})
+	c.Assert(err, ErrorMatches, `Unknown metadata field "unknown"`)
+
+	info, err := r.Testing.FindEmptyMetaFields(nil)
+	c.Assert(err, IsNil)
+	c.Assert(info, IsNil)
+
+	info, err = r.Testing.FindEmptyMetaFields([]string{META_FIELD_SUMMARY, META_FIELD_LICENSE})
+	c.Assert(err, IsNil)
+	c.Assert(info, HasLen, 0)
+
+	r.storage = &FailStorage{}
+	_, err = r.Testing.FindEmptyMetaFields(MetaFields)
+	c.Assert(err, NotNil)
+}
+
+func (s *RepoSuite) TestSubRepositoryFindMissingMetaFiles(c *C) {
+	r, err := NewRepository("test", makeFSStorage(c))
+	c.Assert(err, IsNil)
+	c.Assert(r, NotNil)
+
+	_, err = r.Testing.FindMissingMetaFiles()
+	c.Assert(err, NotNil)
+	c.Assert(err, DeepEquals, ErrNotInitialized)
+
+	err = r.Initialize([]string{data.ARCH_X64})
+	c.Assert(err, IsNil)
+
+	err = r.Testing.AddPackage("../testdata/test-package-1.0.0-0.el7.x86_64.rpm")
+	c.Assert(err, IsNil)
+
+	_, err = r.Testing.Reindex(false, nil)
+	c.Assert(err, IsNil)
+
+	missing, err := r.Testing.FindMissingMetaFiles()
+	c.Assert(err, IsNil)
+	c.Assert(missing, HasLen, 0)
+
+	r.storage = &FailStorage{}
+	_, err = r.Testing.FindMissingMetaFiles()
+	c.Assert(err, NotNil)
+}
+
+func (s *RepoSuite) TestSubRepositoryFindOrphanPackages(c *C) {
+	r, err := NewRepository("test", makeFSStorage(c))
+	c.Assert(err, IsNil)
+	c.Assert(r, NotNil)
+
+	_, err = r.Testing.FindOrphanPackages()
+	c.Assert(err, NotNil)
+	c.Assert(err, DeepEquals, ErrNotInitialized)
+
+	err = r.Initialize([]string{data.ARCH_X64})
+	c.Assert(err, IsNil)
+
+	err = r.Testing.AddPackage("../testdata/test-package-1.0.0-0.el7.x86_64.rpm")
+	c.Assert(err, IsNil)
+
+	orphans, err := r.Testing.FindOrphanPackages()
+	c.Assert(err, IsNil)
+	c.Assert(orphans[data.ARCH_X64], HasLen, 1)
+
+	_, err = r.Testing.Reindex(false, nil)
+	c.Assert(err, IsNil)
+
+	orphans, err = r.Testing.FindOrphanPackages()
+	c.Assert(err, IsNil)
+	c.Assert(orphans, HasLen, 0)
+
+	r.storage = &FailStorage{}
+	_, err = r.Testing.FindOrphanPackages()
+	c.Assert(err, NotNil)
+}
+
+func (s *RepoSuite) TestSubRepositoryDepGraph(c *C) {
+	r, err := NewRepository("test", makeFSStorage(c))
+	c.Assert(err, IsNil)
+	c.Assert(r, NotNil)
+
+	_, err = r.Testing.DepGraph()
+	c.Assert(err, NotNil)
+	c.Assert(err, DeepEquals, ErrNotInitialized)
+
+	err = r.Initialize([]string{data.ARCH_X64})
+	c.Assert(err, IsNil)
+
+	err = r.Testing.AddPackage("../testdata/test-package-1.0.0-0.el7.x86_64.rpm")
+	c.Assert(err, IsNil)
+	err = r.Testing.AddPackage("../testdata/git-all-2.27.0-0.el7.noarch.rpm")
+	c.Assert(err, IsNil)
+
+	_, err = r.Testing.Reindex(false, nil)
+	c.Assert(err, IsNil)
+
+	graph, err := r.Testing.DepGraph()
+	c.Assert(err, IsNil)
+	c.Assert(graph, NotNil)
+	c.Assert(graph.Nodes, DeepEquals, []string{"git-all", "test-package"})
+
+	r.storage = &FailStorage{}
+	_, err = r.Testing.DepGraph()
+	c.Assert(err, NotNil)
+}
+
 func (s *RepoSuite) TestSubRepositoryList(c *C) {
 	r, err := NewRepository("test", makeFSStorage(c))
 	c.Assert(err, IsNil)
@@ -511,7 +1066,7 @@ func (s *RepoSuite) TestSubRepositoryList(c *C) {
 	c.Assert(err, IsNil)
 	err = r.Testing.AddPackage("../testdata/git-all-2.27.0-0.el7.noarch.rpm")
 	c.Assert(err, IsNil)
-	err = r.Testing.Reindex(false, nil)
+	_, err = r.Testing.Reindex(false, nil)
 	c.Assert(err, IsNil)
 
 	stk, err := r.Testing.List("", false)
@@ -521,11 +1076,30 @@ func (s *RepoSuite) TestSubRepositoryList(c *C) {
 	stk, err = r.Testing.List("", true)
 	c.Assert(err, IsNil)
 	c.Assert(stk, HasLen, 2)
+	c.Assert(stk[0][0].Size, Not(Equals), uint64(0))
+	c.Assert(stk[0][0].DateAdded.IsZero(), Equals, false)
+	c.Assert(stk[0][0].DateBuild.IsZero(), Equals, false)
 
 	stk, err = r.Testing.List("git", false)
 	c.Assert(err, IsNil)
 	c.Assert(stk, HasLen, 1)
 
+	stk, err = r.Testing.List("git*", false)
+	c.Assert(err, IsNil)
+	c.Assert(stk, HasLen, 1)
+
+	stk, err = r.Testing.List("*-all", false)
+	c.Assert(err, IsNil)
+	c.Assert(stk, HasLen, 1)
+
+	stk, err = r.Testing.List("all*", false)
+	c.Assert(err, IsNil)
+	c.Assert(stk, HasLen, 0)
+
+	stk, err = r.Testing.List("test-package-1.?.0*", false)
+	c.Assert(err, IsNil)
+	c.Assert(stk, HasLen, 1)
+
 	r.storage = &FailStorage{}
 	_, err = r.Testing.List("git", false)
 	c.Assert(err, NotNil)
@@ -546,7 +1120,7 @@ func (s *RepoSuite) TestSubRepositoryFind(c *C) {
 	c.Assert(err, IsNil)
 	err = r.Testing.AddPackage("../testdata/git-all-2.27.0-0.el7.noarch.rpm")
 	c.Assert(err, IsNil)
-	err = r.Testing.Reindex(false, nil)
+	_, err = r.Testing.Reindex(false, nil)
 	c.Assert(err, IsNil)
 
 	ps, err := r.Testing.Find(nil)
@@ -565,6 +1139,26 @@ func (s *RepoSuite) TestSubRepositoryFind(c *C) {
 	c.Assert(err, IsNil)
 	c.Assert(ps, HasLen, 0)
 
+	ps, err = r.Testing.FindWithMatches(search.Query{
+		search.TermName("git-*"),
+		search.TermProvides(data.Dependency{Name: "git-all"}),
+	})
+	c.Assert(err, IsNil)
+	c.Assert(ps, HasLen, 1)
+	c.Assert(ps[0][0].Matches, HasLen, 2)
+	c.Assert(ps[0][0].Matches[0].Term.Type, Equals, search.TERM_NAME)
+	c.Assert(ps[0][0].Matches[0].Value, Equals, "git-all")
+	c.Assert(ps[0][0].Matches[1].Term.Type, Equals, search.TERM_PROVIDES)
+	c.Assert(ps[0][0].Matches[1].Value, Equals, "git-all")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r.Context = ctx
+	cancel()
+
+	_, err = r.Testing.Find(search.Query{search.TermName("git-all")})
+	c.Assert(err, ErrorMatches, `.*context canceled`)
+
+	r.Context = nil
 	r.storage = &FailStorage{}
 	_, err = r.Testing.Find(search.Query{search.TermName("git-all")})
 	c.Assert(err, NotNil)
@@ -575,7 +1169,7 @@ func (s *RepoSuite) TestSubRepositoryReindex(c *C) {
 	c.Assert(err, IsNil)
 	c.Assert(r, NotNil)
 
-	err = r.Testing.Reindex(false, make(chan string, 99))
+	_, err = r.Testing.Reindex(false, make(chan string, 99))
 	c.Assert(err, NotNil)
 	c.Assert(err, DeepEquals, ErrNotInitialized)
 
@@ -585,14 +1179,41 @@ func (s *RepoSuite) TestSubRepositoryReindex(c *C) {
 	c.Assert(err, IsNil)
 	err = r.Testing.AddPackage("../testdata/git-all-2.27.0-0.el7.noarch.rpm")
 	c.Assert(err, IsNil)
-	err = r.Testing.Reindex(false, make(chan string, 99))
+	stats, err := r.Testing.Reindex(false, make(chan string, 99))
 	c.Assert(err, IsNil)
+	c.Assert(stats, HasLen, 1)
+	c.Assert(stats[data.ARCH_X64], NotNil)
+	c.Assert(stats[data.ARCH_X64].Packages, Equals, 2)
 
 	r.storage = &FailStorage{}
-	err = r.Testing.Reindex(false, make(chan string, 99))
+	_, err = r.Testing.Reindex(false, make(chan string, 99))
 	c.Assert(err, NotNil)
 }
 
+func (s *RepoSuite) TestSubRepositoryReindexArchs(c *C) {
+	r, err := NewRepository("test", makeFSStorage(c))
+	c.Assert(err, IsNil)
+	c.Assert(r, NotNil)
+
+	_, err = r.Testing.ReindexArchs(context.Background(), []string{data.ARCH_X64}, false, nil, nil)
+	c.Assert(err, DeepEquals, ErrNotInitialized)
+
+	err = r.Initialize([]string{data.ARCH_X64, data.ARCH_AARCH64})
+	c.Assert(err, IsNil)
+	err = r.Testing.AddPackage("../testdata/test-package-1.0.0-0.el7.x86_64.rpm")
+	c.Assert(err, IsNil)
+
+	stats, err := r.Testing.ReindexArchs(context.Background(), []string{data.ARCH_X64}, false, make(chan string, 99), nil)
+	c.Assert(err, IsNil)
+	c.Assert(stats, HasLen, 1)
+	c.Assert(stats[data.ARCH_X64], NotNil)
+	c.Assert(stats[data.ARCH_X64].Packages, Equals, 1)
+
+	stats, err = r.Testing.ReindexArchs(context.Background(), nil, false, nil, nil)
+	c.Assert(err, IsNil)
+	c.Assert(stats, HasLen, 2)
+}
+
 func (s *RepoSuite) TestSubRepositoryCaching(c *C) {
 	r, err := NewRepository("test", makeFSStorage(c))
 	c.Assert(err, IsNil)
@@ -600,22 +1221,27 @@ func (s *RepoSuite) TestSubRepositoryCaching(c *C) {
 
 	c.Assert(r.Testing.IsCacheValid(), Equals, false)
 	c.Assert(r.Testing.WarmupCache(), DeepEquals, ErrNotInitialized)
+	c.Assert(r.Testing.PurgeCache(), DeepEquals, ErrNotInitialized)
 
 	err = r.Initialize([]string{data.ARCH_X64})
 	c.Assert(err, IsNil)
 
 	err = r.Testing.AddPackage("../testdata/git-all-2.27.0-0.el7.noarch.rpm")
 	c.Assert(err, IsNil)
-	err = r.Testing.Reindex(true, nil)
+	_, err = r.Testing.Reindex(true, nil)
 	c.Assert(err, IsNil)
 
 	c.Assert(r.Testing.IsCacheValid(), Equals, false)
 	c.Assert(r.Testing.WarmupCache(), IsNil)
 	c.Assert(r.Testing.IsCacheValid(), Equals, true)
 
+	c.Assert(r.Testing.PurgeCache(), IsNil)
+	c.Assert(r.Testing.IsCacheValid(), Equals, false)
+
 	r.storage = &FailStorage{}
 
 	c.Assert(r.Testing.WarmupCache(), NotNil)
+	c.Assert(r.Testing.PurgeCache(), ErrorMatches, `Can't purge testing cache: ERROR`)
 }
 
 func (s *RepoSuite) TestSubRepositoryGetFullPackagePath(c *C) {
@@ -672,6 +1298,14 @@ func (s *FailStorage) AddPackage(repo, rpmFilePath string) error {
 	return fmt.Errorf("ERROR")
 }
 
+func (s *FailStorage) AddPackages(repo string, rpmFilePaths []string) ([]string, error) {
+	return nil, fmt.Errorf("ERROR")
+}
+
+func (s *FailStorage) SyncNoarchPackages(repo string) ([]string, error) {
+	return nil, fmt.Errorf("ERROR")
+}
+
 func (s *FailStorage) RemovePackage(repo, arch, rpmFileRelPath string) error {
 	return fmt.Errorf("ERROR")
 }
@@ -704,18 +1338,50 @@ func (s *FailStorage) GetPackagePath(repo, arch, pkg string) string {
 	return ""
 }
 
-func (s *FailStorage) Reindex(repo, arch string, full bool) error {
-	return fmt.Errorf("ERROR")
+func (s *FailStorage) OpenPackage(repo, arch, rpmFileRelPath string) (io.ReadCloser, error) {
+	return nil, fmt.Errorf("ERROR")
+}
+
+func (s *FailStorage) Reindex(ctx context.Context, repo, arch string, full bool, progress chan<- index.Progress) (*index.GenerateStats, error) {
+	return nil, fmt.Errorf("ERROR")
 }
 
 func (s *FailStorage) GetDB(repo, arch, dbType string) (*sql.DB, error) {
 	return nil, fmt.Errorf("ERROR")
 }
 
+func (s *FailStorage) Query(ctx context.Context, repo, arch, dbType, query string, args ...any) (*sql.Rows, error) {
+	return nil, fmt.Errorf("ERROR")
+}
+
 func (s *FailStorage) GetModTime(repo, arch string) (time.Time, error) {
 	return time.Time{}, nil
 }
 
+func (s *FailStorage) GetMetaIndexPath(repo, arch string) string {
+	return ""
+}
+
+func (s *FailStorage) FindMissingMetaFiles(repo, arch string) ([]string, error) {
+	return nil, fmt.Errorf("ERROR")
+}
+
+func (s *FailStorage) RepairPackageAttrs(repo, arch, rpmFileRelPath string) error {
+	return fmt.Errorf("ERROR")
+}
+
+func (s *FailStorage) ReadAuxData(name string) ([]byte, error) {
+	return nil, fmt.Errorf("ERROR")
+}
+
+func (s *FailStorage) WriteAuxData(name string, data []byte) error {
+	return fmt.Errorf("ERROR")
+}
+
+func (s *FailStorage) FindOrphanPackages(repo, arch string) ([]string, error) {
+	return nil, fmt.Errorf("ERROR")
+}
+
 func (s *FailStorage) InvalidateCache() error {
 	return fmt.Errorf("ERROR")
 }
@@ -728,6 +1394,18 @@ func (s *FailStorage) PurgeCache() error {
 	return fmt.Errorf("ERROR")
 }
 
+func (s *FailStorage) FindDuplicates(repo string) (map[string][]string, error) {
+	return nil, fmt.Errorf("ERROR")
+}
+
+func (s *FailStorage) Verify(repo, arch string) ([]error, error) {
+	return nil, fmt.Errorf("ERROR")
+}
+
+func (s *FailStorage) PurgeCacheFor(repo, arch string) error {
+	return fmt.Errorf("ERROR")
+}
+
 func (s *FailStorage) WarmupCache(repo, arch string) error {
 	return fmt.Errorf("ERROR")
 }
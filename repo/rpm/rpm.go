@@ -8,11 +8,17 @@ package rpm
 // ////////////////////////////////////////////////////////////////////////////////// //
 
 import (
+	"bufio"
 	"encoding/binary"
 	"errors"
 	"io"
 	"os"
 	"strings"
+	"time"
+
+	"github.com/sassoftware/go-rpmutils"
+
+	"github.com/essentialkaos/rep/v3/repo/data"
 )
 
 // ////////////////////////////////////////////////////////////////////////////////// //
@@ -161,6 +167,42 @@ type LEAD struct {
 	IsSrc    bool
 }
 
+// Info contains package metadata from RPM header
+type Info struct {
+	Name          string
+	Epoch         string
+	Version       string
+	Release       string
+	Arch          string
+	Src           string
+	Summary       string
+	Desc          string
+	URL           string
+	Vendor        string
+	Packager      string
+	Group         string
+	License       string
+	SizeInstalled uint64
+	DateBuild     time.Time
+	Requires      []data.Dependency
+	Provides      []data.Dependency
+	Payload       []PayloadObject
+	Changelog     *Changelog
+}
+
+// PayloadObject contains info about file or directory in package payload
+type PayloadObject struct {
+	IsDir bool
+	Path  string
+}
+
+// Changelog contains the latest changelog record
+type Changelog struct {
+	Author string
+	Text   string
+	Date   time.Time
+}
+
 // ////////////////////////////////////////////////////////////////////////////////// //
 
 // IsRPM checks if given file is an RPM file
@@ -201,8 +243,183 @@ func ReadLEAD(file string) (LEAD, error) {
 	}, err
 }
 
+// ReadInfo reads package metadata from RPM header
+func ReadInfo(file string) (*Info, error) {
+	fd, err := os.OpenFile(file, os.O_RDONLY, 0)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer fd.Close()
+
+	hdr, err := rpmutils.ReadHeader(bufio.NewReader(fd))
+
+	if err != nil {
+		return nil, err
+	}
+
+	nevra, err := hdr.GetNEVRA()
+
+	if err != nil {
+		return nil, err
+	}
+
+	info := &Info{
+		Name:     nevra.Name,
+		Epoch:    nevra.Epoch,
+		Version:  nevra.Version,
+		Release:  nevra.Release,
+		Arch:     nevra.Arch,
+		Src:      getHeaderString(hdr, rpmutils.SOURCERPM),
+		Summary:  getHeaderString(hdr, rpmutils.SUMMARY),
+		Desc:     getHeaderString(hdr, rpmutils.DESCRIPTION),
+		URL:      getHeaderString(hdr, rpmutils.URL),
+		Vendor:   getHeaderString(hdr, rpmutils.VENDOR),
+		Packager: getHeaderString(hdr, rpmutils.PACKAGER),
+		Group:    getHeaderString(hdr, rpmutils.GROUP),
+		License:  getHeaderString(hdr, rpmutils.LICENSE),
+	}
+
+	if info.Src == "" {
+		info.Arch = data.ARCH_SRC
+	}
+
+	size, err := hdr.InstalledSize()
+
+	if err == nil {
+		info.SizeInstalled = uint64(size)
+	}
+
+	buildTime, err := hdr.GetUint64s(rpmutils.BUILDTIME)
+
+	if err == nil && len(buildTime) != 0 {
+		info.DateBuild = time.Unix(int64(buildTime[0]), 0)
+	}
+
+	info.Requires = getHeaderDeps(
+		hdr, rpmutils.REQUIRENAME, rpmutils.REQUIREFLAGS, rpmutils.REQUIREVERSION,
+	)
+
+	info.Provides = getHeaderDeps(
+		hdr, rpmutils.PROVIDENAME, rpmutils.PROVIDEFLAGS, rpmutils.PROVIDEVERSION,
+	)
+
+	info.Payload, err = getHeaderPayload(hdr)
+
+	if err != nil {
+		return nil, err
+	}
+
+	info.Changelog = getHeaderChangelog(hdr)
+
+	return info, nil
+}
+
 // ////////////////////////////////////////////////////////////////////////////////// //
 
+// getHeaderString returns value of string tag or empty string if tag is not present
+func getHeaderString(hdr *rpmutils.RpmHeader, tag int) string {
+	value, _ := hdr.GetString(tag)
+	return value
+}
+
+// getHeaderDeps returns slice with dependencies (requires or provides)
+func getHeaderDeps(hdr *rpmutils.RpmHeader, nameTag, flagsTag, versionTag int) []data.Dependency {
+	names, _ := hdr.GetStrings(nameTag)
+	flags, _ := hdr.GetUint64s(flagsTag)
+	versions, _ := hdr.GetStrings(versionTag)
+
+	if len(names) != len(flags) || len(names) != len(versions) {
+		return nil
+	}
+
+	var result []data.Dependency
+
+	for i, name := range names {
+		if strings.HasPrefix(name, "rpmlib(") {
+			continue
+		}
+
+		dep := data.Dependency{Name: name, Flag: parseSenseFlag(flags[i])}
+		dep.Epoch, dep.Version, dep.Release = parseEVR(versions[i])
+
+		result = append(result, dep)
+	}
+
+	return result
+}
+
+// getHeaderPayload returns slice with info about package payload
+func getHeaderPayload(hdr *rpmutils.RpmHeader) ([]PayloadObject, error) {
+	files, err := hdr.GetFiles()
+
+	if err != nil {
+		return nil, err
+	}
+
+	var result []PayloadObject
+
+	for _, file := range files {
+		result = append(result, PayloadObject{
+			IsDir: file.Mode()&0170000 == 0040000,
+			Path:  file.Name(),
+		})
+	}
+
+	return result, nil
+}
+
+// getHeaderChangelog returns the latest changelog record
+func getHeaderChangelog(hdr *rpmutils.RpmHeader) *Changelog {
+	authors, _ := hdr.GetStrings(rpmutils.CHANGELOGNAME)
+	texts, _ := hdr.GetStrings(rpmutils.CHANGELOGTEXT)
+	dates, _ := hdr.GetUint64s(rpmutils.CHANGELOGTIME)
+
+	if len(authors) == 0 || len(texts) == 0 || len(dates) == 0 {
+		return nil
+	}
+
+	return &Changelog{
+		Author: authors[0],
+		Text:   texts[0],
+		Date:   time.Unix(int64(dates[0]), 0),
+	}
+}
+
+// parseSenseFlag converts RPMSENSE flags to comparison flag
+func parseSenseFlag(flag uint64) data.CompFlag {
+	switch flag & (rpmutils.RPMSENSE_LESS | rpmutils.RPMSENSE_GREATER | rpmutils.RPMSENSE_EQUAL) {
+	case rpmutils.RPMSENSE_EQUAL:
+		return data.COMP_FLAG_EQ
+	case rpmutils.RPMSENSE_LESS:
+		return data.COMP_FLAG_LT
+	case rpmutils.RPMSENSE_LESS | rpmutils.RPMSENSE_EQUAL:
+		return data.COMP_FLAG_LE
+	case rpmutils.RPMSENSE_GREATER:
+		return data.COMP_FLAG_GT
+	case rpmutils.RPMSENSE_GREATER | rpmutils.RPMSENSE_EQUAL:
+		return data.COMP_FLAG_GE
+	}
+
+	return data.COMP_FLAG_ANY
+}
+
+// parseEVR parses [epoch:]version[-release] string
+func parseEVR(evr string) (string, string, string) {
+	var epoch, release string
+
+	if index := strings.Index(evr, ":"); index != -1 {
+		epoch, evr = evr[:index], evr[index+1:]
+	}
+
+	if index := strings.LastIndex(evr, "-"); index != -1 {
+		evr, release = evr[:index], evr[index+1:]
+	}
+
+	return epoch, evr, release
+}
+
 // readLead reads first 80 bytes of RPM file
 func readLead(file string, size int) ([]byte, error) {
 	fd, err := os.OpenFile(file, os.O_RDONLY, 0)
@@ -12,6 +12,8 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/essentialkaos/rep/v3/repo/data"
+
 	. "github.com/essentialkaos/check"
 )
 
@@ -80,3 +82,47 @@ func (s *RPMSuite) TestErrors(c *C) {
 	_, err = ReadLEAD(p3)
 	c.Assert(err, NotNil)
 }
+
+func (s *RPMSuite) TestInfoReading(c *C) {
+	info, err := ReadInfo("../../testdata/test-package-1.0.0-0.el7.x86_64.rpm")
+
+	c.Assert(err, IsNil)
+	c.Assert(info, NotNil)
+	c.Assert(info.Name, Equals, "test-package")
+	c.Assert(info.Version, Equals, "1.0.0")
+	c.Assert(info.Release, Equals, "0.el7")
+	c.Assert(info.Arch, Equals, "x86_64")
+	c.Assert(info.DateBuild.IsZero(), Equals, false)
+	c.Assert(info.Payload, Not(HasLen), 0)
+
+	info, err = ReadInfo("../../testdata/test-package-1.0.0-0.el7.src.rpm")
+
+	c.Assert(err, IsNil)
+	c.Assert(info, NotNil)
+	c.Assert(info.Arch, Equals, "src")
+
+	_, err = ReadInfo(s.TmpDir + "/unknown.rpm")
+	c.Assert(err, NotNil)
+
+	_, err = ReadInfo("../../testdata/comps.xml")
+	c.Assert(err, NotNil)
+}
+
+func (s *RPMSuite) TestEVRParsing(c *C) {
+	e, v, r := parseEVR("1:2.3.4-5.el7")
+	c.Assert(e, Equals, "1")
+	c.Assert(v, Equals, "2.3.4")
+	c.Assert(r, Equals, "5.el7")
+
+	e, v, r = parseEVR("2.3.4")
+	c.Assert(e, Equals, "")
+	c.Assert(v, Equals, "2.3.4")
+	c.Assert(r, Equals, "")
+
+	c.Assert(parseSenseFlag(0), Equals, data.COMP_FLAG_ANY)
+	c.Assert(parseSenseFlag(8), Equals, data.COMP_FLAG_EQ)
+	c.Assert(parseSenseFlag(2), Equals, data.COMP_FLAG_LT)
+	c.Assert(parseSenseFlag(10), Equals, data.COMP_FLAG_LE)
+	c.Assert(parseSenseFlag(4), Equals, data.COMP_FLAG_GT)
+	c.Assert(parseSenseFlag(12), Equals, data.COMP_FLAG_GE)
+}
@@ -9,6 +9,7 @@ package search
 
 import (
 	"fmt"
+	"regexp"
 	"sort"
 	"strings"
 
@@ -45,10 +46,14 @@ const (
 	TERM_BUILD_HOST
 	TERM_SIZE
 	TERM_PAYLOAD
+	TERM_OR
+	TERM_REGEX
+	TERM_PACKAGER
 )
 
 const (
 	TERM_MOD_NEGATIVE uint8 = 1 << iota
+	TERM_MOD_IGNORE_CASE
 )
 
 const (
@@ -63,7 +68,8 @@ const (
 // ////////////////////////////////////////////////////////////////////////////////// //
 
 const (
-	_SQL_QUERY_TEMPLATE = `SELECT pkgKey FROM %s WHERE %s;`
+	_SQL_QUERY_TEMPLATE       = `SELECT pkgKey FROM %s WHERE %s;`
+	_SQL_MATCH_QUERY_TEMPLATE = `SELECT pkgKey,%s FROM %s WHERE %s;`
 )
 
 // ////////////////////////////////////////////////////////////////////////////////// //
@@ -129,6 +135,9 @@ var termPrettyNameMap = map[uint8]string{
 	TERM_BUILD_HOST:  "build-host",
 	TERM_SIZE:        "size",
 	TERM_ARCH:        "arch",
+	TERM_OR:          "or",
+	TERM_REGEX:       "regex",
+	TERM_PACKAGER:    "packager",
 
 	TERM_UNKNOWN: "unknown",
 }
@@ -158,6 +167,9 @@ var termPriorityMap = map[uint8]int{
 	TERM_SIZE:        8,
 	TERM_ARCH:        0,
 	TERM_PAYLOAD:     9,
+	TERM_OR:          10,
+	TERM_REGEX:       2,
+	TERM_PACKAGER:    7,
 }
 
 // termTargetTableMap contains target table for each term
@@ -185,6 +197,8 @@ var termTargetTableMap = map[uint8]string{
 	TERM_BUILD_HOST:  "packages",
 	TERM_SIZE:        "packages",
 	TERM_PAYLOAD:     "filelist",
+	TERM_REGEX:       "packages",
+	TERM_PACKAGER:    "packages",
 }
 
 // termTargetColumnMap contains target table for each term
@@ -203,6 +217,22 @@ var termTargetColumnMap = map[uint8]string{
 	TERM_DATE_BUILD: "time_build",
 	TERM_BUILD_HOST: "rpm_buildhost",
 	TERM_SIZE:       "size_package",
+	TERM_REGEX:      "name",
+	TERM_PACKAGER:   "rpm_packager",
+}
+
+// termMatchColumnMap contains column with matched value for terms without
+// target column
+var termMatchColumnMap = map[uint8]string{
+	TERM_PROVIDES:    "name",
+	TERM_REQUIRES:    "name",
+	TERM_RECOMMENDS:  "name",
+	TERM_CONFLICTS:   "name",
+	TERM_OBSOLETES:   "name",
+	TERM_ENHANCES:    "name",
+	TERM_SUGGESTS:    "name",
+	TERM_SUPPLEMENTS: "name",
+	TERM_PAYLOAD:     "dirname",
 }
 
 // termTargetDBMap contains target DB for each term
@@ -230,6 +260,8 @@ var termTargetDBMap = map[uint8]string{
 	TERM_BUILD_HOST:  data.DB_PRIMARY,
 	TERM_SIZE:        data.DB_PRIMARY,
 	TERM_PAYLOAD:     data.DB_FILELISTS,
+	TERM_REGEX:       data.DB_PRIMARY,
+	TERM_PACKAGER:    data.DB_PRIMARY,
 }
 
 // ////////////////////////////////////////////////////////////////////////////////// //
@@ -349,17 +381,56 @@ func TermPayload(value string, mods ...uint8) *Term {
 	return &Term{Type: TERM_PAYLOAD, Value: value, Modificator: getModificatorFromSlice(mods)}
 }
 
+// TermPackager creates packager search term with given value and modificators
+func TermPackager(value string, mods ...uint8) *Term {
+	return &Term{Type: TERM_PACKAGER, Value: value, Modificator: getModificatorFromSlice(mods)}
+}
+
+// TermRegex creates package name regular expression search term with given
+// value and modificators
+func TermRegex(value string, mods ...uint8) *Term {
+	return &Term{Type: TERM_REGEX, Value: value, Modificator: getModificatorFromSlice(mods)}
+}
+
+// TermOr creates group of alternative queries. Package matches this term if it
+// matches any of given queries.
+func TermOr(queries ...Query) *Term {
+	return &Term{Type: TERM_OR, Value: queries}
+}
+
 // ////////////////////////////////////////////////////////////////////////////////// //
 
 // String returns string representation of search term
 func (t *Term) String() string {
 	var strMod string
 
+	var strCase string
+
 	if t.Modificator&TERM_MOD_NEGATIVE == TERM_MOD_NEGATIVE {
 		strMod = "!"
 	}
 
-	return fmt.Sprintf("[%s%s:%s]", strMod, termPrettyNameMap[t.Type], t.Value)
+	if t.Modificator&TERM_MOD_IGNORE_CASE == TERM_MOD_IGNORE_CASE {
+		strCase = "~"
+	}
+
+	if t.IsGroup() {
+		var alts []string
+
+		for _, query := range t.Value.([]Query) {
+			var terms []string
+
+			for _, term := range query {
+				terms = append(terms, term.String())
+			}
+
+			alts = append(alts, strings.Join(terms, " "))
+		}
+
+		return fmt.Sprintf("[%s:(%s)]", termPrettyNameMap[t.Type], strings.Join(alts, " | "))
+	}
+
+	return fmt.Sprintf("[%s%s%s:%s]", strMod, termPrettyNameMap[t.Type], strCase, t.Value)
 }
 
 // IsNegative returns true if is negative search term
@@ -367,10 +438,36 @@ func (t *Term) IsNegative() bool {
 	return t.Modificator&TERM_MOD_NEGATIVE == TERM_MOD_NEGATIVE
 }
 
-// SQL returns target db and term as a slice with SQL queries
+// IsCaseInsensitive returns true if term value must be matched ignoring case
+func (t *Term) IsCaseInsensitive() bool {
+	return t.Modificator&TERM_MOD_IGNORE_CASE == TERM_MOD_IGNORE_CASE
+}
+
+// IsGroup returns true if term is a group of alternative queries
+func (t *Term) IsGroup() bool {
+	return t.Type == TERM_OR
+}
+
+// Alternatives returns slice with alternative queries of group term
+func (t *Term) Alternatives() []Query {
+	if !t.IsGroup() {
+		return nil
+	}
+
+	queries, _ := t.Value.([]Query)
+
+	return queries
+}
+
+// SQL returns target db and term as a slice with SQL queries. Group terms
+// don't have SQL queries, use queries of alternatives instead.
 func (t *Term) SQL() (string, []string) {
 	var result []string
 
+	if t.IsGroup() {
+		return "", nil
+	}
+
 	for _, cond := range termToCond(t) {
 		result = append(result, fmt.Sprintf(
 			_SQL_QUERY_TEMPLATE,
@@ -382,6 +479,44 @@ func (t *Term) SQL() (string, []string) {
 	return termTargetDBMap[t.Type], result
 }
 
+// MatchSQL returns target db and term as a slice with SQL queries which return
+// package key and matched value
+func (t *Term) MatchSQL() (string, []string) {
+	var result []string
+
+	if t.IsGroup() {
+		return "", nil
+	}
+
+	column := termTargetColumnMap[t.Type]
+
+	if column == "" {
+		column = termMatchColumnMap[t.Type]
+	}
+
+	for _, cond := range termToCond(t) {
+		result = append(result, fmt.Sprintf(
+			_SQL_MATCH_QUERY_TEMPLATE,
+			column,
+			termTargetTableMap[t.Type],
+			cond,
+		))
+	}
+
+	return termTargetDBMap[t.Type], result
+}
+
+// PrettyName returns pretty name of term type
+func (t *Term) PrettyName() string {
+	name, ok := termPrettyNameMap[t.Type]
+
+	if !ok {
+		return termPrettyNameMap[TERM_UNKNOWN]
+	}
+
+	return name
+}
+
 // String returns string representation of range
 func (r Range) String() string {
 	return fmt.Sprintf("%d→%d", r.Start, r.End)
@@ -396,6 +531,11 @@ func (q Query) Validate() []error {
 	var errs []error
 
 	for index, term := range q {
+		if term.IsGroup() {
+			errs = append(errs, validateGroupTerm(index, term)...)
+			continue
+		}
+
 		switch term.Value.(type) {
 		case string, Range, data.Dependency:
 			// skip
@@ -406,6 +546,22 @@ func (q Query) Validate() []error {
 		if termTargetTableMap[term.Type] == "" {
 			errs = append(errs, fmt.Errorf("Can't find DB table for term %d:%s", index, term))
 		}
+
+		if term.IsCaseInsensitive() {
+			_, isString := term.Value.(string)
+
+			if !isString || term.Type == TERM_PAYLOAD {
+				errs = append(errs, fmt.Errorf("Search term %d:%s doesn't support case-insensitive matching", index, term))
+			}
+		}
+
+		if term.Type == TERM_REGEX {
+			_, err := regexp.Compile(fmt.Sprint(term.Value))
+
+			if err != nil {
+				errs = append(errs, fmt.Errorf("Search term %d:%s contains invalid regular expression: %v", index, term, err))
+			}
+		}
 	}
 
 	return errs
@@ -419,6 +575,28 @@ func (q Query) Terms() []*Term {
 
 // ////////////////////////////////////////////////////////////////////////////////// //
 
+// validateGroupTerm validates group term and all its alternatives
+func validateGroupTerm(index int, term *Term) []error {
+	queries, ok := term.Value.([]Query)
+
+	if !ok || len(queries) == 0 {
+		return []error{fmt.Errorf("Search term %d:%s doesn't contain alternative queries", index, term)}
+	}
+
+	var errs []error
+
+	for _, query := range queries {
+		if len(query) == 0 {
+			errs = append(errs, fmt.Errorf("Search term %d:%s contains empty alternative query", index, term))
+			continue
+		}
+
+		errs = append(errs, query.Validate()...)
+	}
+
+	return errs
+}
+
 // updateTermPriority adds priority for each term in query
 func updateTermPriority(query Query) {
 	for _, term := range query {
@@ -445,19 +623,30 @@ func termToCond(term *Term) []string {
 func genBasicTermCond(term *Term) string {
 	var cond string
 
+	column, href := termTargetColumnMap[term.Type], "location_href"
+
 	switch t := term.Value.(type) {
 	case string:
-		cond = genStrTermCond(t, term.IsNegative())
+		switch {
+		case term.Type == TERM_REGEX && term.IsCaseInsensitive():
+			cond = genRegexSQL("(?i)"+strutil.ReplaceAll(t, "'\"", ""), term.IsNegative())
+		case term.Type == TERM_REGEX:
+			cond = genRegexSQL(strutil.ReplaceAll(t, "'\"", ""), term.IsNegative())
+		case term.IsCaseInsensitive():
+			// GLOB is always case-sensitive, so we compare lowercased values
+			cond = genStrTermCond(strings.ToLower(t), term.IsNegative())
+			column, href = "lower("+column+")", "lower("+href+")"
+		default:
+			cond = genStrTermCond(t, term.IsNegative())
+		}
 	case Range:
 		cond = genRangeTermCond(t, term.IsNegative())
 	}
 
-	column := termTargetColumnMap[term.Type]
-
 	if term.Type == TERM_SOURCE {
 		return fmt.Sprintf(
-			"(%s %s OR location_href %s OR substr(location_href, 3) %s)",
-			column, cond, cond, cond,
+			"(%s %s OR %s %s OR substr(%s, 3) %s)",
+			column, cond, href, cond, href, cond,
 		)
 	}
 
@@ -631,6 +820,15 @@ func genLikeSQL(value string, isNegative bool) string {
 	return "LIKE \"%" + value + "%\""
 }
 
+// genRegexSQL generates part of SQL query for regular expression
+func genRegexSQL(value string, isNegative bool) string {
+	if isNegative {
+		return "NOT REGEXP \"" + value + "\""
+	}
+
+	return "REGEXP \"" + value + "\""
+}
+
 // genExactSQL generates part of SQL query for exact value
 func genExactSQL(value string, isNegative bool) string {
 	if isNegative {
@@ -53,6 +53,8 @@ func (s *SearchSuite) TestHelpers(c *C) {
 	c.Assert(TermBuildHost("test").Type, Equals, TERM_BUILD_HOST)
 	c.Assert(TermSize(0, 1).Type, Equals, TERM_SIZE)
 	c.Assert(TermPayload("file").Type, Equals, TERM_PAYLOAD)
+	c.Assert(TermRegex("^test$").Type, Equals, TERM_REGEX)
+	c.Assert(TermPackager("test").Type, Equals, TERM_PACKAGER)
 }
 
 func (s *SearchSuite) TestTermsHelpers(c *C) {
@@ -66,6 +68,15 @@ func (s *SearchSuite) TestTermsHelpers(c *C) {
 
 	c.Assert(t1.IsNegative(), Equals, false)
 	c.Assert(t2.IsNegative(), Equals, true)
+
+	t4 := TermName("TeSt", TERM_MOD_IGNORE_CASE)
+	t5 := TermName("TeSt", TERM_MOD_NEGATIVE, TERM_MOD_IGNORE_CASE)
+
+	c.Assert(t4.String(), Equals, "[name~:TeSt]")
+	c.Assert(t5.String(), Equals, "[!name~:TeSt]")
+	c.Assert(t1.IsCaseInsensitive(), Equals, false)
+	c.Assert(t4.IsCaseInsensitive(), Equals, true)
+	c.Assert(t5.IsCaseInsensitive(), Equals, true)
 }
 
 func (s *SearchSuite) TestTermsValidation(c *C) {
@@ -80,6 +91,53 @@ func (s *SearchSuite) TestTermsValidation(c *C) {
 
 	q = Query{&Term{Type: TERM_NAME, Value: nil}}
 	c.Assert(q.Validate(), HasLen, 1)
+
+	q = Query{TermRegex("^test-[0-9]+$")}
+	c.Assert(q.Validate(), HasLen, 0)
+
+	q = Query{TermRegex("^test-[0-9+$")}
+	c.Assert(q.Validate(), HasLen, 1)
+
+	q = Query{TermName("Test", TERM_MOD_IGNORE_CASE), TermRegex("^Test", TERM_MOD_IGNORE_CASE)}
+	c.Assert(q.Validate(), HasLen, 0)
+
+	q = Query{TermSize(10, 65, TERM_MOD_IGNORE_CASE)}
+	c.Assert(q.Validate(), HasLen, 1)
+
+	q = Query{TermPayload("/usr/bin/Test", TERM_MOD_IGNORE_CASE)}
+	c.Assert(q.Validate(), HasLen, 1)
+}
+
+func (s *SearchSuite) TestGroupTerm(c *C) {
+	t := TermOr(
+		Query{TermName("nginx")},
+		Query{TermName("haproxy"), TermVersion("2.*")},
+	)
+
+	c.Assert(t.Type, Equals, TERM_OR)
+	c.Assert(t.IsGroup(), Equals, true)
+	c.Assert(TermName("nginx").IsGroup(), Equals, false)
+	c.Assert(TermName("nginx").Alternatives(), IsNil)
+	c.Assert(t.Alternatives(), HasLen, 2)
+	c.Assert(t.String(), Equals, "[or:([name:nginx] | [name:haproxy] [version:2.*])]")
+
+	qd, qc := t.SQL()
+	c.Assert(qd, Equals, "")
+	c.Assert(qc, IsNil)
+
+	qd, qc = t.MatchSQL()
+	c.Assert(qd, Equals, "")
+	c.Assert(qc, IsNil)
+
+	q := Query{t, TermArch("x86_64")}
+	c.Assert(q.Validate(), HasLen, 0)
+	c.Assert(q.Terms()[1], Equals, t)
+
+	q = Query{TermOr()}
+	c.Assert(q.Validate(), HasLen, 1)
+
+	q = Query{TermOr(Query{}, Query{&Term{Type: 255, Value: "test"}})}
+	c.Assert(q.Validate(), HasLen, 2)
 }
 
 func (s *SearchSuite) TestQueryToSQL(c *C) {
@@ -119,6 +177,23 @@ func (s *SearchSuite) TestQueryToSQL(c *C) {
 	})
 }
 
+func (s *SearchSuite) TestMatchSQL(c *C) {
+	qd, qc := TermLicense("*Apache*").MatchSQL()
+	c.Assert(qd, Equals, "primary")
+	c.Assert(qc, DeepEquals, []string{"SELECT pkgKey,rpm_license FROM packages WHERE rpm_license GLOB \"*Apache*\";"})
+
+	qd, qc = TermProvides(data.Dependency{Name: "libssl.so*"}).MatchSQL()
+	c.Assert(qd, Equals, "primary")
+	c.Assert(qc, DeepEquals, []string{"SELECT pkgKey,name FROM provides WHERE name GLOB \"libssl.so*\";"})
+
+	qd, qc = TermPayload("/usr/bin/*").MatchSQL()
+	c.Assert(qd, Equals, "filelists")
+	c.Assert(qc, DeepEquals, []string{"SELECT pkgKey,dirname FROM filelist WHERE dirname LIKE \"%/usr/bin%\";"})
+
+	c.Assert(TermProvides(data.Dependency{Name: "test"}).PrettyName(), Equals, "provides")
+	c.Assert((&Term{Type: 255}).PrettyName(), Equals, "unknown")
+}
+
 func (s *SearchSuite) TestTermToCond(c *C) {
 	c.Assert(tc(TermName("abcd")), Equals, "name = \"abcd\"")
 	c.Assert(tc(TermName("abcd", TERM_MOD_NEGATIVE)), Equals, "name != \"abcd\"")
@@ -130,6 +205,15 @@ func (s *SearchSuite) TestTermToCond(c *C) {
 	c.Assert(tc(TermSource("abcd", TERM_MOD_NEGATIVE)), Equals, "(rpm_sourcerpm != \"abcd\" OR location_href != \"abcd\" OR substr(location_href, 3) != \"abcd\")")
 	c.Assert(tc(TermSize(0, 100)), Equals, "size_package BETWEEN 0 AND 100")
 	c.Assert(tc(TermSize(0, 100, TERM_MOD_NEGATIVE)), Equals, "size_package NOT BETWEEN 0 AND 100")
+	c.Assert(tc(TermPackager("John Doe*")), Equals, "rpm_packager GLOB \"John Doe*\"")
+	c.Assert(tc(TermRegex("^ng(inx|x)-[0-9]+$")), Equals, "name REGEXP \"^ng(inx|x)-[0-9]+$\"")
+	c.Assert(tc(TermRegex("^ng\"inx", TERM_MOD_NEGATIVE)), Equals, "name NOT REGEXP \"^nginx\"")
+	c.Assert(tc(TermRegex("^NGINX", TERM_MOD_IGNORE_CASE)), Equals, "name REGEXP \"(?i)^NGINX\"")
+	c.Assert(tc(TermName("NGINX", TERM_MOD_IGNORE_CASE)), Equals, "lower(name) = \"nginx\"")
+	c.Assert(tc(TermName("NGINX", TERM_MOD_NEGATIVE, TERM_MOD_IGNORE_CASE)), Equals, "lower(name) != \"nginx\"")
+	c.Assert(tc(TermName("NGINX-*", TERM_MOD_IGNORE_CASE)), Equals, "lower(name) GLOB \"nginx-*\"")
+	c.Assert(tc(TermName("NGINX|HAProxy", TERM_MOD_IGNORE_CASE)), Equals, "lower(name) IN (\"nginx\",\"haproxy\")")
+	c.Assert(tc(TermSource("Redis*", TERM_MOD_IGNORE_CASE)), Equals, "(lower(rpm_sourcerpm) GLOB \"redis*\" OR lower(location_href) GLOB \"redis*\" OR substr(lower(location_href), 3) GLOB \"redis*\")")
 
 	d := data.Dependency{
 		Name:    "test",
@@ -10,14 +10,19 @@ package sign
 import (
 	"bytes"
 	"fmt"
+	"io"
 	"os"
+	"os/exec"
+	"strings"
 
 	"github.com/essentialkaos/ek/v13/directio"
+	"github.com/essentialkaos/ek/v13/fsutil"
 	"github.com/essentialkaos/ek/v13/secstr"
 
 	"github.com/sassoftware/go-rpmutils"
 
 	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
 	"github.com/ProtonMail/go-crypto/openpgp/packet"
 )
 
@@ -27,21 +32,35 @@ import (
 type ArmoredKey struct {
 	IsEncrypted bool
 
-	data []byte
+	data     []byte
+	agentID  string
+	isPublic bool
 }
 
 // Key contains parsed OpenGPG entity
 type Key struct {
-	entity *openpgp.Entity
+	entity   *openpgp.Entity
+	agentID  string // ID of key stored in GPG agent (entity contains only public key)
+	isPublic bool   // Key contains only public part and can be used only for verification
 }
 
 // ////////////////////////////////////////////////////////////////////////////////// //
 
+// GPGBinary is name of gpg binary used for signing with GPG agent
+var GPGBinary = "gpg"
+
+// RPMSignBinary is name of rpmsign binary used for signing packages with GPG agent
+var RPMSignBinary = "rpmsign"
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
 var (
 	ErrKeyIsEncrypted = fmt.Errorf("Key is encrypted (decrypted key is required)")
 	ErrKeyIsNil       = fmt.Errorf("Key is nil")
 	ErrKeyIsEmpty     = fmt.Errorf("Key is empty")
 	ErrKeyringIsEmpty = fmt.Errorf("Keyring is empty (there is no private key)")
+	ErrNoVerifyKeys   = fmt.Errorf("At least one key is required for signature verification")
+	ErrEmptyKeyID     = fmt.Errorf("Key ID is empty")
 )
 
 // ////////////////////////////////////////////////////////////////////////////////// //
@@ -55,6 +74,10 @@ func SignPackage(pkgFile, output string, key *Key) error {
 		return err
 	}
 
+	if key.IsAgent() {
+		return signPackageWithAgent(pkgFile, output, key)
+	}
+
 	fd, err := os.OpenFile(pkgFile, os.O_RDONLY, 0)
 
 	if err != nil {
@@ -92,12 +115,32 @@ func SignFile(file string, key *Key) error {
 
 	defer outFd.Close()
 
+	if key.IsAgent() {
+		w, err := armor.Encode(outFd, openpgp.SignatureType, nil)
+
+		if err != nil {
+			return err
+		}
+
+		err = detachSign(w, key, srcFd)
+
+		if err != nil {
+			return err
+		}
+
+		return w.Close()
+	}
+
 	return openpgp.ArmoredDetachSign(outFd, key.entity, srcFd, &packet.Config{})
 }
 
 // IsPackageSignatureValid checks if package is signed with given key
 func IsPackageSignatureValid(pkgFile string, key *Key) (bool, error) {
-	if key == nil || key.entity == nil || key.entity.PrivateKey == nil {
+	if key == nil || key.entity == nil || key.entity.PrimaryKey == nil {
+		return false, ErrKeyIsNil
+	}
+
+	if !key.IsAgent() && !key.isPublic && key.entity.PrivateKey == nil {
 		return false, ErrKeyIsNil
 	}
 
@@ -111,7 +154,29 @@ func IsPackageSignatureValid(pkgFile string, key *Key) (bool, error) {
 		return false, nil
 	}
 
-	return checkSignature(hdr, key.entity.PrivateKey)
+	return checkSignature(hdr, key.entity.PrimaryKey.KeyId)
+}
+
+// IsPackageSignatureValidWithKeys checks if package is signed with any of
+// given keys
+func IsPackageSignatureValidWithKeys(pkgFile string, keys ...*Key) (bool, error) {
+	if len(keys) == 0 {
+		return false, ErrNoVerifyKeys
+	}
+
+	for _, key := range keys {
+		isValid, err := IsPackageSignatureValid(pkgFile, key)
+
+		if err != nil {
+			return false, err
+		}
+
+		if isValid {
+			return true, nil
+		}
+	}
+
+	return false, nil
 }
 
 // IsPackageSigned checks if package has PGP/GPG signature
@@ -152,8 +217,70 @@ func ReadKey(file string) (*ArmoredKey, error) {
 	return LoadKey(data)
 }
 
+// LoadPublicKey loads public key data (or public part of private key) which
+// can be used only for signature verification
+func LoadPublicKey(data []byte) (*ArmoredKey, error) {
+	rk := &ArmoredKey{IsEncrypted: false, data: data, isPublic: true}
+	_, err := rk.Read(nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return rk, nil
+}
+
+// ReadPublicKey reads public key (or public part of private key) from file
+func ReadPublicKey(file string) (*ArmoredKey, error) {
+	data, err := os.ReadFile(file)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return LoadPublicKey(data)
+}
+
+// ReadAgentKey reads public part of key with given ID (or fingerprint) from
+// GPG keyring. Private key stays in GPG agent (or on hardware token) and is
+// used only through gpg and rpmsign.
+func ReadAgentKey(keyID string) (*ArmoredKey, error) {
+	if keyID == "" {
+		return nil, ErrEmptyKeyID
+	}
+
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.Command(GPGBinary, "--batch", "--armor", "--export", keyID)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+
+	err := cmd.Run()
+
+	if err != nil {
+		return nil, fmt.Errorf("Can't export public key %s: %w", keyID, getCommandError(err, stderr.String()))
+	}
+
+	if stdout.Len() == 0 {
+		return nil, fmt.Errorf("Can't export public key %s: Key not found in keyring", keyID)
+	}
+
+	rk := &ArmoredKey{IsEncrypted: false, data: stdout.Bytes(), agentID: keyID}
+	_, err = rk.Read(nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return rk, nil
+}
+
 // ////////////////////////////////////////////////////////////////////////////////// //
 
+// IsAgent returns true if private key is stored in GPG agent
+func (k *ArmoredKey) IsAgent() bool {
+	return k != nil && k.agentID != ""
+}
+
 // Read reads and decrypts (if password is provided) raw OpenPGP key
 //
 // You MUST NOT decrypt signing key (provide password) for checking package
@@ -170,6 +297,14 @@ func (k *ArmoredKey) Read(password *secstr.String) (*Key, error) {
 		return nil, err
 	}
 
+	if k.agentID != "" {
+		return &Key{entity: kr[0], agentID: k.agentID}, nil
+	}
+
+	if k.isPublic {
+		return &Key{entity: kr[0], isPublic: true}, nil
+	}
+
 	if kr[0].PrivateKey == nil {
 		return nil, ErrKeyringIsEmpty
 	}
@@ -182,13 +317,29 @@ func (k *ArmoredKey) Read(password *secstr.String) (*Key, error) {
 		}
 	}
 
-	return &Key{kr[0]}, nil
+	return &Key{entity: kr[0]}, nil
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// IsAgent returns true if key is stored in GPG agent
+func (k *Key) IsAgent() bool {
+	return k != nil && k.agentID != ""
+}
+
+// IsPublic returns true if key contains only public part
+func (k *Key) IsPublic() bool {
+	return k != nil && k.isPublic
 }
 
 // ////////////////////////////////////////////////////////////////////////////////// //
 
 // checkKey checks key for problems
 func checkKey(key *Key) error {
+	if key.IsAgent() && key.entity != nil {
+		return nil
+	}
+
 	if key == nil || key.entity == nil || key.entity.PrivateKey == nil {
 		return ErrKeyIsNil
 	}
@@ -213,19 +364,19 @@ func readHeader(pkgFile string) (*rpmutils.RpmHeader, error) {
 	return rpmutils.ReadHeader(f)
 }
 
-// checkSignature checks signature from header and compare it with private key
-func checkSignature(hdr *rpmutils.RpmHeader, privateKey *packet.PrivateKey) (bool, error) {
+// checkSignature checks signature from header and compare it with key ID
+func checkSignature(hdr *rpmutils.RpmHeader, keyID uint64) (bool, error) {
 	sigBlob, err := hdr.GetBytes(rpmutils.SIG_PGP)
 
 	if err != nil {
 		return false, fmt.Errorf("Can't read signature tag: %w", err)
 	}
 
-	return checkSignaturePacket(sigBlob, privateKey)
+	return checkSignaturePacket(sigBlob, keyID)
 }
 
 // checkSignaturePacket checks signature packet
-func checkSignaturePacket(signature []byte, privateKey *packet.PrivateKey) (bool, error) {
+func checkSignaturePacket(signature []byte, keyID uint64) (bool, error) {
 	packetReader := packet.NewReader(bytes.NewReader(signature))
 	pkt, err := packetReader.Next()
 
@@ -233,7 +384,7 @@ func checkSignaturePacket(signature []byte, privateKey *packet.PrivateKey) (bool
 		return false, fmt.Errorf("Can't decode signature: %w", err)
 	}
 
-	return getSigKeyID(pkt) == privateKey.KeyId, nil
+	return getSigKeyID(pkt) == keyID, nil
 }
 
 // getSigKeyID returns signature key ID
@@ -254,3 +405,69 @@ func getSigV4KeyID(pkt *packet.Signature) uint64 {
 
 	return 0
 }
+
+// detachSign writes binary detached signature of data from reader to writer
+func detachSign(w io.Writer, key *Key, r io.Reader) error {
+	if !key.IsAgent() {
+		return openpgp.DetachSign(w, key.entity, r, &packet.Config{})
+	}
+
+	var stderr bytes.Buffer
+
+	cmd := exec.Command(
+		GPGBinary, "--batch", "--yes", "--local-user", key.agentID,
+		"--detach-sign", "--output", "-",
+	)
+
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = r, w, &stderr
+
+	err := cmd.Run()
+
+	if err != nil {
+		return getCommandError(err, stderr.String())
+	}
+
+	return nil
+}
+
+// signPackageWithAgent signs package using rpmsign and key from GPG agent
+func signPackageWithAgent(pkgFile, output string, key *Key) error {
+	err := fsutil.CopyFile(pkgFile, output)
+
+	if err != nil {
+		return fmt.Errorf("Can't copy package for signing: %w", err)
+	}
+
+	var stderr bytes.Buffer
+
+	// Signature check uses V3 (header+payload) signature, so we must force
+	// rpmsign to add it
+	cmd := exec.Command(
+		RPMSignBinary, "--rpmv3",
+		"--define", "_gpg_name "+key.agentID,
+		"--define", "__gpg "+GPGBinary,
+		"--addsign", output,
+	)
+
+	cmd.Stderr = &stderr
+
+	err = cmd.Run()
+
+	if err != nil {
+		os.Remove(output)
+		return fmt.Errorf("Can't sign package with rpmsign: %w", getCommandError(err, stderr.String()))
+	}
+
+	return nil
+}
+
+// getCommandError returns error with command stderr output
+func getCommandError(err error, stderr string) error {
+	errText := strings.TrimSpace(stderr)
+
+	if errText == "" {
+		return err
+	}
+
+	return fmt.Errorf("%w (%s)", err, errText)
+}
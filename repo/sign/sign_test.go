@@ -126,15 +126,100 @@ func (s *SignSuite) TestReadKey(c *C) {
 	_, err = ReadKey(tmpFile)
 	c.Assert(err, ErrorMatches, "openpgp: invalid argument: no armored data found")
 
-	armKey = &ArmoredKey{false, []byte{}}
+	armKey = &ArmoredKey{IsEncrypted: false, data: []byte{}}
 	_, err = armKey.Read(password)
 	c.Assert(err, ErrorMatches, ErrKeyIsEmpty.Error())
 
-	armKey = &ArmoredKey{false, []byte("TEST")}
+	armKey = &ArmoredKey{IsEncrypted: false, data: []byte("TEST")}
 	_, err = armKey.Read(password)
 	c.Assert(err, ErrorMatches, "openpgp: invalid argument: no armored data found")
 }
 
+func (s *SignSuite) TestPublicKey(c *C) {
+	tmpDir := c.MkDir()
+	srcPkg := "../../testdata/test-package-1.0.0-0.el7.x86_64.rpm"
+	trgPkg := tmpDir + "/test-package-1.0.0-0.el7.x86_64.rpm"
+
+	armKey, err := ReadKey("../../testdata/reptest.private")
+	c.Assert(err, IsNil)
+
+	password, _ := secstr.NewSecureString("test1234TEST")
+	privKey, err := armKey.Read(password)
+	c.Assert(err, IsNil)
+	c.Assert(SignPackage(srcPkg, trgPkg, privKey), IsNil)
+
+	pubArmKey, err := ReadPublicKey("../../testdata/reptest.public")
+	c.Assert(pubArmKey, NotNil)
+	c.Assert(err, IsNil)
+
+	pubKey, err := pubArmKey.Read(nil)
+	c.Assert(pubKey, NotNil)
+	c.Assert(err, IsNil)
+	c.Assert(pubKey.IsPublic(), Equals, true)
+	c.Assert(privKey.IsPublic(), Equals, false)
+
+	c.Assert(SignPackage(srcPkg, tmpDir+"/test.rpm", pubKey), Equals, ErrKeyIsNil)
+
+	isValid, err := IsPackageSignatureValid(trgPkg, pubKey)
+	c.Assert(isValid, Equals, true)
+	c.Assert(err, IsNil)
+
+	isValid, err = IsPackageSignatureValidWithKeys(srcPkg, pubKey, privKey)
+	c.Assert(isValid, Equals, false)
+	c.Assert(err, IsNil)
+
+	isValid, err = IsPackageSignatureValidWithKeys(trgPkg, &Key{entity: privKey.entity, isPublic: true}, pubKey)
+	c.Assert(isValid, Equals, true)
+	c.Assert(err, IsNil)
+
+	_, err = IsPackageSignatureValidWithKeys(trgPkg)
+	c.Assert(err, Equals, ErrNoVerifyKeys)
+
+	_, err = IsPackageSignatureValidWithKeys(trgPkg, nil)
+	c.Assert(err, Equals, ErrKeyIsNil)
+
+	_, err = ReadPublicKey(tmpDir + "/unknown.public")
+	c.Assert(err, NotNil)
+
+	_, err = LoadPublicKey([]byte("TEST"))
+	c.Assert(err, ErrorMatches, "openpgp: invalid argument: no armored data found")
+}
+
+func (s *SignSuite) TestAgentKey(c *C) {
+	_, err := ReadAgentKey("")
+	c.Assert(err, Equals, ErrEmptyKeyID)
+
+	gpgBinary := GPGBinary
+	GPGBinary = "_unknown_"
+
+	_, err = ReadAgentKey("ABCD1234")
+	c.Assert(err, NotNil)
+
+	GPGBinary = gpgBinary
+
+	var armKey *ArmoredKey
+	var key *Key
+
+	c.Assert(armKey.IsAgent(), Equals, false)
+	c.Assert(key.IsAgent(), Equals, false)
+
+	armKey = &ArmoredKey{data: []byte{}, agentID: "ABCD1234"}
+	c.Assert(armKey.IsAgent(), Equals, true)
+
+	_, err = armKey.Read(nil)
+	c.Assert(err, Equals, ErrKeyIsEmpty)
+
+	key = &Key{agentID: "ABCD1234"}
+	c.Assert(key.IsAgent(), Equals, true)
+	c.Assert(checkKey(key), Equals, ErrKeyIsNil)
+
+	_, err = IsPackageSignatureValid("_unknown_", key)
+	c.Assert(err, Equals, ErrKeyIsNil)
+
+	c.Assert(getCommandError(ErrKeyIsNil, ""), Equals, ErrKeyIsNil)
+	c.Assert(getCommandError(ErrKeyIsNil, " test\n").Error(), Equals, "Key is nil (test)")
+}
+
 func (s *SignSuite) TestErrors(c *C) {
 	_, err := ReadKey("../../testdata/empty.private")
 
@@ -167,10 +252,10 @@ func (s *SignSuite) TestErrors(c *C) {
 	c.Assert(err, NotNil)
 
 	hdr, _ := readHeader("../../testdata/test-package-1.0.0-0.el7.x86_64.rpm")
-	_, err = checkSignature(hdr, key.entity.PrivateKey)
+	_, err = checkSignature(hdr, key.entity.PrimaryKey.KeyId)
 	c.Assert(err, NotNil)
 
-	_, err = checkSignaturePacket([]byte("ABCD"), nil)
+	_, err = checkSignaturePacket([]byte("ABCD"), 0)
 	c.Assert(err, NotNil)
 
 	c.Assert(getSigV4KeyID(nil), Equals, uint64(0))
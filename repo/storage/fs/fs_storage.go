@@ -8,14 +8,22 @@ package fs
 // ////////////////////////////////////////////////////////////////////////////////// //
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"regexp"
+	"slices"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 
+	"github.com/essentialkaos/ek/v13/fmtutil"
 	"github.com/essentialkaos/ek/v13/fsutil"
+	"github.com/essentialkaos/ek/v13/hash"
 	"github.com/essentialkaos/ek/v13/path"
 	"github.com/essentialkaos/ek/v13/strutil"
 	"github.com/essentialkaos/ek/v13/system"
@@ -36,6 +44,30 @@ const (
 	PERMS_FILE os.FileMode = 0644 // Default permissions for files
 )
 
+const (
+	SPLIT_DEPTH_DEFAULT = 1 // Default length of directory name for split files
+	SPLIT_DEPTH_MAX     = 4 // Maximum length of directory name for split files
+)
+
+const (
+	LAYOUT_NESTED     = "{repo}/{arch}" // Default layout (testing/x86_64)
+	LAYOUT_ARCH_FIRST = "{arch}/{repo}" // Architecture directories first (x86_64/testing)
+	LAYOUT_FLAT       = "{repo}-{arch}" // All depots in data directory (testing-x86_64)
+)
+
+const (
+	ATTRS_RETRY_DELAY = 100 * time.Millisecond // Default delay before chown/chmod retry
+)
+
+const (
+	MEM_CACHE_DIR      = "/dev/shm"       // Default memory-backed directory
+	MEM_CACHE_MAX_SIZE = 64 * 1024 * 1024 // Default maximum size of DB kept in memory
+)
+
+const (
+	_SQL_LOCATIONS = `SELECT location_href FROM packages;`
+)
+
 // ////////////////////////////////////////////////////////////////////////////////// //
 
 // Storage is repository storage
@@ -43,7 +75,8 @@ type Storage struct {
 	dataOptions  *Options       // Data storage options
 	indexOptions *index.Options // Index generation options
 
-	depots DepotBundle // Map [repo name] → [depot]
+	depots DepotBundle   // Map [repo name] → [depot]
+	mu     *sync.RWMutex // Lock for depots map
 }
 
 // Options is storage options
@@ -51,22 +84,54 @@ type Options struct {
 	DataDir  string // Path to directory with RPM files
 	CacheDir string // Path to directory for cached data
 
-	SplitFiles bool // Split files to separate directories
+	SplitFiles  bool     // Split files to separate directories
+	SplitDepth  int      // Length of directory name prefix for split files (default: 1)
+	NestedCache bool     // Store cached DBs in per-repo/per-arch subdirectories
+	HardLink    bool     // Hard-link packages instead of copying if possible
+	VerifyCopy  bool     // Verify checksum of package after copying between repositories
+	SkipDBs     []string // Types of DBs which will not be cached
+
+	MinFreeSpace uint64 // Minimal free space (in bytes) which must be kept in data directory
+
+	Layout string // Template of path to depot directory relative to data directory (default: {repo}/{arch})
+
+	MemCache        bool   // Unpack SQLite DBs to memory-backed directory instead of cache directory
+	MemCacheDir     string // Path to memory-backed directory (default: /dev/shm)
+	MemCacheMaxSize uint64 // Maximum size of unpacked DB which can be kept in memory
 
 	User      string      // Repository data directory owner username
 	Group     string      // Repository data directory owner group
 	DirPerms  os.FileMode // Permissions for directories
 	FilePerms os.FileMode // Permissions for files
+
+	AttrsRetries    int           // Number of retries of chown/chmod on transient errors
+	AttrsRetryDelay time.Duration // Delay before first retry (doubled after each retry)
+}
+
+// objectOwner contains UID and GID of objects owner
+type objectOwner struct {
+	UID int
+	GID int
 }
 
 // Depot is storage for specific repository (type + arch)
 type Depot struct {
 	id           string         // Repository ID (repo + - + arch)
 	dataDir      string         // Path to sub-repository directory
+	cacheDir     string         // Path to directory with cached DBs
 	dataOptions  *Options       // Data storage options
 	indexOptions *index.Options // Index generation options
 	meta         *meta.Index    // Sub-repository metadata index
 	dbs          DBBundle       // Map [db type] → [SQL connection]
+	memDir       string         // Path to temporary memory-backed directory with DBs
+
+	mu *sync.RWMutex // Lock for meta index and DB connections
+}
+
+// Error is storage error with kind which can be checked using errors.Is
+type Error struct {
+	Kind error  // Error kind (ErrRepoNotFound, ErrArchNotSupported…)
+	Desc string // Error description
 }
 
 // RepoStorageBundle is map [repo name] → [repo storage]
@@ -85,6 +150,16 @@ var (
 	ErrUnknownArch    = fmt.Errorf("Unknown or unsupported architecture")
 	ErrPseudoArch     = fmt.Errorf("Noarch is pseudo architecture and can't be used")
 	ErrNilDepot       = fmt.Errorf("Can't find depot for given repository or architecture")
+
+	ErrRepoNotFound     = fmt.Errorf("Repository doesn't exist")
+	ErrArchNotSupported = fmt.Errorf("Repository doesn't support architecture")
+	ErrNotRPM           = fmt.Errorf("File is not an RPM package")
+	ErrDBSkipped        = fmt.Errorf("DB is excluded from caching")
+	ErrDBDisabled       = fmt.Errorf("Database generation is disabled")
+	ErrNoFreeSpace      = fmt.Errorf("Not enough free space in repository storage")
+	ErrHashMismatch     = fmt.Errorf("Package checksum mismatch")
+	ErrMissingPackage   = fmt.Errorf("Package referenced in index doesn't exist")
+	ErrOrphanPackage    = fmt.Errorf("Package isn't referenced in index")
 )
 
 // DirNameValidatorRegex is directory name validation regexp
@@ -93,10 +168,16 @@ var DirNameValidatorRegex = regexp.MustCompile(`[a-zA-Z0-9]+`)
 // ////////////////////////////////////////////////////////////////////////////////// //
 
 var (
-	chownFunc  = os.Chown
-	chmodFunc  = os.Chmod
-	removeFunc = os.Remove
-	mkdirFunc  = os.Mkdir
+	chownFunc    = os.Chown
+	chmodFunc    = os.Chmod
+	removeFunc   = os.Remove
+	mkdirFunc    = os.Mkdir
+	fileHashFunc = hash.FileHash
+)
+
+var (
+	memDirs     = make(map[string]bool) // Set of memory-backed directories created by depots
+	memDirsLock = &sync.Mutex{}         // Lock for memDirs set
 )
 
 // ////////////////////////////////////////////////////////////////////////////////// //
@@ -134,6 +215,11 @@ func NewStorage(dataOptions *Options, indexOptions *index.Options) (*Storage, er
 			filelistGlobberFunc, true,
 		)
 
+		RegisterFunc(
+			data.DB_PRIMARY, "regexp",
+			regexpFunc, true,
+		)
+
 		registerDrivers()
 	}
 
@@ -141,11 +227,25 @@ func NewStorage(dataOptions *Options, indexOptions *index.Options) (*Storage, er
 		dataOptions:  dataOptions,
 		indexOptions: indexOptions,
 		depots:       make(DepotBundle),
+		mu:           &sync.RWMutex{},
 	}
 
 	return storage, nil
 }
 
+// RemoveMemCacheDirs removes all memory-backed directories with unpacked DBs
+// created by depots. This function must be used for cleanup if app exits
+// without cache invalidation.
+func RemoveMemCacheDirs() {
+	memDirsLock.Lock()
+	defer memDirsLock.Unlock()
+
+	for dir := range memDirs {
+		os.RemoveAll(dir)
+		delete(memDirs, dir)
+	}
+}
+
 // ////////////////////////////////////////////////////////////////////////////////// //
 
 // Validate validates storage data options
@@ -162,9 +262,87 @@ func (o *Options) Validate() error {
 		return err
 	}
 
+	if o.SplitDepth < 0 || o.SplitDepth > SPLIT_DEPTH_MAX {
+		return fmt.Errorf("Split depth must be in range 0-%d", SPLIT_DEPTH_MAX)
+	}
+
+	err = checkLayout(o.Layout)
+
+	if err != nil {
+		return err
+	}
+
+	for _, dbType := range o.SkipDBs {
+		switch {
+		case !slices.Contains(data.DBList, dbType):
+			return fmt.Errorf("Unknown DB type %q", dbType)
+		case dbType == data.DB_PRIMARY:
+			return fmt.Errorf("Primary DB can't be excluded from caching")
+		}
+	}
+
 	return nil
 }
 
+// IsDBSkipped returns true if DB with given type is excluded from caching
+func (o *Options) IsDBSkipped(dbType string) bool {
+	return slices.Contains(o.SkipDBs, dbType)
+}
+
+// GetSplitDepth returns length of directory name for split files
+func (o *Options) GetSplitDepth() int {
+	if o.SplitDepth <= 0 {
+		return SPLIT_DEPTH_DEFAULT
+	}
+
+	return o.SplitDepth
+}
+
+// GetLayout returns template of path to depot directory
+func (o *Options) GetLayout() string {
+	if o.Layout == "" {
+		return LAYOUT_NESTED
+	}
+
+	return o.Layout
+}
+
+// GetDepotDir returns path to directory with packages for given repository
+// and arch
+func (o *Options) GetDepotDir(repo, arch string) string {
+	return joinPath(o.DataDir, formatLayout(o.GetLayout(), repo, arch))
+}
+
+// GetMemCacheDir returns path to memory-backed directory for DBs
+func (o *Options) GetMemCacheDir() string {
+	switch {
+	case o.MemCacheDir != "":
+		return o.MemCacheDir
+	case fsutil.IsExist(MEM_CACHE_DIR):
+		return MEM_CACHE_DIR
+	}
+
+	return os.TempDir()
+}
+
+// GetMemCacheMaxSize returns maximum size of DB which can be kept in memory
+func (o *Options) GetMemCacheMaxSize() uint64 {
+	if o.MemCacheMaxSize == 0 {
+		return MEM_CACHE_MAX_SIZE
+	}
+
+	return o.MemCacheMaxSize
+}
+
+// GetAttrsRetryDelay returns delay before first chown/chmod retry
+func (o *Options) GetAttrsRetryDelay() time.Duration {
+	if o.AttrsRetryDelay <= 0 {
+		return ATTRS_RETRY_DELAY
+	}
+
+	return o.AttrsRetryDelay
+}
+
 // GetDirPerms returns permissions for directories
 func (o *Options) GetDirPerms() os.FileMode {
 	if o.DirPerms == 0 {
@@ -207,19 +385,15 @@ func (s *Storage) Initialize(repoList, archList []string) error {
 		switch {
 		case data.SupportedArchs[arch].Flag == data.ARCH_FLAG_UNKNOWN,
 			data.SupportedArchs[arch].Flag == data.ARCH_FLAG_NOARCH:
-			return fmt.Errorf("Can't initialize the new storage: Unsupported architecture %q", arch)
+			return fmt.Errorf("Can't initialize the new storage: %w", newError(ErrUnknownArch, "Unsupported architecture %q", arch))
 		}
 	}
 
 	dirList := []string{s.dataOptions.DataDir}
 
 	for _, repo := range repoList {
-		dirList = append(dirList, joinPath(s.dataOptions.DataDir, repo))
 		for _, arch := range archList {
-			dirList = append(
-				dirList,
-				joinPath(s.dataOptions.DataDir, repo, data.SupportedArchs[arch].Dir),
-			)
+			dirList = append(dirList, getLayoutDirs(s.dataOptions, repo, arch)...)
 		}
 	}
 
@@ -228,7 +402,7 @@ func (s *Storage) Initialize(repoList, archList []string) error {
 			continue
 		}
 
-		err := mkdirFunc(dir, 0700)
+		err := mkdirFunc(dir, s.dataOptions.GetDirPerms())
 
 		if err != nil {
 			return fmt.Errorf("Can't initialize the new storage: %w", err)
@@ -247,13 +421,19 @@ func (s *Storage) Initialize(repoList, archList []string) error {
 // AddPackage adds package file to the given repository
 // Important: This method DO NOT run repository reindex
 func (s *Storage) AddPackage(repo, rpmFilePath string) error {
+	return s.addPackage(repo, rpmFilePath, nil)
+}
+
+// addPackage adds package file to the given repository. If owner is nil, it
+// will be resolved from storage options.
+func (s *Storage) addPackage(repo, rpmFilePath string, owner *objectOwner) error {
 	switch {
 	case repo == "":
 		return fmt.Errorf("Can't add package to storage: %w", ErrEmptyRepoName)
 	case rpmFilePath == "":
 		return fmt.Errorf("Can't add package to storage: %w", ErrEmptyPath)
 	case !s.HasRepo(repo):
-		return fmt.Errorf("Can't add package to storage: Repository %q doesn't exist", repo)
+		return fmt.Errorf("Can't add package to storage: %w", newError(ErrRepoNotFound, "Repository %q doesn't exist", repo))
 	}
 
 	err := fsutil.ValidatePerms("FRS", rpmFilePath)
@@ -263,7 +443,7 @@ func (s *Storage) AddPackage(repo, rpmFilePath string) error {
 	}
 
 	if !rpm.IsRPM(rpmFilePath) {
-		return fmt.Errorf("Can't add file to storage: %s is not an RPM package", rpmFilePath)
+		return fmt.Errorf("Can't add file to storage: %w", newError(ErrNotRPM, "%s is not an RPM package", rpmFilePath))
 	}
 
 	arch, err := helpers.ExtractPackageArch(rpmFilePath)
@@ -273,26 +453,111 @@ func (s *Storage) AddPackage(repo, rpmFilePath string) error {
 	}
 
 	if data.SupportedArchs[arch].Flag == data.ARCH_FLAG_UNKNOWN {
-		return fmt.Errorf("Unsupported package architecture %q", arch)
+		return newError(ErrUnknownArch, "Unsupported package architecture %q", arch)
 	}
 
 	if arch != data.ARCH_NOARCH {
-		return s.GetDepot(repo, arch).AddPackage(rpmFilePath)
+		return s.GetDepot(repo, arch).addPackage(rpmFilePath, "", owner)
 	}
 
-	for _, a := range data.BinArchList {
-		if !s.HasArch(repo, a) {
+	_, err = s.addNoarchPackage(repo, rpmFilePath, false, owner)
+
+	return err
+}
+
+// AddPackages adds package files to the given repository and returns paths
+// (relative to repository directory) of added files
+// Important: This method DO NOT run repository reindex
+func (s *Storage) AddPackages(repo string, rpmFilePaths []string) ([]string, error) {
+	switch {
+	case repo == "":
+		return nil, fmt.Errorf("Can't add packages to storage: %w", ErrEmptyRepoName)
+	case len(rpmFilePaths) == 0:
+		return nil, fmt.Errorf("Can't add packages to storage: %w", ErrEmptyPath)
+	case !s.HasRepo(repo):
+		return nil, fmt.Errorf("Can't add packages to storage: %w", newError(ErrRepoNotFound, "Repository %q doesn't exist", repo))
+	}
+
+	owner, err := getObjectOwner(s.dataOptions)
+
+	if err != nil {
+		return nil, fmt.Errorf("Can't add packages to storage: %w", err)
+	}
+
+	var added []string
+	var errs []error
+
+	// UID and GID are resolved only once for all files
+	for _, rpmFilePath := range rpmFilePaths {
+		err = s.addPackage(repo, rpmFilePath, owner)
+
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", path.Base(rpmFilePath), err))
+			continue
+		}
+
+		added = append(added, s.getPackageRelPaths(repo, path.Base(rpmFilePath))...)
+	}
+
+	return added, errors.Join(errs...)
+}
+
+// SyncNoarchPackages makes sure that every noarch package file is present in all
+// binary arch depots of the given repository and returns paths (relative to
+// repository directory) of added files
+// Important: This method DO NOT run repository reindex
+func (s *Storage) SyncNoarchPackages(repo string) ([]string, error) {
+	switch {
+	case repo == "":
+		return nil, fmt.Errorf("Can't sync noarch packages: %w", ErrEmptyRepoName)
+	case !s.IsInitialized():
+		return nil, fmt.Errorf("Can't sync noarch packages: %w", ErrNotInitialized)
+	case !s.HasRepo(repo):
+		return nil, fmt.Errorf("Can't sync noarch packages: %w", newError(ErrRepoNotFound, "Repository %q doesn't exist", repo))
+	}
+
+	noarchFiles := make(map[string]string)
+
+	for _, arch := range data.BinArchList {
+		if !s.HasArch(repo, arch) {
 			continue
 		}
 
-		err := s.GetDepot(repo, a).AddPackage(rpmFilePath)
+		depot := s.GetDepot(repo, arch)
+
+		for _, file := range depot.listPackageFiles() {
+			fileName := path.Base(file)
+
+			if !isNoarchPackageFile(fileName) || noarchFiles[fileName] != "" {
+				continue
+			}
+
+			noarchFiles[fileName] = joinPath(depot.dataDir, file)
+		}
+	}
+
+	var fileNames []string
+
+	for fileName := range noarchFiles {
+		fileNames = append(fileNames, fileName)
+	}
+
+	slices.Sort(fileNames)
+
+	var added []string
+	var errs []error
+
+	for _, fileName := range fileNames {
+		relPaths, err := s.addNoarchPackage(repo, noarchFiles[fileName], true, nil)
+
+		added = append(added, relPaths...)
 
 		if err != nil {
-			return err
+			errs = append(errs, fmt.Errorf("%s: %w", fileName, err))
 		}
 	}
 
-	return nil
+	return added, errors.Join(errs...)
 }
 
 // RemovePackage removes package with given relative path from the given repository
@@ -310,9 +575,9 @@ func (s *Storage) RemovePackage(repo, arch, rpmFileRelPath string) error {
 	case arch == data.ARCH_NOARCH:
 		return fmt.Errorf("Can't remove package from storage: %w", ErrPseudoArch)
 	case !s.HasRepo(repo):
-		return fmt.Errorf("Can't remove package from storage: Repository %q doesn't exist", repo)
+		return fmt.Errorf("Can't remove package from storage: %w", newError(ErrRepoNotFound, "Repository %q doesn't exist", repo))
 	case !s.HasArch(repo, arch):
-		return fmt.Errorf("Can't remove package from storage: Repository %q doesn't support %q architecture", repo, arch)
+		return fmt.Errorf("Can't remove package from storage: %w", newError(ErrArchNotSupported, "Repository %q doesn't support %q architecture", repo, arch))
 	}
 
 	return s.GetDepot(repo, arch).RemovePackage(rpmFileRelPath)
@@ -335,36 +600,79 @@ func (s *Storage) CopyPackage(fromRepo, toRepo, arch, rpmFileRelPath string) err
 	case arch == data.ARCH_NOARCH:
 		return fmt.Errorf("Can't remove package from storage: %w", ErrPseudoArch)
 	case !s.HasRepo(fromRepo):
-		return fmt.Errorf("Can't copy package in storage: Source repository %q doesn't exist", fromRepo)
+		return fmt.Errorf("Can't copy package in storage: %w", newError(ErrRepoNotFound, "Source repository %q doesn't exist", fromRepo))
 	case !s.HasRepo(toRepo):
-		return fmt.Errorf("Can't copy package in storage: Target repository %q doesn't exist", toRepo)
+		return fmt.Errorf("Can't copy package in storage: %w", newError(ErrRepoNotFound, "Target repository %q doesn't exist", toRepo))
 	case !s.HasArch(fromRepo, arch):
-		return fmt.Errorf("Can't copy package in storage: Source repository %q doesn't support %q architecture", fromRepo, arch)
+		return fmt.Errorf("Can't copy package in storage: %w", newError(ErrArchNotSupported, "Source repository %q doesn't support %q architecture", fromRepo, arch))
 	case !s.HasArch(toRepo, arch):
-		return fmt.Errorf("Can't copy package in storage: Target repository %q doesn't support %q architecture", toRepo, arch)
+		return fmt.Errorf("Can't copy package in storage: %w", newError(ErrArchNotSupported, "Target repository %q doesn't support %q architecture", toRepo, arch))
+	}
+
+	srcFile := s.GetDepot(fromRepo, arch).GetPackagePath(rpmFileRelPath)
+
+	if !s.dataOptions.VerifyCopy {
+		return s.AddPackage(toRepo, srcFile)
+	}
+
+	srcHash := fileHashFunc(srcFile)
+
+	if srcHash == "" {
+		return fmt.Errorf("Can't copy package in storage: Can't calculate checksum for %s", srcFile)
+	}
+
+	err := s.addVerifiedPackage(toRepo, srcFile, srcHash)
+
+	if err != nil {
+		return fmt.Errorf("Can't copy package in storage: %w", err)
+	}
+
+	return nil
+}
+
+// RepairPackageAttrs sets owner and permissions defined in storage options for
+// package file with given relative path and its directory
+func (s *Storage) RepairPackageAttrs(repo, arch, rpmFileRelPath string) error {
+	switch {
+	case repo == "":
+		return fmt.Errorf("Can't repair package attributes: %w", ErrEmptyRepoName)
+	case rpmFileRelPath == "":
+		return fmt.Errorf("Can't repair package attributes: %w", ErrEmptyPath)
+	case arch == "":
+		return fmt.Errorf("Can't repair package attributes: %w", ErrEmptyArchName)
+	case data.SupportedArchs[arch].Flag == data.ARCH_FLAG_UNKNOWN:
+		return fmt.Errorf("Can't repair package attributes: %w", ErrUnknownArch)
+	case arch == data.ARCH_NOARCH:
+		return fmt.Errorf("Can't repair package attributes: %w", ErrPseudoArch)
+	case !s.HasRepo(repo):
+		return fmt.Errorf("Can't repair package attributes: %w", newError(ErrRepoNotFound, "Repository %q doesn't exist", repo))
+	case !s.HasArch(repo, arch):
+		return fmt.Errorf("Can't repair package attributes: %w", newError(ErrArchNotSupported, "Repository %q doesn't support %q architecture", repo, arch))
 	}
 
-	return s.AddPackage(toRepo, s.GetDepot(fromRepo, arch).GetPackagePath(rpmFileRelPath))
+	return s.GetDepot(repo, arch).RepairPackageAttrs(rpmFileRelPath)
 }
 
 // Reindex generates index metadata for the given repository and arch
-func (s *Storage) Reindex(repo, arch string, full bool) error {
+// and returns index generation statistics (nil if reindex was skipped). If
+// progress channel is not nil, info about generation progress will be sent to it.
+func (s *Storage) Reindex(ctx context.Context, repo, arch string, full bool, progress chan<- index.Progress) (*index.GenerateStats, error) {
 	switch {
 	case repo == "":
-		return fmt.Errorf("Can't generate index: %w", ErrEmptyRepoName)
+		return nil, fmt.Errorf("Can't generate index: %w", ErrEmptyRepoName)
 	case arch == "":
-		return fmt.Errorf("Can't generate index: %w", ErrEmptyArchName)
+		return nil, fmt.Errorf("Can't generate index: %w", ErrEmptyArchName)
 	case data.SupportedArchs[arch].Flag == data.ARCH_FLAG_UNKNOWN:
-		return fmt.Errorf("Can't generate index: %w", ErrUnknownArch)
+		return nil, fmt.Errorf("Can't generate index: %w", ErrUnknownArch)
 	case arch == data.ARCH_NOARCH:
-		return fmt.Errorf("Can't generate index: Unsupported architecture %q", arch)
+		return nil, fmt.Errorf("Can't generate index: %w", newError(ErrUnknownArch, "Unsupported architecture %q", arch))
 	case !s.HasRepo(repo):
-		return fmt.Errorf("Can't generate index: Repository %q doesn't exist", repo)
+		return nil, fmt.Errorf("Can't generate index: %w", newError(ErrRepoNotFound, "Repository %q doesn't exist", repo))
 	case !s.HasArch(repo, arch):
-		return fmt.Errorf("Can't generate index: Repository %q doesn't contain %q architecture", repo, arch)
+		return nil, fmt.Errorf("Can't generate index: %w", newError(ErrArchNotSupported, "Repository %q doesn't contain %q architecture", repo, arch))
 	}
 
-	return s.GetDepot(repo, arch).Reindex(full)
+	return s.GetDepot(repo, arch).Reindex(ctx, full, progress)
 }
 
 // IsInitialized returns true if repository already initialized and ready for work
@@ -394,7 +702,13 @@ func (s *Storage) HasRepo(repo string) bool {
 		return false
 	}
 
-	return fsutil.IsExist(joinPath(s.dataOptions.DataDir, repo))
+	for _, arch := range data.ArchList {
+		if arch != data.ARCH_NOARCH && fsutil.IsExist(s.dataOptions.GetDepotDir(repo, arch)) {
+			return true
+		}
+	}
+
+	return false
 }
 
 // HasArch returns true if repository storage contains directory for specific arch
@@ -408,16 +722,9 @@ func (s *Storage) HasArch(repo, arch string) bool {
 		return false
 	}
 
-	repoDir := joinPath(s.dataOptions.DataDir, repo)
-
 	if arch == data.ARCH_NOARCH {
-		for _, archInfo := range data.SupportedArchs {
-			switch archInfo.Flag {
-			case data.ARCH_FLAG_SRC, data.ARCH_FLAG_NOARCH:
-				continue
-			}
-
-			if fsutil.IsExist(joinPath(repoDir, archInfo.Dir)) {
+		for _, binArch := range data.BinArchList {
+			if fsutil.IsExist(s.dataOptions.GetDepotDir(repo, binArch)) {
 				return true
 			}
 		}
@@ -425,13 +732,7 @@ func (s *Storage) HasArch(repo, arch string) bool {
 		return false
 	}
 
-	archInfo, hasArch := data.SupportedArchs[arch]
-
-	if !hasArch {
-		return false
-	}
-
-	return fsutil.IsExist(joinPath(repoDir, archInfo.Dir))
+	return fsutil.IsExist(s.dataOptions.GetDepotDir(repo, arch))
 }
 
 // HasPackage checks if repository contains file with given name
@@ -480,6 +781,23 @@ func (s *Storage) GetPackagePath(repo, arch, rpmFileRelPath string) string {
 	return depot.GetPackagePath(rpmFileRelPath)
 }
 
+// OpenPackage opens package file for reading
+func (s *Storage) OpenPackage(repo, arch, rpmFileRelPath string) (io.ReadCloser, error) {
+	pkgFile := s.GetPackagePath(repo, arch, rpmFileRelPath)
+
+	if pkgFile == "" {
+		return nil, fmt.Errorf("Can't open package: Can't find package %q in repository %q", rpmFileRelPath, repo)
+	}
+
+	fd, err := os.Open(pkgFile)
+
+	if err != nil {
+		return nil, fmt.Errorf("Can't open package: %w", err)
+	}
+
+	return fd, nil
+}
+
 // ////////////////////////////////////////////////////////////////////////////////// //
 
 // GetDB returns connection to SQLite DB
@@ -500,6 +818,26 @@ func (s *Storage) GetDB(repo, arch, dbType string) (*sql.DB, error) {
 	return s.GetDepot(repo, arch).GetDB(dbType)
 }
 
+// Query executes SQL query over SQLite DB. Unlike GetDB, it guarantees that
+// DB connection will not be closed by cache invalidation while query is
+// starting.
+func (s *Storage) Query(ctx context.Context, repo, arch, dbType, query string, args ...any) (*sql.Rows, error) {
+	switch {
+	case repo == "":
+		return nil, fmt.Errorf("Can't execute query: %w", ErrEmptyRepoName)
+	case arch == "":
+		return nil, fmt.Errorf("Can't execute query: %w", ErrEmptyArchName)
+	case data.SupportedArchs[arch].Flag == data.ARCH_FLAG_UNKNOWN:
+		return nil, fmt.Errorf("Can't execute query: %w", ErrUnknownArch)
+	case dbType == "":
+		return nil, fmt.Errorf("Can't execute query: DB type can't be empty")
+	case !s.IsInitialized():
+		return nil, fmt.Errorf("Can't execute query: %w", ErrNotInitialized)
+	}
+
+	return s.GetDepot(repo, arch).Query(ctx, dbType, query, args...)
+}
+
 // GetDepot creates new depot or returns one from the cache
 func (s *Storage) GetDepot(repo, arch string) *Depot {
 	if repo == "" || arch == "" || data.SupportedArchs[arch].Flag == data.ARCH_FLAG_UNKNOWN {
@@ -507,7 +845,20 @@ func (s *Storage) GetDepot(repo, arch string) *Depot {
 	}
 
 	id := repo + "-" + arch
+
+	s.mu.RLock()
 	depot := s.depots[id]
+	s.mu.RUnlock()
+
+	if depot != nil {
+		return depot
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Depot could be created by another goroutine while we were waiting for lock
+	depot = s.depots[id]
 
 	if depot != nil {
 		return depot
@@ -517,8 +868,14 @@ func (s *Storage) GetDepot(repo, arch string) *Depot {
 		id:           id,
 		dataOptions:  s.dataOptions,
 		indexOptions: s.indexOptions,
-		dataDir:      joinPath(s.dataOptions.DataDir, repo, data.SupportedArchs[arch].Dir),
+		dataDir:      s.dataOptions.GetDepotDir(repo, arch),
+		cacheDir:     s.dataOptions.CacheDir,
 		dbs:          make(map[string]*sql.DB),
+		mu:           &sync.RWMutex{},
+	}
+
+	if s.dataOptions.NestedCache {
+		depot.cacheDir = joinPath(s.dataOptions.CacheDir, repo, arch)
 	}
 
 	s.depots[id] = depot
@@ -526,6 +883,120 @@ func (s *Storage) GetDepot(repo, arch string) *Depot {
 	return depot
 }
 
+// getDepots returns slice with all created depots
+func (s *Storage) getDepots() []*Depot {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]*Depot, 0, len(s.depots))
+
+	for _, depot := range s.depots {
+		result = append(result, depot)
+	}
+
+	return result
+}
+
+// getPackageRelPaths returns paths (relative to repository directory) to all
+// copies of package with given name
+func (s *Storage) getPackageRelPaths(repo, rpmFileName string) []string {
+	var result []string
+
+	for _, arch := range data.ArchList {
+		if arch == data.ARCH_NOARCH || !s.HasArch(repo, arch) {
+			continue
+		}
+
+		depot := s.GetDepot(repo, arch)
+
+		if depot.HasPackage(rpmFileName) {
+			result = append(result, depot.getPackageRelPath(arch, depot.GetPackagePath(rpmFileName)))
+		}
+	}
+
+	return result
+}
+
+// addNoarchPackage adds noarch package file to all binary arch depots of the
+// given repository and returns paths (relative to repository directory) of
+// added files
+func (s *Storage) addNoarchPackage(repo, rpmFilePath string, onlyMissing bool, owner *objectOwner) ([]string, error) {
+	var added []string
+
+	rpmFileName := path.Base(rpmFilePath)
+
+	for _, arch := range data.BinArchList {
+		if !s.HasArch(repo, arch) {
+			continue
+		}
+
+		depot := s.GetDepot(repo, arch)
+
+		if onlyMissing && depot.HasPackage(rpmFileName) {
+			continue
+		}
+
+		err := depot.addPackage(rpmFilePath, "", owner)
+
+		if err != nil {
+			return added, err
+		}
+
+		added = append(added, depot.getPackageRelPath(arch, depot.GetPackagePath(rpmFileName)))
+	}
+
+	return added, nil
+}
+
+// addVerifiedPackage adds package file to the given repository and verifies
+// checksum of every copy (noarch package is copied to all binary arch depots).
+// If any copy is broken, all files created by this call are removed, files which
+// were present before are kept untouched.
+func (s *Storage) addVerifiedPackage(repo, rpmFilePath, srcHash string) error {
+	arch, err := helpers.ExtractPackageArch(rpmFilePath)
+
+	if err != nil {
+		return fmt.Errorf("Can't extract package architecture tag: %w", err)
+	}
+
+	archs := []string{arch}
+
+	if arch == data.ARCH_NOARCH {
+		archs = nil
+
+		for _, binArch := range data.BinArchList {
+			if s.HasArch(repo, binArch) {
+				archs = append(archs, binArch)
+			}
+		}
+	}
+
+	var created []*Depot
+
+	rpmFileName := path.Base(rpmFilePath)
+
+	for _, arch := range archs {
+		depot := s.GetDepot(repo, arch)
+		isExist := depot.HasPackage(rpmFileName)
+
+		err = depot.addPackage(rpmFilePath, srcHash, nil)
+
+		if err != nil {
+			for _, d := range created {
+				d.RemovePackage(rpmFileName)
+			}
+
+			return err
+		}
+
+		if !isExist {
+			created = append(created, depot)
+		}
+	}
+
+	return nil
+}
+
 // GetBinDepot returns any depot with binary packages (useful for working with noarch packages)
 func (s *Storage) GetBinDepot(repo string) *Depot {
 	for _, a := range data.BinArchList {
@@ -552,7 +1023,7 @@ func (s *Storage) GetModTime(repo, arch string) (time.Time, error) {
 		return time.Time{}, fmt.Errorf("Can't check repository index modification date: %w", ErrNotInitialized)
 	}
 
-	indexFile := joinPath(s.dataOptions.DataDir, repo, data.SupportedArchs[arch].Dir, "/repodata/repomd.xml")
+	indexFile := joinPath(s.dataOptions.GetDepotDir(repo, arch), "/repodata/repomd.xml")
 	mTime, err := fsutil.GetMTime(indexFile)
 
 	if err != nil {
@@ -562,201 +1033,994 @@ func (s *Storage) GetModTime(repo, arch string) (time.Time, error) {
 	return mTime, nil
 }
 
-// InvalidateCache invalidates cache and removes SQLite files from cache directory
-func (s *Storage) InvalidateCache() error {
-	if !s.IsInitialized() {
-		return fmt.Errorf("Can't invalidate cache: %w", ErrNotInitialized)
-	}
-
-	for _, depot := range s.depots {
-		err := depot.InvalidateCache()
+// GetMetaIndexPath returns path to repository index file (repomd.xml)
+func (s *Storage) GetMetaIndexPath(repo, arch string) string {
+	switch {
+	case repo == "", arch == "", arch == data.ARCH_NOARCH:
+		return ""
+	case data.SupportedArchs[arch].Flag == data.ARCH_FLAG_UNKNOWN:
+		return ""
+	case !s.HasArch(repo, arch):
+		return ""
+	}
+
+	return s.GetDepot(repo, arch).GetMetaIndexPath()
+}
+
+// FindMissingMetaFiles returns list of metadata files referenced in index but
+// missing in the storage
+func (s *Storage) FindMissingMetaFiles(repo, arch string) ([]string, error) {
+	switch {
+	case repo == "":
+		return nil, fmt.Errorf("Can't check metadata files: %w", ErrEmptyRepoName)
+	case arch == "":
+		return nil, fmt.Errorf("Can't check metadata files: %w", ErrEmptyArchName)
+	case data.SupportedArchs[arch].Flag == data.ARCH_FLAG_UNKNOWN:
+		return nil, fmt.Errorf("Can't check metadata files: %w", ErrUnknownArch)
+	case !s.IsInitialized():
+		return nil, fmt.Errorf("Can't check metadata files: %w", ErrNotInitialized)
+	}
+
+	return s.GetDepot(repo, arch).FindMissingMetaFiles()
+}
+
+// Verify checks that all packages referenced in index exist in the storage
+// and all packages in the storage are referenced in index
+func (s *Storage) Verify(repo, arch string) ([]error, error) {
+	switch {
+	case repo == "":
+		return nil, fmt.Errorf("Can't verify storage: %w", ErrEmptyRepoName)
+	case arch == "":
+		return nil, fmt.Errorf("Can't verify storage: %w", ErrEmptyArchName)
+	case data.SupportedArchs[arch].Flag == data.ARCH_FLAG_UNKNOWN:
+		return nil, fmt.Errorf("Can't verify storage: %w", ErrUnknownArch)
+	case arch == data.ARCH_NOARCH:
+		return nil, fmt.Errorf("Can't verify storage: %w", ErrPseudoArch)
+	case !s.IsInitialized():
+		return nil, fmt.Errorf("Can't verify storage: %w", ErrNotInitialized)
+	}
+
+	problems, err := s.GetDepot(repo, arch).Verify()
+
+	if err != nil {
+		return nil, fmt.Errorf("Can't verify storage: %w", err)
+	}
+
+	return problems, nil
+}
+
+// FindOrphanPackages returns list of package files (paths relative to depot
+// directory) which exist in the storage but aren't referenced in index
+func (s *Storage) FindOrphanPackages(repo, arch string) ([]string, error) {
+	switch {
+	case repo == "":
+		return nil, fmt.Errorf("Can't find orphan packages: %w", ErrEmptyRepoName)
+	case arch == "":
+		return nil, fmt.Errorf("Can't find orphan packages: %w", ErrEmptyArchName)
+	case data.SupportedArchs[arch].Flag == data.ARCH_FLAG_UNKNOWN:
+		return nil, fmt.Errorf("Can't find orphan packages: %w", ErrUnknownArch)
+	case arch == data.ARCH_NOARCH:
+		return nil, fmt.Errorf("Can't find orphan packages: %w", ErrPseudoArch)
+	case !s.IsInitialized():
+		return nil, fmt.Errorf("Can't find orphan packages: %w", ErrNotInitialized)
+	}
+
+	orphans, err := s.GetDepot(repo, arch).FindOrphanPackages()
+
+	if err != nil {
+		return nil, fmt.Errorf("Can't find orphan packages: %w", err)
+	}
+
+	return orphans, nil
+}
+
+// ReadAuxData reads auxiliary data (e.g. packages tags) with given name. If
+// data doesn't exist, nil is returned.
+func (s *Storage) ReadAuxData(name string) ([]byte, error) {
+	err := validateAuxDataName(name)
+
+	if err != nil {
+		return nil, fmt.Errorf("Can't read auxiliary data: %w", err)
+	}
+
+	dataFile := s.GetAuxDataPath(name)
+
+	if !fsutil.IsExist(dataFile) {
+		return nil, nil
+	}
+
+	auxData, err := os.ReadFile(dataFile)
+
+	if err != nil {
+		return nil, fmt.Errorf("Can't read auxiliary data: %w", err)
+	}
+
+	return auxData, nil
+}
+
+// WriteAuxData atomically writes auxiliary data (e.g. packages tags) with
+// given name
+func (s *Storage) WriteAuxData(name string, auxData []byte) error {
+	err := validateAuxDataName(name)
+
+	if err != nil {
+		return fmt.Errorf("Can't write auxiliary data: %w", err)
+	}
+
+	if !s.IsInitialized() {
+		return fmt.Errorf("Can't write auxiliary data: %w", ErrNotInitialized)
+	}
+
+	dataFile := s.GetAuxDataPath(name)
+	tmpFile := dataFile + ".tmp"
+
+	err = os.WriteFile(tmpFile, auxData, s.dataOptions.GetFilePerms())
+
+	if err == nil {
+		err = updateObjectAttrs(tmpFile, s.dataOptions, false)
+	}
+
+	if err == nil {
+		err = os.Rename(tmpFile, dataFile)
+	}
+
+	if err != nil {
+		os.Remove(tmpFile)
+		return fmt.Errorf("Can't write auxiliary data: %w", err)
+	}
+
+	return nil
+}
+
+// GetAuxDataPath returns path to file with auxiliary data with given name (or
+// empty string if name is invalid)
+func (s *Storage) GetAuxDataPath(name string) string {
+	if validateAuxDataName(name) != nil {
+		return ""
+	}
+
+	return joinPath(s.dataOptions.DataDir, "."+name)
+}
+
+// FindDuplicates returns names of package files which exist in repository more
+// than once with paths (relative to repository directory) to all their copies
+func (s *Storage) FindDuplicates(repo string) (map[string][]string, error) {
+	switch {
+	case repo == "":
+		return nil, fmt.Errorf("Can't find duplicates: %w", ErrEmptyRepoName)
+	case !s.IsInitialized():
+		return nil, fmt.Errorf("Can't find duplicates: %w", ErrNotInitialized)
+	case !s.HasRepo(repo):
+		return nil, fmt.Errorf("Can't find duplicates: %w", newError(ErrRepoNotFound, "Repository %q doesn't exist", repo))
+	}
+
+	var files []string
+
+	for _, arch := range data.ArchList {
+		archDir := data.SupportedArchs[arch].Dir
+
+		if arch == data.ARCH_NOARCH || archDir == "" || !s.HasArch(repo, arch) {
+			continue
+		}
+
+		for _, file := range s.GetDepot(repo, arch).listPackageFiles() {
+			files = append(files, joinPath(archDir, file))
+		}
+	}
+
+	result := make(map[string][]string)
+
+	for fileName, paths := range groupFilesByName(files) {
+		if len(paths) < 2 {
+			continue
+		}
+
+		if !isNoarchPackageFile(fileName) {
+			result[fileName] = paths
+			continue
+		}
+
+		// Noarch packages are stored in all binary arch directories, so only
+		// copies in the same arch directory are duplicates
+		archPaths := make(map[string][]string)
+
+		for _, filePath := range paths {
+			archDir, _, _ := strings.Cut(filePath, "/")
+			archPaths[archDir] = append(archPaths[archDir], filePath)
+		}
+
+		for _, archDir := range data.ArchList {
+			dirPaths := archPaths[data.SupportedArchs[archDir].Dir]
+
+			if len(dirPaths) > 1 {
+				result[fileName] = append(result[fileName], dirPaths...)
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// InvalidateCache invalidates cache and removes SQLite files from cache directory
+func (s *Storage) InvalidateCache() error {
+	if !s.IsInitialized() {
+		return fmt.Errorf("Can't invalidate cache: %w", ErrNotInitialized)
+	}
+
+	for _, depot := range s.getDepots() {
+		err := depot.InvalidateCache()
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// IsCacheValid returns true if cache is valid
+func (s *Storage) IsCacheValid(repo, arch string) bool {
+	return s.GetDepot(repo, arch).IsCacheValid()
+}
+
+// PurgeCache deletes all SQLite files from cache directory
+func (s *Storage) PurgeCache() error {
+	if !s.IsInitialized() {
+		return fmt.Errorf("Can't purge cache: %w", ErrNotInitialized)
+	}
+
+	files := fsutil.ListAllFiles(s.dataOptions.CacheDir, true, fsutil.ListingFilter{
+		MatchPatterns: []string{"*.sqlite"},
+	})
+
+	fsutil.ListToAbsolute(s.dataOptions.CacheDir, files)
+
+	for _, sqlFile := range files {
+		err := removeFunc(sqlFile)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// PurgeCacheFor deletes SQLite files for given repository and arch from cache
+// directory
+func (s *Storage) PurgeCacheFor(repo, arch string) error {
+	switch {
+	case repo == "":
+		return fmt.Errorf("Can't purge cache: %w", ErrEmptyRepoName)
+	case arch == "":
+		return fmt.Errorf("Can't purge cache: %w", ErrEmptyArchName)
+	case data.SupportedArchs[arch].Flag == data.ARCH_FLAG_UNKNOWN:
+		return fmt.Errorf("Can't purge cache: %w", ErrUnknownArch)
+	case !s.IsInitialized():
+		return fmt.Errorf("Can't purge cache: %w", ErrNotInitialized)
+	}
+
+	depot := s.GetDepot(repo, arch)
+	err := depot.InvalidateCache()
+
+	if err != nil {
+		return fmt.Errorf("Can't purge cache: %w", err)
+	}
+
+	for _, dbType := range data.DBList {
+		dbFile := depot.GetDBFilePath(dbType)
+
+		if !fsutil.IsExist(dbFile) {
+			continue
+		}
+
+		err = removeFunc(dbFile)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WarmupCache warmups cache
+func (s *Storage) WarmupCache(repo, arch string) error {
+	switch {
+	case repo == "":
+		return fmt.Errorf("Can't warmup cache: %w", ErrEmptyRepoName)
+	case arch == "":
+		return fmt.Errorf("Can't warmup cache: %w", ErrEmptyArchName)
+	case data.SupportedArchs[arch].Flag == data.ARCH_FLAG_UNKNOWN:
+		return fmt.Errorf("Can't warmup cache: %w", ErrUnknownArch)
+	case !s.IsInitialized():
+		return fmt.Errorf("Can't warmup cache: %w", ErrNotInitialized)
+	}
+
+	var dbTypes []string
+
+	for _, dbType := range data.DBList {
+		if !s.dataOptions.IsDBSkipped(dbType) {
+			dbTypes = append(dbTypes, dbType)
+		}
+	}
+
+	return s.GetDepot(repo, arch).WarmupCache(dbTypes)
+}
+
+// GetDiskUsage returns total and free space of filesystem with repository data
+func (s *Storage) GetDiskUsage() (uint64, uint64, error) {
+	if s == nil || s.dataOptions == nil {
+		return 0, 0, fmt.Errorf("Can't get disk usage info: %w", ErrNotInitialized)
+	}
+
+	total, free, err := getDiskUsage(s.dataOptions.DataDir)
+
+	if err != nil {
+		return 0, 0, fmt.Errorf("Can't get disk usage info: %w", err)
+	}
+
+	return total, free, nil
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Error returns error description
+func (e *Error) Error() string {
+	if e == nil {
+		return ""
+	}
+
+	return e.Desc
+}
+
+// Unwrap returns error kind
+func (e *Error) Unwrap() error {
+	if e == nil {
+		return nil
+	}
+
+	return e.Kind
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Reindex generates index metadata for the given repository and arch and returns
+// index generation statistics (nil if reindex was skipped). If full is true, index
+// is regenerated even if it isn't outdated.
+func (d *Depot) Reindex(ctx context.Context, full bool, progress chan<- index.Progress) (*index.GenerateStats, error) {
+	if d == nil {
+		return nil, ErrNilDepot
+	}
+
+	// Skip index generation if there were no changes since the last reindex
+	if !full && !d.IsIndexOutdated() {
+		return nil, nil
+	}
+
+	stats, err := index.Generate(ctx, d.dataDir, d.indexOptions, full, progress)
+
+	if err != nil {
+		return nil, err
+	}
+
+	d.markReindexed()
+
+	return stats, nil
+}
+
+// IsIndexOutdated returns true if depot contains changes made after the last
+// reindex, index options or groupfile were changed since the last reindex or
+// some of metadata files are missing
+func (d *Depot) IsIndexOutdated() bool {
+	if d == nil || !fsutil.IsExist(d.GetMetaIndexPath()) {
+		return true
+	}
+
+	missingMeta, err := d.FindMissingMetaFiles()
+
+	if err != nil || len(missingMeta) != 0 {
+		return true
+	}
+
+	markFile := d.getReindexMarkPath()
+	markTime, err := fsutil.GetMTime(markFile)
+
+	if err != nil {
+		return true
+	}
+
+	markData, err := os.ReadFile(markFile)
+
+	if err != nil || string(markData) != d.getIndexFingerprint() {
+		return true
+	}
+
+	objects := []string{d.dataDir}
+
+	if d.dataOptions.SplitFiles {
+		dirs := fsutil.List(d.dataDir, true, fsutil.ListingFilter{
+			Perms:            "D",
+			NotMatchPatterns: []string{"repodata", "drpms"},
+		})
+
+		fsutil.ListToAbsolute(d.dataDir, dirs)
+		objects = append(objects, dirs...)
+	}
+
+	files := fsutil.ListAllFiles(d.dataDir, true, fsutil.ListingFilter{
+		MatchPatterns: []string{"*.rpm"},
+	})
+
+	fsutil.ListToAbsolute(d.dataDir, files)
+	objects = append(objects, files...)
+
+	for _, object := range objects {
+		modTime, err := fsutil.GetMTime(object)
+
+		if err != nil || !modTime.Before(markTime) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// AddPackage adds package to depot
+func (d *Depot) AddPackage(rpmFile string) error {
+	return d.addPackage(rpmFile, "", nil)
+}
+
+// addPackage adds package to depot. If source checksum is not empty, checksum
+// of copied file will be verified before it replaces the target file. If owner
+// is nil, it will be resolved from storage options.
+func (d *Depot) addPackage(rpmFile, srcHash string, owner *objectOwner) error {
+	if rpmFile == "" {
+		return fmt.Errorf("Can't add package to storage depot: %w", ErrEmptyPath)
+	}
+
+	if d == nil {
+		return fmt.Errorf("Can't add package to storage depot: %w", ErrNilDepot)
+	}
+
+	err := fsutil.ValidatePerms("FRS", rpmFile)
+
+	if err != nil {
+		return fmt.Errorf("Can't add package to storage depot: %w", err)
+	}
+
+	if !rpm.IsRPM(rpmFile) {
+		return fmt.Errorf("Can't add file to storage depot: %w", newError(ErrNotRPM, "%s is not an RPM package", rpmFile))
+	}
+
+	err = d.checkFreeSpace(rpmFile)
+
+	if err != nil {
+		return fmt.Errorf("Can't add package to storage depot: %w", err)
+	}
+
+	if owner == nil {
+		owner, err = getObjectOwner(d.dataOptions)
+
+		if err != nil {
+			return fmt.Errorf("Can't add package to storage depot: %w", err)
+		}
+	}
+
+	packageDir := d.dataDir
+
+	if d.dataOptions.SplitFiles {
+		packageDir, err = d.makePackageDir(rpmFile, owner)
+
+		if err != nil {
+			return fmt.Errorf("Can't add package to storage depot: %w", err)
+		}
+	}
+
+	err = d.copyFile(rpmFile, packageDir, srcHash, owner)
+
+	switch {
+	case errors.Is(err, ErrHashMismatch):
+		return err
+	case err != nil:
+		return fmt.Errorf("Can't copy package to storage depot: %w", err)
+	}
+
+	return nil
+}
+
+// RemovePackage removes package from depot
+func (d *Depot) RemovePackage(rpmFile string) error {
+	if rpmFile == "" {
+		return fmt.Errorf("Can't remove package from storage depot: %w", ErrEmptyPath)
+	}
+
+	if d == nil {
+		return fmt.Errorf("Can't remove package from storage depot: %w", ErrNilDepot)
+	}
+
+	// Path from metadata can contain location prefix, so we use only file name
+	filePath := d.GetPackagePath(rpmFile)
+	err := fsutil.ValidatePerms("FW", filePath)
+
+	if err != nil {
+		return fmt.Errorf("Can't remove package from storage depot: %w", err)
+	}
+
+	err = removeFunc(filePath)
+
+	if err != nil {
+		return fmt.Errorf("Can't remove package from storage depot: %w", err)
+	}
+
+	if d.dataOptions.SplitFiles {
+		err = d.removePackageDir(rpmFile)
+
+		if err != nil {
+			return fmt.Errorf("Can't remove package from storage depot: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// RepairPackageAttrs sets owner and permissions defined in storage options for
+// package file and its directory
+func (d *Depot) RepairPackageAttrs(rpmFile string) error {
+	if rpmFile == "" {
+		return fmt.Errorf("Can't repair package attributes: %w", ErrEmptyPath)
+	}
+
+	if d == nil {
+		return fmt.Errorf("Can't repair package attributes: %w", ErrNilDepot)
+	}
+
+	filePath, ok := d.findPackageFile(path.Base(rpmFile))
+
+	if !ok {
+		return fmt.Errorf("Can't repair package attributes: %w", newError(ErrMissingPackage, "Package file %s doesn't exist", path.Base(rpmFile)))
+	}
+
+	err := updateObjectAttrs(path.Dir(filePath), d.dataOptions, true)
+
+	if err != nil {
+		return fmt.Errorf("Can't repair package directory attributes: %w", err)
+	}
+
+	err = updateObjectAttrs(filePath, d.dataOptions, false)
+
+	if err != nil {
+		return fmt.Errorf("Can't repair package attributes: %w", err)
+	}
+
+	return nil
+}
+
+// GetPackagePath returns full path to package RPM file
+func (d *Depot) GetPackagePath(rpmFileRelPath string) string {
+	if d == nil {
+		return ""
+	}
+
+	filePath, _ := d.findPackageFile(path.Base(rpmFileRelPath))
+
+	return filePath
+}
+
+// HasPackage checks if depot contains file with given name
+func (d *Depot) HasPackage(rpmFileName string) bool {
+	if d == nil {
+		return false
+	}
+
+	_, exist := d.findPackageFile(rpmFileName)
+
+	return exist
+}
+
+// FindDuplicates returns names of package files which exist in depot more than
+// once (e.g. in both flat directory and split subdirectory) with relative paths
+// to all their copies
+func (d *Depot) FindDuplicates() (map[string][]string, error) {
+	if d == nil {
+		return nil, ErrNilDepot
+	}
+
+	if !fsutil.IsExist(d.dataDir) {
+		return nil, fmt.Errorf("Directory %s doesn't exist", d.dataDir)
+	}
+
+	result := make(map[string][]string)
+
+	for fileName, paths := range groupFilesByName(d.listPackageFiles()) {
+		if len(paths) > 1 {
+			result[fileName] = paths
+		}
+	}
+
+	return result, nil
+}
+
+// IsEmpty returns true if repository is empty (no packages)
+func (d *Depot) IsEmpty() bool {
+	if d == nil {
+		return true
+	}
+
+	return fsutil.IsEmptyDir(d.dataDir)
+}
+
+// IsCacheValid checks if database files was updated and depot contains outdated
+// metadata info and keeps connection to outdated SQL databases
+func (d *Depot) IsCacheValid() bool {
+	if d == nil {
+		return false
+	}
+
+	return d.CheckCache() == nil
+}
+
+// CheckCache checks if cache is valid and healthy
+func (d *Depot) CheckCache() error {
+	if d == nil {
+		return ErrNilDepot
+	}
 
-		if err != nil {
-			return err
-		}
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	return d.checkCache()
+}
+
+// InvalidateCache invalidates repository cache
+func (d *Depot) InvalidateCache() error {
+	if d == nil {
+		return ErrNilDepot
 	}
 
-	return nil
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.invalidateCache()
 }
 
-// IsCacheValid returns true if cache is valid
-func (s *Storage) IsCacheValid(repo, arch string) bool {
-	return s.GetDepot(repo, arch).IsCacheValid()
+// IsDBCached returns true if SQLite DB is cached
+func (d *Depot) IsDBCached(dbType string) bool {
+	if d == nil {
+		return false
+	}
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	return d.isDBCached(dbType)
 }
 
-// PurgeCache deletes all SQLite files from cache directory
-func (s *Storage) PurgeCache() error {
-	if !s.IsInitialized() {
-		return fmt.Errorf("Can't purge cache: %w", ErrNotInitialized)
+// CacheDB caches (saves unpacked DB file) SQLite DB
+func (d *Depot) CacheDB(dbType string) error {
+	if dbType == "" {
+		return fmt.Errorf("Can't cache DB: DB type can't be empty")
 	}
 
-	files := fsutil.List(s.dataOptions.CacheDir, true, fsutil.ListingFilter{
-		MatchPatterns: []string{"*.sqlite"},
-	})
+	if d == nil {
+		return fmt.Errorf("Can't cache DB: %w", ErrNilDepot)
+	}
 
-	fsutil.ListToAbsolute(s.dataOptions.CacheDir, files)
+	d.mu.Lock()
+	defer d.mu.Unlock()
 
-	for _, sqlFile := range files {
-		err := removeFunc(sqlFile)
+	return d.cacheDB(dbType)
+}
 
-		if err != nil {
-			return err
-		}
+// OpenDB opens SQLite DB
+func (d *Depot) OpenDB(dbType string) error {
+	if d == nil {
+		return ErrNilDepot
 	}
 
-	return nil
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.openDB(dbType)
 }
 
-// WarmupCache warmups cache
-func (s *Storage) WarmupCache(repo, arch string) error {
-	switch {
-	case repo == "":
-		return fmt.Errorf("Can't warmup cache: %w", ErrEmptyRepoName)
-	case arch == "":
-		return fmt.Errorf("Can't warmup cache: %w", ErrEmptyArchName)
-	case data.SupportedArchs[arch].Flag == data.ARCH_FLAG_UNKNOWN:
-		return fmt.Errorf("Can't warmup cache: %w", ErrUnknownArch)
-	case !s.IsInitialized():
-		return fmt.Errorf("Can't warmup cache: %w", ErrNotInitialized)
+// GetDB returns connection to SQLite DB
+func (d *Depot) GetDB(dbType string) (*sql.DB, error) {
+	if d == nil {
+		return nil, ErrNilDepot
 	}
 
-	for _, dbType := range data.DBList {
-		_, err := s.GetDB(repo, arch, dbType)
+	if d.indexOptions.NoDatabase {
+		return nil, newError(ErrDBDisabled, "DB %q is not available because database generation is disabled by index configuration", dbType)
+	}
 
-		if err != nil {
-			return err
+	if d.dataOptions.IsDBSkipped(dbType) {
+		return nil, newError(ErrDBSkipped, "DB %q is excluded from caching by storage configuration", dbType)
+	}
+
+	d.mu.RLock()
+	db := d.dbs[dbType]
+
+	// Fast path: connection already opened and cache is still valid
+	if db != nil && d.checkCache() == nil {
+		d.mu.RUnlock()
+		return db, nil
+	}
+
+	d.mu.RUnlock()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	db, err := d.getDB(dbType)
+
+	// Repository could be reindexed while we were warming up the cache, so we
+	// try to invalidate the cache and open DB again
+	if err != nil && d.isMetaChanged() {
+		if d.invalidateCache() == nil {
+			return d.getDB(dbType)
 		}
 	}
 
-	return nil
+	return db, err
 }
 
-// ////////////////////////////////////////////////////////////////////////////////// //
-
-// Reindex generates index metadata for the given repository and arch
-func (d *Depot) Reindex(full bool) error {
+// Query executes SQL query over SQLite DB with given type. Query is started
+// while holding depot read lock, so connection can't be closed by cache
+// invalidation until query is started (rows keep their own connection).
+func (d *Depot) Query(ctx context.Context, dbType, query string, args ...any) (*sql.Rows, error) {
 	if d == nil {
-		return ErrNilDepot
+		return nil, ErrNilDepot
 	}
 
-	return index.Generate(d.dataDir, d.indexOptions, full)
-}
+	for i := 0; i < 2; i++ {
+		d.mu.RLock()
+		db := d.dbs[dbType]
 
-// AddPackage adds package to depot
-func (d *Depot) AddPackage(rpmFile string) error {
-	if rpmFile == "" {
-		return fmt.Errorf("Can't add package to storage depot: %w", ErrEmptyPath)
+		if db != nil && d.checkCache() == nil {
+			rows, err := db.QueryContext(ctx, query, args...)
+			d.mu.RUnlock()
+			return rows, err
+		}
+
+		d.mu.RUnlock()
+
+		_, err := d.GetDB(dbType)
+
+		if err != nil {
+			return nil, err
+		}
 	}
 
+	return nil, fmt.Errorf("Can't execute query: DB %q was invalidated during opening", dbType)
+}
+
+// WarmupCache unpacks and opens SQLite DBs with given types. DBs are unpacked
+// concurrently.
+func (d *Depot) WarmupCache(dbTypes []string) error {
 	if d == nil {
-		return fmt.Errorf("Can't add package to storage depot: %w", ErrNilDepot)
+		return ErrNilDepot
 	}
 
-	err := fsutil.ValidatePerms("FRS", rpmFile)
+	if d.indexOptions.NoDatabase {
+		return newError(ErrDBDisabled, "Databases are not available because their generation is disabled by index configuration")
+	}
+
+	d.mu.Lock()
+
+	err := d.loadMeta()
 
 	if err != nil {
-		return fmt.Errorf("Can't add package to storage depot: %w", err)
+		d.mu.Unlock()
+		return err
 	}
 
-	if !rpm.IsRPM(rpmFile) {
-		return fmt.Errorf("Can't add file to storage depot: %s is not an RPM package", rpmFile)
-	}
+	var wg sync.WaitGroup
 
-	packageDir := d.dataDir
+	errs := make([]error, len(dbTypes))
 
-	if d.dataOptions.SplitFiles {
-		packageDir, err = d.makePackageDir(rpmFile)
+	// Unpacking doesn't modify depot state, so it is safe to unpack DBs
+	// concurrently while we are holding the lock
+	for i, dbType := range dbTypes {
+		if d.isDBCached(dbType) {
+			continue
+		}
+
+		wg.Add(1)
+
+		go func(i int, dbType string) {
+			defer wg.Done()
+			errs[i] = d.cacheDB(dbType)
+		}(i, dbType)
+	}
+
+	wg.Wait()
+	d.mu.Unlock()
 
+	for _, err := range errs {
 		if err != nil {
-			return fmt.Errorf("Can't add package to storage depot: %w", err)
+			return fmt.Errorf("Can't cache DB: %w", err)
 		}
 	}
 
-	err = d.copyFile(rpmFile, packageDir)
+	for _, dbType := range dbTypes {
+		_, err = d.GetDB(dbType)
 
-	if err != nil {
-		return fmt.Errorf("Can't copy package to storage depot: %w", err)
+		if err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
-// RemovePackage removes package from depot
-func (d *Depot) RemovePackage(rpmFile string) error {
-	if rpmFile == "" {
-		return fmt.Errorf("Can't remove package from storage depot: %w", ErrEmptyPath)
+// GetMetaIndex reads repository metadata
+func (d *Depot) GetMetaIndex() (*meta.Index, error) {
+	if d == nil {
+		return nil, ErrNilDepot
 	}
 
+	metaFile := d.GetMetaIndexPath()
+
+	if !fsutil.CheckPerms("FRS", metaFile) {
+		return nil, fmt.Errorf("%s must be path to readable, non-empty XML file", metaFile)
+	}
+
+	return meta.Read(metaFile)
+}
+
+// FindMissingMetaFiles returns list of metadata files referenced in index but
+// missing on disk
+func (d *Depot) FindMissingMetaFiles() ([]string, error) {
 	if d == nil {
-		return fmt.Errorf("Can't remove package from storage depot: %w", ErrNilDepot)
+		return nil, ErrNilDepot
 	}
 
-	filePath := joinPath(d.dataDir, rpmFile)
-	err := fsutil.ValidatePerms("FW", filePath)
+	metaIndex, err := d.GetMetaIndex()
 
 	if err != nil {
-		return fmt.Errorf("Can't remove package from storage depot: %w", err)
+		return nil, fmt.Errorf("Can't read meta index: %w", err)
 	}
 
-	err = removeFunc(filePath)
+	var result []string
+
+	for _, metaInfo := range metaIndex.Data {
+		if !fsutil.IsExist(joinPath(d.dataDir, metaInfo.Location.HREF)) {
+			result = append(result, metaInfo.Location.HREF)
+		}
+	}
+
+	return result, nil
+}
+
+// GetIndexedPackages returns full paths to all packages referenced in index
+func (d *Depot) GetIndexedPackages() ([]string, error) {
+	if d == nil {
+		return nil, ErrNilDepot
+	}
+
+	rows, err := d.Query(context.Background(), data.DB_PRIMARY, _SQL_LOCATIONS)
 
 	if err != nil {
-		return fmt.Errorf("Can't remove package from storage depot: %w", err)
+		return nil, fmt.Errorf("Can't execute query: %w", err)
 	}
 
-	if d.dataOptions.SplitFiles {
-		err = d.removePackageDir(rpmFile)
+	defer rows.Close()
+
+	var result []string
+
+	for rows.Next() {
+		var href string
+
+		err = rows.Scan(&href)
 
 		if err != nil {
-			return fmt.Errorf("Can't remove package from storage depot: %w", err)
+			return nil, fmt.Errorf("Can't parse query result: %w", err)
 		}
+
+		result = append(result, d.GetPackagePath(href))
 	}
 
-	return nil
+	return result, rows.Err()
 }
 
-// GetPackagePath returns full path to package RPM file
-func (d *Depot) GetPackagePath(rpmFileRelPath string) string {
+// Verify returns problems with packages referenced in index but missing in
+// depot and packages in depot which aren't referenced in index
+func (d *Depot) Verify() ([]error, error) {
 	if d == nil {
-		return ""
+		return nil, ErrNilDepot
+	}
+
+	indexed, err := d.GetIndexedPackages()
+
+	if err != nil {
+		return nil, err
+	}
+
+	orphans, err := d.FindOrphanPackages()
+
+	if err != nil {
+		return nil, err
+	}
+
+	var problems []error
+
+	for _, file := range indexed {
+		if !fsutil.IsExist(file) {
+			problems = append(problems, newError(
+				ErrMissingPackage, "Package %s is referenced in index but doesn't exist",
+				strings.TrimPrefix(file, d.dataDir+"/"),
+			))
+		}
+	}
+
+	for _, file := range orphans {
+		problems = append(problems, newError(
+			ErrOrphanPackage, "Package %s isn't referenced in index", file,
+		))
 	}
 
-	rpmFileName := path.Base(rpmFileRelPath)
-	return joinPath(d.getPackageDir(rpmFileName), rpmFileName)
+	return problems, nil
 }
 
-// HasPackage checks if depot contains file with given name
-func (d *Depot) HasPackage(rpmFileName string) bool {
+// FindOrphanPackages returns list of package files (paths relative to depot
+// directory) which exist in depot but aren't referenced in index
+func (d *Depot) FindOrphanPackages() ([]string, error) {
 	if d == nil {
-		return false
+		return nil, ErrNilDepot
 	}
 
-	filePath := joinPath(d.getPackageDir(rpmFileName), rpmFileName)
-	return fsutil.IsExist(filePath)
+	// Depot was never indexed, so all package files are orphans
+	if !fsutil.IsExist(d.GetMetaIndexPath()) {
+		return d.findOrphanFiles(nil), nil
+	}
+
+	indexed, err := d.GetIndexedPackages()
+
+	if err != nil {
+		return nil, err
+	}
+
+	indexedFiles := make(map[string]bool)
+
+	for _, file := range indexed {
+		indexedFiles[file] = true
+	}
+
+	return d.findOrphanFiles(indexedFiles), nil
 }
 
-// IsEmpty returns true if repository is empty (no packages)
-func (d *Depot) IsEmpty() bool {
+// GetMetaIndexPath returns path to metadata index file (repomd.xml)
+func (d *Depot) GetMetaIndexPath() string {
 	if d == nil {
-		return true
+		return ""
 	}
 
-	return fsutil.IsEmptyDir(d.dataDir)
+	return joinPath(d.dataDir, "/repodata/repomd.xml")
 }
 
-// IsCacheValid checks if database files was updated and depot contains outdated
-// metadata info and keeps connection to outdated SQL databases
-func (d *Depot) IsCacheValid() bool {
+// GetDBFilePath returns path to SQLite DB file
+func (d *Depot) GetDBFilePath(dbType string) string {
 	if d == nil {
-		return false
+		return ""
 	}
 
-	return d.CheckCache() == nil
+	if d.isMemCached(dbType) {
+		return joinPath(d.memDir, dbType+".sqlite")
+	}
+
+	if d.dataOptions.NestedCache {
+		return joinPath(d.cacheDir, dbType+".sqlite")
+	}
+
+	return joinPath(d.cacheDir, fmt.Sprintf("%s-%s.sqlite", d.id, dbType))
 }
 
-// CheckCache checks if cache is valid and healthy
-func (d *Depot) CheckCache() error {
-	if d == nil || d.meta == nil {
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// checkCache checks if cache is valid and healthy
+func (d *Depot) checkCache() error {
+	if d.meta == nil {
 		return ErrNilDepot
 	}
 
@@ -799,12 +2063,8 @@ func (d *Depot) CheckCache() error {
 	return nil
 }
 
-// InvalidateCache invalidates repository cache
-func (d *Depot) InvalidateCache() error {
-	if d == nil {
-		return ErrNilDepot
-	}
-
+// invalidateCache invalidates repository cache
+func (d *Depot) invalidateCache() error {
 	d.meta = nil
 
 	for dbName, db := range d.dbs {
@@ -819,15 +2079,17 @@ func (d *Depot) InvalidateCache() error {
 		delete(d.dbs, dbName)
 	}
 
+	if d.memDir != "" {
+		os.RemoveAll(d.memDir)
+		unregisterMemDir(d.memDir)
+		d.memDir = ""
+	}
+
 	return nil
 }
 
-// IsDBCached returns true if SQLite DB is cached
-func (d *Depot) IsDBCached(dbType string) bool {
-	if d == nil {
-		return false
-	}
-
+// isDBCached returns true if SQLite DB is cached
+func (d *Depot) isDBCached(dbType string) bool {
 	dbFile := d.GetDBFilePath(dbType)
 
 	if !fsutil.IsExist(dbFile) {
@@ -853,16 +2115,8 @@ func (d *Depot) IsDBCached(dbType string) bool {
 	return true
 }
 
-// CacheDB caches (saves unpacked DB file) SQLite DB
-func (d *Depot) CacheDB(dbType string) error {
-	if dbType == "" {
-		return fmt.Errorf("Can't cache DB: DB type can't be empty")
-	}
-
-	if d == nil {
-		return fmt.Errorf("Can't cache DB: %w", ErrNilDepot)
-	}
-
+// cacheDB caches (saves unpacked DB file) SQLite DB
+func (d *Depot) cacheDB(dbType string) error {
 	dbInfo := d.meta.Get(dbType + "_db")
 
 	if dbInfo == nil {
@@ -875,6 +2129,14 @@ func (d *Depot) CacheDB(dbType string) error {
 		return fmt.Errorf("Can't cache DB: Can't find file with SQLite database %q", dbType)
 	}
 
+	if !fsutil.IsExist(d.cacheDir) {
+		err := os.MkdirAll(d.cacheDir, 0700)
+
+		if err != nil {
+			return fmt.Errorf("Can't cache DB: Can't create cache directory: %w", err)
+		}
+	}
+
 	cachedDB := d.GetDBFilePath(dbType)
 	err := utils.UnpackDB(dbFile, cachedDB)
 
@@ -882,15 +2144,11 @@ func (d *Depot) CacheDB(dbType string) error {
 		return fmt.Errorf("Can't cache DB: %w", err)
 	}
 
-	return nil
-}
-
-// OpenDB opens SQLite DB
-func (d *Depot) OpenDB(dbType string) error {
-	if d == nil {
-		return ErrNilDepot
-	}
-
+	return nil
+}
+
+// openDB opens SQLite DB
+func (d *Depot) openDB(dbType string) error {
 	dbFile := d.GetDBFilePath(dbType)
 
 	if !fsutil.IsExist(dbFile) {
@@ -915,115 +2173,203 @@ func (d *Depot) OpenDB(dbType string) error {
 	return nil
 }
 
-// GetDB returns connection to SQLite DB
-func (d *Depot) GetDB(dbType string) (*sql.DB, error) {
-	if d == nil {
-		return nil, ErrNilDepot
-	}
+// getDB returns connection to SQLite DB with given type
+func (d *Depot) getDB(dbType string) (*sql.DB, error) {
+	err := d.loadMeta()
 
-	var err error
+	if err != nil {
+		return nil, err
+	}
 
-	if !d.IsCacheValid() {
-		err := d.InvalidateCache()
+	if !d.isDBCached(dbType) {
+		err = d.cacheDB(dbType)
 
 		if err != nil {
-			return nil, fmt.Errorf("Can't invalidate cache: %w", err)
+			return nil, fmt.Errorf("Can't cache DB: %w", err)
 		}
 	}
 
-	if d.meta == nil {
-		d.meta, err = d.GetMetaIndex()
+	if d.dbs[dbType] == nil {
+		err = d.openDB(dbType)
 
 		if err != nil {
-			return nil, fmt.Errorf("Can't read meta index: %w", err)
+			return nil, fmt.Errorf("Can't open DB: %w", err)
 		}
 	}
 
-	if !d.IsDBCached(dbType) {
-		err = d.CacheDB(dbType)
+	return d.dbs[dbType], nil
+}
+
+// loadMeta invalidates outdated cache and reads meta index if required
+func (d *Depot) loadMeta() error {
+	var err error
+
+	if d.checkCache() != nil {
+		err = d.invalidateCache()
 
 		if err != nil {
-			return nil, fmt.Errorf("Can't cache DB: %w", err)
+			return fmt.Errorf("Can't invalidate cache: %w", err)
 		}
 	}
 
-	if d.dbs[dbType] == nil {
-		err = d.OpenDB(dbType)
+	if d.meta == nil {
+		d.meta, err = d.GetMetaIndex()
 
 		if err != nil {
-			return nil, fmt.Errorf("Can't open DB: %w", err)
+			return fmt.Errorf("Can't read meta index: %w", err)
 		}
 	}
 
-	return d.dbs[dbType], nil
-}
+	// If we can't create temporary directory, DBs will be cached on disk
+	if d.dataOptions.MemCache && d.memDir == "" {
+		d.memDir, _ = os.MkdirTemp(d.dataOptions.GetMemCacheDir(), "rep-"+d.id+"-")
 
-// GetMetaIndex reads repository metadata
-func (d *Depot) GetMetaIndex() (*meta.Index, error) {
-	if d == nil {
-		return nil, ErrNilDepot
+		if d.memDir != "" {
+			registerMemDir(d.memDir)
+		}
 	}
 
-	metaFile := d.GetMetaIndexPath()
+	return nil
+}
 
-	if !fsutil.CheckPerms("FRS", metaFile) {
-		return nil, fmt.Errorf("%s must be path to readable, non-empty XML file", metaFile)
+// isMemCached returns true if DB with given type must be kept in memory-backed
+// directory. Large DBs are always cached on disk.
+func (d *Depot) isMemCached(dbType string) bool {
+	if d.memDir == "" || d.meta == nil {
+		return false
 	}
 
-	return meta.Read(metaFile)
+	dbInfo := d.meta.Get(dbType + "_db")
+
+	return dbInfo != nil && dbInfo.OpenSize > 0 &&
+		uint64(dbInfo.OpenSize) <= d.dataOptions.GetMemCacheMaxSize()
 }
 
-// GetMetaIndexPath returns path to metadata index file (repomd.xml)
-func (d *Depot) GetMetaIndexPath() string {
-	if d == nil {
-		return ""
+// isMetaChanged returns true if meta index on disk differs from the one loaded
+// into the depot
+func (d *Depot) isMetaChanged() bool {
+	metaIndex, err := d.GetMetaIndex()
+
+	if err != nil {
+		return false
 	}
 
-	return joinPath(d.dataDir, "/repodata/repomd.xml")
+	return d.meta == nil || d.meta.Revision != metaIndex.Revision
 }
 
-// GetDBFilePath returns path to SQLite DB file
-func (d *Depot) GetDBFilePath(dbType string) string {
-	if d == nil {
-		return ""
+// checkFreeSpace checks if there is enough free space in data directory for
+// given package
+func (d *Depot) checkFreeSpace(rpmFile string) error {
+	if d.dataOptions.MinFreeSpace == 0 {
+		return nil
 	}
 
-	return joinPath(d.dataOptions.CacheDir, fmt.Sprintf("%s-%s.sqlite", d.id, dbType))
-}
+	_, free, err := getDiskUsage(d.dataDir)
 
-// ////////////////////////////////////////////////////////////////////////////////// //
+	if err != nil {
+		return fmt.Errorf("Can't get disk usage info: %w", err)
+	}
+
+	required := uint64(fsutil.GetSize(rpmFile)) + d.dataOptions.MinFreeSpace
+
+	if free < required {
+		return newError(
+			ErrNoFreeSpace, "Not enough free space in repository storage (required: %s, available: %s)",
+			fmtutil.PrettySize(required), fmtutil.PrettySize(free),
+		)
+	}
+
+	return nil
+}
 
-// copyFile copies package into package directory and change permissions for it
-func (d *Depot) copyFile(rpmFile, packageDir string) error {
+// copyFile copies (or hard-links) package into package directory and change
+// permissions for it
+func (d *Depot) copyFile(rpmFile, packageDir, srcHash string, owner *objectOwner) error {
 	if d == nil {
 		return fmt.Errorf("Can't change package attributes: %w", ErrNilDepot)
 	}
 
-	err := fsutil.CopyFile(rpmFile, packageDir, 0600)
+	var isLinked bool
+
+	targetFile := joinPath(packageDir, path.Base(rpmFile))
+	tmpFile := targetFile + ".tmp"
+
+	os.Remove(tmpFile)
+
+	// Link shares inode with source file, so we can link only files which
+	// already have required attributes. Fallback to copying on cross-device,
+	// attributes mismatch or any other linking error.
+	if d.dataOptions.HardLink && isSameDevice(rpmFile, packageDir) &&
+		hasObjectAttrs(rpmFile, d.dataOptions, owner) {
+		isLinked = linkFile(rpmFile, tmpFile) == nil
+	}
+
+	if isLinked {
+		// Link shares data with source file, so instead of comparing checksums
+		// we make sure that it points to the same inode
+		if srcHash != "" && !isSameFile(rpmFile, tmpFile) {
+			os.Remove(tmpFile)
+
+			return newError(
+				ErrHashMismatch, "Linked package %s doesn't point to source file",
+				path.Base(targetFile),
+			)
+		}
+
+		err := os.Rename(tmpFile, targetFile)
+
+		// Rename does nothing if target is already a link to the same file
+		os.Remove(tmpFile)
+
+		return err
+	}
+
+	err := fsutil.CopyFile(rpmFile, tmpFile, 0600)
 
 	if err != nil {
+		os.Remove(tmpFile)
 		return err
 	}
 
-	targetFile := joinPath(packageDir, path.Base(rpmFile))
-	err = updateObjectAttrs(targetFile, d.dataOptions, false)
+	if srcHash != "" {
+		dstHash := fileHashFunc(tmpFile)
+
+		if dstHash != srcHash {
+			os.Remove(tmpFile)
+
+			return newError(
+				ErrHashMismatch, "Checksum of copied package %s (%s) doesn't match source checksum (%s)",
+				path.Base(targetFile), strutil.Head(dstHash, 7), strutil.Head(srcHash, 7),
+			)
+		}
+	}
+
+	err = setObjectAttrs(tmpFile, d.dataOptions, owner, false)
 
 	if err != nil {
+		os.Remove(tmpFile)
 		return fmt.Errorf("Can't change package attributes: %w", err)
 	}
 
+	err = os.Rename(tmpFile, targetFile)
+
+	if err != nil {
+		os.Remove(tmpFile)
+		return err
+	}
+
 	return nil
 }
 
 // makePackageDir creates directory if required and returns path to directory for packages
 // if split-files option is enabled
-func (d *Depot) makePackageDir(rpmFile string) (string, error) {
+func (d *Depot) makePackageDir(rpmFile string, owner *objectOwner) (string, error) {
 	if d == nil {
 		return "", fmt.Errorf("Can't create directory for package: %w", ErrNilDepot)
 	}
 
 	rpmFileName := path.Base(rpmFile)
-	dirName := strutil.Head(rpmFileName, 1)
+	dirName := strutil.Head(rpmFileName, d.dataOptions.GetSplitDepth())
 
 	if !DirNameValidatorRegex.MatchString(dirName) {
 		return "", fmt.Errorf("Can't create directory for package: Can't use name %q for directory", dirName)
@@ -1035,13 +2381,13 @@ func (d *Depot) makePackageDir(rpmFile string) (string, error) {
 		return packageDir, nil
 	}
 
-	err := mkdirFunc(packageDir, 0700)
+	err := mkdirFunc(packageDir, d.dataOptions.GetDirPerms())
 
 	if err != nil {
 		return "", err
 	}
 
-	err = updateObjectAttrs(packageDir, d.dataOptions, true)
+	err = setObjectAttrs(packageDir, d.dataOptions, owner, true)
 
 	if err != nil {
 		return "", fmt.Errorf("Can't change package directory attributes: %w", err)
@@ -1050,25 +2396,124 @@ func (d *Depot) makePackageDir(rpmFile string) (string, error) {
 	return packageDir, err
 }
 
-// removePackageDir removes package
+// removePackageDir removes empty package directory and all its empty parent
+// directories inside depot data directory
 func (d *Depot) removePackageDir(rpmFile string) error {
 	if d == nil {
 		return ErrNilDepot
 	}
 
-	rpmFileDir := path.Dir(rpmFile)
+	dir := path.Dir(d.GetPackagePath(rpmFile))
 
-	if rpmFileDir == "." || rpmFileDir == "/" {
-		return nil
+	for strings.HasPrefix(dir, d.dataDir+"/") && fsutil.IsEmptyDir(dir) {
+		err := removeFunc(dir)
+
+		if err != nil {
+			return err
+		}
+
+		dir = path.Dir(dir)
 	}
 
-	rpmFileDirFull := joinPath(d.dataDir, rpmFileDir)
+	return nil
+}
 
-	if !fsutil.IsEmptyDir(rpmFileDirFull) {
-		return nil
+// getReindexMarkPath returns path to file with the date of the last reindex
+func (d *Depot) getReindexMarkPath() string {
+	if d == nil {
+		return ""
+	}
+
+	if d.dataOptions.NestedCache {
+		return joinPath(d.cacheDir, "reindex.mark")
+	}
+
+	return joinPath(d.cacheDir, d.id+"-reindex.mark")
+}
+
+// markReindexed updates the date of the last reindex
+func (d *Depot) markReindexed() {
+	// Mark is only used for skipping reindex, so we don't care about errors here.
+	// We rewrite the file instead of changing times because mtime must be set by
+	// the same (coarse) clock used for all other objects in the file system.
+	os.MkdirAll(d.cacheDir, 0755)
+	os.WriteFile(d.getReindexMarkPath(), []byte(d.getIndexFingerprint()), 0644)
+}
+
+// getIndexFingerprint returns fingerprint of index generation options and
+// groupfile. Index generated with different options is treated as outdated.
+func (d *Depot) getIndexFingerprint() string {
+	fingerprint := strings.Join(d.indexOptions.ToArgs(), " ")
+
+	if d.indexOptions.GroupFile == "" {
+		return fingerprint
+	}
+
+	// Groupfile can be modified in place, so its path is not enough
+	groupMTime, _ := fsutil.GetMTime(d.indexOptions.GroupFile)
+
+	return fmt.Sprintf(
+		"%s groupfile:%d:%d", fingerprint,
+		groupMTime.UnixNano(), fsutil.GetSize(d.indexOptions.GroupFile),
+	)
+}
+
+// findPackageFile returns path to package file with given name and true if
+// file exists. If there is no file in the expected location, the other possible
+// location (flat directory or split subdirectory) is checked, so files placed
+// manually or before changing files splitting preferences can be found too.
+func (d *Depot) findPackageFile(rpmFileName string) (string, bool) {
+	filePath := joinPath(d.getPackageDir(rpmFileName), rpmFileName)
+
+	if fsutil.IsExist(filePath) {
+		return filePath, true
+	}
+
+	altFilePath := joinPath(d.dataDir, rpmFileName)
+
+	if !d.dataOptions.SplitFiles {
+		altFilePath = joinPath(
+			d.dataDir, strutil.Head(rpmFileName, d.dataOptions.GetSplitDepth()), rpmFileName,
+		)
+	}
+
+	if fsutil.IsExist(altFilePath) {
+		return altFilePath, true
+	}
+
+	return filePath, false
+}
+
+// findOrphanFiles returns paths (relative to depot directory) of package files
+// which are not in the given set of indexed files (full paths)
+func (d *Depot) findOrphanFiles(indexedFiles map[string]bool) []string {
+	var result []string
+
+	for _, file := range d.listPackageFiles() {
+		if !indexedFiles[joinPath(d.dataDir, file)] {
+			result = append(result, file)
+		}
 	}
 
-	return removeFunc(rpmFileDirFull)
+	return result
+}
+
+// listPackageFiles returns sorted slice with paths (relative to depot directory)
+// of all package files in depot
+func (d *Depot) listPackageFiles() []string {
+	files := fsutil.ListAllFiles(d.dataDir, true, fsutil.ListingFilter{
+		MatchPatterns: []string{"*.rpm"},
+	})
+
+	slices.Sort(files)
+
+	return files
+}
+
+// getPackageRelPath returns path to package file relative to repository directory
+// (e.g. x86_64/t/test-package-1.0.0-0.el7.x86_64.rpm) regardless of used layout
+func (d *Depot) getPackageRelPath(arch, filePath string) string {
+	return joinPath(data.SupportedArchs[arch].Dir, strings.TrimPrefix(filePath, d.dataDir+"/"))
 }
 
 // getPackageDir returns full path to directory for given rpm file
@@ -1078,7 +2523,7 @@ func (d *Depot) getPackageDir(rpmFileName string) string {
 	}
 
 	if d.dataOptions.SplitFiles {
-		dirName := strutil.Head(rpmFileName, 1)
+		dirName := strutil.Head(rpmFileName, d.dataOptions.GetSplitDepth())
 		return joinPath(d.dataDir, dirName)
 	}
 
@@ -1089,45 +2534,187 @@ func (d *Depot) getPackageDir(rpmFileName string) string {
 
 // updateObjectAttrs update object (directory or file) attributes
 func updateObjectAttrs(path string, options *Options, isDir bool) error {
+	return setObjectAttrs(path, options, nil, isDir)
+}
+
+// setObjectAttrs sets owner and permissions of object (directory or file). If
+// owner is nil, it will be resolved from options.
+func setObjectAttrs(path string, options *Options, owner *objectOwner, isDir bool) error {
+	var err error
 	var perms os.FileMode
 
-	uid, gid := -1, -1
+	if owner == nil {
+		owner, err = getObjectOwner(options)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	if owner.UID != -1 || owner.GID != -1 {
+		err = retryOnTransientError(options, func() error {
+			return chownFunc(path, owner.UID, owner.GID)
+		})
+
+		if err != nil {
+			return err
+		}
+	}
+
+	if isDir {
+		perms = options.GetDirPerms()
+	} else {
+		perms = options.GetFilePerms()
+	}
+
+	return retryOnTransientError(options, func() error {
+		return chmodFunc(path, perms)
+	})
+}
+
+// retryOnTransientError runs given function and retries it with backoff if it
+// returns transient error
+func retryOnTransientError(options *Options, fn func() error) error {
+	delay := options.GetAttrsRetryDelay()
+
+	for i := 0; ; i++ {
+		err := fn()
+
+		if err == nil || i >= options.AttrsRetries || !isTransientError(err) {
+			return err
+		}
+
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+// isTransientError returns true if given error is temporary and operation
+// can be retried
+func isTransientError(err error) bool {
+	return errors.Is(err, syscall.EIO) || errors.Is(err, syscall.EAGAIN) ||
+		errors.Is(err, syscall.EINTR) || errors.Is(err, syscall.EBUSY) ||
+		errors.Is(err, syscall.ETIMEDOUT)
+}
+
+// validateAuxDataName validates name of auxiliary data
+func validateAuxDataName(name string) error {
+	switch {
+	case name == "":
+		return fmt.Errorf("Name can't be empty")
+	case strings.ContainsAny(name, "/\\") || strings.HasPrefix(name, "."):
+		return fmt.Errorf("Name %q contains invalid symbols", name)
+	}
+
+	return nil
+}
+
+// getObjectOwner returns UID and GID of user and group defined in options
+func getObjectOwner(options *Options) (*objectOwner, error) {
+	owner := &objectOwner{-1, -1}
 
 	if options.User != "" {
 		newUser, err := system.LookupUser(options.User)
 
 		if err != nil {
-			return fmt.Errorf("Can't get UID for user %q", options.User)
-		} else {
-			uid = newUser.UID
+			return nil, fmt.Errorf("Can't get UID for user %q", options.User)
 		}
+
+		owner.UID = newUser.UID
 	}
 
 	if options.Group != "" {
 		newGroup, err := system.LookupGroup(options.Group)
 
 		if err != nil {
-			return fmt.Errorf("Can't get GID for group %q", options.Group)
-		} else {
-			gid = newGroup.GID
+			return nil, fmt.Errorf("Can't get GID for group %q", options.Group)
 		}
+
+		owner.GID = newGroup.GID
+	}
+
+	return owner, nil
+}
+
+// getDiskUsage returns total and available space of filesystem with given directory
+func getDiskUsage(dir string) (uint64, uint64, error) {
+	var stat syscall.Statfs_t
+
+	err := syscall.Statfs(dir, &stat)
+
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return stat.Blocks * uint64(stat.Bsize), stat.Bavail * uint64(stat.Bsize), nil
+}
+
+// linkFile creates hard link to given file, replacing existing target file
+func linkFile(file, target string) error {
+	tmpTarget := target + ".link"
+
+	os.Remove(tmpTarget)
+
+	err := os.Link(file, tmpTarget)
+
+	if err != nil {
+		return err
+	}
+
+	err = os.Rename(tmpTarget, target)
+
+	if err != nil {
+		os.Remove(tmpTarget)
+		return err
+	}
+
+	return nil
+}
+
+// hasObjectAttrs returns true if file already has owner and permissions defined
+// in options
+func hasObjectAttrs(file string, options *Options, owner *objectOwner) bool {
+	info, err := os.Stat(file)
+
+	if err != nil || info.Mode().Perm() != options.GetFilePerms() {
+		return false
 	}
 
-	if uid != -1 || gid != -1 {
-		err := chownFunc(path, uid, gid)
+	if owner == nil {
+		owner, err = getObjectOwner(options)
 
 		if err != nil {
-			return err
+			return false
 		}
 	}
 
-	if isDir {
-		perms = options.GetDirPerms()
-	} else {
-		perms = options.GetFilePerms()
+	stat, ok := info.Sys().(*syscall.Stat_t)
+
+	return ok && (owner.UID == -1 || int(stat.Uid) == owner.UID) &&
+		(owner.GID == -1 || int(stat.Gid) == owner.GID)
+}
+
+// isSameDevice returns true if both objects are placed on the same device
+func isSameDevice(obj1, obj2 string) bool {
+	info1, err1 := os.Stat(obj1)
+	info2, err2 := os.Stat(obj2)
+
+	if err1 != nil || err2 != nil {
+		return false
 	}
 
-	return chmodFunc(path, perms)
+	stat1, ok1 := info1.Sys().(*syscall.Stat_t)
+	stat2, ok2 := info2.Sys().(*syscall.Stat_t)
+
+	return ok1 && ok2 && stat1.Dev == stat2.Dev
+}
+
+// isSameFile returns true if both paths point to the same file (inode)
+func isSameFile(file1, file2 string) bool {
+	info1, err1 := os.Stat(file1)
+	info2, err2 := os.Stat(file2)
+
+	return err1 == nil && err2 == nil && os.SameFile(info1, info2)
 }
 
 // checkDataDir checks repository directory permissions
@@ -1139,6 +2726,24 @@ func checkDataDir(dir string) error {
 	return nil
 }
 
+// checkLayout checks depot directories layout template
+func checkLayout(layout string) error {
+	if layout == "" {
+		return nil
+	}
+
+	switch {
+	case !strings.Contains(layout, "{repo}"):
+		return fmt.Errorf("Layout %q doesn't contain {repo} placeholder", layout)
+	case !strings.Contains(layout, "{arch}"):
+		return fmt.Errorf("Layout %q doesn't contain {arch} placeholder", layout)
+	case strings.HasPrefix(layout, "/"), slices.Contains(strings.Split(layout, "/"), ".."):
+		return fmt.Errorf("Layout %q must be relative to data directory", layout)
+	}
+
+	return nil
+}
+
 // checkCacheDir checks cache directory permissions
 func checkCacheDir(dir string) error {
 	if dir == "" {
@@ -1148,7 +2753,73 @@ func checkCacheDir(dir string) error {
 	return fsutil.ValidatePerms("DRWX", dir)
 }
 
+// newError creates new storage error with given kind
+func newError(kind error, format string, args ...any) *Error {
+	return &Error{Kind: kind, Desc: fmt.Sprintf(format, args...)}
+}
+
+// groupFilesByName groups given file paths by file name
+func groupFilesByName(files []string) map[string][]string {
+	result := make(map[string][]string)
+
+	for _, file := range files {
+		fileName := path.Base(file)
+		result[fileName] = append(result[fileName], file)
+	}
+
+	return result
+}
+
+// formatLayout returns path to depot directory relative to data directory for
+// given layout template
+func formatLayout(layout, repo, arch string) string {
+	return strings.NewReplacer(
+		"{repo}", repo,
+		"{arch}", data.SupportedArchs[arch].Dir,
+	).Replace(layout)
+}
+
+// getLayoutDirs returns paths to all directories which must be created for
+// depot with given repository and arch
+func getLayoutDirs(o *Options, repo, arch string) []string {
+	var result []string
+
+	dir := o.DataDir
+
+	for _, dirName := range strings.Split(formatLayout(o.GetLayout(), repo, arch), "/") {
+		if dirName == "" {
+			continue
+		}
+
+		dir = joinPath(dir, dirName)
+		result = append(result, dir)
+	}
+
+	return result
+}
+
+// isNoarchPackageFile returns true if given file is noarch package
+func isNoarchPackageFile(rpmFileName string) bool {
+	return strings.HasSuffix(rpmFileName, "."+data.ARCH_NOARCH+".rpm")
+}
+
 // joinPath joins path elements into one string
 func joinPath(objs ...string) string {
 	return path.Clean(path.Join(objs...))
 }
+
+// registerMemDir adds memory-backed directory to the set of directories which
+// must be removed on exit
+func registerMemDir(dir string) {
+	memDirsLock.Lock()
+	memDirs[dir] = true
+	memDirsLock.Unlock()
+}
+
+// unregisterMemDir removes memory-backed directory from the set of directories
+// which must be removed on exit
+func unregisterMemDir(dir string) {
+	memDirsLock.Lock()
+	delete(memDirs, dir)
+	memDirsLock.Unlock()
+}
@@ -8,12 +8,20 @@ package fs
 // ////////////////////////////////////////////////////////////////////////////////// //
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"math"
 	"os"
+	"path"
+	"strings"
+	"sync"
+	"syscall"
 	"testing"
 	"time"
 
 	"github.com/essentialkaos/ek/v13/fsutil"
+	"github.com/essentialkaos/ek/v13/hash"
 
 	"github.com/essentialkaos/rep/v3/repo/data"
 	"github.com/essentialkaos/rep/v3/repo/index"
@@ -101,15 +109,25 @@ func (s *StorageSuite) TestNewStorage(c *C) {
 func (s *StorageSuite) TestNewStorageErrors(c *C) {
 	dopts := genStorageOptions(c, "")
 
-	_, err := NewStorage(&Options{"", dopts.CacheDir, false, "", "", 0, 0}, index.DefaultOptions)
+	_, err := NewStorage(&Options{DataDir: "", CacheDir: dopts.CacheDir}, index.DefaultOptions)
 	c.Assert(err, ErrorMatches, `Can't create storage: Path to repository directory can't be empty`)
 
-	_, err = NewStorage(&Options{dopts.DataDir, "", false, "", "", 0, 0}, index.DefaultOptions)
+	_, err = NewStorage(&Options{DataDir: dopts.DataDir, CacheDir: ""}, index.DefaultOptions)
 	c.Assert(err, ErrorMatches, `Can't create storage: Path to cache directory can't be empty`)
 
-	_, err = NewStorage(&Options{dopts.DataDir, "/unknown", false, "", "", 0, 0}, index.DefaultOptions)
+	_, err = NewStorage(&Options{DataDir: dopts.DataDir, CacheDir: "/unknown"}, index.DefaultOptions)
 	c.Assert(err, ErrorMatches, `Can't create storage: Directory /unknown doesn't exist or not accessible`)
 
+	_, err = NewStorage(&Options{DataDir: dopts.DataDir, CacheDir: dopts.CacheDir, SplitDepth: 10}, index.DefaultOptions)
+	c.Assert(err, ErrorMatches, `Can't create storage: Split depth must be in range 0-4`)
+
+	_, err = NewStorage(&Options{DataDir: dopts.DataDir, CacheDir: dopts.CacheDir, Layout: "{arch}"}, index.DefaultOptions)
+	c.Assert(err, ErrorMatches, `Can't create storage: Layout "{arch}" doesn't contain {repo} placeholder`)
+	_, err = NewStorage(&Options{DataDir: dopts.DataDir, CacheDir: dopts.CacheDir, Layout: "{repo}"}, index.DefaultOptions)
+	c.Assert(err, ErrorMatches, `Can't create storage: Layout "{repo}" doesn't contain {arch} placeholder`)
+	_, err = NewStorage(&Options{DataDir: dopts.DataDir, CacheDir: dopts.CacheDir, Layout: "../{repo}/{arch}"}, index.DefaultOptions)
+	c.Assert(err, ErrorMatches, `Can't create storage: Layout "../{repo}/{arch}" must be relative to data directory`)
+
 	_, err = NewStorage(dopts, nil)
 	c.Assert(err, ErrorMatches, `Can't create storage: Index options cannot be nil`)
 
@@ -216,6 +234,33 @@ func (s *StorageSuite) TestStorageInitialize(c *C) {
 	mkdirFunc = os.Mkdir
 }
 
+func (s *StorageSuite) TestInitializeDirPerms(c *C) {
+	opts := genStorageOptions(c, "")
+	opts.DirPerms = 0770
+
+	fs, err := NewStorage(opts, index.DefaultOptions)
+
+	c.Assert(fs, NotNil)
+	c.Assert(err, IsNil)
+
+	var dirModes []os.FileMode
+
+	mkdirFunc = func(name string, mode os.FileMode) error {
+		dirModes = append(dirModes, mode)
+		return os.Mkdir(name, mode)
+	}
+
+	err = fs.Initialize(defRepos, defArchs)
+	mkdirFunc = os.Mkdir
+
+	c.Assert(err, IsNil)
+	c.Assert(dirModes, Not(HasLen), 0)
+
+	for _, mode := range dirModes {
+		c.Assert(mode, Equals, os.FileMode(0770))
+	}
+}
+
 func (s *StorageSuite) TestAddPackage(c *C) {
 	chownFunc = func(name string, uid, gid int) error { return nil }
 
@@ -274,24 +319,283 @@ func (s *StorageSuite) TestAddPackage(c *C) {
 
 	c.Assert(fs.AddPackage(data.REPO_RELEASE, "unknown-package-1.0.0-0.el7.noarch.rpm"), ErrorMatches, `Can't add package to storage: File unknown-package-1.0.0-0.el7.noarch.rpm doesn't exist or not accessible`)
 
-	_, err = dp.makePackageDir("пакет.x86_64.rpm")
+	_, err = dp.makePackageDir("пакет.x86_64.rpm", nil)
 	c.Assert(err, ErrorMatches, `Can't create directory for package: Can't use name "п" for directory`)
 
 	chownFunc = func(name string, uid, gid int) error { return fmt.Errorf("ERROR") }
 	chmodFunc = func(name string, mode os.FileMode) error { return fmt.Errorf("ERROR") }
 
 	opts.SplitFiles = true
-	_, err = dp.makePackageDir("abcd-package.rpm")
+	_, err = dp.makePackageDir("abcd-package.rpm", nil)
 	c.Assert(err, ErrorMatches, `.*: ERROR`)
 	opts.SplitFiles = false
 
-	err = dp.copyFile("../../../testdata/test-package-1.0.0-0.el7.x86_64.rpm", dp.dataDir)
+	err = dp.copyFile("../../../testdata/test-package-1.0.0-0.el7.x86_64.rpm", dp.dataDir, "", nil)
 	c.Assert(err, ErrorMatches, `.*: ERROR`)
 
 	chownFunc = os.Chown
 	chmodFunc = os.Chmod
 }
 
+func (s *StorageSuite) TestSyncNoarchPackages(c *C) {
+	opts := genStorageOptions(c, "")
+	opts.SplitFiles = true
+
+	fs, err := NewStorage(opts, index.DefaultOptions)
+
+	c.Assert(fs, NotNil)
+	c.Assert(err, IsNil)
+
+	_, err = fs.SyncNoarchPackages("")
+	c.Assert(err, ErrorMatches, `Can't sync noarch packages: Repository name can't be empty`)
+	_, err = fs.SyncNoarchPackages(data.REPO_TESTING)
+	c.Assert(err, ErrorMatches, `Can't sync noarch packages: Repository storage is not initialized`)
+
+	c.Assert(fs.Initialize(defRepos, []string{data.ARCH_X64}), IsNil)
+
+	_, err = fs.SyncNoarchPackages("unknown")
+	c.Assert(err, ErrorMatches, `Can't sync noarch packages: Repository "unknown" doesn't exist`)
+
+	c.Assert(fs.AddPackage(data.REPO_TESTING, "../../../testdata/test-package-1.0.0-0.el7.x86_64.rpm"), IsNil)
+	c.Assert(fs.AddPackage(data.REPO_TESTING, "../../../testdata/git-all-2.27.0-0.el7.noarch.rpm"), IsNil)
+
+	added, err := fs.SyncNoarchPackages(data.REPO_TESTING)
+
+	c.Assert(err, IsNil)
+	c.Assert(added, HasLen, 0)
+
+	// Add new architecture to existing repository
+	c.Assert(fs.Initialize(defRepos, []string{data.ARCH_X64, data.ARCH_AARCH64}), IsNil)
+
+	c.Assert(fs.HasPackage(data.REPO_TESTING, data.ARCH_AARCH64, "git-all-2.27.0-0.el7.noarch.rpm"), Equals, false)
+
+	added, err = fs.SyncNoarchPackages(data.REPO_TESTING)
+
+	c.Assert(err, IsNil)
+	c.Assert(added, DeepEquals, []string{"aarch64/g/git-all-2.27.0-0.el7.noarch.rpm"})
+	c.Assert(fs.HasPackage(data.REPO_TESTING, data.ARCH_AARCH64, "git-all-2.27.0-0.el7.noarch.rpm"), Equals, true)
+	c.Assert(fs.HasPackage(data.REPO_TESTING, data.ARCH_AARCH64, "test-package-1.0.0-0.el7.x86_64.rpm"), Equals, false)
+
+	added, err = fs.SyncNoarchPackages(data.REPO_TESTING)
+
+	c.Assert(err, IsNil)
+	c.Assert(added, HasLen, 0)
+}
+
+func (s *StorageSuite) TestAddPackages(c *C) {
+	opts := genStorageOptions(c, "")
+	opts.SplitFiles = true
+
+	fs, err := NewStorage(opts, index.DefaultOptions)
+
+	c.Assert(fs, NotNil)
+	c.Assert(err, IsNil)
+
+	err = fs.Initialize(defRepos, defArchs)
+
+	c.Assert(err, IsNil)
+
+	_, err = fs.AddPackages("", []string{"/path/to/file"})
+	c.Assert(err, ErrorMatches, `Can't add packages to storage: Repository name can't be empty`)
+	_, err = fs.AddPackages(data.REPO_TESTING, nil)
+	c.Assert(err, ErrorMatches, `Can't add packages to storage: Path to file can't be empty`)
+	_, err = fs.AddPackages("unknown", []string{"/path/to/file"})
+	c.Assert(err, ErrorMatches, `Can't add packages to storage: Repository "unknown" doesn't exist`)
+
+	opts.User = "_unknown_"
+	_, err = fs.AddPackages(data.REPO_TESTING, []string{"/path/to/file"})
+	c.Assert(err, ErrorMatches, `Can't add packages to storage: Can't get UID for user "_unknown_"`)
+	opts.User = ""
+
+	added, err := fs.AddPackages(data.REPO_TESTING, []string{
+		"../../../testdata/test-package-1.0.0-0.el7.x86_64.rpm",
+		"/pkgs/unknown-1.0.0-0.el7.x86_64.rpm",
+		"../../../testdata/git-all-2.27.0-0.el7.noarch.rpm",
+	})
+
+	c.Assert(err, ErrorMatches, `unknown-1.0.0-0.el7.x86_64.rpm: Can't add package to storage: File /pkgs/unknown-1.0.0-0.el7.x86_64.rpm doesn't exist or not accessible`)
+	c.Assert(added, DeepEquals, []string{
+		"x86_64/t/test-package-1.0.0-0.el7.x86_64.rpm",
+		"x86_64/g/git-all-2.27.0-0.el7.noarch.rpm",
+	})
+}
+
+func (s *StorageSuite) TestDiskUsage(c *C) {
+	opts := genStorageOptions(c, "")
+
+	fs, err := NewStorage(opts, index.DefaultOptions)
+
+	c.Assert(fs, NotNil)
+	c.Assert(err, IsNil)
+
+	c.Assert(fs.Initialize(defRepos, defArchs), IsNil)
+
+	total, free, err := fs.GetDiskUsage()
+
+	c.Assert(err, IsNil)
+	c.Assert(total, Not(Equals), uint64(0))
+	c.Assert(free <= total, Equals, true)
+
+	opts.MinFreeSpace = math.MaxUint64 / 2
+
+	err = fs.AddPackage(data.REPO_RELEASE, "../../../testdata/test-package-1.0.0-0.el7.x86_64.rpm")
+
+	c.Assert(err, ErrorMatches, `Can't add package to storage depot: Not enough free space in repository storage \(required: .*, available: .*\)`)
+	c.Assert(errors.Is(err, ErrNoFreeSpace), Equals, true)
+
+	opts.MinFreeSpace = 1
+
+	c.Assert(fs.AddPackage(data.REPO_RELEASE, "../../../testdata/test-package-1.0.0-0.el7.x86_64.rpm"), IsNil)
+
+	_, _, err = getDiskUsage("/unknown")
+	c.Assert(err, NotNil)
+
+	var nilStorage *Storage
+
+	_, _, err = nilStorage.GetDiskUsage()
+	c.Assert(err, ErrorMatches, "Can't get disk usage info: Repository storage is not initialized")
+
+	opts.DataDir = "/unknown"
+
+	_, _, err = fs.GetDiskUsage()
+	c.Assert(err, ErrorMatches, "Can't get disk usage info: .*")
+}
+
+func (s *StorageSuite) TestSplitDepth(c *C) {
+	opts := genStorageOptions(c, "")
+	opts.SplitFiles = true
+	opts.SplitDepth = 2
+
+	fs, err := NewStorage(opts, index.DefaultOptions)
+
+	c.Assert(fs, NotNil)
+	c.Assert(err, IsNil)
+
+	c.Assert(fs.Initialize(defRepos, defArchs), IsNil)
+
+	dp := fs.GetDepot(data.REPO_RELEASE, data.ARCH_X64)
+
+	c.Assert(fs.AddPackage(data.REPO_RELEASE, "../../../testdata/test-package-1.0.0-0.el7.x86_64.rpm"), IsNil)
+	c.Assert(fsutil.IsExist(dp.dataDir+"/te/test-package-1.0.0-0.el7.x86_64.rpm"), Equals, true)
+	c.Assert(dp.HasPackage("test-package-1.0.0-0.el7.x86_64.rpm"), Equals, true)
+	c.Assert(dp.GetPackagePath("test-package-1.0.0-0.el7.x86_64.rpm"), Equals, dp.dataDir+"/te/test-package-1.0.0-0.el7.x86_64.rpm")
+
+	c.Assert(fs.RemovePackage(data.REPO_RELEASE, data.ARCH_X64, "te/test-package-1.0.0-0.el7.x86_64.rpm"), IsNil)
+	c.Assert(fsutil.IsExist(dp.dataDir+"/te"), Equals, false)
+	c.Assert(fsutil.IsExist(dp.dataDir), Equals, true)
+
+	opts.SplitDepth = 0
+
+	c.Assert(opts.GetSplitDepth(), Equals, SPLIT_DEPTH_DEFAULT)
+	c.Assert(dp.GetPackagePath("test-package-1.0.0-0.el7.x86_64.rpm"), Equals, dp.dataDir+"/t/test-package-1.0.0-0.el7.x86_64.rpm")
+}
+
+func (s *StorageSuite) TestLayout(c *C) {
+	opts := genStorageOptions(c, "")
+
+	c.Assert(opts.GetLayout(), Equals, LAYOUT_NESTED)
+	c.Assert(opts.GetDepotDir(data.REPO_RELEASE, data.ARCH_X64), Equals, opts.DataDir+"/release/x86_64")
+
+	opts.Layout = LAYOUT_FLAT
+	c.Assert(opts.GetDepotDir(data.REPO_RELEASE, data.ARCH_SRC), Equals, opts.DataDir+"/release-SRPMS")
+
+	opts.Layout = LAYOUT_ARCH_FIRST
+	opts.SplitFiles = true
+
+	fs, err := NewStorage(opts, index.DefaultOptions)
+
+	c.Assert(fs, NotNil)
+	c.Assert(err, IsNil)
+
+	c.Assert(fs.Initialize(defRepos, []string{data.ARCH_X64}), IsNil)
+
+	c.Assert(fsutil.IsDir(opts.DataDir+"/x86_64/release"), Equals, true)
+	c.Assert(fsutil.IsDir(opts.DataDir+"/x86_64/testing"), Equals, true)
+	c.Assert(fsutil.IsExist(opts.DataDir+"/release"), Equals, false)
+
+	c.Assert(fs.IsInitialized(), Equals, true)
+	c.Assert(fs.HasRepo(data.REPO_RELEASE), Equals, true)
+	c.Assert(fs.HasRepo("unknown"), Equals, false)
+	c.Assert(fs.HasArch(data.REPO_RELEASE, data.ARCH_X64), Equals, true)
+	c.Assert(fs.HasArch(data.REPO_RELEASE, data.ARCH_NOARCH), Equals, true)
+	c.Assert(fs.HasArch(data.REPO_RELEASE, data.ARCH_AARCH64), Equals, false)
+
+	added, err := fs.AddPackages(data.REPO_RELEASE, []string{"../../../testdata/test-package-1.0.0-0.el7.x86_64.rpm"})
+
+	c.Assert(err, IsNil)
+	c.Assert(added, DeepEquals, []string{"x86_64/t/test-package-1.0.0-0.el7.x86_64.rpm"})
+	c.Assert(fsutil.IsExist(opts.DataDir+"/x86_64/release/t/test-package-1.0.0-0.el7.x86_64.rpm"), Equals, true)
+	c.Assert(fs.HasPackage(data.REPO_RELEASE, data.ARCH_X64, "test-package-1.0.0-0.el7.x86_64.rpm"), Equals, true)
+	c.Assert(fs.HasPackage(data.REPO_TESTING, data.ARCH_X64, "test-package-1.0.0-0.el7.x86_64.rpm"), Equals, false)
+}
+
+func (s *StorageSuite) TestHardLink(c *C) {
+	opts := genStorageOptions(c, "")
+	opts.HardLink = true
+
+	fs, err := NewStorage(opts, index.DefaultOptions)
+
+	c.Assert(fs, NotNil)
+	c.Assert(err, IsNil)
+
+	c.Assert(fs.Initialize(defRepos, defArchs), IsNil)
+
+	srcFile := c.MkDir() + "/test-package-1.0.0-0.el7.x86_64.rpm"
+	fsutil.CopyFile("../../../testdata/test-package-1.0.0-0.el7.x86_64.rpm", srcFile, 0644)
+
+	c.Assert(fs.AddPackage(data.REPO_RELEASE, srcFile), IsNil)
+
+	targetFile := opts.DataDir + "/release/x86_64/test-package-1.0.0-0.el7.x86_64.rpm"
+
+	srcInfo, err := os.Stat(srcFile)
+	c.Assert(err, IsNil)
+	targetInfo, err := os.Stat(targetFile)
+	c.Assert(err, IsNil)
+
+	c.Assert(os.SameFile(srcInfo, targetInfo), Equals, true)
+
+	// Replace already linked package
+	c.Assert(fs.AddPackage(data.REPO_RELEASE, srcFile), IsNil)
+	c.Assert(fsutil.IsExist(targetFile+".link"), Equals, false)
+	c.Assert(fsutil.IsExist(targetFile+".tmp"), Equals, false)
+
+	opts.HardLink = false
+
+	c.Assert(fs.AddPackage(data.REPO_TESTING, srcFile), IsNil)
+
+	targetInfo, err = os.Stat(opts.DataDir + "/testing/x86_64/test-package-1.0.0-0.el7.x86_64.rpm")
+	c.Assert(err, IsNil)
+	c.Assert(os.SameFile(srcInfo, targetInfo), Equals, false)
+
+	opts.HardLink = true
+
+	// Package with different permissions must be copied, source file must
+	// stay untouched
+	c.Assert(os.Chmod(srcFile, 0600), IsNil)
+	c.Assert(fs.AddPackage(data.REPO_TESTING, srcFile), IsNil)
+
+	srcInfo, err = os.Stat(srcFile)
+	c.Assert(err, IsNil)
+	targetInfo, err = os.Stat(opts.DataDir + "/testing/x86_64/test-package-1.0.0-0.el7.x86_64.rpm")
+	c.Assert(err, IsNil)
+
+	c.Assert(os.SameFile(srcInfo, targetInfo), Equals, false)
+	c.Assert(srcInfo.Mode().Perm(), Equals, os.FileMode(0600))
+	c.Assert(targetInfo.Mode().Perm(), Equals, os.FileMode(0644))
+
+	// Linked copy with verification
+	c.Assert(os.Chmod(srcFile, 0644), IsNil)
+
+	dp := fs.GetDepot(data.REPO_TESTING, data.ARCH_X64)
+	c.Assert(dp.copyFile(srcFile, dp.dataDir, fileHashFunc(srcFile), nil), IsNil)
+	c.Assert(isSameFile(srcFile, opts.DataDir+"/testing/x86_64/test-package-1.0.0-0.el7.x86_64.rpm"), Equals, true)
+
+	c.Assert(hasObjectAttrs("/unknown", opts, nil), Equals, false)
+	c.Assert(isSameDevice(srcFile, "/unknown"), Equals, false)
+	c.Assert(isSameFile(srcFile, "/unknown"), Equals, false)
+	c.Assert(linkFile("/unknown", targetFile), NotNil)
+}
+
 func (s *StorageSuite) TestRemovePackage(c *C) {
 	opts := genStorageOptions(c, "")
 	fs, err := NewStorage(opts, index.DefaultOptions)
@@ -382,6 +686,200 @@ func (s *StorageSuite) TestCopyPackage(c *C) {
 	c.Assert(fs.CopyPackage(data.REPO_TESTING, data.REPO_RELEASE, "i386", "test-package-1.0.1-0.el7.i386.rpm"), ErrorMatches, `Can't copy package in storage: Source repository "testing" doesn't support "i386" architecture`)
 	c.Assert(os.Mkdir(fs.dataOptions.DataDir+"/testing/i386", 0755), IsNil)
 	c.Assert(fs.CopyPackage(data.REPO_TESTING, data.REPO_RELEASE, "i386", "test-package-1.0.1-0.el7.i386.rpm"), ErrorMatches, `Can't copy package in storage: Target repository "release" doesn't support "i386" architecture`)
+
+	opts.VerifyCopy = true
+
+	c.Assert(fs.CopyPackage(data.REPO_TESTING, data.REPO_RELEASE, data.ARCH_X64, "test-package-1.0.0-0.el7.x86_64.rpm"), IsNil)
+
+	fileHashFunc = func(file string) string { return "" }
+
+	c.Assert(fs.CopyPackage(data.REPO_TESTING, data.REPO_RELEASE, data.ARCH_X64, "test-package-1.0.0-0.el7.x86_64.rpm"), ErrorMatches, `Can't copy package in storage: Can't calculate checksum for .*/testing/x86_64/test-package-1.0.0-0.el7.x86_64.rpm`)
+
+	fileHashFunc = func(file string) string { return file }
+
+	err = fs.CopyPackage(data.REPO_TESTING, data.REPO_RELEASE, data.ARCH_X64, "test-package-1.0.0-0.el7.x86_64.rpm")
+
+	c.Assert(err, ErrorMatches, `Can't copy package in storage: Checksum of copied package test-package-1.0.0-0.el7.x86_64.rpm \(.*\) doesn't match source checksum \(.*\)`)
+	c.Assert(errors.Is(err, ErrHashMismatch), Equals, true)
+	c.Assert(fsutil.IsExist(fs.dataOptions.DataDir+"/release/x86_64/test-package-1.0.0-0.el7.x86_64.rpm"), Equals, true)
+	c.Assert(fsutil.IsExist(fs.dataOptions.DataDir+"/release/x86_64/test-package-1.0.0-0.el7.x86_64.rpm.tmp"), Equals, false)
+
+	c.Assert(os.Mkdir(fs.dataOptions.DataDir+"/release/aarch64", 0755), IsNil)
+	c.Assert(fs.RemovePackage(data.REPO_RELEASE, data.ARCH_X64, "git-all-2.27.0-0.el7.noarch.rpm"), IsNil)
+
+	fileHashFunc = func(file string) string {
+		if strings.Contains(file, "/aarch64/") {
+			return "0000000000"
+		}
+
+		return hash.FileHash(file)
+	}
+
+	err = fs.CopyPackage(data.REPO_TESTING, data.REPO_RELEASE, data.ARCH_X64, "git-all-2.27.0-0.el7.noarch.rpm")
+
+	c.Assert(errors.Is(err, ErrHashMismatch), Equals, true)
+	c.Assert(fsutil.IsExist(fs.dataOptions.DataDir+"/release/x86_64/git-all-2.27.0-0.el7.noarch.rpm"), Equals, false)
+	c.Assert(fsutil.IsExist(fs.dataOptions.DataDir+"/release/aarch64/git-all-2.27.0-0.el7.noarch.rpm"), Equals, false)
+
+	fileHashFunc = hash.FileHash
+
+	c.Assert(fs.CopyPackage(data.REPO_TESTING, data.REPO_RELEASE, data.ARCH_X64, "git-all-2.27.0-0.el7.noarch.rpm"), IsNil)
+	c.Assert(fsutil.IsExist(fs.dataOptions.DataDir+"/release/x86_64/git-all-2.27.0-0.el7.noarch.rpm"), Equals, true)
+	c.Assert(fsutil.IsExist(fs.dataOptions.DataDir+"/release/aarch64/git-all-2.27.0-0.el7.noarch.rpm"), Equals, true)
+}
+
+func (s *StorageSuite) TestRepairPackageAttrs(c *C) {
+	opts := genStorageOptions(c, "")
+	opts.SplitFiles = true
+
+	fs, err := NewStorage(opts, index.DefaultOptions)
+
+	c.Assert(fs, NotNil)
+	c.Assert(err, IsNil)
+
+	c.Assert(fs.Initialize(defRepos, []string{data.ARCH_X64}), IsNil)
+
+	c.Assert(fs.RepairPackageAttrs("", "", ""), ErrorMatches, `Can't repair package attributes: Repository name can't be empty`)
+	c.Assert(fs.RepairPackageAttrs(data.REPO_TESTING, "", ""), ErrorMatches, `Can't repair package attributes: Path to file can't be empty`)
+	c.Assert(fs.RepairPackageAttrs(data.REPO_TESTING, "", "test.rpm"), ErrorMatches, `Can't repair package attributes: Arch name can't be empty`)
+	c.Assert(fs.RepairPackageAttrs(data.REPO_TESTING, "unknown", "test.rpm"), ErrorMatches, `Can't repair package attributes: Unknown or unsupported architecture`)
+	c.Assert(fs.RepairPackageAttrs(data.REPO_TESTING, data.ARCH_NOARCH, "test.rpm"), ErrorMatches, `Can't repair package attributes: Noarch is pseudo architecture and can't be used`)
+	c.Assert(fs.RepairPackageAttrs("unknown", data.ARCH_X64, "test.rpm"), ErrorMatches, `Can't repair package attributes: Repository "unknown" doesn't exist`)
+	c.Assert(fs.RepairPackageAttrs(data.REPO_TESTING, data.ARCH_AARCH64, "test.rpm"), ErrorMatches, `Can't repair package attributes: Repository "testing" doesn't support "aarch64" architecture`)
+	c.Assert(fs.RepairPackageAttrs(data.REPO_TESTING, data.ARCH_X64, "test.rpm"), ErrorMatches, `Can't repair package attributes: Package file test.rpm doesn't exist`)
+
+	c.Assert(fs.AddPackage(data.REPO_TESTING, "../../../testdata/test-package-1.0.0-0.el7.x86_64.rpm"), IsNil)
+
+	pkgFile := fs.GetPackagePath(data.REPO_TESTING, data.ARCH_X64, "test-package-1.0.0-0.el7.x86_64.rpm")
+
+	c.Assert(os.Chmod(pkgFile, 0600), IsNil)
+	c.Assert(os.Chmod(path.Dir(pkgFile), 0700), IsNil)
+
+	opts.FilePerms = 0640
+	opts.DirPerms = 0750
+
+	c.Assert(fs.RepairPackageAttrs(data.REPO_TESTING, data.ARCH_X64, "t/test-package-1.0.0-0.el7.x86_64.rpm"), IsNil)
+	c.Assert(fsutil.GetMode(pkgFile), Equals, os.FileMode(0640))
+	c.Assert(fsutil.GetMode(path.Dir(pkgFile)), Equals, os.FileMode(0750))
+
+	chmodFunc = func(path string, mode os.FileMode) error { return fmt.Errorf("ERROR") }
+
+	c.Assert(fs.RepairPackageAttrs(data.REPO_TESTING, data.ARCH_X64, "test-package-1.0.0-0.el7.x86_64.rpm"), ErrorMatches, `Can't repair package .*attributes: ERROR`)
+
+	chmodFunc = os.Chmod
+
+	var d *Depot
+
+	c.Assert(d.RepairPackageAttrs(""), ErrorMatches, `Can't repair package attributes: Path to file can't be empty`)
+	c.Assert(d.RepairPackageAttrs("test.rpm"), ErrorMatches, `Can't repair package attributes: Can't find depot for given repository or architecture`)
+}
+
+func (s *StorageSuite) TestRepairPackageAttrsNoSplit(c *C) {
+	opts := genStorageOptions(c, "")
+	opts.SplitFiles = false
+
+	fs, err := NewStorage(opts, index.DefaultOptions)
+
+	c.Assert(fs, NotNil)
+	c.Assert(err, IsNil)
+
+	c.Assert(fs.Initialize(defRepos, []string{data.ARCH_X64}), IsNil)
+	c.Assert(fs.AddPackage(data.REPO_TESTING, "../../../testdata/test-package-1.0.0-0.el7.x86_64.rpm"), IsNil)
+
+	pkgFile := fs.GetPackagePath(data.REPO_TESTING, data.ARCH_X64, "test-package-1.0.0-0.el7.x86_64.rpm")
+
+	c.Assert(path.Dir(pkgFile), Equals, fs.GetDepot(data.REPO_TESTING, data.ARCH_X64).dataDir)
+	c.Assert(os.Chmod(path.Dir(pkgFile), 0700), IsNil)
+
+	opts.FilePerms = 0640
+	opts.DirPerms = 0750
+
+	c.Assert(fs.RepairPackageAttrs(data.REPO_TESTING, data.ARCH_X64, "test-package-1.0.0-0.el7.x86_64.rpm"), IsNil)
+	c.Assert(fsutil.GetMode(pkgFile), Equals, os.FileMode(0640))
+	c.Assert(fsutil.GetMode(path.Dir(pkgFile)), Equals, os.FileMode(0750))
+}
+
+func (s *StorageSuite) TestAuxData(c *C) {
+	fs, err := NewStorage(genStorageOptions(c, ""), index.DefaultOptions)
+
+	c.Assert(fs, NotNil)
+	c.Assert(err, IsNil)
+
+	c.Assert(fs.WriteAuxData("tags.json", []byte("{}")), ErrorMatches, `Can't write auxiliary data: Repository storage is not initialized`)
+	c.Assert(fs.Initialize(defRepos, []string{data.ARCH_X64}), IsNil)
+
+	auxData, err := fs.ReadAuxData("tags.json")
+
+	c.Assert(err, IsNil)
+	c.Assert(auxData, IsNil)
+
+	c.Assert(fs.WriteAuxData("tags.json", []byte(`{"test":["tag"]}`)), IsNil)
+	c.Assert(fsutil.IsExist(fs.dataOptions.DataDir+"/.tags.json"), Equals, true)
+	c.Assert(fsutil.IsExist(fs.dataOptions.DataDir+"/.tags.json.tmp"), Equals, false)
+
+	auxData, err = fs.ReadAuxData("tags.json")
+
+	c.Assert(err, IsNil)
+	c.Assert(string(auxData), Equals, `{"test":["tag"]}`)
+
+	c.Assert(fs.GetAuxDataPath("tags.json"), Equals, fs.dataOptions.DataDir+"/.tags.json")
+	c.Assert(fs.GetAuxDataPath("../tags.json"), Equals, "")
+
+	_, err = fs.ReadAuxData("")
+	c.Assert(err, ErrorMatches, `Can't read auxiliary data: Name can't be empty`)
+	_, err = fs.ReadAuxData(".tags.json")
+	c.Assert(err, ErrorMatches, `Can't read auxiliary data: Name ".tags.json" contains invalid symbols`)
+	c.Assert(fs.WriteAuxData("../tags.json", nil), ErrorMatches, `Can't write auxiliary data: Name "../tags.json" contains invalid symbols`)
+
+	chmodFunc = func(path string, mode os.FileMode) error { return fmt.Errorf("ERROR") }
+
+	c.Assert(fs.WriteAuxData("tags.json", []byte("{}")), ErrorMatches, `Can't write auxiliary data: ERROR`)
+	c.Assert(fsutil.IsExist(fs.dataOptions.DataDir+"/.tags.json.tmp"), Equals, false)
+
+	chmodFunc = os.Chmod
+
+	auxData, err = fs.ReadAuxData("tags.json")
+
+	c.Assert(err, IsNil)
+	c.Assert(string(auxData), Equals, `{"test":["tag"]}`)
+}
+
+func (s *StorageSuite) TestStorageErrors(c *C) {
+	fs, err := NewStorage(genStorageOptions(c, ""), index.DefaultOptions)
+
+	c.Assert(fs, NotNil)
+	c.Assert(err, IsNil)
+
+	err = fs.Initialize(defRepos, []string{data.ARCH_X64})
+
+	c.Assert(err, IsNil)
+
+	tempDir := c.MkDir()
+	fsutil.CopyFile("../../../testdata/comps.xml", tempDir+"/test.rpm", 0644)
+
+	err = fs.AddPackage("unknown", "../../../testdata/test-package-1.0.0-0.el7.x86_64.rpm")
+	c.Assert(errors.Is(err, ErrRepoNotFound), Equals, true)
+	c.Assert(errors.Is(err, ErrArchNotSupported), Equals, false)
+
+	err = fs.AddPackage(data.REPO_RELEASE, tempDir+"/test.rpm")
+	c.Assert(errors.Is(err, ErrNotRPM), Equals, true)
+
+	err = fs.RemovePackage(data.REPO_TESTING, data.ARCH_I386, "test-package-1.0.0-0.el7.i386.rpm")
+	c.Assert(errors.Is(err, ErrArchNotSupported), Equals, true)
+
+	_, err = fs.Reindex(context.Background(), data.REPO_TESTING, data.ARCH_NOARCH, false, nil)
+	c.Assert(errors.Is(err, ErrUnknownArch), Equals, true)
+
+	var storageErr *Error
+
+	err = fs.CopyPackage("unknown", data.REPO_RELEASE, data.ARCH_X64, "test-package-1.0.0-0.el7.x86_64.rpm")
+	c.Assert(errors.As(err, &storageErr), Equals, true)
+	c.Assert(storageErr.Kind, Equals, ErrRepoNotFound)
+	c.Assert(storageErr.Error(), Equals, `Source repository "unknown" doesn't exist`)
+
+	storageErr = nil
+	c.Assert(storageErr.Error(), Equals, "")
+	c.Assert(storageErr.Unwrap(), IsNil)
 }
 
 func (s *StorageSuite) TestHasPackage(c *C) {
@@ -455,8 +953,59 @@ func (s *StorageSuite) TestUpdateObjectOwner(c *C) {
 	chmodFunc = os.Chmod
 }
 
-func (s *StorageSuite) TestStorageReindex(c *C) {
-	fs, err := NewStorage(genStorageOptions(c, ""), index.DefaultOptions)
+func (s *StorageSuite) TestUpdateObjectAttrsRetry(c *C) {
+	var chownCalls, chmodCalls int
+
+	chownFunc = func(name string, uid, gid int) error {
+		chownCalls++
+
+		if chownCalls == 1 {
+			return &os.PathError{Op: "chown", Path: name, Err: syscall.EIO}
+		}
+
+		return nil
+	}
+
+	chmodFunc = func(name string, mode os.FileMode) error {
+		chmodCalls++
+		return &os.PathError{Op: "chmod", Path: name, Err: syscall.EPERM}
+	}
+
+	options := &Options{
+		User:            "nobody",
+		Group:           "nobody",
+		AttrsRetries:    3,
+		AttrsRetryDelay: time.Millisecond,
+	}
+
+	// Transient chown error must be retried, permanent chmod error must not
+	c.Assert(updateObjectAttrs("/path", options, false), ErrorMatches, `chmod /path: operation not permitted`)
+	c.Assert(chownCalls, Equals, 2)
+	c.Assert(chmodCalls, Equals, 1)
+
+	chownCalls = 0
+	chownFunc = func(name string, uid, gid int) error {
+		chownCalls++
+		return &os.PathError{Op: "chown", Path: name, Err: syscall.EAGAIN}
+	}
+
+	c.Assert(updateObjectAttrs("/path", options, false), ErrorMatches, `chown /path: resource temporarily unavailable`)
+	c.Assert(chownCalls, Equals, 4)
+
+	options.AttrsRetries = 0
+	chownCalls = 0
+
+	c.Assert(updateObjectAttrs("/path", options, false), NotNil)
+	c.Assert(chownCalls, Equals, 1)
+
+	c.Assert((&Options{}).GetAttrsRetryDelay(), Equals, ATTRS_RETRY_DELAY)
+
+	chownFunc = os.Chown
+	chmodFunc = os.Chmod
+}
+
+func (s *StorageSuite) TestStorageReindex(c *C) {
+	fs, err := NewStorage(genStorageOptions(c, ""), index.DefaultOptions)
 
 	c.Assert(fs, NotNil)
 	c.Assert(err, IsNil)
@@ -473,15 +1022,28 @@ func (s *StorageSuite) TestStorageReindex(c *C) {
 
 	c.Assert(err, IsNil)
 
-	c.Assert(fs.Reindex("", data.ARCH_X64, false), ErrorMatches, `Can't generate index: Repository name can't be empty`)
-	c.Assert(fs.Reindex(data.REPO_TESTING, "", false), ErrorMatches, `Can't generate index: Arch name can't be empty`)
-	c.Assert(fs.Reindex(data.REPO_TESTING, data.ARCH_NOARCH, false), ErrorMatches, `Can't generate index: Unsupported architecture "noarch"`)
-	c.Assert(fs.Reindex(data.REPO_TESTING, "src", false), ErrorMatches, `Can't generate index: Repository "testing" doesn't contain "src" architecture`)
-	c.Assert(fs.Reindex("unknown", data.ARCH_X64, false), ErrorMatches, `Can't generate index: Repository "unknown" doesn't exist`)
-	c.Assert(fs.Reindex(data.REPO_TESTING, "unknown", false), ErrorMatches, `Can't generate index: Unknown or unsupported architecture`)
+	_, err = fs.Reindex(context.Background(), "", data.ARCH_X64, false, nil)
+	c.Assert(err, ErrorMatches, `Can't generate index: Repository name can't be empty`)
+	_, err = fs.Reindex(context.Background(), data.REPO_TESTING, "", false, nil)
+	c.Assert(err, ErrorMatches, `Can't generate index: Arch name can't be empty`)
+	_, err = fs.Reindex(context.Background(), data.REPO_TESTING, data.ARCH_NOARCH, false, nil)
+	c.Assert(err, ErrorMatches, `Can't generate index: Unsupported architecture "noarch"`)
+	_, err = fs.Reindex(context.Background(), data.REPO_TESTING, "src", false, nil)
+	c.Assert(err, ErrorMatches, `Can't generate index: Repository "testing" doesn't contain "src" architecture`)
+	_, err = fs.Reindex(context.Background(), "unknown", data.ARCH_X64, false, nil)
+	c.Assert(err, ErrorMatches, `Can't generate index: Repository "unknown" doesn't exist`)
+	_, err = fs.Reindex(context.Background(), data.REPO_TESTING, "unknown", false, nil)
+	c.Assert(err, ErrorMatches, `Can't generate index: Unknown or unsupported architecture`)
+
+	stats, err := fs.Reindex(context.Background(), data.REPO_TESTING, data.ARCH_X64, false, nil)
+	c.Assert(err, IsNil)
+	c.Assert(stats, NotNil)
+	c.Assert(stats.Packages, Equals, 1)
 
-	err = fs.Reindex(data.REPO_TESTING, data.ARCH_X64, false)
+	stats, err = fs.Reindex(context.Background(), data.REPO_TESTING, data.ARCH_X64, false, nil)
 	c.Assert(err, IsNil)
+	c.Assert(stats, IsNil)
+
 	c.Assert(fsutil.CheckPerms("FRS", fs.dataOptions.DataDir+"/testing/x86_64/repodata/filelists.sqlite.bz2"), Equals, true)
 	c.Assert(fsutil.CheckPerms("FRS", fs.dataOptions.DataDir+"/testing/x86_64/repodata/filelists.xml.bz2"), Equals, true)
 	c.Assert(fsutil.CheckPerms("FRS", fs.dataOptions.DataDir+"/testing/x86_64/repodata/other.sqlite.bz2"), Equals, true)
@@ -618,6 +1180,25 @@ func (s *StorageSuite) TestStorageGetPackagePath(c *C) {
 	)
 }
 
+func (s *StorageSuite) TestStorageOpenPackage(c *C) {
+	fs, err := NewStorage(genStorageOptions(c, dataDir), index.DefaultOptions)
+
+	c.Assert(fs, NotNil)
+	c.Assert(err, IsNil)
+
+	fd, err := fs.OpenPackage(data.REPO_RELEASE, data.ARCH_X64, "test-package-1.0.0-0.el7.x86_64.rpm")
+
+	c.Assert(err, IsNil)
+	c.Assert(fd, NotNil)
+	c.Assert(fd.Close(), IsNil)
+
+	_, err = fs.OpenPackage("unknown", data.ARCH_X64, "test-package-1.0.0-0.el7.x86_64.rpm")
+	c.Assert(err, ErrorMatches, `Can't open package: Can't find package "test-package-1.0.0-0.el7.x86_64.rpm" in repository "unknown"`)
+
+	_, err = fs.OpenPackage(data.REPO_RELEASE, data.ARCH_X64, "test-package-9.9.9-0.el7.x86_64.rpm")
+	c.Assert(err, ErrorMatches, `Can't open package: .*no such file or directory`)
+}
+
 func (s *StorageSuite) TestStorageGetDepot(c *C) {
 	fs, err := NewStorage(genStorageOptions(c, dataDir), index.DefaultOptions)
 
@@ -709,7 +1290,7 @@ func (s *StorageSuite) TestStorageGetModTime(c *C) {
 	err = fs.Initialize(defRepos, []string{data.ARCH_X64})
 	c.Assert(err, IsNil)
 
-	err = fs.Reindex(data.REPO_TESTING, data.ARCH_X64, false)
+	_, err = fs.Reindex(context.Background(), data.REPO_TESTING, data.ARCH_X64, false, nil)
 	c.Assert(err, IsNil)
 
 	os.Remove(joinPath(fs.dataOptions.DataDir, data.REPO_TESTING, data.ARCH_X64, "/repodata/repomd.xml"))
@@ -720,6 +1301,61 @@ func (s *StorageSuite) TestStorageGetModTime(c *C) {
 	c.Assert(err, ErrorMatches, `Can't check repository index modification date: Can't get file info for .*`)
 }
 
+func (s *StorageSuite) TestStorageGetMetaIndexPath(c *C) {
+	fs, err := NewStorage(genStorageOptions(c, dataDir), index.DefaultOptions)
+
+	c.Assert(fs, NotNil)
+	c.Assert(err, IsNil)
+
+	c.Assert(fs.GetMetaIndexPath("", data.ARCH_X64), Equals, "")
+	c.Assert(fs.GetMetaIndexPath(data.REPO_RELEASE, ""), Equals, "")
+	c.Assert(fs.GetMetaIndexPath(data.REPO_RELEASE, data.ARCH_NOARCH), Equals, "")
+	c.Assert(fs.GetMetaIndexPath(data.REPO_RELEASE, "unknown"), Equals, "")
+	c.Assert(fs.GetMetaIndexPath(data.REPO_RELEASE, data.ARCH_PPC64), Equals, "")
+
+	c.Assert(
+		fs.GetMetaIndexPath(data.REPO_RELEASE, data.ARCH_X64), Equals,
+		joinPath(fs.dataOptions.DataDir, data.REPO_RELEASE, data.ARCH_X64, "/repodata/repomd.xml"),
+	)
+}
+
+func (s *StorageSuite) TestStorageFindMissingMetaFiles(c *C) {
+	fs, err := NewStorage(genStorageOptions(c, ""), index.DefaultOptions)
+
+	c.Assert(fs, NotNil)
+	c.Assert(err, IsNil)
+
+	_, err = fs.FindMissingMetaFiles("", data.ARCH_X64)
+	c.Assert(err, ErrorMatches, `Can't check metadata files: Repository name can't be empty`)
+	_, err = fs.FindMissingMetaFiles(data.REPO_TESTING, "")
+	c.Assert(err, ErrorMatches, `Can't check metadata files: Arch name can't be empty`)
+	_, err = fs.FindMissingMetaFiles(data.REPO_TESTING, "unknown")
+	c.Assert(err, ErrorMatches, `Can't check metadata files: Unknown or unsupported architecture`)
+	_, err = fs.FindMissingMetaFiles(data.REPO_TESTING, data.ARCH_X64)
+	c.Assert(err, ErrorMatches, `Can't check metadata files: Repository storage is not initialized`)
+
+	err = fs.Initialize(defRepos, []string{data.ARCH_X64})
+	c.Assert(err, IsNil)
+
+	_, err = fs.Reindex(context.Background(), data.REPO_TESTING, data.ARCH_X64, false, nil)
+	c.Assert(err, IsNil)
+
+	missing, err := fs.FindMissingMetaFiles(data.REPO_TESTING, data.ARCH_X64)
+	c.Assert(err, IsNil)
+	c.Assert(missing, HasLen, 0)
+
+	dp := fs.GetDepot(data.REPO_TESTING, data.ARCH_X64)
+	metaIndex, err := dp.GetMetaIndex()
+	c.Assert(err, IsNil)
+
+	primaryHREF := metaIndex.Get(data.DB_PRIMARY).Location.HREF
+	os.Remove(joinPath(fs.dataOptions.DataDir, data.REPO_TESTING, data.ARCH_X64, primaryHREF))
+
+	missing, err = fs.FindMissingMetaFiles(data.REPO_TESTING, data.ARCH_X64)
+	c.Assert(err, IsNil)
+	c.Assert(missing, DeepEquals, []string{primaryHREF})
+}
+
 func (s *StorageSuite) TestStorageWarmupCache(c *C) {
 	fs, err := NewStorage(genStorageOptions(c, dataDir), index.DefaultOptions)
 
@@ -792,6 +1428,106 @@ func (s *StorageSuite) TestStoragePurgeCache(c *C) {
 	removeFunc = os.Remove
 }
 
+func (s *StorageSuite) TestStoragePurgeCacheFor(c *C) {
+	fs, err := NewStorage(genStorageOptions(c, ""), index.DefaultOptions)
+
+	c.Assert(fs, NotNil)
+	c.Assert(err, IsNil)
+
+	c.Assert(fs.PurgeCacheFor("", data.ARCH_X64), ErrorMatches, `Can't purge cache: Repository name can't be empty`)
+	c.Assert(fs.PurgeCacheFor(data.REPO_RELEASE, ""), ErrorMatches, `Can't purge cache: Arch name can't be empty`)
+	c.Assert(fs.PurgeCacheFor(data.REPO_RELEASE, "unknown"), ErrorMatches, `Can't purge cache: Unknown or unsupported architecture`)
+	c.Assert(fs.PurgeCacheFor(data.REPO_RELEASE, data.ARCH_X64), ErrorMatches, `Can't purge cache: Repository storage is not initialized`)
+
+	opts := genStorageOptions(c, dataDir)
+	fs, err = NewStorage(opts, index.DefaultOptions)
+
+	c.Assert(fs, NotNil)
+	c.Assert(err, IsNil)
+
+	c.Assert(fs.WarmupCache(data.REPO_RELEASE, data.ARCH_X64), IsNil)
+
+	otherDB := opts.CacheDir + "/testing-x86_64-primary.sqlite"
+	c.Assert(fsutil.TouchFile(otherDB, 0644), IsNil)
+
+	c.Assert(fs.PurgeCacheFor(data.REPO_RELEASE, data.ARCH_X64), IsNil)
+	c.Assert(fsutil.IsExist(opts.CacheDir+"/release-x86_64-primary.sqlite"), Equals, false)
+	c.Assert(fsutil.IsExist(otherDB), Equals, true)
+	c.Assert(fs.IsCacheValid(data.REPO_RELEASE, data.ARCH_X64), Equals, false)
+
+	c.Assert(fs.WarmupCache(data.REPO_RELEASE, data.ARCH_X64), IsNil)
+
+	removeFunc = func(path string) error { return fmt.Errorf("ERROR") }
+	c.Assert(fs.PurgeCacheFor(data.REPO_RELEASE, data.ARCH_X64), ErrorMatches, `ERROR`)
+	removeFunc = os.Remove
+}
+
+func (s *StorageSuite) TestStorageNestedCache(c *C) {
+	opts := genStorageOptions(c, dataDir)
+	opts.NestedCache = true
+
+	fs, err := NewStorage(opts, index.DefaultOptions)
+
+	c.Assert(fs, NotNil)
+	c.Assert(err, IsNil)
+
+	c.Assert(fs.WarmupCache(data.REPO_RELEASE, data.ARCH_X64), IsNil)
+
+	dp := fs.depots["release-x86_64"]
+	dbFile := opts.CacheDir + "/release/x86_64/primary.sqlite"
+
+	c.Assert(dp, NotNil)
+	c.Assert(dp.GetDBFilePath(data.DB_PRIMARY), Equals, dbFile)
+	c.Assert(fsutil.IsExist(dbFile), Equals, true)
+
+	c.Assert(fs.PurgeCache(), IsNil)
+	c.Assert(fsutil.IsExist(dbFile), Equals, false)
+}
+
+func (s *StorageSuite) TestStorageSkipDBs(c *C) {
+	opts := genStorageOptions(c, dataDir)
+
+	opts.SkipDBs = []string{"unknown"}
+	_, err := NewStorage(opts, index.DefaultOptions)
+	c.Assert(err, NotNil)
+
+	opts.SkipDBs = []string{data.DB_PRIMARY}
+	_, err = NewStorage(opts, index.DefaultOptions)
+	c.Assert(err, NotNil)
+
+	opts.SkipDBs = []string{data.DB_OTHER}
+	fs, err := NewStorage(opts, index.DefaultOptions)
+
+	c.Assert(fs, NotNil)
+	c.Assert(err, IsNil)
+
+	c.Assert(fs.WarmupCache(data.REPO_RELEASE, data.ARCH_X64), IsNil)
+	c.Assert(fs.GetDepot(data.REPO_RELEASE, data.ARCH_X64).IsDBCached(data.DB_OTHER), Equals, false)
+
+	_, err = fs.GetDB(data.REPO_RELEASE, data.ARCH_X64, data.DB_OTHER)
+	c.Assert(errors.Is(err, ErrDBSkipped), Equals, true)
+
+	_, err = fs.GetDB(data.REPO_RELEASE, data.ARCH_X64, data.DB_PRIMARY)
+	c.Assert(err, IsNil)
+}
+
+func (s *StorageSuite) TestStorageNoDatabase(c *C) {
+	opts := index.DefaultOptions.Clone()
+	opts.NoDatabase = true
+
+	fs, err := NewStorage(genStorageOptions(c, dataDir), opts)
+
+	c.Assert(fs, NotNil)
+	c.Assert(err, IsNil)
+
+	err = fs.WarmupCache(data.REPO_RELEASE, data.ARCH_X64)
+	c.Assert(errors.Is(err, ErrDBDisabled), Equals, true)
+
+	_, err = fs.GetDB(data.REPO_RELEASE, data.ARCH_X64, data.DB_PRIMARY)
+	c.Assert(err, ErrorMatches, `DB "primary" is not available because database generation is disabled by index configuration`)
+	c.Assert(errors.Is(err, ErrDBDisabled), Equals, true)
+}
+
 func (s *StorageSuite) TestDepotIsCacheValid(c *C) {
 	fs, err := NewStorage(genStorageOptions(c, dataDir), index.DefaultOptions)
 
@@ -831,6 +1567,400 @@ func (s *StorageSuite) TestDepotIsCacheValid(c *C) {
 	dp.dataDir = origDataDir
 }
 
+func (s *StorageSuite) TestStorageConcurrentAccess(c *C) {
+	fs, err := NewStorage(genStorageOptions(c, dataDir), index.DefaultOptions)
+
+	c.Assert(fs, NotNil)
+	c.Assert(err, IsNil)
+
+	var wg sync.WaitGroup
+
+	errs := make(chan error, 64)
+
+	for i := range 64 {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			switch {
+			case i%16 == 0:
+				errs <- fs.InvalidateCache()
+				return
+			case i%4 == 0:
+				if fs.GetDepot(data.REPO_RELEASE, data.ARCH_X64) == nil {
+					errs <- fmt.Errorf("Depot is nil")
+					return
+				}
+			}
+
+			rows, err := fs.Query(context.Background(), data.REPO_RELEASE, data.ARCH_X64, data.DB_PRIMARY, "SELECT name FROM packages;")
+
+			if err != nil {
+				errs <- err
+				return
+			}
+
+			for rows.Next() {
+				var name string
+				err = rows.Scan(&name)
+			}
+
+			rows.Close()
+
+			errs <- err
+		}(i)
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		c.Assert(err, IsNil)
+	}
+
+	c.Assert(fs.getDepots(), HasLen, 1)
+
+	_, err = fs.Query(context.Background(), "", data.ARCH_X64, data.DB_PRIMARY, "")
+	c.Assert(err, ErrorMatches, `Can't execute query: Repository name can't be empty`)
+	_, err = fs.Query(context.Background(), data.REPO_RELEASE, "", data.DB_PRIMARY, "")
+	c.Assert(err, ErrorMatches, `Can't execute query: Arch name can't be empty`)
+	_, err = fs.Query(context.Background(), data.REPO_RELEASE, "unknown", data.DB_PRIMARY, "")
+	c.Assert(err, ErrorMatches, `Can't execute query: Unknown or unsupported architecture`)
+	_, err = fs.Query(context.Background(), data.REPO_RELEASE, data.ARCH_X64, "", "")
+	c.Assert(err, ErrorMatches, `Can't execute query: DB type can't be empty`)
+
+	canceledCtx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err = fs.Query(canceledCtx, data.REPO_RELEASE, data.ARCH_X64, data.DB_PRIMARY, "SELECT name FROM packages;")
+	c.Assert(err, ErrorMatches, `.*context canceled`)
+
+	var nilDepot *Depot
+	_, err = nilDepot.Query(context.Background(), data.DB_PRIMARY, "")
+	c.Assert(err, Equals, ErrNilDepot)
+}
+
+func (s *StorageSuite) TestDepotConcurrentAccess(c *C) {
+	fs, err := NewStorage(genStorageOptions(c, dataDir), index.DefaultOptions)
+
+	c.Assert(fs, NotNil)
+	c.Assert(err, IsNil)
+
+	dp := fs.GetDepot(data.REPO_RELEASE, data.ARCH_X64)
+
+	c.Assert(dp, NotNil)
+
+	var wg sync.WaitGroup
+
+	errs := make(chan error, 32)
+
+	for i := range 32 {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			if i%8 == 0 {
+				errs <- dp.InvalidateCache()
+				return
+			}
+
+			dbType := data.DBList[i%len(data.DBList)]
+			db, err := dp.GetDB(dbType)
+
+			if err == nil && db == nil {
+				err = fmt.Errorf("DB %q is nil", dbType)
+			}
+
+			errs <- err
+		}(i)
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		c.Assert(err, IsNil)
+	}
+
+	c.Assert(dp.IsDBCached(data.DB_PRIMARY), Equals, true)
+}
+
+func (s *StorageSuite) TestDepotWarmupCache(c *C) {
+	fs, err := NewStorage(genStorageOptions(c, dataDir), index.DefaultOptions)
+
+	c.Assert(fs, NotNil)
+	c.Assert(err, IsNil)
+
+	dp := fs.GetDepot(data.REPO_RELEASE, data.ARCH_X64)
+
+	c.Assert(dp, NotNil)
+	c.Assert(dp.WarmupCache(data.DBList), IsNil)
+
+	for _, dbType := range data.DBList {
+		c.Assert(dp.IsDBCached(dbType), Equals, true)
+		c.Assert(dp.dbs[dbType], NotNil)
+	}
+
+	c.Assert(dp.WarmupCache([]string{"unknown"}), ErrorMatches, `Can't cache DB: Can't cache DB: Can't find info about DB "unknown"`)
+
+	dp.dataDir = "/_unknown_"
+	c.Assert(dp.WarmupCache(data.DBList), ErrorMatches, `Can't read meta index: .*`)
+
+	var nilDepot *Depot
+	c.Assert(nilDepot.WarmupCache(data.DBList), Equals, ErrNilDepot)
+}
+
+func (s *StorageSuite) TestVerify(c *C) {
+	fs, err := NewStorage(genStorageOptions(c, ""), index.DefaultOptions)
+
+	c.Assert(fs, NotNil)
+	c.Assert(err, IsNil)
+
+	_, err = fs.Verify("", data.ARCH_X64)
+	c.Assert(err, ErrorMatches, `Can't verify storage: Repository name can't be empty`)
+	_, err = fs.Verify(data.REPO_RELEASE, "")
+	c.Assert(err, ErrorMatches, `Can't verify storage: Arch name can't be empty`)
+	_, err = fs.Verify(data.REPO_RELEASE, "unknown")
+	c.Assert(err, ErrorMatches, `Can't verify storage: Unknown or unsupported architecture`)
+	_, err = fs.Verify(data.REPO_RELEASE, data.ARCH_NOARCH)
+	c.Assert(err, ErrorMatches, `Can't verify storage: Noarch is pseudo architecture and can't be used`)
+	_, err = fs.Verify(data.REPO_RELEASE, data.ARCH_X64)
+	c.Assert(err, ErrorMatches, `Can't verify storage: Repository storage is not initialized`)
+
+	tmpDataDir := c.MkDir()
+	c.Assert(fsutil.CopyDir(dataDir, tmpDataDir), IsNil)
+
+	fs, err = NewStorage(genStorageOptions(c, tmpDataDir), index.DefaultOptions)
+
+	c.Assert(fs, NotNil)
+	c.Assert(err, IsNil)
+
+	dp := fs.GetDepot(data.REPO_RELEASE, data.ARCH_X64)
+	indexed, err := dp.GetIndexedPackages()
+
+	c.Assert(err, IsNil)
+	c.Assert(indexed, Not(HasLen), 0)
+
+	// Test repository doesn't contain package files, only metadata
+	problems, err := fs.Verify(data.REPO_RELEASE, data.ARCH_X64)
+
+	c.Assert(err, IsNil)
+	c.Assert(problems, HasLen, len(indexed))
+	c.Assert(errors.Is(problems[0], ErrMissingPackage), Equals, true)
+
+	for _, file := range indexed {
+		c.Assert(os.MkdirAll(path.Dir(file), 0755), IsNil)
+		c.Assert(fsutil.TouchFile(file, 0644), IsNil)
+	}
+
+	problems, err = fs.Verify(data.REPO_RELEASE, data.ARCH_X64)
+
+	c.Assert(err, IsNil)
+	c.Assert(problems, HasLen, 0)
+
+	missingFile := strings.TrimPrefix(indexed[0], dp.dataDir+"/")
+
+	c.Assert(os.Remove(indexed[0]), IsNil)
+	c.Assert(fsutil.TouchFile(dp.dataDir+"/orphan-1.0.0-0.el7.x86_64.rpm", 0644), IsNil)
+
+	problems, err = fs.Verify(data.REPO_RELEASE, data.ARCH_X64)
+
+	c.Assert(err, IsNil)
+	c.Assert(problems, HasLen, 2)
+	c.Assert(problems[0], ErrorMatches, `Package `+missingFile+` is referenced in index but doesn't exist`)
+	c.Assert(errors.Is(problems[0], ErrMissingPackage), Equals, true)
+	c.Assert(problems[1], ErrorMatches, `Package orphan-1.0.0-0.el7.x86_64.rpm isn't referenced in index`)
+	c.Assert(errors.Is(problems[1], ErrOrphanPackage), Equals, true)
+
+	dp.dataDir = "/_unknown_"
+	_, err = fs.Verify(data.REPO_RELEASE, data.ARCH_X64)
+	c.Assert(err, ErrorMatches, `Can't verify storage: .*`)
+
+	var nilDepot *Depot
+	_, err = nilDepot.Verify()
+	c.Assert(err, Equals, ErrNilDepot)
+	_, err = nilDepot.GetIndexedPackages()
+	c.Assert(err, Equals, ErrNilDepot)
+}
+
+func (s *StorageSuite) TestFindOrphanPackages(c *C) {
+	fs, err := NewStorage(genStorageOptions(c, ""), index.DefaultOptions)
+
+	c.Assert(fs, NotNil)
+	c.Assert(err, IsNil)
+
+	_, err = fs.FindOrphanPackages("", data.ARCH_X64)
+	c.Assert(err, ErrorMatches, `Can't find orphan packages: Repository name can't be empty`)
+	_, err = fs.FindOrphanPackages(data.REPO_RELEASE, "")
+	c.Assert(err, ErrorMatches, `Can't find orphan packages: Arch name can't be empty`)
+	_, err = fs.FindOrphanPackages(data.REPO_RELEASE, "unknown")
+	c.Assert(err, ErrorMatches, `Can't find orphan packages: Unknown or unsupported architecture`)
+	_, err = fs.FindOrphanPackages(data.REPO_RELEASE, data.ARCH_NOARCH)
+	c.Assert(err, ErrorMatches, `Can't find orphan packages: Noarch is pseudo architecture and can't be used`)
+	_, err = fs.FindOrphanPackages(data.REPO_RELEASE, data.ARCH_X64)
+	c.Assert(err, ErrorMatches, `Can't find orphan packages: Repository storage is not initialized`)
+
+	tmpDataDir := c.MkDir()
+	c.Assert(fsutil.CopyDir(dataDir, tmpDataDir), IsNil)
+
+	fs, err = NewStorage(genStorageOptions(c, tmpDataDir), index.DefaultOptions)
+
+	c.Assert(fs, NotNil)
+	c.Assert(err, IsNil)
+
+	dp := fs.GetDepot(data.REPO_RELEASE, data.ARCH_X64)
+	indexed, err := dp.GetIndexedPackages()
+
+	c.Assert(err, IsNil)
+	c.Assert(indexed, Not(HasLen), 0)
+
+	orphans, err := fs.FindOrphanPackages(data.REPO_RELEASE, data.ARCH_X64)
+
+	c.Assert(err, IsNil)
+	c.Assert(orphans, HasLen, 0)
+
+	c.Assert(fsutil.TouchFile(indexed[0], 0644), IsNil)
+	c.Assert(fsutil.TouchFile(dp.dataDir+"/orphan-1.0.0-0.el7.x86_64.rpm", 0644), IsNil)
+
+	orphans, err = fs.FindOrphanPackages(data.REPO_RELEASE, data.ARCH_X64)
+
+	c.Assert(err, IsNil)
+	c.Assert(orphans, DeepEquals, []string{"orphan-1.0.0-0.el7.x86_64.rpm"})
+
+	os.Remove(dp.GetMetaIndexPath())
+
+	orphans, err = fs.FindOrphanPackages(data.REPO_RELEASE, data.ARCH_X64)
+
+	c.Assert(err, IsNil)
+	c.Assert(orphans, HasLen, 2)
+
+	var nilDepot *Depot
+	_, err = nilDepot.FindOrphanPackages()
+	c.Assert(err, Equals, ErrNilDepot)
+}
+
+func (s *StorageSuite) TestFindDuplicates(c *C) {
+	opts := genStorageOptions(c, "")
+	opts.SplitFiles = true
+
+	fs, err := NewStorage(opts, index.DefaultOptions)
+
+	c.Assert(fs, NotNil)
+	c.Assert(err, IsNil)
+
+	_, err = fs.FindDuplicates("")
+	c.Assert(err, ErrorMatches, `Can't find duplicates: Repository name can't be empty`)
+	_, err = fs.FindDuplicates(data.REPO_TESTING)
+	c.Assert(err, ErrorMatches, `Can't find duplicates: Repository storage is not initialized`)
+
+	c.Assert(fs.Initialize(defRepos, []string{data.ARCH_X64}), IsNil)
+
+	_, err = fs.FindDuplicates("unknown")
+	c.Assert(err, ErrorMatches, `Can't find duplicates: Repository "unknown" doesn't exist`)
+
+	c.Assert(fs.AddPackage(data.REPO_TESTING, "../../../testdata/test-package-1.0.0-0.el7.x86_64.rpm"), IsNil)
+	c.Assert(fs.AddPackage(data.REPO_TESTING, "../../../testdata/git-all-2.27.0-0.el7.noarch.rpm"), IsNil)
+
+	duplicates, err := fs.FindDuplicates(data.REPO_TESTING)
+
+	c.Assert(err, IsNil)
+	c.Assert(duplicates, HasLen, 0)
+
+	dp := fs.GetDepot(data.REPO_TESTING, data.ARCH_X64)
+
+	c.Assert(fsutil.CopyFile(
+		"../../../testdata/test-package-1.0.0-0.el7.x86_64.rpm",
+		dp.dataDir+"/test-package-1.0.0-0.el7.x86_64.rpm", 0644,
+	), IsNil)
+
+	duplicates, err = dp.FindDuplicates()
+
+	c.Assert(err, IsNil)
+	c.Assert(duplicates, HasLen, 1)
+	c.Assert(duplicates["test-package-1.0.0-0.el7.x86_64.rpm"], DeepEquals, []string{
+		"t/test-package-1.0.0-0.el7.x86_64.rpm",
+		"test-package-1.0.0-0.el7.x86_64.rpm",
+	})
+
+	duplicates, err = fs.FindDuplicates(data.REPO_TESTING)
+
+	c.Assert(err, IsNil)
+	c.Assert(duplicates, HasLen, 1)
+	c.Assert(duplicates["test-package-1.0.0-0.el7.x86_64.rpm"], DeepEquals, []string{
+		"x86_64/t/test-package-1.0.0-0.el7.x86_64.rpm",
+		"x86_64/test-package-1.0.0-0.el7.x86_64.rpm",
+	})
+
+	c.Assert(os.Remove(dp.dataDir+"/t/test-package-1.0.0-0.el7.x86_64.rpm"), IsNil)
+
+	// Package file must be found in flat directory if split directory doesn't contain it
+	c.Assert(fs.HasPackage(data.REPO_TESTING, data.ARCH_X64, "test-package-1.0.0-0.el7.x86_64.rpm"), Equals, true)
+	c.Assert(dp.GetPackagePath("test-package-1.0.0-0.el7.x86_64.rpm"), Equals, dp.dataDir+"/test-package-1.0.0-0.el7.x86_64.rpm")
+
+	dp.dataDir = "/_unknown_"
+	_, err = dp.FindDuplicates()
+	c.Assert(err, ErrorMatches, `Directory /_unknown_ doesn't exist`)
+
+	var nilDepot *Depot
+	_, err = nilDepot.FindDuplicates()
+	c.Assert(err, Equals, ErrNilDepot)
+}
+
+func (s *StorageSuite) TestMemCache(c *C) {
+	opts := genStorageOptions(c, dataDir)
+	opts.MemCache = true
+	opts.MemCacheDir = c.MkDir()
+
+	fs, err := NewStorage(opts, index.DefaultOptions)
+
+	c.Assert(fs, NotNil)
+	c.Assert(err, IsNil)
+
+	dp := fs.GetDepot(data.REPO_RELEASE, data.ARCH_X64)
+
+	_, err = dp.GetDB(data.DB_PRIMARY)
+	c.Assert(err, IsNil)
+
+	memDir := dp.memDir
+
+	c.Assert(memDir, Not(Equals), "")
+	c.Assert(dp.GetDBFilePath(data.DB_PRIMARY), Equals, memDir+"/primary.sqlite")
+	c.Assert(fsutil.IsExist(memDir+"/primary.sqlite"), Equals, true)
+	c.Assert(fsutil.IsExist(opts.CacheDir+"/release-x86_64-primary.sqlite"), Equals, false)
+
+	c.Assert(memDirs[memDir], Equals, true)
+
+	c.Assert(dp.InvalidateCache(), IsNil)
+	c.Assert(dp.memDir, Equals, "")
+	c.Assert(fsutil.IsExist(memDir), Equals, false)
+	c.Assert(memDirs[memDir], Equals, false)
+
+	opts.MemCacheMaxSize = 1
+
+	_, err = dp.GetDB(data.DB_PRIMARY)
+	c.Assert(err, IsNil)
+	c.Assert(dp.GetDBFilePath(data.DB_PRIMARY), Equals, opts.CacheDir+"/release-x86_64-primary.sqlite")
+
+	memDir = dp.memDir
+
+	c.Assert(fsutil.IsExist(memDir), Equals, true)
+
+	RemoveMemCacheDirs()
+
+	c.Assert(fsutil.IsExist(memDir), Equals, false)
+	c.Assert(memDirs, HasLen, 0)
+
+	c.Assert(opts.GetMemCacheMaxSize(), Equals, uint64(1))
+	opts.MemCacheMaxSize = 0
+	c.Assert(opts.GetMemCacheMaxSize(), Equals, uint64(MEM_CACHE_MAX_SIZE))
+
+	c.Assert(opts.GetMemCacheDir(), Not(Equals), "")
+	opts.MemCacheDir = ""
+	c.Assert(opts.GetMemCacheDir(), Not(Equals), "")
+}
+
 func (s *StorageSuite) TestDepotIsDBCached(c *C) {
 	fs, err := NewStorage(genStorageOptions(c, dataDir), index.DefaultOptions)
 
@@ -881,6 +2011,88 @@ func (s *StorageSuite) TestDepotCacheDB(c *C) {
 	dp.dataDir = origDataDir
 }
 
+func (s *StorageSuite) TestDepotIsIndexOutdated(c *C) {
+	fs, err := NewStorage(genStorageOptions(c, ""), index.DefaultOptions)
+
+	c.Assert(fs, NotNil)
+	c.Assert(err, IsNil)
+
+	err = fs.Initialize(defRepos, []string{data.ARCH_X64})
+	c.Assert(err, IsNil)
+
+	dp := fs.GetDepot(data.REPO_TESTING, data.ARCH_X64)
+
+	c.Assert(dp, NotNil)
+	c.Assert(dp.IsIndexOutdated(), Equals, true)
+
+	stats, err := dp.Reindex(context.Background(), false, nil)
+	c.Assert(err, IsNil)
+	c.Assert(stats, NotNil)
+	c.Assert(fsutil.IsExist(dp.getReindexMarkPath()), Equals, true)
+
+	markTime := time.Now().Add(time.Hour)
+	os.Chtimes(dp.getReindexMarkPath(), markTime, markTime)
+
+	c.Assert(dp.IsIndexOutdated(), Equals, false)
+
+	stats, err = dp.Reindex(context.Background(), false, nil)
+	c.Assert(err, IsNil)
+	c.Assert(stats, IsNil)
+
+	origIndexOptions := dp.indexOptions
+	dp.indexOptions = &index.Options{CompressType: index.COMPRESSION_XZ}
+
+	c.Assert(dp.IsIndexOutdated(), Equals, true)
+
+	dp.indexOptions = origIndexOptions
+
+	c.Assert(dp.IsIndexOutdated(), Equals, false)
+
+	groupFile := c.MkDir() + "/comps.xml"
+	c.Assert(fsutil.CopyFile("../../../testdata/comps.xml", groupFile, 0644), IsNil)
+
+	dp.indexOptions = origIndexOptions.Clone()
+	dp.indexOptions.GroupFile = groupFile
+	dp.markReindexed()
+	os.Chtimes(dp.getReindexMarkPath(), markTime, markTime)
+
+	c.Assert(dp.IsIndexOutdated(), Equals, false)
+
+	// Groupfile modified in place
+	c.Assert(os.WriteFile(groupFile, []byte("<comps></comps>"), 0644), IsNil)
+	c.Assert(dp.IsIndexOutdated(), Equals, true)
+
+	dp.indexOptions = origIndexOptions
+	dp.markReindexed()
+	os.Chtimes(dp.getReindexMarkPath(), markTime, markTime)
+
+	c.Assert(dp.IsIndexOutdated(), Equals, false)
+
+	// Damaged metadata
+	metaIndex, err := dp.GetMetaIndex()
+	c.Assert(err, IsNil)
+
+	missingMeta := joinPath(dp.dataDir, metaIndex.Get(data.DB_PRIMARY).Location.HREF)
+	metaData, err := os.ReadFile(missingMeta)
+	c.Assert(err, IsNil)
+	c.Assert(os.Remove(missingMeta), IsNil)
+
+	c.Assert(dp.IsIndexOutdated(), Equals, true)
+
+	c.Assert(os.WriteFile(missingMeta, metaData, 0644), IsNil)
+
+	c.Assert(dp.AddPackage("../../../testdata/test-package-1.0.0-0.el7.x86_64.rpm"), IsNil)
+
+	pkgTime := markTime.Add(time.Minute)
+	os.Chtimes(dp.GetPackagePath("test-package-1.0.0-0.el7.x86_64.rpm"), pkgTime, pkgTime)
+
+	c.Assert(dp.IsIndexOutdated(), Equals, true)
+
+	os.Remove(dp.getReindexMarkPath())
+
+	c.Assert(dp.IsIndexOutdated(), Equals, true)
+}
+
 func (s *StorageSuite) TestDepotGetMetaIndex(c *C) {
 	fs, err := NewStorage(genStorageOptions(c, dataDir), index.DefaultOptions)
 
@@ -927,7 +2139,8 @@ func (s *StorageSuite) TestNilDepot(c *C) {
 	var d *Depot
 	// var err error
 
-	c.Assert(d.Reindex(true), Equals, ErrNilDepot)
+	_, err := d.Reindex(context.Background(), true, nil)
+	c.Assert(err, Equals, ErrNilDepot)
 	c.Assert(d.AddPackage("test.rpm"), ErrorMatches, "Can't add package to storage depot: Can't find depot for given repository or architecture")
 	c.Assert(d.RemovePackage("test.rpm"), ErrorMatches, "Can't remove package from storage depot: Can't find depot for given repository or architecture")
 	c.Assert(d.GetPackagePath("test.rpm"), Equals, "")
@@ -940,18 +2153,23 @@ func (s *StorageSuite) TestNilDepot(c *C) {
 	c.Assert(d.CacheDB("test"), ErrorMatches, "Can't cache DB: Can't find depot for given repository or architecture")
 	c.Assert(d.OpenDB("test"), Equals, ErrNilDepot)
 	c.Assert(d.GetMetaIndexPath(), Equals, "")
+	c.Assert(d.IsIndexOutdated(), Equals, true)
+	c.Assert(d.getReindexMarkPath(), Equals, "")
 	c.Assert(d.GetDBFilePath("test"), Equals, "")
-	c.Assert(d.copyFile("test", "test"), ErrorMatches, "Can't change package attributes: Can't find depot for given repository or architecture")
+	c.Assert(d.copyFile("test", "test", "", nil), ErrorMatches, "Can't change package attributes: Can't find depot for given repository or architecture")
 	c.Assert(d.removePackageDir("test"), Equals, ErrNilDepot)
 	c.Assert(d.getPackageDir("test"), Equals, "")
 
-	_, err := d.GetDB("test")
+	_, err = d.GetDB("test")
 	c.Assert(err, Equals, ErrNilDepot)
 
 	_, err = d.GetMetaIndex()
 	c.Assert(err, Equals, ErrNilDepot)
 
-	_, err = d.makePackageDir("test")
+	_, err = d.FindMissingMetaFiles()
+	c.Assert(err, Equals, ErrNilDepot)
+
+	_, err = d.makePackageDir("test", nil)
 	c.Assert(err, ErrorMatches, "Can't create directory for package: Can't find depot for given repository or architecture")
 }
 
@@ -961,14 +2179,42 @@ func (s *StorageSuite) TestFuncs(c *C) {
 
 	c.Assert(filelistGlobberFunc("a/e", "a", "b/c/d", 1), Equals, true)
 	c.Assert(filelistGlobberFunc("a/b", "a", "b/c/d", 1), Equals, false)
+
+	ok, err := regexpFunc("^test-[0-9]+$", "test-123")
+	c.Assert(err, IsNil)
+	c.Assert(ok, Equals, true)
+
+	ok, err = regexpFunc("^test-[0-9]+$", "test-abc")
+	c.Assert(err, IsNil)
+	c.Assert(ok, Equals, false)
+
+	_, err = regexpFunc("^test-[0-9+$", "test-123")
+	c.Assert(err, NotNil)
+
+	fs, err := NewStorage(genStorageOptions(c, dataDir), index.DefaultOptions)
+
+	c.Assert(fs, NotNil)
+	c.Assert(err, IsNil)
+
+	db, err := fs.GetDB(data.REPO_RELEASE, data.ARCH_X64, data.DB_PRIMARY)
+
+	c.Assert(db, NotNil)
+	c.Assert(err, IsNil)
+
+	var count int
+
+	err = db.QueryRow(`SELECT count(*) FROM packages WHERE name REGEXP ".+";`).Scan(&count)
+
+	c.Assert(err, IsNil)
+	c.Assert(count, Not(Equals), 0)
 }
 
 // ////////////////////////////////////////////////////////////////////////////////// //
 
 func genStorageOptions(c *C, dataDir string) *Options {
 	if dataDir == "" {
-		return &Options{c.MkDir() + "/testrepo", c.MkDir(), false, "", "", 0, 0}
+		return &Options{DataDir: c.MkDir() + "/testrepo", CacheDir: c.MkDir()}
 	}
 
-	return &Options{dataDir, c.MkDir(), false, "", "", 0, 0}
+	return &Options{DataDir: dataDir, CacheDir: c.MkDir()}
 }
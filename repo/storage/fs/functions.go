@@ -11,7 +11,9 @@ import (
 	"database/sql"
 	"fmt"
 	"path"
+	"regexp"
 	"strings"
+	"sync"
 
 	sqlite3 "github.com/mattn/go-sqlite3"
 )
@@ -30,6 +32,10 @@ type customFunc struct {
 var customFuncs = map[string][]*customFunc{}
 var hasCustomDriver = map[string]bool{}
 
+// regexpCache contains compiled regular expressions used by regexp function
+var regexpCache = map[string]*regexp.Regexp{}
+var regexpCacheMx = &sync.Mutex{}
+
 // ////////////////////////////////////////////////////////////////////////////////// //
 
 // RegisterFunc registers new custom function for given DB type
@@ -86,3 +92,26 @@ func filelistGlobberFunc(glob, dir, files string, isNegative int) bool {
 
 	return isNegative == 1
 }
+
+// regexpFunc is implementation of REGEXP operator (X REGEXP Y → regexp(Y, X))
+func regexpFunc(pattern, value string) (bool, error) {
+	regexpCacheMx.Lock()
+	re, ok := regexpCache[pattern]
+
+	if !ok {
+		var err error
+
+		re, err = regexp.Compile(pattern)
+
+		if err != nil {
+			regexpCacheMx.Unlock()
+			return false, fmt.Errorf("Invalid regular expression %q: %w", pattern, err)
+		}
+
+		regexpCache[pattern] = re
+	}
+
+	regexpCacheMx.Unlock()
+
+	return re.MatchString(value), nil
+}
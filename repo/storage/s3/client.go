@@ -0,0 +1,443 @@
+package s3
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2024 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+const (
+	SIGN_ALGORITHM = "AWS4-HMAC-SHA256"
+	SIGN_SERVICE   = "s3"
+	EMPTY_SHA256   = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+)
+
+// DEFAULT_TIMEOUT is default timeout for requests to S3 API
+const DEFAULT_TIMEOUT = 15 * time.Minute
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Client is minimal S3 API client
+type Client struct {
+	Endpoint  string // Endpoint URL (e.g. https://s3.amazonaws.com)
+	Region    string // Region name
+	Bucket    string // Bucket name
+	AccessKey string // Access key ID
+	SecretKey string // Secret access key
+
+	HTTP *http.Client // HTTP client (client with DEFAULT_TIMEOUT is used if nil)
+}
+
+// Object contains basic info about object in bucket
+type Object struct {
+	Key     string
+	Size    int64
+	ModTime time.Time
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// listBucketResult is ListObjectsV2 response
+type listBucketResult struct {
+	Contents []struct {
+		Key          string    `xml:"Key"`
+		Size         int64     `xml:"Size"`
+		LastModified time.Time `xml:"LastModified"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+// errorResponse is S3 API error response
+type errorResponse struct {
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// ErrNotFound is returned if object doesn't exist
+var ErrNotFound = fmt.Errorf("Object doesn't exist")
+
+// nowFunc returns current time (used for signing)
+var nowFunc = time.Now
+
+// defaultHTTPClient is HTTP client used if client doesn't have custom one
+var defaultHTTPClient = &http.Client{Timeout: DEFAULT_TIMEOUT}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// PutObject uploads file to bucket
+func (c *Client) PutObject(ctx context.Context, key, file string) error {
+	payloadHash, err := getFileHash(file)
+
+	if err != nil {
+		return fmt.Errorf("Can't calculate file checksum: %w", err)
+	}
+
+	fd, err := os.Open(file)
+
+	if err != nil {
+		return err
+	}
+
+	defer fd.Close()
+
+	info, err := fd.Stat()
+
+	if err != nil {
+		return err
+	}
+
+	req, err := c.newRequest(ctx, http.MethodPut, key, nil, fd)
+
+	if err != nil {
+		return err
+	}
+
+	req.ContentLength = info.Size()
+
+	resp, err := c.do(req, payloadHash)
+
+	if err != nil {
+		return fmt.Errorf("Can't upload object %q: %w", key, err)
+	}
+
+	resp.Body.Close()
+
+	return nil
+}
+
+// GetObject downloads object from bucket to given file
+func (c *Client) GetObject(ctx context.Context, key, file string) error {
+	body, err := c.OpenObject(ctx, key)
+
+	if err != nil {
+		return err
+	}
+
+	defer body.Close()
+
+	tmpFile := file + ".part"
+	fd, err := os.OpenFile(tmpFile, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(fd, body)
+
+	fd.Close()
+
+	if err != nil {
+		os.Remove(tmpFile)
+		return fmt.Errorf("Can't download object %q: %w", key, err)
+	}
+
+	return os.Rename(tmpFile, file)
+}
+
+// OpenObject opens object in bucket for reading
+func (c *Client) OpenObject(ctx context.Context, key string) (io.ReadCloser, error) {
+	req, err := c.newRequest(ctx, http.MethodGet, key, nil, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.do(req, EMPTY_SHA256)
+
+	if err != nil {
+		return nil, fmt.Errorf("Can't download object %q: %w", key, err)
+	}
+
+	return resp.Body, nil
+}
+
+// DeleteObject removes object from bucket
+func (c *Client) DeleteObject(ctx context.Context, key string) error {
+	req, err := c.newRequest(ctx, http.MethodDelete, key, nil, nil)
+
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.do(req, EMPTY_SHA256)
+
+	if err != nil {
+		return fmt.Errorf("Can't delete object %q: %w", key, err)
+	}
+
+	resp.Body.Close()
+
+	return nil
+}
+
+// HeadObject returns info about object in bucket
+func (c *Client) HeadObject(ctx context.Context, key string) (*Object, error) {
+	req, err := c.newRequest(ctx, http.MethodHead, key, nil, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.do(req, EMPTY_SHA256)
+
+	if err != nil {
+		return nil, fmt.Errorf("Can't get info about object %q: %w", key, err)
+	}
+
+	resp.Body.Close()
+
+	modTime, _ := http.ParseTime(resp.Header.Get("Last-Modified"))
+
+	return &Object{Key: key, Size: resp.ContentLength, ModTime: modTime}, nil
+}
+
+// ListObjects returns list of objects with given prefix
+func (c *Client) ListObjects(ctx context.Context, prefix string) ([]Object, error) {
+	var result []Object
+	var token string
+
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+
+		req, err := c.newRequest(ctx, http.MethodGet, "", query, nil)
+
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := c.do(req, EMPTY_SHA256)
+
+		if err != nil {
+			return nil, fmt.Errorf("Can't list objects with prefix %q: %w", prefix, err)
+		}
+
+		list := &listBucketResult{}
+		err = xml.NewDecoder(resp.Body).Decode(list)
+
+		resp.Body.Close()
+
+		if err != nil {
+			return nil, fmt.Errorf("Can't decode objects list: %w", err)
+		}
+
+		for _, obj := range list.Contents {
+			result = append(result, Object{obj.Key, obj.Size, obj.LastModified})
+		}
+
+		if !list.IsTruncated || list.NextContinuationToken == "" {
+			break
+		}
+
+		token = list.NextContinuationToken
+	}
+
+	return result, nil
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// newRequest creates new path-style request to the bucket
+func (c *Client) newRequest(ctx context.Context, method, key string, query url.Values, body io.Reader) (*http.Request, error) {
+	reqURL := strings.TrimRight(c.Endpoint, "/") + "/" + uriEncode(c.Bucket, false)
+
+	if key != "" {
+		reqURL += "/" + uriEncode(key, false)
+	}
+
+	if len(query) != 0 {
+		reqURL += "?" + getCanonicalQuery(query)
+	}
+
+	return http.NewRequestWithContext(ctx, method, reqURL, body)
+}
+
+// do signs and sends request
+func (c *Client) do(req *http.Request, payloadHash string) (*http.Response, error) {
+	c.sign(req, payloadHash, nowFunc().UTC())
+
+	httpClient := c.HTTP
+
+	if httpClient == nil {
+		httpClient = defaultHTTPClient
+	}
+
+	resp, err := httpClient.Do(req)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound && req.Method == http.MethodHead {
+		return nil, ErrNotFound
+	}
+
+	apiErr := &errorResponse{}
+
+	if xml.NewDecoder(resp.Body).Decode(apiErr) == nil && apiErr.Code != "" {
+		if apiErr.Code == "NoSuchKey" {
+			return nil, ErrNotFound
+		}
+
+		return nil, fmt.Errorf("%s (%s)", apiErr.Message, apiErr.Code)
+	}
+
+	return nil, fmt.Errorf("Server returned status code %d", resp.StatusCode)
+}
+
+// sign adds AWS Signature Version 4 headers to request
+func (c *Client) sign(req *http.Request, payloadHash string, t time.Time) {
+	amzDate := t.Format("20060102T150405Z")
+	scope := t.Format("20060102") + "/" + c.Region + "/" + SIGN_SERVICE + "/aws4_request"
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{"host": req.URL.Host}
+
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+
+	var headerNames []string
+
+	for name := range headers {
+		headerNames = append(headerNames, name)
+	}
+
+	sort.Strings(headerNames)
+
+	var canonicalHeaders strings.Builder
+
+	for _, name := range headerNames {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+
+	signedHeaders := strings.Join(headerNames, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		getCanonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	stringToSign := strings.Join([]string{
+		SIGN_ALGORITHM, amzDate, scope, hashSHA256([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+c.SecretKey), t.Format("20060102"))
+	key = hmacSHA256(key, c.Region)
+	key = hmacSHA256(key, SIGN_SERVICE)
+	key = hmacSHA256(key, "aws4_request")
+
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"%s Credential=%s/%s,SignedHeaders=%s,Signature=%s",
+		SIGN_ALGORITHM, c.AccessKey, scope, signedHeaders, signature,
+	))
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// getCanonicalQuery returns query string in canonical form
+func getCanonicalQuery(query url.Values) string {
+	var result []string
+
+	for name, values := range query {
+		for _, value := range values {
+			result = append(result, uriEncode(name, true)+"="+uriEncode(value, true))
+		}
+	}
+
+	sort.Strings(result)
+
+	return strings.Join(result, "&")
+}
+
+// uriEncode encodes string using S3 URI encoding rules
+func uriEncode(s string, encodeSlash bool) string {
+	var buf strings.Builder
+
+	for _, b := range []byte(s) {
+		switch {
+		case b >= 'A' && b <= 'Z', b >= 'a' && b <= 'z', b >= '0' && b <= '9',
+			b == '-', b == '_', b == '.', b == '~':
+			buf.WriteByte(b)
+		case b == '/' && !encodeSlash:
+			buf.WriteByte(b)
+		default:
+			fmt.Fprintf(&buf, "%%%02X", b)
+		}
+	}
+
+	return buf.String()
+}
+
+// getFileHash returns SHA-256 hash of file
+func getFileHash(file string) (string, error) {
+	fd, err := os.Open(file)
+
+	if err != nil {
+		return "", err
+	}
+
+	defer fd.Close()
+
+	hasher := sha256.New()
+
+	_, err = io.Copy(hasher, fd)
+
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// hashSHA256 returns hex-encoded SHA-256 hash of data
+func hashSHA256(data []byte) string {
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:])
+}
+
+// hmacSHA256 calculates HMAC-SHA256
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,824 @@
+package s3
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2024 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/essentialkaos/ek/v13/fsutil"
+	"github.com/essentialkaos/ek/v13/path"
+
+	"github.com/essentialkaos/rep/v3/repo/data"
+	"github.com/essentialkaos/rep/v3/repo/index"
+	"github.com/essentialkaos/rep/v3/repo/storage"
+	"github.com/essentialkaos/rep/v3/repo/storage/fs"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Storage is repository storage which keeps data in S3-compatible object storage.
+// Local filesystem storage is used as a mirror for running createrepo_c and
+// caching SQLite databases.
+type Storage struct {
+	local       *fs.Storage     // Local mirror
+	client      *Client         // S3 API client
+	s3Options   *Options        // S3 options
+	dataOptions *fs.Options     // Local mirror options
+	synced      map[string]bool // Map [repo-arch] → [metadata synced]
+	syncedLock  sync.RWMutex    // Lock for synced map
+}
+
+// Options is S3 storage options
+type Options struct {
+	Endpoint  string // Endpoint URL
+	Region    string // Region name
+	Bucket    string // Bucket name
+	Prefix    string // Prefix for all objects keys
+	AccessKey string // Access key ID
+	SecretKey string // Secret access key
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// validate storage interface
+var _ storage.Storage = (*Storage)(nil)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// NewStorage creates new S3 storage
+func NewStorage(s3Options *Options, dataOptions *fs.Options, indexOptions *index.Options) (*Storage, error) {
+	if s3Options == nil {
+		return nil, fmt.Errorf("Can't create storage: S3 options cannot be nil")
+	}
+
+	err := s3Options.Validate()
+
+	if err != nil {
+		return nil, fmt.Errorf("Can't create storage: %w", err)
+	}
+
+	local, err := fs.NewStorage(dataOptions, indexOptions)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &Storage{
+		local:       local,
+		s3Options:   s3Options,
+		dataOptions: dataOptions,
+		synced:      make(map[string]bool),
+		client: &Client{
+			Endpoint:  s3Options.Endpoint,
+			Region:    s3Options.Region,
+			Bucket:    s3Options.Bucket,
+			AccessKey: s3Options.AccessKey,
+			SecretKey: s3Options.SecretKey,
+		},
+	}, nil
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Validate validates S3 storage options
+func (o *Options) Validate() error {
+	switch {
+	case o.Endpoint == "":
+		return fmt.Errorf("S3 endpoint can't be empty")
+	case !strings.HasPrefix(o.Endpoint, "http://") && !strings.HasPrefix(o.Endpoint, "https://"):
+		return fmt.Errorf("S3 endpoint must be an HTTP or HTTPS URL")
+	case o.Region == "":
+		return fmt.Errorf("S3 region can't be empty")
+	case o.Bucket == "":
+		return fmt.Errorf("S3 bucket name can't be empty")
+	case o.AccessKey == "":
+		return fmt.Errorf("S3 access key can't be empty")
+	case o.SecretKey == "":
+		return fmt.Errorf("S3 secret key can't be empty")
+	}
+
+	return nil
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Initialize initializes the new repository storage and creates all required directories
+func (s *Storage) Initialize(repoList, archList []string) error {
+	return s.local.Initialize(repoList, archList)
+}
+
+// AddPackage adds package file to the given repository
+// Important: This method DO NOT run repository reindex
+func (s *Storage) AddPackage(repo, rpmFilePath string) error {
+	err := s.local.AddPackage(repo, rpmFilePath)
+
+	if err != nil {
+		return err
+	}
+
+	err = s.uploadPackage(context.Background(), repo, path.Base(rpmFilePath))
+
+	if err != nil {
+		return fmt.Errorf("Can't add package to storage: %w", err)
+	}
+
+	return nil
+}
+
+// AddPackages adds package files to the given repository and returns paths
+// (relative to repository directory) of added files
+// Important: This method DO NOT run repository reindex
+func (s *Storage) AddPackages(repo string, rpmFilePaths []string) ([]string, error) {
+	added, addErr := s.local.AddPackages(repo, rpmFilePaths)
+
+	var uploaded []string
+	var errs []error
+
+	for _, relPath := range added {
+		pkgFile := s.getPackageFile(repo, relPath)
+		err := s.client.PutObject(context.Background(), s.getKey(pkgFile), pkgFile)
+
+		if err != nil {
+			// Local mirror must not contain files which are absent in bucket
+			os.Remove(pkgFile)
+			errs = append(errs, fmt.Errorf("%s: %w", path.Base(pkgFile), err))
+			continue
+		}
+
+		uploaded = append(uploaded, relPath)
+	}
+
+	return uploaded, errors.Join(append([]error{addErr}, errs...)...)
+}
+
+// SyncNoarchPackages makes sure that every noarch package file is present in all
+// binary arch depots of the given repository and returns paths (relative to
+// repository directory) of added files
+// Important: This method DO NOT run repository reindex
+func (s *Storage) SyncNoarchPackages(repo string) ([]string, error) {
+	for _, arch := range data.BinArchList {
+		if !s.local.HasArch(repo, arch) {
+			continue
+		}
+
+		err := s.pull(context.Background(), s.getArchDir(repo, arch))
+
+		if err != nil {
+			return nil, fmt.Errorf("Can't sync noarch packages: %w", err)
+		}
+
+		s.setSynced(repo, arch)
+	}
+
+	added, syncErr := s.local.SyncNoarchPackages(repo)
+
+	var uploaded []string
+	var errs []error
+
+	for _, relPath := range added {
+		pkgFile := s.getPackageFile(repo, relPath)
+		err := s.client.PutObject(context.Background(), s.getKey(pkgFile), pkgFile)
+
+		if err != nil {
+			// Local mirror must not contain files which are absent in bucket
+			os.Remove(pkgFile)
+			errs = append(errs, fmt.Errorf("%s: %w", path.Base(pkgFile), err))
+			continue
+		}
+
+		uploaded = append(uploaded, relPath)
+	}
+
+	return uploaded, errors.Join(append([]error{syncErr}, errs...)...)
+}
+
+// RemovePackage removes package with given relative path from the given repository
+// Important: This method DO NOT run repository reindex
+func (s *Storage) RemovePackage(repo, arch, rpmFileRelPath string) error {
+	pkgFile := s.local.GetPackagePath(repo, arch, rpmFileRelPath)
+	err := s.fetchPackage(context.Background(), pkgFile)
+
+	if err != nil {
+		return fmt.Errorf("Can't remove package from storage: %w", err)
+	}
+
+	err = s.local.RemovePackage(repo, arch, rpmFileRelPath)
+
+	if err != nil {
+		return err
+	}
+
+	err = s.client.DeleteObject(context.Background(), s.getKey(pkgFile))
+
+	if err != nil {
+		return fmt.Errorf("Can't remove package from storage: %w", err)
+	}
+
+	return nil
+}
+
+// CopyPackage copies file from one repository to another
+// Important: This method DO NOT run repository reindex
+func (s *Storage) CopyPackage(fromRepo, toRepo, arch, rpmFileRelPath string) error {
+	err := s.fetchPackage(context.Background(), s.local.GetPackagePath(fromRepo, arch, rpmFileRelPath))
+
+	if err != nil {
+		return fmt.Errorf("Can't copy package in storage: %w", err)
+	}
+
+	err = s.local.CopyPackage(fromRepo, toRepo, arch, rpmFileRelPath)
+
+	if err != nil {
+		return err
+	}
+
+	err = s.uploadPackage(context.Background(), toRepo, path.Base(rpmFileRelPath))
+
+	if err != nil {
+		return fmt.Errorf("Can't copy package in storage: %w", err)
+	}
+
+	return nil
+}
+
+// RepairPackageAttrs sets owner and permissions defined in storage options
+// for package file and its directory. S3 objects don't have owner and
+// permissions, so only files in local mirror are repaired.
+func (s *Storage) RepairPackageAttrs(repo, arch, rpmFileRelPath string) error {
+	err := s.fetchPackage(context.Background(), s.local.GetPackagePath(repo, arch, rpmFileRelPath))
+
+	if err != nil {
+		return fmt.Errorf("Can't repair package attributes: %w", err)
+	}
+
+	return s.local.RepairPackageAttrs(repo, arch, rpmFileRelPath)
+}
+
+// Reindex generates index metadata for the given repository and arch
+func (s *Storage) Reindex(ctx context.Context, repo, arch string, full bool, progress chan<- index.Progress) (*index.GenerateStats, error) {
+	if !s.local.HasArch(repo, arch) || arch == data.ARCH_NOARCH {
+		return s.local.Reindex(ctx, repo, arch, full, progress)
+	}
+
+	archDir := s.getArchDir(repo, arch)
+	err := s.pull(ctx, archDir)
+
+	if err != nil {
+		return nil, fmt.Errorf("Can't generate index: %w", err)
+	}
+
+	stats, err := s.local.Reindex(ctx, repo, arch, full, progress)
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.push(ctx, path.Join(archDir, "repodata"))
+
+	if err != nil {
+		return nil, fmt.Errorf("Can't upload index: %w", err)
+	}
+
+	s.setSynced(repo, arch)
+
+	return stats, nil
+}
+
+// IsInitialized returns true if repository already initialized and ready for work
+func (s *Storage) IsInitialized() bool {
+	return s.local.IsInitialized()
+}
+
+// IsEmpty returns true if repository is empty (no packages)
+func (s *Storage) IsEmpty(repo, arch string) bool {
+	return s.local.IsEmpty(repo, arch)
+}
+
+// HasRepo returns true if given repository exists
+func (s *Storage) HasRepo(repo string) bool {
+	return s.local.HasRepo(repo)
+}
+
+// HasArch returns true if repository storage contains directory for specific arch
+func (s *Storage) HasArch(repo, arch string) bool {
+	return s.local.HasArch(repo, arch)
+}
+
+// HasPackage checks if repository contains file with given name
+func (s *Storage) HasPackage(repo, arch, rpmFileName string) bool {
+	return s.local.HasPackage(repo, arch, rpmFileName)
+}
+
+// GetPackagePath returns full path to package RPM file. If package file is
+// absent in local mirror, it is downloaded from bucket.
+func (s *Storage) GetPackagePath(repo, arch, rpmFileRelPath string) string {
+	pkgFile := s.local.GetPackagePath(repo, arch, rpmFileRelPath)
+
+	if s.fetchPackage(context.Background(), pkgFile) != nil {
+		return ""
+	}
+
+	return pkgFile
+}
+
+// OpenPackage opens package file for reading. If package file is absent in
+// local mirror, it is read directly from bucket.
+func (s *Storage) OpenPackage(repo, arch, rpmFileRelPath string) (io.ReadCloser, error) {
+	pkgFile := s.local.GetPackagePath(repo, arch, rpmFileRelPath)
+
+	if pkgFile == "" || fsutil.IsExist(pkgFile) {
+		return s.local.OpenPackage(repo, arch, rpmFileRelPath)
+	}
+
+	pkgReader, err := s.client.OpenObject(context.Background(), s.getKey(pkgFile))
+
+	if err != nil {
+		return nil, fmt.Errorf("Can't open package: %w", err)
+	}
+
+	return pkgReader, nil
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// GetDB returns connection to SQLite DB
+func (s *Storage) GetDB(repo, arch, dbType string) (*sql.DB, error) {
+	err := s.syncMeta(context.Background(), repo, arch)
+
+	if err != nil {
+		return nil, fmt.Errorf("Can't find DB connection: %w", err)
+	}
+
+	return s.local.GetDB(repo, arch, dbType)
+}
+
+// Query executes SQL query over SQLite DB
+func (s *Storage) Query(ctx context.Context, repo, arch, dbType, query string, args ...any) (*sql.Rows, error) {
+	err := s.syncMeta(ctx, repo, arch)
+
+	if err != nil {
+		return nil, fmt.Errorf("Can't execute query: %w", err)
+	}
+
+	return s.local.Query(ctx, repo, arch, dbType, query, args...)
+}
+
+// GetModTime returns date of repository index modification
+func (s *Storage) GetModTime(repo, arch string) (time.Time, error) {
+	err := s.syncMeta(context.Background(), repo, arch)
+
+	if err != nil {
+		return time.Time{}, fmt.Errorf("Can't check repository index modification date: %w", err)
+	}
+
+	return s.local.GetModTime(repo, arch)
+}
+
+// GetMetaIndexPath returns path to repository index file (repomd.xml)
+func (s *Storage) GetMetaIndexPath(repo, arch string) string {
+	if s.syncMeta(context.Background(), repo, arch) != nil {
+		return ""
+	}
+
+	return s.local.GetMetaIndexPath(repo, arch)
+}
+
+// FindMissingMetaFiles returns list of metadata files referenced in index but
+// missing in the storage
+func (s *Storage) FindMissingMetaFiles(repo, arch string) ([]string, error) {
+	err := s.syncMeta(context.Background(), repo, arch)
+
+	if err != nil {
+		return nil, fmt.Errorf("Can't check metadata files: %w", err)
+	}
+
+	return s.local.FindMissingMetaFiles(repo, arch)
+}
+
+// Verify checks that all packages referenced in index exist in the storage
+// and all packages in the storage are referenced in index
+func (s *Storage) Verify(repo, arch string) ([]error, error) {
+	if s.local.HasArch(repo, arch) && arch != data.ARCH_NOARCH {
+		err := s.pull(context.Background(), s.getArchDir(repo, arch))
+
+		if err != nil {
+			return nil, fmt.Errorf("Can't verify storage: %w", err)
+		}
+
+		s.setSynced(repo, arch)
+	}
+
+	return s.local.Verify(repo, arch)
+}
+
+// FindOrphanPackages returns list of package files which exist in the storage
+// but aren't referenced in index
+func (s *Storage) FindOrphanPackages(repo, arch string) ([]string, error) {
+	if s.local.HasArch(repo, arch) && arch != data.ARCH_NOARCH {
+		err := s.pull(context.Background(), s.getArchDir(repo, arch))
+
+		if err != nil {
+			return nil, fmt.Errorf("Can't find orphan packages: %w", err)
+		}
+
+		s.setSynced(repo, arch)
+	}
+
+	return s.local.FindOrphanPackages(repo, arch)
+}
+
+// FindDuplicates returns names of package files which exist in repository more
+// than once with paths (relative to repository directory) to all their copies
+func (s *Storage) FindDuplicates(repo string) (map[string][]string, error) {
+	for _, arch := range data.ArchList {
+		if arch == data.ARCH_NOARCH || !s.local.HasArch(repo, arch) {
+			continue
+		}
+
+		err := s.pull(context.Background(), s.getArchDir(repo, arch))
+
+		if err != nil {
+			return nil, fmt.Errorf("Can't find duplicates: %w", err)
+		}
+
+		s.setSynced(repo, arch)
+	}
+
+	return s.local.FindDuplicates(repo)
+}
+
+// ReadAuxData reads auxiliary data (e.g. packages tags) with given name. If
+// data doesn't exist, nil is returned.
+func (s *Storage) ReadAuxData(name string) ([]byte, error) {
+	dataFile := s.local.GetAuxDataPath(name)
+
+	if dataFile == "" {
+		return s.local.ReadAuxData(name)
+	}
+
+	err := s.client.GetObject(context.Background(), s.getKey(dataFile), dataFile)
+
+	switch {
+	case errors.Is(err, ErrNotFound):
+		os.Remove(dataFile)
+		return nil, nil
+	case err != nil:
+		return nil, fmt.Errorf("Can't read auxiliary data: %w", err)
+	}
+
+	return s.local.ReadAuxData(name)
+}
+
+// WriteAuxData atomically writes auxiliary data (e.g. packages tags) with
+// given name
+func (s *Storage) WriteAuxData(name string, auxData []byte) error {
+	err := s.local.WriteAuxData(name, auxData)
+
+	if err != nil {
+		return err
+	}
+
+	dataFile := s.local.GetAuxDataPath(name)
+	err = s.client.PutObject(context.Background(), s.getKey(dataFile), dataFile)
+
+	if err != nil {
+		return fmt.Errorf("Can't write auxiliary data: %w", err)
+	}
+
+	return nil
+}
+
+// InvalidateCache invalidates cache and removes SQLite files from cache directory
+func (s *Storage) InvalidateCache() error {
+	s.syncedLock.Lock()
+	s.synced = make(map[string]bool)
+	s.syncedLock.Unlock()
+
+	return s.local.InvalidateCache()
+}
+
+// IsCacheValid returns true if cache is valid
+func (s *Storage) IsCacheValid(repo, arch string) bool {
+	return s.isSynced(repo, arch) && s.local.IsCacheValid(repo, arch)
+}
+
+// PurgeCache deletes all SQLite files from cache directory
+func (s *Storage) PurgeCache() error {
+	return s.local.PurgeCache()
+}
+
+// PurgeCacheFor deletes SQLite files for given repository and arch from cache
+// directory
+func (s *Storage) PurgeCacheFor(repo, arch string) error {
+	return s.local.PurgeCacheFor(repo, arch)
+}
+
+// WarmupCache warmups cache
+func (s *Storage) WarmupCache(repo, arch string) error {
+	err := s.syncMeta(context.Background(), repo, arch)
+
+	if err != nil {
+		return fmt.Errorf("Can't warmup cache: %w", err)
+	}
+
+	return s.local.WarmupCache(repo, arch)
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// uploadPackage uploads package with given name from all arch directories of
+// local mirror. If upload fails, package is removed from local mirror and
+// already uploaded copies are removed from bucket.
+func (s *Storage) uploadPackage(ctx context.Context, repo, rpmFileName string) error {
+	var pkgFiles, uploaded []string
+
+	for _, arch := range data.ArchList {
+		if arch == data.ARCH_NOARCH || !s.local.HasPackage(repo, arch, rpmFileName) {
+			continue
+		}
+
+		pkgFiles = append(pkgFiles, s.local.GetPackagePath(repo, arch, rpmFileName))
+	}
+
+	for _, pkgFile := range pkgFiles {
+		err := s.client.PutObject(ctx, s.getKey(pkgFile), pkgFile)
+
+		if err == nil {
+			uploaded = append(uploaded, pkgFile)
+			continue
+		}
+
+		for _, file := range uploaded {
+			s.client.DeleteObject(ctx, s.getKey(file))
+		}
+
+		for _, file := range pkgFiles {
+			os.Remove(file)
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// fetchPackage downloads package file from bucket if it is absent in local
+// mirror (e.g. package was added on another host)
+func (s *Storage) fetchPackage(ctx context.Context, pkgFile string) error {
+	if pkgFile == "" || fsutil.IsExist(pkgFile) {
+		return nil
+	}
+
+	err := os.MkdirAll(path.Dir(pkgFile), s.dataOptions.GetDirPerms())
+
+	if err != nil {
+		return err
+	}
+
+	err = s.client.GetObject(ctx, s.getKey(pkgFile), pkgFile)
+
+	if err != nil {
+		return err
+	}
+
+	return os.Chmod(pkgFile, s.dataOptions.GetFilePerms())
+}
+
+// syncMeta downloads repository metadata from bucket once per storage instance
+func (s *Storage) syncMeta(ctx context.Context, repo, arch string) error {
+	if s.isSynced(repo, arch) || !s.local.HasArch(repo, arch) || arch == data.ARCH_NOARCH {
+		return nil
+	}
+
+	err := s.pull(ctx, path.Join(s.getArchDir(repo, arch), "repodata"))
+
+	if err != nil {
+		return err
+	}
+
+	s.setSynced(repo, arch)
+
+	return nil
+}
+
+// isSynced returns true if metadata for given repository and arch is already
+// synced with bucket
+func (s *Storage) isSynced(repo, arch string) bool {
+	s.syncedLock.RLock()
+	defer s.syncedLock.RUnlock()
+
+	return s.synced[repo+"-"+arch]
+}
+
+// setSynced marks metadata for given repository and arch as synced
+func (s *Storage) setSynced(repo, arch string) {
+	s.syncedLock.Lock()
+	s.synced[repo+"-"+arch] = true
+	s.syncedLock.Unlock()
+}
+
+// pull downloads new and changed objects from bucket to local directory and
+// removes local files which are absent in bucket
+func (s *Storage) pull(ctx context.Context, dir string) error {
+	objects, err := s.client.ListObjects(ctx, s.getKey(dir)+"/")
+
+	if err != nil {
+		return err
+	}
+
+	remoteFiles := make(map[string]bool)
+
+	for _, obj := range objects {
+		file := s.getFile(obj.Key)
+		remoteFiles[file] = true
+
+		if isFileActual(file, obj) {
+			continue
+		}
+
+		err = os.MkdirAll(path.Dir(file), s.dataOptions.GetDirPerms())
+
+		if err != nil {
+			return err
+		}
+
+		err = s.client.GetObject(ctx, obj.Key, file)
+
+		if err != nil {
+			return err
+		}
+
+		err = os.Chmod(file, s.dataOptions.GetFilePerms())
+
+		if err != nil {
+			return err
+		}
+
+		// Packages must keep the download time as mtime because it's used
+		// for checking if index is outdated
+		if !isPackageFile(file) {
+			err = os.Chtimes(file, obj.ModTime, obj.ModTime)
+
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, file := range listFiles(dir) {
+		if remoteFiles[file] || !isSyncedFile(file) {
+			continue
+		}
+
+		err = os.Remove(file)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// push uploads files from local directory to bucket and removes objects which
+// are absent in local directory
+func (s *Storage) push(ctx context.Context, dir string) error {
+	var indexFile string
+
+	localKeys := make(map[string]bool)
+
+	for _, file := range listFiles(dir) {
+		key := s.getKey(file)
+		localKeys[key] = true
+
+		// repomd.xml must be uploaded last, so clients never see index which
+		// refers to missing files
+		if path.Base(file) == "repomd.xml" {
+			indexFile = file
+			continue
+		}
+
+		err := s.client.PutObject(ctx, key, file)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	if indexFile != "" {
+		err := s.client.PutObject(ctx, s.getKey(indexFile), indexFile)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	objects, err := s.client.ListObjects(ctx, s.getKey(dir)+"/")
+
+	if err != nil {
+		return err
+	}
+
+	for _, obj := range objects {
+		if localKeys[obj.Key] {
+			continue
+		}
+
+		err = s.client.DeleteObject(ctx, obj.Key)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// getArchDir returns path to local directory with packages for given arch
+func (s *Storage) getArchDir(repo, arch string) string {
+	return s.dataOptions.GetDepotDir(repo, arch)
+}
+
+// getPackageFile returns path to local package file for given path relative
+// to repository directory
+func (s *Storage) getPackageFile(repo, relPath string) string {
+	archDir, filePath, _ := strings.Cut(relPath, "/")
+
+	for _, arch := range data.ArchList {
+		if arch != data.ARCH_NOARCH && data.SupportedArchs[arch].Dir == archDir {
+			return path.Join(s.getArchDir(repo, arch), filePath)
+		}
+	}
+
+	return path.Join(s.dataOptions.DataDir, repo, relPath)
+}
+
+// getKey returns object key for given local file
+func (s *Storage) getKey(file string) string {
+	relPath := strings.TrimPrefix(file, path.Clean(s.dataOptions.DataDir)+"/")
+	return strings.TrimLeft(path.Join(s.s3Options.Prefix, relPath), "/")
+}
+
+// getFile returns path to local file for given object key
+func (s *Storage) getFile(key string) string {
+	prefix := strings.Trim(s.s3Options.Prefix, "/")
+
+	if prefix != "" {
+		key = strings.TrimPrefix(key, prefix+"/")
+	}
+
+	return path.Join(s.dataOptions.DataDir, key)
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// listFiles returns list of all files in given directory
+func listFiles(dir string) []string {
+	if !fsutil.IsExist(dir) {
+		return nil
+	}
+
+	files := fsutil.ListAllFiles(dir, true)
+	fsutil.ListToAbsolute(dir, files)
+
+	return files
+}
+
+// isFileActual returns true if local file is the same as object in bucket
+func isFileActual(file string, obj Object) bool {
+	if fsutil.GetSize(file) != obj.Size {
+		return false
+	}
+
+	// Package files names contain full version, so we can check only size
+	if isPackageFile(file) {
+		return true
+	}
+
+	modTime, err := fsutil.GetMTime(file)
+
+	return err == nil && !obj.ModTime.After(modTime)
+}
+
+// isSyncedFile returns true if file is package or metadata file
+func isSyncedFile(file string) bool {
+	return isPackageFile(file) || path.Base(path.Dir(file)) == "repodata"
+}
+
+// isPackageFile returns true if file is an RPM package
+func isPackageFile(file string) bool {
+	return strings.HasSuffix(file, ".rpm")
+}
@@ -8,14 +8,23 @@ package storage
 // ////////////////////////////////////////////////////////////////////////////////// //
 
 import (
+	"context"
 	"database/sql"
+	"fmt"
+	"io"
+	"os"
 	"time"
+
+	"github.com/essentialkaos/ek/v13/path"
+
+	"github.com/essentialkaos/rep/v3/repo/index"
 )
 
 // ////////////////////////////////////////////////////////////////////////////////// //
 
 const (
 	TYPE_FS = "fs"
+	TYPE_S3 = "s3"
 )
 
 // ////////////////////////////////////////////////////////////////////////////////// //
@@ -31,6 +40,17 @@ type Storage interface {
 	// Important: This method DO NOT run repository reindex
 	AddPackage(repo, rpmFilePath string) error
 
+	// AddPackages adds package files to the given repository and returns paths
+	// (relative to repository directory) of added files
+	// Important: This method DO NOT run repository reindex
+	AddPackages(repo string, rpmFilePaths []string) ([]string, error)
+
+	// SyncNoarchPackages makes sure that every noarch package file is present in
+	// all binary arch depots of the given repository and returns paths (relative
+	// to repository directory) of added files
+	// Important: This method DO NOT run repository reindex
+	SyncNoarchPackages(repo string) ([]string, error)
+
 	// RemovePackage removes package with given relative path from the given repository
 	// Important: This method DO NOT run repository reindex
 	RemovePackage(repo, arch, rpmFileRelPath string) error
@@ -39,6 +59,10 @@ type Storage interface {
 	// Important: This method DO NOT run repository reindex
 	CopyPackage(fromRepo, toRepo, arch, rpmFileRelPath string) error
 
+	// RepairPackageAttrs sets owner and permissions defined in storage options
+	// for package file and its directory
+	RepairPackageAttrs(repo, arch, rpmFileRelPath string) error
+
 	// IsInitialized returns true if the repository already initialized and ready for work
 	IsInitialized() bool
 
@@ -57,17 +81,53 @@ type Storage interface {
 	// GetPackagePath returns path to package file
 	GetPackagePath(repo, arch, pkg string) string
 
+	// OpenPackage opens package file for reading
+	OpenPackage(repo, arch, rpmFileRelPath string) (io.ReadCloser, error)
+
 	// METADATA & DB --
 
-	// Reindex generates index metadata for the given repository and arch
-	Reindex(repo, arch string, full bool) error
+	// Reindex generates index metadata for the given repository and arch. If
+	// progress channel is not nil, info about generation progress will be sent to it.
+	Reindex(ctx context.Context, repo, arch string, full bool, progress chan<- index.Progress) (*index.GenerateStats, error)
 
 	// GetDB returns connection to SQLite DB
 	GetDB(repo, arch, dbType string) (*sql.DB, error)
 
+	// Query executes SQL query over SQLite DB. Connection used by query can't be
+	// closed by cache invalidation while query is starting. Query can be
+	// interrupted by canceling given context.
+	Query(ctx context.Context, repo, arch, dbType, query string, args ...any) (*sql.Rows, error)
+
 	// GetModTime returns date of repository index modification
 	GetModTime(repo, arch string) (time.Time, error)
 
+	// GetMetaIndexPath returns path to repository index file (repomd.xml)
+	GetMetaIndexPath(repo, arch string) string
+
+	// FindMissingMetaFiles returns list of metadata files referenced in index but
+	// missing in the storage
+	FindMissingMetaFiles(repo, arch string) ([]string, error)
+
+	// Verify checks that all packages referenced in index exist in the storage
+	// and all packages in the storage are referenced in index
+	Verify(repo, arch string) ([]error, error)
+
+	// FindOrphanPackages returns list of package files which exist in the storage
+	// but aren't referenced in index
+	FindOrphanPackages(repo, arch string) ([]string, error)
+
+	// FindDuplicates returns names of package files which exist in repository
+	// more than once with paths to all their copies
+	FindDuplicates(repo string) (map[string][]string, error)
+
+	// ReadAuxData reads auxiliary data (e.g. packages tags) with given name. If
+	// data doesn't exist, nil is returned.
+	ReadAuxData(name string) ([]byte, error)
+
+	// WriteAuxData atomically writes auxiliary data (e.g. packages tags) with
+	// given name
+	WriteAuxData(name string, data []byte) error
+
 	// InvalidateCache invalidates cache
 	InvalidateCache() error
 
@@ -77,8 +137,87 @@ type Storage interface {
 	// PurgeCache deletes all SQLite files from cache directory
 	PurgeCache() error
 
+	// PurgeCacheFor deletes SQLite files for given repository and arch from cache
+	// directory
+	PurgeCacheFor(repo, arch string) error
+
 	// WarmupCache warmups cache
 	WarmupCache(repo, arch string) error
 }
 
 // ////////////////////////////////////////////////////////////////////////////////// //
+
+// Copy copies package file from one storage to another. If source and target
+// storages are the same, the storage CopyPackage method is used. Otherwise,
+// package data is saved to given temporary directory (or to the system temporary
+// directory if it is empty) and added to the target storage.
+// Important: This method DO NOT run repository reindex
+func Copy(src, dst Storage, fromRepo, toRepo, arch, rpmFileRelPath, tmpDir string) error {
+	switch {
+	case src == nil:
+		return fmt.Errorf("Can't copy package between storages: Source storage is nil")
+	case dst == nil:
+		return fmt.Errorf("Can't copy package between storages: Target storage is nil")
+	case rpmFileRelPath == "":
+		return fmt.Errorf("Can't copy package between storages: Path to file can't be empty")
+	}
+
+	if src == dst {
+		return src.CopyPackage(fromRepo, toRepo, arch, rpmFileRelPath)
+	}
+
+	switch {
+	case !src.IsInitialized():
+		return fmt.Errorf("Can't copy package between storages: Source storage is not initialized")
+	case !dst.IsInitialized():
+		return fmt.Errorf("Can't copy package between storages: Target storage is not initialized")
+	}
+
+	pkgReader, err := src.OpenPackage(fromRepo, arch, rpmFileRelPath)
+
+	if err != nil {
+		return fmt.Errorf(
+			"Can't copy package between storages: Can't read package %q from source repository %q: %w",
+			rpmFileRelPath, fromRepo, err,
+		)
+	}
+
+	defer pkgReader.Close()
+
+	stageDir, err := os.MkdirTemp(tmpDir, "rep-")
+
+	if err != nil {
+		return fmt.Errorf("Can't copy package between storages: %w", err)
+	}
+
+	defer os.RemoveAll(stageDir)
+
+	tmpFile := path.Join(stageDir, path.Base(rpmFileRelPath))
+	err = writeFile(tmpFile, pkgReader)
+
+	if err != nil {
+		return fmt.Errorf("Can't copy package between storages: %w", err)
+	}
+
+	return dst.AddPackage(toRepo, tmpFile)
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// writeFile writes all data from given reader to file
+func writeFile(file string, r io.Reader) error {
+	fd, err := os.OpenFile(file, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(fd, r)
+
+	if err != nil {
+		fd.Close()
+		return err
+	}
+
+	return fd.Close()
+}